@@ -18,15 +18,26 @@ module Bosh::Template::Test
       }
     end
 
+    let(:links) do
+      [
+        Link.new(
+          name: 'service-discovery-controller',
+          properties: {
+            'route_emitter_interval_seconds' => 20
+          }
+        )
+      ]
+    end
+
     describe 'handlers.json' do
       let(:template) {job.template('dns/handlers.json')}
 
       it 'creates a dns/handlers.json from properties' do
-        config = JSON.parse(template.render(merged_manifest_properties))
+        config = JSON.parse(template.render(merged_manifest_properties, consumes: links))
         expect(config).to eq([
           {
             'domain' => 'my.internal.app.domain.',
-            'cache' => {'enabled' => false},
+            'cache' => {'enabled' => true},
             'source' => {
               'type' => 'http',
               'url' => 'http://127.0.0.1:8053'
@@ -34,7 +45,7 @@ module Bosh::Template::Test
           },
           {
             'domain' => 'other.internal.app.domain.',
-            'cache' => {'enabled' => false},
+            'cache' => {'enabled' => true},
             'source' => {
               'type' => 'http',
               'url' => 'http://127.0.0.1:8053'
@@ -47,11 +58,11 @@ module Bosh::Template::Test
         let(:merged_manifest_properties) { {} }
 
         it 'should render a json file with default apps.internal domain' do
-          config = JSON.parse(template.render(merged_manifest_properties))
+          config = JSON.parse(template.render(merged_manifest_properties, consumes: links))
           expect(config).to eq([
             {
               'domain' => 'apps.internal.',
-              'cache' => {'enabled' => false},
+              'cache' => {'enabled' => true},
               'source' => {
                 'type' => 'http',
                 'url' => 'http://127.0.0.1:8053'
@@ -61,6 +72,40 @@ module Bosh::Template::Test
         end
       end
 
+      context 'when every TTL is 0' do
+        let(:merged_manifest_properties) do
+          {
+            'ttl_seconds' => 0,
+            'negative_ttl_seconds' => 0
+          }
+        end
+
+        it 'disables the cache' do
+          config = JSON.parse(template.render(merged_manifest_properties, consumes: links))
+          expect(config.map { |handler| handler['cache'] }).to eq([{'enabled' => false}])
+        end
+      end
+
+      context 'when reverse lookups are enabled' do
+        let(:merged_manifest_properties) do
+          {
+            'reverse_lookups' => {
+              'enabled' => true,
+              'overlay_network' => '10.255.0.0/16'
+            }
+          }
+        end
+
+        it 'adds a handler for the reverse zone of the overlay network' do
+          config = JSON.parse(template.render(merged_manifest_properties, consumes: links))
+          expect(config.map { |handler| handler['domain'] }).to eq(['apps.internal.', '255.10.in-addr.arpa.'])
+          expect(config.last['source']).to eq({
+            'type' => 'http',
+            'url' => 'http://127.0.0.1:8053'
+          })
+        end
+      end
+
       context 'when cf_app_sd_disable is true' do
         let(:disabled_manifest_properties) do
         {
@@ -74,5 +119,80 @@ module Bosh::Template::Test
         end
       end
     end
+
+    describe 'config.json' do
+      let(:template) {job.template('config/config.json')}
+      let(:links) do
+        [
+          Link.new(
+            name: 'service-discovery-controller',
+            properties: {
+              'route_emitter_interval_seconds' => 20,
+              'port' => 8054
+            }
+          )
+        ]
+      end
+
+      context 'when the service-discovery-controller filters lookups by policy' do
+        let(:links) do
+          [
+            Link.new(
+              name: 'service-discovery-controller',
+              properties: {
+                'route_emitter_interval_seconds' => 20,
+                'port' => 8054,
+                'policy_filter' => { 'enabled' => true }
+              }
+            )
+          ]
+        end
+
+        it 'raises an error when the native DNS server is not enabled' do
+          expect {
+            template.render(merged_manifest_properties, consumes: links)
+          }.to raise_error('dns_server.enabled is required when policy_filter.enabled is true on the service-discovery-controller')
+        end
+
+        it 'renders when the native DNS server is enabled' do
+          merged_manifest_properties['dns_server'] = { 'enabled' => true }
+          config = JSON.parse(template.render(merged_manifest_properties, consumes: links))
+          expect(config['dns_port']).to eq(53)
+        end
+      end
+
+      it 'does not enable reverse lookups by default' do
+        config = JSON.parse(template.render(merged_manifest_properties, consumes: links))
+        expect(config).not_to have_key('overlay_cidr')
+      end
+
+      context 'when reverse lookups are enabled' do
+        let(:merged_manifest_properties) do
+          {
+            'reverse_lookups' => {
+              'enabled' => true,
+              'overlay_network' => '10.255.0.0/16'
+            }
+          }
+        end
+
+        it 'configures the overlay network' do
+          config = JSON.parse(template.render(merged_manifest_properties, consumes: links))
+          expect(config['overlay_cidr']).to eq('10.255.0.0/16')
+        end
+
+        context 'when the overlay network is not a CIDR' do
+          before do
+            merged_manifest_properties['reverse_lookups']['overlay_network'] = '10.255.0.0'
+          end
+
+          it 'raises an error' do
+            expect {
+              template.render(merged_manifest_properties, consumes: links)
+            }.to raise_error('reverse_lookups.overlay_network must be an IPv4 CIDR')
+          end
+        end
+      end
+    end
   end
 end
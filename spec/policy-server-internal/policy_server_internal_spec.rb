@@ -16,6 +16,8 @@ module Bosh::Template::Test
         'debug_port' => 1234,
         'health_check_port' => 2345,
         'internal_listen_port' => 3456,
+        'internal_grpc_listen_port' => 3457,
+        'watch_poll_interval_seconds' => 7,
         'ca_cert' => 'meow-a-real-cert',
         'server_key' => 'password-please',
         'metron_port' => 4567,
@@ -81,6 +83,8 @@ module Bosh::Template::Test
           'debug_server_port' => 1234,
           'health_check_port' => 2345,
           'internal_listen_port' => 3456,
+          'internal_grpc_listen_port' => 3457,
+          'watch_poll_interval_seconds' => 7,
           'database' => {
             'type' => 'some-database-type',
             'user' => 'some-database-username',
@@ -43,6 +43,10 @@ module Bosh::Template::Test
         'metron_port' => 6789,
         'log_level' => 'debug',
         'allowed_cors_domains' => ['some-cors-domain'],
+        'rate_limits' => {
+          'create_policies' => {'requests_per_second' => 1, 'burst' => 20},
+        },
+        'idempotency_key_window_seconds' => 600,
       }
     end
 
@@ -91,6 +95,10 @@ module Bosh::Template::Test
           'max_policies' => 2,
           'enable_space_developer_self_service' => true,
           'allowed_cors_domains' => ['some-cors-domain'],
+          'rate_limits' => {
+            'create_policies' => {'requests_per_second' => 1, 'burst' => 20},
+          },
+          'idempotency_key_window_seconds' => 600,
           'uaa_ca' => '/var/vcap/jobs/policy-server/config/certs/uaa_ca.crt',
           'request_timeout' => 5,
         })
@@ -0,0 +1,13 @@
+package answers_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAnswers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Answers Suite")
+}
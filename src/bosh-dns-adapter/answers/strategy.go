@@ -0,0 +1,77 @@
+package answers
+
+import (
+	"bosh-dns-adapter/sdcclient"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Orders in which answers can be returned.
+const (
+	OrderShuffle      = "shuffle"
+	OrderRegistration = "registration"
+)
+
+// Localities that answers can be preferred by.
+const (
+	LocalityCell = "cell"
+	LocalityAZ   = "az"
+)
+
+// Strategy decides which endpoints to answer a query with, and in which
+// order. Clients tend to use the first record, so the order decides where
+// traffic goes.
+type Strategy struct {
+	// Order is OrderShuffle or OrderRegistration. Empty means OrderShuffle.
+	Order string
+	// Limit is the maximum number of endpoints returned, or 0 for all.
+	Limit int
+	// PreferLocality is LocalityCell, LocalityAZ, or empty for none.
+	// Preferring the cell also prefers the AZ over other AZs.
+	PreferLocality string
+	// CellID and AZ locate the requester.
+	CellID string
+	AZ     string
+}
+
+// Apply returns the endpoints to answer with. Endpoints nearest to the
+// requester come first, in the configured order within each locality, and
+// the limit is applied last so that near endpoints are kept.
+func (s Strategy) Apply(endpoints []sdcclient.Endpoint) []sdcclient.Endpoint {
+	ordered := make([]sdcclient.Endpoint, len(endpoints))
+	copy(ordered, endpoints)
+
+	if s.Order != OrderRegistration {
+		shuffle(ordered)
+	}
+
+	if s.PreferLocality != "" {
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return s.distance(ordered[i]) < s.distance(ordered[j])
+		})
+	}
+
+	if s.Limit > 0 && len(ordered) > s.Limit {
+		ordered = ordered[:s.Limit]
+	}
+	return ordered
+}
+
+// distance is 0 for the requester's cell, 1 for its AZ and 2 otherwise.
+func (s Strategy) distance(endpoint sdcclient.Endpoint) int {
+	if s.PreferLocality == LocalityCell && s.CellID != "" && endpoint.CellID == s.CellID {
+		return 0
+	}
+	if s.AZ != "" && endpoint.AZ == s.AZ {
+		return 1
+	}
+	return 2
+}
+
+func shuffle(endpoints []sdcclient.Endpoint) {
+	r := rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+	r.Shuffle(len(endpoints), func(i, j int) {
+		endpoints[i], endpoints[j] = endpoints[j], endpoints[i]
+	})
+}
@@ -0,0 +1,107 @@
+package answers_test
+
+import (
+	"bosh-dns-adapter/answers"
+	"bosh-dns-adapter/sdcclient"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Strategy", func() {
+	var endpoints []sdcclient.Endpoint
+
+	BeforeEach(func() {
+		endpoints = []sdcclient.Endpoint{
+			{IP: "192.168.0.1", CellID: "cell-1", AZ: "z1"},
+			{IP: "192.168.0.2", CellID: "cell-2", AZ: "z2"},
+			{IP: "192.168.0.3", CellID: "cell-3", AZ: "z1"},
+			{IP: "192.168.0.4"},
+		}
+	})
+
+	ips := func(endpoints []sdcclient.Endpoint) []string {
+		ips := []string{}
+		for _, endpoint := range endpoints {
+			ips = append(ips, endpoint.IP)
+		}
+		return ips
+	}
+
+	Context("by default", func() {
+		It("shuffles the endpoints on each query", func() {
+			strategy := answers.Strategy{}
+
+			Eventually(func() []string {
+				return ips(strategy.Apply(endpoints))
+			}).Should(Equal([]string{"192.168.0.4", "192.168.0.3", "192.168.0.2", "192.168.0.1"}))
+			Expect(ips(endpoints)).To(Equal([]string{"192.168.0.1", "192.168.0.2", "192.168.0.3", "192.168.0.4"}))
+		})
+	})
+
+	Context("when the order is registration", func() {
+		It("keeps the order", func() {
+			strategy := answers.Strategy{Order: answers.OrderRegistration}
+
+			Expect(strategy.Apply(endpoints)).To(Equal(endpoints))
+		})
+	})
+
+	Context("when there is a limit", func() {
+		It("returns at most that many endpoints", func() {
+			strategy := answers.Strategy{Order: answers.OrderRegistration, Limit: 2}
+
+			Expect(ips(strategy.Apply(endpoints))).To(Equal([]string{"192.168.0.1", "192.168.0.2"}))
+		})
+
+		It("returns every endpoint when there are fewer", func() {
+			strategy := answers.Strategy{Limit: 10}
+
+			Expect(strategy.Apply(endpoints)).To(HaveLen(4))
+		})
+	})
+
+	Context("when the cell is preferred", func() {
+		It("returns the requester's cell first, then its AZ", func() {
+			strategy := answers.Strategy{
+				Order:          answers.OrderRegistration,
+				PreferLocality: answers.LocalityCell,
+				CellID:         "cell-3",
+				AZ:             "z1",
+			}
+
+			Expect(ips(strategy.Apply(endpoints))).To(Equal([]string{"192.168.0.3", "192.168.0.1", "192.168.0.2", "192.168.0.4"}))
+		})
+
+		It("applies the limit after ordering by locality", func() {
+			strategy := answers.Strategy{
+				PreferLocality: answers.LocalityCell,
+				CellID:         "cell-2",
+				AZ:             "z2",
+				Limit:          1,
+			}
+
+			Expect(ips(strategy.Apply(endpoints))).To(Equal([]string{"192.168.0.2"}))
+		})
+	})
+
+	Context("when the AZ is preferred", func() {
+		It("returns the requester's AZ first, shuffled", func() {
+			strategy := answers.Strategy{
+				PreferLocality: answers.LocalityAZ,
+				CellID:         "cell-3",
+				AZ:             "z1",
+			}
+
+			Eventually(func() []string {
+				return ips(strategy.Apply(endpoints))[:2]
+			}).Should(Equal([]string{"192.168.0.3", "192.168.0.1"}))
+			Eventually(func() []string {
+				return ips(strategy.Apply(endpoints))[:2]
+			}).Should(Equal([]string{"192.168.0.1", "192.168.0.3"}))
+			Consistently(func() []string {
+				return ips(strategy.Apply(endpoints))[:2]
+			}).Should(ConsistOf("192.168.0.1", "192.168.0.3"))
+		})
+	})
+})
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"bosh-dns-adapter/sdcclient"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// Cache keeps the last answer of the service discovery controllers to each
+// query, so that it can be served while none of them is reachable. Answers
+// older than the max-stale duration are not served, and are dropped.
+type Cache struct {
+	maxStale  time.Duration
+	clock     clock.Clock
+	entries   map[key]entry
+	lastSweep time.Time
+	lock      sync.Mutex
+}
+
+type key struct {
+	name     string
+	family   string
+	sourceIP string
+}
+
+type entry struct {
+	endpoints []sdcclient.Endpoint
+	storedAt  time.Time
+}
+
+// New returns a cache that serves answers for up to maxStale after they were
+// stored. A maxStale of 0 disables it.
+func New(maxStale time.Duration, clock clock.Clock) *Cache {
+	return &Cache{
+		maxStale:  maxStale,
+		clock:     clock,
+		entries:   map[key]entry{},
+		lastSweep: clock.Now(),
+	}
+}
+
+// Put stores the answer to a query. Answers are kept apart per source IP,
+// since controllers that enforce network policies answer each app
+// differently.
+func (c *Cache) Put(name, family, sourceIP string, endpoints []sdcclient.Endpoint) {
+	if c.maxStale == 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.clock.Now()
+	c.entries[key{name: name, family: family, sourceIP: sourceIP}] = entry{
+		endpoints: endpoints,
+		storedAt:  now,
+	}
+
+	if now.Sub(c.lastSweep) >= c.maxStale {
+		c.sweep(now)
+	}
+}
+
+// Get returns the stored answer to a query and its age, or false when there
+// is none younger than the max-stale duration.
+func (c *Cache) Get(name, family, sourceIP string) ([]sdcclient.Endpoint, time.Duration, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	k := key{name: name, family: family, sourceIP: sourceIP}
+	e, ok := c.entries[k]
+	if !ok {
+		return nil, 0, false
+	}
+
+	age := c.clock.Since(e.storedAt)
+	if age > c.maxStale {
+		delete(c.entries, k)
+		return nil, 0, false
+	}
+	return e.endpoints, age, true
+}
+
+// Len returns the number of stored answers.
+func (c *Cache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.entries)
+}
+
+func (c *Cache) sweep(now time.Time) {
+	for k, e := range c.entries {
+		if now.Sub(e.storedAt) > c.maxStale {
+			delete(c.entries, k)
+		}
+	}
+	c.lastSweep = now
+}
@@ -0,0 +1,95 @@
+package cache_test
+
+import (
+	"bosh-dns-adapter/cache"
+	"bosh-dns-adapter/sdcclient"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		answerCache *cache.Cache
+		fakeClock   *fakeclock.FakeClock
+		endpoints   []sdcclient.Endpoint
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		answerCache = cache.New(time.Minute, fakeClock)
+		endpoints = []sdcclient.Endpoint{{IP: "192.168.0.1", Port: 8080}}
+	})
+
+	It("returns the stored answer and its age", func() {
+		answerCache.Put("app-id.apps.internal.", sdcclient.FamilyIPv4, "", endpoints)
+		fakeClock.Increment(10 * time.Second)
+
+		cached, age, ok := answerCache.Get("app-id.apps.internal.", sdcclient.FamilyIPv4, "")
+		Expect(ok).To(BeTrue())
+		Expect(cached).To(Equal(endpoints))
+		Expect(age).To(Equal(10 * time.Second))
+	})
+
+	It("keeps answers for each source apart", func() {
+		answerCache.Put("app-id.apps.internal.", sdcclient.FamilyIPv4, "10.255.0.1", endpoints)
+
+		_, _, ok := answerCache.Get("app-id.apps.internal.", sdcclient.FamilyIPv4, "10.255.0.2")
+		Expect(ok).To(BeFalse())
+		_, _, ok = answerCache.Get("app-id.apps.internal.", sdcclient.FamilyIPv4, "10.255.0.1")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("keeps answers for each family apart", func() {
+		answerCache.Put("app-id.apps.internal.", sdcclient.FamilyIPv4, "", endpoints)
+
+		_, _, ok := answerCache.Get("app-id.apps.internal.", sdcclient.FamilyIPv6, "")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("replaces the stored answer", func() {
+		answerCache.Put("app-id.apps.internal.", "", "", endpoints)
+		fakeClock.Increment(30 * time.Second)
+		answerCache.Put("app-id.apps.internal.", "", "", []sdcclient.Endpoint{{IP: "192.168.0.2"}})
+
+		cached, age, ok := answerCache.Get("app-id.apps.internal.", "", "")
+		Expect(ok).To(BeTrue())
+		Expect(cached).To(Equal([]sdcclient.Endpoint{{IP: "192.168.0.2"}}))
+		Expect(age).To(Equal(time.Duration(0)))
+	})
+
+	Context("when the answer is older than the max-stale duration", func() {
+		It("does not return it", func() {
+			answerCache.Put("app-id.apps.internal.", sdcclient.FamilyIPv4, "", endpoints)
+			fakeClock.Increment(time.Minute + time.Second)
+
+			_, _, ok := answerCache.Get("app-id.apps.internal.", sdcclient.FamilyIPv4, "")
+			Expect(ok).To(BeFalse())
+			Expect(answerCache.Len()).To(Equal(0))
+		})
+
+		It("drops it when other answers are stored", func() {
+			answerCache.Put("app-id.apps.internal.", sdcclient.FamilyIPv4, "", endpoints)
+			fakeClock.Increment(time.Minute + time.Second)
+
+			answerCache.Put("other.apps.internal.", sdcclient.FamilyIPv4, "", endpoints)
+			Expect(answerCache.Len()).To(Equal(1))
+		})
+	})
+
+	Context("when the max-stale duration is 0", func() {
+		BeforeEach(func() {
+			answerCache = cache.New(0, fakeClock)
+		})
+
+		It("stores nothing", func() {
+			answerCache.Put("app-id.apps.internal.", sdcclient.FamilyIPv4, "", endpoints)
+
+			_, _, ok := answerCache.Get("app-id.apps.internal.", sdcclient.FamilyIPv4, "")
+			Expect(ok).To(BeFalse())
+			Expect(answerCache.Len()).To(Equal(0))
+		})
+	})
+})
@@ -3,6 +3,8 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+
 	"gopkg.in/validator.v2"
 )
 
@@ -18,6 +20,26 @@ type Config struct {
 	MetricsEmitSeconds                int    `json:"metrics_emit_seconds" validate:"min=1"`
 	LogLevelAddress                   string `json:"log_level_address" validate:"nonzero"`
 	LogLevelPort                      int    `json:"log_level_port" validate:"min=1"`
+
+	AnswerOrder      string `json:"answer_order" validate:"regexp=^(|shuffle|registration)$"`
+	MaxAnswers       int    `json:"max_answers" validate:"min=0"`
+	PreferLocality   string `json:"prefer_locality" validate:"regexp=^(|cell|az)$"`
+	CellID           string `json:"cell_id"`
+	AvailabilityZone string `json:"availability_zone"`
+
+	TTLSeconds         int            `json:"ttl_seconds" validate:"min=0"`
+	DomainTTLSeconds   map[string]int `json:"domain_ttl_seconds"`
+	NegativeTTLSeconds int            `json:"negative_ttl_seconds" validate:"min=0"`
+	InternalDomains    []string       `json:"internal_domains"`
+
+	ServiceDiscoveryControllerAddresses        []string `json:"service_discovery_controller_addresses"`
+	ServiceDiscoveryControllerUnhealthySeconds int      `json:"service_discovery_controller_unhealthy_seconds" validate:"min=0"`
+	MaxStaleSeconds                            int      `json:"max_stale_seconds" validate:"min=0"`
+
+	DNSAddress string `json:"dns_address"`
+	DNSPort    int    `json:"dns_port" validate:"min=0"`
+
+	OverlayCIDR string `json:"overlay_cidr"`
 }
 
 func NewConfig(configJSON []byte) (*Config, error) {
@@ -32,5 +54,21 @@ func NewConfig(configJSON []byte) (*Config, error) {
 		return nil, fmt.Errorf("invalid config: %s", err)
 	}
 
+	for domain, ttl := range adapterConfig.DomainTTLSeconds {
+		if ttl < 0 {
+			return nil, fmt.Errorf("invalid config: DomainTTLSeconds: %s: less than min", domain)
+		}
+	}
+
+	if adapterConfig.DNSPort > 0 && adapterConfig.DNSAddress == "" {
+		return nil, fmt.Errorf("invalid config: DNSAddress: zero value")
+	}
+
+	if adapterConfig.OverlayCIDR != "" {
+		if _, _, err := net.ParseCIDR(adapterConfig.OverlayCIDR); err != nil {
+			return nil, fmt.Errorf("invalid config: OverlayCIDR: %s", err)
+		}
+	}
+
 	return adapterConfig, err
 }
@@ -24,8 +24,22 @@ var _ = Describe("Config", func() {
 				"metrics_emit_seconds": 6,
 				"metron_port": 8080,
 				"log_level_address": "log-level-address",
-				"log_level_port": 9090
-
+				"log_level_port": 9090,
+				"answer_order": "registration",
+				"max_answers": 5,
+				"prefer_locality": "cell",
+				"cell_id": "cell-1",
+				"availability_zone": "z1",
+				"ttl_seconds": 30,
+				"domain_ttl_seconds": {"apps.internal.": 10},
+				"negative_ttl_seconds": 5,
+				"internal_domains": ["apps.internal.", "my.apps.internal."],
+				"service_discovery_controller_addresses": ["10.0.0.1", "10.0.0.2"],
+				"service_discovery_controller_unhealthy_seconds": 10,
+				"max_stale_seconds": 300,
+				"dns_address": "169.254.0.3",
+				"dns_port": 53,
+				"overlay_cidr": "10.255.0.0/16"
 			}`)
 
 			parsedConfig, err := NewConfig(configJSON)
@@ -43,6 +57,21 @@ var _ = Describe("Config", func() {
 			Expect(parsedConfig.MetronPort).To(Equal(8080))
 			Expect(parsedConfig.LogLevelAddress).To(Equal("log-level-address"))
 			Expect(parsedConfig.LogLevelPort).To(Equal(9090))
+			Expect(parsedConfig.AnswerOrder).To(Equal("registration"))
+			Expect(parsedConfig.MaxAnswers).To(Equal(5))
+			Expect(parsedConfig.PreferLocality).To(Equal("cell"))
+			Expect(parsedConfig.CellID).To(Equal("cell-1"))
+			Expect(parsedConfig.AvailabilityZone).To(Equal("z1"))
+			Expect(parsedConfig.TTLSeconds).To(Equal(30))
+			Expect(parsedConfig.DomainTTLSeconds).To(Equal(map[string]int{"apps.internal.": 10}))
+			Expect(parsedConfig.NegativeTTLSeconds).To(Equal(5))
+			Expect(parsedConfig.InternalDomains).To(Equal([]string{"apps.internal.", "my.apps.internal."}))
+			Expect(parsedConfig.ServiceDiscoveryControllerAddresses).To(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+			Expect(parsedConfig.ServiceDiscoveryControllerUnhealthySeconds).To(Equal(10))
+			Expect(parsedConfig.MaxStaleSeconds).To(Equal(300))
+			Expect(parsedConfig.DNSAddress).To(Equal("169.254.0.3"))
+			Expect(parsedConfig.DNSPort).To(Equal(53))
+			Expect(parsedConfig.OverlayCIDR).To(Equal("10.255.0.0/16"))
 		})
 	})
 
@@ -93,6 +122,17 @@ var _ = Describe("Config", func() {
 		Entry("invalid ca_cert", "ca_cert", "", "CACert: zero value"),
 		Entry("invalid log_level_address", "log_level_address", "", "LogLevelAddress: zero value"),
 		Entry("invalid log_level_port", "log_level_port", -2, "LogLevelPort: less than min"),
+		Entry("invalid answer_order", "answer_order", "random", "AnswerOrder: regular expression mismatch"),
+		Entry("invalid max_answers", "max_answers", -1, "MaxAnswers: less than min"),
+		Entry("invalid prefer_locality", "prefer_locality", "rack", "PreferLocality: regular expression mismatch"),
+		Entry("invalid ttl_seconds", "ttl_seconds", -1, "TTLSeconds: less than min"),
+		Entry("invalid negative_ttl_seconds", "negative_ttl_seconds", -1, "NegativeTTLSeconds: less than min"),
+		Entry("invalid domain_ttl_seconds", "domain_ttl_seconds", map[string]int{"apps.internal.": -1}, "DomainTTLSeconds: apps.internal.: less than min"),
+		Entry("invalid service_discovery_controller_unhealthy_seconds", "service_discovery_controller_unhealthy_seconds", -1, "ServiceDiscoveryControllerUnhealthySeconds: less than min"),
+		Entry("invalid max_stale_seconds", "max_stale_seconds", -1, "MaxStaleSeconds: less than min"),
+		Entry("invalid dns_port", "dns_port", -1, "DNSPort: less than min"),
+		Entry("dns_port without dns_address", "dns_port", 53, "DNSAddress: zero value"),
+		Entry("invalid overlay_cidr", "overlay_cidr", "10.255.0.0", "OverlayCIDR: invalid CIDR address: 10.255.0.0"),
 	)
 })
 
@@ -0,0 +1,13 @@
+package dnsserver_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDnsserver(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dnsserver Suite")
+}
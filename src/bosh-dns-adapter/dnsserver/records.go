@@ -0,0 +1,134 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Record is a resource record with its data written as in the bosh-dns HTTP
+// JSON protocol, e.g. "10.255.0.1" for an A record, "0 0 8080 target." for
+// an SRV record or "app.apps.internal." for a PTR record.
+type Record struct {
+	Name string
+	Type dnsmessage.Type
+	TTL  uint32
+	Data string
+}
+
+// Response is the answer to a query. RCode is the response code, which is
+// RCodeSuccess when it is left unset.
+type Response struct {
+	RCode      dnsmessage.RCode
+	Answers    []Record
+	Additional []Record
+	Authority  []Record
+}
+
+func resources(records []Record) ([]dnsmessage.Resource, error) {
+	converted := []dnsmessage.Resource{}
+	for _, record := range records {
+		resource, err := resourceFor(record)
+		if err != nil {
+			return nil, fmt.Errorf("%s record for %s: %s", record.Type, record.Name, err)
+		}
+		converted = append(converted, resource)
+	}
+	return converted, nil
+}
+
+func resourceFor(record Record) (dnsmessage.Resource, error) {
+	name, err := dnsmessage.NewName(fqdn(record.Name))
+	if err != nil {
+		return dnsmessage.Resource{}, err
+	}
+
+	body, err := bodyFor(record)
+	if err != nil {
+		return dnsmessage.Resource{}, err
+	}
+
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{
+			Name:  name,
+			Type:  record.Type,
+			Class: dnsmessage.ClassINET,
+			TTL:   record.TTL,
+		},
+		Body: body,
+	}, nil
+}
+
+func bodyFor(record Record) (dnsmessage.ResourceBody, error) {
+	switch record.Type {
+	case dnsmessage.TypeA:
+		ip := net.ParseIP(record.Data).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IPv4 address %q", record.Data)
+		}
+		body := &dnsmessage.AResource{}
+		copy(body.A[:], ip)
+		return body, nil
+	case dnsmessage.TypeAAAA:
+		ip := net.ParseIP(record.Data)
+		if ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("invalid IPv6 address %q", record.Data)
+		}
+		body := &dnsmessage.AAAAResource{}
+		copy(body.AAAA[:], ip.To16())
+		return body, nil
+	case dnsmessage.TypeSRV:
+		var priority, weight, port uint16
+		var target string
+		_, err := fmt.Sscanf(record.Data, "%d %d %d %s", &priority, &weight, &port, &target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SRV data %q", record.Data)
+		}
+		targetName, err := dnsmessage.NewName(fqdn(target))
+		if err != nil {
+			return nil, err
+		}
+		return &dnsmessage.SRVResource{Priority: priority, Weight: weight, Port: port, Target: targetName}, nil
+	case dnsmessage.TypePTR:
+		ptrName, err := dnsmessage.NewName(fqdn(record.Data))
+		if err != nil {
+			return nil, err
+		}
+		return &dnsmessage.PTRResource{PTR: ptrName}, nil
+	case dnsmessage.TypeSOA:
+		var ns, mbox string
+		var serial, refresh, retry, expire, minTTL uint32
+		_, err := fmt.Sscanf(record.Data, "%s %s %d %d %d %d %d", &ns, &mbox, &serial, &refresh, &retry, &expire, &minTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOA data %q", record.Data)
+		}
+		nsName, err := dnsmessage.NewName(fqdn(ns))
+		if err != nil {
+			return nil, err
+		}
+		mboxName, err := dnsmessage.NewName(fqdn(mbox))
+		if err != nil {
+			return nil, err
+		}
+		return &dnsmessage.SOAResource{
+			NS:      nsName,
+			MBox:    mboxName,
+			Serial:  serial,
+			Refresh: refresh,
+			Retry:   retry,
+			Expire:  expire,
+			MinTTL:  minTTL,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported record type")
+	}
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
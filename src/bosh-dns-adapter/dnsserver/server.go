@@ -0,0 +1,295 @@
+package dnsserver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// minUDPSize is the size of UDP answers that every resolver accepts.
+	// Resolvers that send an EDNS(0) OPT record can accept up to maxUDPSize.
+	minUDPSize = 512
+	maxUDPSize = 4096
+
+	// tcpIdleTimeout is how long a TCP connection is kept open without a
+	// query.
+	tcpIdleTimeout = 10 * time.Second
+)
+
+//go:generate counterfeiter -o fakes/resolver.go --fake-name Resolver . Resolver
+
+// Resolver answers a query. sourceIP is the IP of the client that sent it.
+type Resolver interface {
+	Resolve(name string, qtype dnsmessage.Type, sourceIP string) (Response, error)
+}
+
+// ResolverFunc adapts a function to a Resolver.
+type ResolverFunc func(name string, qtype dnsmessage.Type, sourceIP string) (Response, error)
+
+func (f ResolverFunc) Resolve(name string, qtype dnsmessage.Type, sourceIP string) (Response, error) {
+	return f(name, qtype, sourceIP)
+}
+
+// Server answers DNS queries in wire format over UDP and TCP, so that it can
+// be used as a nameserver without bosh-dns in front of it.
+type Server struct {
+	address  string
+	resolver Resolver
+	logger   lager.Logger
+}
+
+func NewServer(address string, resolver Resolver, logger lager.Logger) *Server {
+	return &Server{
+		address:  address,
+		resolver: resolver,
+		logger:   logger,
+	}
+}
+
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	udpConn, err := net.ListenPacket("udp", s.address)
+	if err != nil {
+		return fmt.Errorf("listen udp: %s", err)
+	}
+	defer udpConn.Close()
+
+	tcpListener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("listen tcp: %s", err)
+	}
+	defer tcpListener.Close()
+
+	go s.serveUDP(udpConn)
+	go s.serveTCP(tcpListener)
+
+	close(ready)
+	s.logger.Info("server-started", lager.Data{"address": s.address})
+
+	signal := <-signals
+	s.logger.Info("server-exited", lager.Data{"signal": signal.String()})
+	return nil
+}
+
+func (s *Server) serveUDP(conn net.PacketConn) {
+	buf := make([]byte, maxUDPSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		query := make([]byte, n)
+		copy(query, buf[:n])
+		go func() {
+			response, ok := s.respond(query, true, hostOf(addr))
+			if !ok {
+				return
+			}
+			_, err := conn.WriteTo(response, addr)
+			if err != nil {
+				s.logger.Error("write-udp-response", err)
+			}
+		}()
+	}
+}
+
+func (s *Server) serveTCP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveTCPConn(conn)
+	}
+}
+
+// serveTCPConn answers queries on conn until the client closes it or stays
+// idle. Messages over TCP are prefixed with their length.
+func (s *Server) serveTCPConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+
+		var length uint16
+		err := binary.Read(conn, binary.BigEndian, &length)
+		if err != nil {
+			return
+		}
+
+		query := make([]byte, length)
+		_, err = io.ReadFull(conn, query)
+		if err != nil {
+			return
+		}
+
+		response, ok := s.respond(query, false, hostOf(conn.RemoteAddr()))
+		if !ok {
+			return
+		}
+
+		prefixed := make([]byte, 2, 2+len(response))
+		binary.BigEndian.PutUint16(prefixed, uint16(len(response)))
+		_, err = conn.Write(append(prefixed, response...))
+		if err != nil {
+			s.logger.Error("write-tcp-response", err)
+			return
+		}
+	}
+}
+
+// respond returns the packed response to query, or false when the query is
+// too malformed to be answered at all.
+func (s *Server) respond(query []byte, udp bool, sourceIP string) ([]byte, bool) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		s.logger.Debug("invalid-query", lager.Data{"error": err.Error()})
+		return nil, false
+	}
+
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               header.ID,
+			Response:         true,
+			OpCode:           header.OpCode,
+			Authoritative:    true,
+			RecursionDesired: header.RecursionDesired,
+		},
+	}
+
+	questions, err := parser.AllQuestions()
+	switch {
+	case err != nil || len(questions) != 1:
+		response.Header.RCode = dnsmessage.RCodeFormatError
+		return s.pack(response, minUDPSize, udp)
+	case header.OpCode != 0:
+		response.Header.RCode = dnsmessage.RCodeNotImplemented
+		return s.pack(response, minUDPSize, udp)
+	}
+
+	response.Questions = questions
+	size, edns := udpSize(&parser)
+
+	s.answer(&response, questions[0], sourceIP)
+
+	if edns {
+		opt := dnsmessage.Resource{Body: &dnsmessage.OPTResource{}}
+		opt.Header.SetEDNS0(maxUDPSize, dnsmessage.RCodeSuccess, false)
+		response.Additionals = append(response.Additionals, opt)
+	}
+	return s.pack(response, size, udp)
+}
+
+// answer fills response with the records answering question, or with the
+// code of the error that prevented it.
+func (s *Server) answer(response *dnsmessage.Message, question dnsmessage.Question, sourceIP string) {
+	if question.Class != dnsmessage.ClassINET {
+		response.Header.RCode = dnsmessage.RCodeNotImplemented
+		return
+	}
+
+	answer, err := s.resolver.Resolve(question.Name.String(), question.Type, sourceIP)
+	if err != nil {
+		response.Header.RCode = dnsmessage.RCodeServerFailure
+		return
+	}
+
+	response.Header.RCode = answer.RCode
+	err = s.fill(response, answer)
+	if err != nil {
+		s.logger.Error("build-response", err, lager.Data{"name": question.Name.String()})
+		response.Header.RCode = dnsmessage.RCodeServerFailure
+		response.Answers, response.Authorities, response.Additionals = nil, nil, nil
+	}
+}
+
+func (s *Server) fill(response *dnsmessage.Message, answer Response) error {
+	var err error
+	response.Answers, err = resources(answer.Answers)
+	if err != nil {
+		return err
+	}
+	response.Authorities, err = resources(answer.Authority)
+	if err != nil {
+		return err
+	}
+	response.Additionals, err = resources(answer.Additional)
+	return err
+}
+
+// pack packs response. UDP responses larger than size are truncated to the
+// header and question, which tells the client to retry over TCP.
+func (s *Server) pack(response dnsmessage.Message, size int, udp bool) ([]byte, bool) {
+	packed, err := response.Pack()
+	if err != nil {
+		s.logger.Error("pack-response", err)
+		return nil, false
+	}
+	if !udp || len(packed) <= size {
+		return packed, true
+	}
+
+	response.Header.Truncated = true
+	response.Answers, response.Authorities = nil, nil
+	response.Additionals = optOnly(response.Additionals)
+	packed, err = response.Pack()
+	if err != nil {
+		s.logger.Error("pack-response", err)
+		return nil, false
+	}
+	return packed, true
+}
+
+// udpSize returns the largest UDP response that the client accepts, and
+// whether it sent an EDNS(0) OPT record to say so.
+func udpSize(parser *dnsmessage.Parser) (int, bool) {
+	if parser.SkipAllAnswers() != nil || parser.SkipAllAuthorities() != nil {
+		return minUDPSize, false
+	}
+
+	for {
+		header, err := parser.AdditionalHeader()
+		if err != nil {
+			return minUDPSize, false
+		}
+		if header.Type == dnsmessage.TypeOPT {
+			size := int(header.Class)
+			if size < minUDPSize {
+				size = minUDPSize
+			}
+			if size > maxUDPSize {
+				size = maxUDPSize
+			}
+			return size, true
+		}
+		if parser.SkipAdditional() != nil {
+			return minUDPSize, false
+		}
+	}
+}
+
+func hostOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+func optOnly(resources []dnsmessage.Resource) []dnsmessage.Resource {
+	opts := []dnsmessage.Resource{}
+	for _, resource := range resources {
+		if resource.Header.Type == dnsmessage.TypeOPT {
+			opts = append(opts, resource)
+		}
+	}
+	return opts
+}
@@ -0,0 +1,284 @@
+package dnsserver_test
+
+import (
+	"bosh-dns-adapter/dnsserver"
+	"bosh-dns-adapter/dnsserver/fakes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/cf-networking-helpers/testsupport/ports"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		resolver   *fakes.Resolver
+		address    string
+		serverProc ifrit.Process
+	)
+
+	BeforeEach(func() {
+		resolver = &fakes.Resolver{}
+		address = fmt.Sprintf("127.0.0.1:%d", ports.PickAPort())
+		server := dnsserver.NewServer(address, resolver, lagertest.NewTestLogger("test"))
+		serverProc = ifrit.Invoke(server)
+	})
+
+	AfterEach(func() {
+		serverProc.Signal(os.Interrupt)
+		Eventually(serverProc.Wait()).Should(Receive())
+	})
+
+	It("answers queries over UDP", func() {
+		resolver.ResolveReturns(dnsserver.Response{
+			Answers: []dnsserver.Record{
+				{Name: "app-id.apps.internal.", Type: dnsmessage.TypeA, TTL: 30, Data: "192.168.0.1"},
+			},
+		}, nil)
+
+		response := exchangeUDP(address, query("app-id.apps.internal.", dnsmessage.TypeA))
+
+		Expect(response.Header.ID).To(Equal(uint16(42)))
+		Expect(response.Header.Response).To(BeTrue())
+		Expect(response.Header.Authoritative).To(BeTrue())
+		Expect(response.Header.RCode).To(Equal(dnsmessage.RCodeSuccess))
+		Expect(response.Questions).To(HaveLen(1))
+		Expect(response.Answers).To(HaveLen(1))
+		Expect(response.Answers[0].Header.Name.String()).To(Equal("app-id.apps.internal."))
+		Expect(response.Answers[0].Header.TTL).To(Equal(uint32(30)))
+		Expect(response.Answers[0].Body).To(Equal(&dnsmessage.AResource{A: [4]byte{192, 168, 0, 1}}))
+
+		Expect(resolver.ResolveCallCount()).To(Equal(1))
+		name, qtype, sourceIP := resolver.ResolveArgsForCall(0)
+		Expect(name).To(Equal("app-id.apps.internal."))
+		Expect(qtype).To(Equal(dnsmessage.TypeA))
+		Expect(sourceIP).To(Equal("127.0.0.1"))
+	})
+
+	It("answers queries over TCP", func() {
+		resolver.ResolveReturns(dnsserver.Response{
+			Answers: []dnsserver.Record{
+				{Name: "app-id.apps.internal.", Type: dnsmessage.TypeAAAA, TTL: 30, Data: "fd00::1"},
+			},
+		}, nil)
+
+		response := exchangeTCP(address, query("app-id.apps.internal.", dnsmessage.TypeAAAA))
+
+		Expect(response.Answers).To(HaveLen(1))
+		Expect(response.Answers[0].Body).To(Equal(&dnsmessage.AAAAResource{
+			AAAA: [16]byte{0xfd, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		}))
+
+		_, _, sourceIP := resolver.ResolveArgsForCall(0)
+		Expect(sourceIP).To(Equal("127.0.0.1"))
+	})
+
+	It("converts SRV and SOA records", func() {
+		resolver.ResolveReturns(dnsserver.Response{
+			Answers: []dnsserver.Record{
+				{Name: "_http._tcp.app-id.apps.internal.", Type: dnsmessage.TypeSRV, TTL: 30, Data: "0 0 8080 192-168-0-1.app-id.apps.internal."},
+			},
+			Additional: []dnsserver.Record{
+				{Name: "192-168-0-1.app-id.apps.internal.", Type: dnsmessage.TypeA, TTL: 30, Data: "192.168.0.1"},
+			},
+			Authority: []dnsserver.Record{
+				{Name: "apps.internal.", Type: dnsmessage.TypeSOA, TTL: 5, Data: "apps.internal. hostmaster.apps.internal. 1 3600 600 86400 5"},
+			},
+		}, nil)
+
+		response := exchangeUDP(address, query("_http._tcp.app-id.apps.internal.", dnsmessage.TypeSRV))
+
+		Expect(response.Answers).To(HaveLen(1))
+		srv := response.Answers[0].Body.(*dnsmessage.SRVResource)
+		Expect(srv.Port).To(Equal(uint16(8080)))
+		Expect(srv.Target.String()).To(Equal("192-168-0-1.app-id.apps.internal."))
+
+		Expect(response.Additionals).To(HaveLen(1))
+		Expect(response.Additionals[0].Body).To(Equal(&dnsmessage.AResource{A: [4]byte{192, 168, 0, 1}}))
+
+		Expect(response.Authorities).To(HaveLen(1))
+		soa := response.Authorities[0].Body.(*dnsmessage.SOAResource)
+		Expect(soa.NS.String()).To(Equal("apps.internal."))
+		Expect(soa.MBox.String()).To(Equal("hostmaster.apps.internal."))
+		Expect(soa.MinTTL).To(Equal(uint32(5)))
+	})
+
+	It("converts PTR records", func() {
+		resolver.ResolveReturns(dnsserver.Response{
+			Answers: []dnsserver.Record{
+				{Name: "4.3.255.10.in-addr.arpa.", Type: dnsmessage.TypePTR, TTL: 30, Data: "app-id.apps.internal."},
+			},
+		}, nil)
+
+		response := exchangeUDP(address, query("4.3.255.10.in-addr.arpa.", dnsmessage.TypePTR))
+
+		Expect(response.Answers).To(HaveLen(1))
+		Expect(response.Answers[0].Header.Name.String()).To(Equal("4.3.255.10.in-addr.arpa."))
+		ptr := response.Answers[0].Body.(*dnsmessage.PTRResource)
+		Expect(ptr.PTR.String()).To(Equal("app-id.apps.internal."))
+	})
+
+	Context("when the resolver fails", func() {
+		It("answers with a server failure", func() {
+			resolver.ResolveReturns(dnsserver.Response{}, errors.New("potato"))
+
+			response := exchangeUDP(address, query("app-id.apps.internal.", dnsmessage.TypeA))
+
+			Expect(response.Header.RCode).To(Equal(dnsmessage.RCodeServerFailure))
+			Expect(response.Answers).To(BeEmpty())
+		})
+	})
+
+	Context("when the resolver sets a response code", func() {
+		It("answers with the response code", func() {
+			resolver.ResolveReturns(dnsserver.Response{RCode: dnsmessage.RCodeRefused}, nil)
+
+			response := exchangeUDP(address, query("app-id.unknown.", dnsmessage.TypeA))
+
+			Expect(response.Header.RCode).To(Equal(dnsmessage.RCodeRefused))
+			Expect(response.Answers).To(BeEmpty())
+		})
+	})
+
+	Context("when a record cannot be converted", func() {
+		It("answers with a server failure", func() {
+			resolver.ResolveReturns(dnsserver.Response{
+				Answers: []dnsserver.Record{
+					{Name: "app-id.apps.internal.", Type: dnsmessage.TypeA, Data: "not-an-ip"},
+				},
+			}, nil)
+
+			response := exchangeUDP(address, query("app-id.apps.internal.", dnsmessage.TypeA))
+
+			Expect(response.Header.RCode).To(Equal(dnsmessage.RCodeServerFailure))
+			Expect(response.Answers).To(BeEmpty())
+		})
+	})
+
+	Context("when the query does not have exactly one question", func() {
+		It("answers with a format error", func() {
+			message := query("app-id.apps.internal.", dnsmessage.TypeA)
+			message.Questions = append(message.Questions, message.Questions[0])
+
+			response := exchangeUDP(address, message)
+
+			Expect(response.Header.RCode).To(Equal(dnsmessage.RCodeFormatError))
+			Expect(resolver.ResolveCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the answer does not fit in a UDP response", func() {
+		BeforeEach(func() {
+			records := []dnsserver.Record{}
+			for i := 0; i < 100; i++ {
+				records = append(records, dnsserver.Record{
+					Name: "app-id.apps.internal.",
+					Type: dnsmessage.TypeA,
+					Data: fmt.Sprintf("192.168.0.%d", i),
+				})
+			}
+			resolver.ResolveReturns(dnsserver.Response{Answers: records}, nil)
+		})
+
+		It("truncates the response", func() {
+			response := exchangeUDP(address, query("app-id.apps.internal.", dnsmessage.TypeA))
+
+			Expect(response.Header.Truncated).To(BeTrue())
+			Expect(response.Answers).To(BeEmpty())
+		})
+
+		It("answers in full over TCP", func() {
+			response := exchangeTCP(address, query("app-id.apps.internal.", dnsmessage.TypeA))
+
+			Expect(response.Header.Truncated).To(BeFalse())
+			Expect(response.Answers).To(HaveLen(100))
+		})
+
+		It("answers in full when the client accepts larger responses", func() {
+			message := query("app-id.apps.internal.", dnsmessage.TypeA)
+			opt := dnsmessage.Resource{Body: &dnsmessage.OPTResource{}}
+			opt.Header.SetEDNS0(4096, dnsmessage.RCodeSuccess, false)
+			message.Additionals = []dnsmessage.Resource{opt}
+
+			response := exchangeUDP(address, message)
+
+			Expect(response.Header.Truncated).To(BeFalse())
+			Expect(response.Answers).To(HaveLen(100))
+			Expect(response.Additionals).To(HaveLen(1))
+			Expect(response.Additionals[0].Header.Type).To(Equal(dnsmessage.TypeOPT))
+		})
+	})
+})
+
+func query(name string, qtype dnsmessage.Type) dnsmessage.Message {
+	return dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName(name),
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+}
+
+func exchangeUDP(address string, message dnsmessage.Message) dnsmessage.Message {
+	packed, err := message.Pack()
+	Expect(err).NotTo(HaveOccurred())
+
+	var conn net.Conn
+	Eventually(func() error {
+		conn, err = net.Dial("udp", address)
+		return err
+	}).Should(Succeed())
+	defer conn.Close()
+
+	buf := make([]byte, 65535)
+	var n int
+	Eventually(func() error {
+		_, err := conn.Write(packed)
+		Expect(err).NotTo(HaveOccurred())
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, err = conn.Read(buf)
+		return err
+	}).Should(Succeed())
+
+	var response dnsmessage.Message
+	Expect(response.Unpack(buf[:n])).To(Succeed())
+	return response
+}
+
+func exchangeTCP(address string, message dnsmessage.Message) dnsmessage.Message {
+	packed, err := message.Pack()
+	Expect(err).NotTo(HaveOccurred())
+
+	var conn net.Conn
+	Eventually(func() error {
+		conn, err = net.Dial("tcp", address)
+		return err
+	}).Should(Succeed())
+	defer conn.Close()
+
+	prefixed := make([]byte, 2, 2+len(packed))
+	binary.BigEndian.PutUint16(prefixed, uint16(len(packed)))
+	_, err = conn.Write(append(prefixed, packed...))
+	Expect(err).NotTo(HaveOccurred())
+
+	var length uint16
+	Expect(binary.Read(conn, binary.BigEndian, &length)).To(Succeed())
+	buf := make([]byte, length)
+	_, err = io.ReadFull(conn, buf)
+	Expect(err).NotTo(HaveOccurred())
+
+	var response dnsmessage.Message
+	Expect(response.Unpack(buf)).To(Succeed())
+	return response
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"bosh-dns-adapter/answers"
+	"bosh-dns-adapter/cache"
 	"bosh-dns-adapter/config"
+	"bosh-dns-adapter/dnsserver"
+	"bosh-dns-adapter/reverse"
 	"bosh-dns-adapter/sdcclient"
+	"bosh-dns-adapter/ttl"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -12,7 +18,9 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 
 	"time"
@@ -20,6 +28,7 @@ import (
 	"code.cloudfoundry.org/cf-networking-helpers/lagerlevel"
 	"code.cloudfoundry.org/cf-networking-helpers/metrics"
 	"code.cloudfoundry.org/cf-networking-helpers/middleware"
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
 	"github.com/cloudfoundry/dropsonde"
 	"github.com/tedsuo/ifrit"
@@ -31,6 +40,8 @@ import (
 func main() {
 	signalChannel := make(chan os.Signal, 1)
 	signal.Notify(signalChannel, syscall.SIGTERM, os.Interrupt)
+	reloadChannel := make(chan os.Signal, 1)
+	signal.Notify(reloadChannel, syscall.SIGHUP)
 
 	logger := lager.NewLogger("bosh-dns-adapter")
 	writerSink := lager.NewWriterSink(os.Stdout, lager.DEBUG)
@@ -60,11 +71,6 @@ func main() {
 		os.Exit(1)
 	}
 
-	sdcServerUrl := fmt.Sprintf("https://%s:%s",
-		config.ServiceDiscoveryControllerAddress,
-		config.ServiceDiscoveryControllerPort,
-	)
-
 	metronAddress := fmt.Sprintf("127.0.0.1:%d", config.MetronPort)
 	err = dropsonde.Initialize(metronAddress, "bosh-dns-adapter")
 	if err != nil {
@@ -72,7 +78,15 @@ func main() {
 		os.Exit(1)
 	}
 
-	sdcClient, err := sdcclient.NewServiceDiscoveryClient(sdcServerUrl, config.CACert, config.ClientCert, config.ClientKey)
+	sdcServerURLs, sdcServerName := serviceDiscoveryControllerURLs(config)
+	sdcClient, err := sdcclient.NewServiceDiscoveryClient(
+		sdcServerURLs,
+		sdcServerName,
+		config.CACert,
+		config.ClientCert,
+		config.ClientKey,
+		time.Duration(config.ServiceDiscoveryControllerUnhealthySeconds)*time.Second,
+	)
 	if err != nil {
 		logger.Error("Unable to create service discovery client", err)
 		os.Exit(1)
@@ -80,10 +94,41 @@ func main() {
 
 	requestLogger := logger.Session("serve-request")
 
+	answerCache := cache.New(time.Duration(config.MaxStaleSeconds)*time.Second, clock.NewClock())
+
+	current := &currentSettings{settings: buildSettings(config)}
+
 	metricSender := metrics.MetricsSender{
 		Logger: logger.Session("bosh-dns-adapter"),
 	}
 
+	// lookup queries the service discovery controllers, and serves the last
+	// answer to the query while none of them can be reached. sourceIP is the
+	// IP of the container that sent the query, when it is known.
+	lookup := func(name, family, sourceIP string) ([]sdcclient.Endpoint, error) {
+		endpoints, err := sdcClient.EndpointsForSource(name, family, sourceIP)
+		if err == nil {
+			answerCache.Put(name, family, sourceIP, endpoints)
+			return endpoints, nil
+		}
+		if err == sdcclient.ErrNotServed {
+			return nil, err
+		}
+
+		cached, age, ok := answerCache.Get(name, family, sourceIP)
+		if !ok {
+			return nil, err
+		}
+
+		requestLogger.Info("serving-stale-answer", lager.Data{
+			"service-name": name,
+			"age":          age.String(),
+			"error":        err.Error(),
+		})
+		metricSender.IncrementCounter("DNSStaleAnswers")
+		return cached, nil
+	}
+
 	metricsWrap := func(name string, handler http.Handler) http.Handler {
 		metricsWrapper := middleware.MetricWrapper{
 			Name:          name,
@@ -92,13 +137,85 @@ func main() {
 		return metricsWrapper.Wrap(handler)
 	}
 
+	// requestFailed logs and counts a query that could not be answered.
+	// Names outside the served domains are not failures, and are returned
+	// as they are so that they can be refused.
+	requestFailed := func(name string, err error) error {
+		if err == sdcclient.ErrNotServed {
+			return err
+		}
+		wrappedErr := errors.New(fmt.Sprintf("Error querying Service Discover Controller: %s", err))
+		requestLogger.Error("could not connect to service discovery controller",
+			wrappedErr,
+			lager.Data{
+				"ips":          "",
+				"service-name": name,
+			})
+
+		metricSender.IncrementCounter("DNSRequestFailures")
+		return wrappedErr
+	}
+
+	// resolve answers a query for name of a supported record type, with the
+	// records and the records for the additional section.
+	resolve := func(settings settings, name string, rrType dnsmessage.Type, sourceIP string) ([]Answer, []Answer, error) {
+		var records, additional []Answer
+		switch rrType {
+		case dnsmessage.TypeSRV:
+			endpoints, err := lookup(serviceName(name), "", sourceIP)
+			if err != nil {
+				return nil, nil, requestFailed(name, err)
+			}
+			records, additional = srvRecords(name, settings.answerStrategy.Apply(withPorts(endpoints)), settings.ttlPolicy.ForServed(name, servedTTL(endpoints)))
+		case dnsmessage.TypePTR:
+			// Only IPs in the overlay network are looked up, so that other
+			// reverse queries are answered as unknown names.
+			ip, ok := reverse.IP(name)
+			if !ok || settings.overlayNetwork == nil || !settings.overlayNetwork.Contains(ip) {
+				break
+			}
+			hostnames, err := sdcClient.HostnamesForSource(ip.String(), sourceIP)
+			if err != nil {
+				return nil, nil, requestFailed(name, err)
+			}
+			records = ptrRecords(name, hostnames, settings.ttlPolicy.For(name))
+		default:
+			family := sdcclient.FamilyIPv4
+			if rrType == dnsmessage.TypeAAAA {
+				family = sdcclient.FamilyIPv6
+			}
+			endpoints, err := lookup(name, family, sourceIP)
+			if err != nil {
+				return nil, nil, requestFailed(name, err)
+			}
+			// The targets of SRV answers are not registered, so they are
+			// answered with the endpoint of the hostname that has their IP.
+			if host, ip, ok := srvTarget(name); ok && len(endpoints) == 0 {
+				hostEndpoints, err := lookup(host, family, sourceIP)
+				if err != nil {
+					return nil, nil, requestFailed(name, err)
+				}
+				endpoints = withIP(hostEndpoints, ip)
+			}
+			records = addressRecords(name, rrType, ipsOf(settings.answerStrategy.Apply(uniqueIPs(endpoints))), settings.ttlPolicy.ForServed(name, servedTTL(endpoints)))
+		}
+
+		requestLogger.Debug("success", lager.Data{
+			"answers":      strings.Join(dataOf(records), ","),
+			"service-name": name,
+		})
+		return records, additional, nil
+	}
+
 	go func() {
 		http.Serve(l, metricsWrap("GetIPs", http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 			dnsType := getQueryParam(req, "type", "1")
 			name := getQueryParam(req, "name", "")
+			settings := current.get()
 
-			if dnsType != "1" {
-				writeResponse(resp, dnsmessage.RCodeSuccess, name, dnsType, nil, logger)
+			rrType, supported := supportedTypes[dnsType]
+			if !supported {
+				writeResponse(resp, dnsmessage.RCodeSuccess, name, dnsType, nil, nil, negativeAuthority(settings.ttlPolicy, name, nil), logger)
 				requestLogger.Debug("unsupported record type", lager.Data{
 					"ips":          "",
 					"service-name": name,
@@ -108,7 +225,7 @@ func main() {
 
 			if name == "" {
 				resp.WriteHeader(http.StatusBadRequest)
-				writeResponse(resp, dnsmessage.RCodeServerFailure, name, dnsType, nil, logger)
+				writeResponse(resp, dnsmessage.RCodeServerFailure, name, dnsType, nil, nil, nil, logger)
 				requestLogger.Debug("name parameter empty", lager.Data{
 					"ips":          "",
 					"service-name": "",
@@ -116,26 +233,18 @@ func main() {
 				return
 			}
 
-			ips, err := sdcClient.IPs(name)
+			// bosh-dns does not forward the IP of the querying container.
+			records, additional, err := resolve(settings, name, rrType, "")
+			if err == sdcclient.ErrNotServed {
+				writeResponse(resp, dnsmessage.RCodeRefused, name, dnsType, nil, nil, nil, logger)
+				return
+			}
 			if err != nil {
-				wrappedErr := errors.New(fmt.Sprintf("Error querying Service Discover Controller: %s", err))
-				writeErrorResponse(resp, wrappedErr, logger)
-				requestLogger.Error("could not connect to service discovery controller",
-					wrappedErr,
-					lager.Data{
-						"ips":          "",
-						"service-name": name,
-					})
-
-				metricSender.IncrementCounter("DNSRequestFailures")
+				writeErrorResponse(resp, err, logger)
 				return
 			}
 
-			writeResponse(resp, dnsmessage.RCodeSuccess, name, dnsType, ips, logger)
-			requestLogger.Debug("success", lager.Data{
-				"ips":          strings.Join(ips, ","),
-				"service-name": name,
-			})
+			writeResponse(resp, dnsmessage.RCodeSuccess, name, dnsType, records, additional, negativeAuthority(settings.ttlPolicy, name, records), logger)
 		})))
 	}()
 
@@ -144,12 +253,46 @@ func main() {
 		lager.NewLogger("bosh-dns-adapter"),
 		time.Duration(config.MetricsEmitSeconds)*time.Second,
 		uptimeSource,
+		metrics.MetricSource{
+			Name:   "healthyServiceDiscoveryControllers",
+			Unit:   "server",
+			Getter: sdcClient.Healthy,
+		},
 	)
 
 	members := grouper.Members{
 		{"metrics-emitter", metricsEmitter},
 		{"log-level-server", lagerlevel.NewServer(config.LogLevelAddress, config.LogLevelPort, sink, logger.Session("log-level-server"))},
 	}
+
+	if config.DNSPort > 0 {
+		dnsResolver := dnsserver.ResolverFunc(func(name string, rrType dnsmessage.Type, sourceIP string) (dnsserver.Response, error) {
+			settings := current.get()
+			if !supportedType(rrType) {
+				return dnsserver.Response{Authority: dnsRecords(negativeAuthority(settings.ttlPolicy, name, nil))}, nil
+			}
+
+			records, additional, err := resolve(settings, name, rrType, sourceIP)
+			if err == sdcclient.ErrNotServed {
+				return dnsserver.Response{RCode: dnsmessage.RCodeRefused}, nil
+			}
+			if err != nil {
+				return dnsserver.Response{}, err
+			}
+
+			return dnsserver.Response{
+				Answers:    dnsRecords(records),
+				Additional: dnsRecords(additional),
+				Authority:  dnsRecords(negativeAuthority(settings.ttlPolicy, name, records)),
+			}, nil
+		})
+
+		dnsAddress := net.JoinHostPort(config.DNSAddress, strconv.Itoa(config.DNSPort))
+		members = append(members, grouper.Member{
+			Name:   "dns-server",
+			Runner: dnsserver.NewServer(dnsAddress, dnsResolver, logger.Session("dns-server")),
+		})
+	}
 	group := grouper.NewOrdered(os.Interrupt, members)
 	monitor := ifrit.Invoke(sigmon.New(group))
 
@@ -162,14 +305,75 @@ func main() {
 	}()
 
 	logger.Info("server-started")
-	select {
-	case sig := <-signalChannel:
-		monitor.Signal(sig)
-		l.Close()
-		logger.Info("server-stopped")
+	for {
+		select {
+		case <-reloadChannel:
+			reload(*configPath, config, sdcClient, current, logger.Session("reload"))
+		case sig := <-signalChannel:
+			monitor.Signal(sig)
+			l.Close()
+			logger.Info("server-stopped")
+			return
+		}
+	}
+}
+
+// reload reads the config file again, and applies the service discovery
+// controllers, their CA and client certificate, the TTLs and the answer
+// strategy, so that they can be changed without a restart. The other fields
+// are only read on start, and changes to them are logged as errors until the
+// adapter is restarted. A config that cannot be read is logged and the
+// current settings are kept.
+func reload(configPath string, running *config.Config, sdcClient *sdcclient.ServiceDiscoveryClient, current *currentSettings, logger lager.Logger) {
+	bytes, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		logger.Error("read-config", err, lager.Data{"path": configPath})
+		return
+	}
+
+	conf, err := config.NewConfig(bytes)
+	if err != nil {
+		logger.Error("parse-config", err, lager.Data{"path": configPath})
+		return
+	}
+
+	if fields := restartRequired(running, conf); len(fields) > 0 {
+		logger.Error("restart-required", errors.New("fields changed that are only read on start"), lager.Data{"fields": fields})
+	}
+
+	sdcServerURLs, sdcServerName := serviceDiscoveryControllerURLs(conf)
+	err = sdcClient.Reload(sdcServerURLs, sdcServerName, conf.CACert, conf.ClientCert, conf.ClientKey)
+	if err != nil {
+		logger.Error("reload-service-discovery-controllers", err)
 		return
 	}
+
+	current.set(buildSettings(conf))
+	logger.Info("reloaded-config")
 }
+
+// restartRequired returns the fields of reloaded that differ from the config
+// the adapter was started with and cannot be applied without a restart.
+func restartRequired(running, reloaded *config.Config) []string {
+	fields := []string{}
+	changed := func(name string, was, is interface{}) {
+		if was != is {
+			fields = append(fields, name)
+		}
+	}
+	changed("address", running.Address, reloaded.Address)
+	changed("port", running.Port, reloaded.Port)
+	changed("metron_port", running.MetronPort, reloaded.MetronPort)
+	changed("metrics_emit_seconds", running.MetricsEmitSeconds, reloaded.MetricsEmitSeconds)
+	changed("log_level_address", running.LogLevelAddress, reloaded.LogLevelAddress)
+	changed("log_level_port", running.LogLevelPort, reloaded.LogLevelPort)
+	changed("service_discovery_controller_unhealthy_seconds", running.ServiceDiscoveryControllerUnhealthySeconds, reloaded.ServiceDiscoveryControllerUnhealthySeconds)
+	changed("max_stale_seconds", running.MaxStaleSeconds, reloaded.MaxStaleSeconds)
+	changed("dns_address", running.DNSAddress, reloaded.DNSAddress)
+	changed("dns_port", running.DNSPort, reloaded.DNSPort)
+	return fields
+}
+
 func getQueryParam(req *http.Request, key, defaultValue string) string {
 	queryValue := req.URL.Query().Get(key)
 	if queryValue == "" {
@@ -187,8 +391,8 @@ func writeErrorResponse(resp http.ResponseWriter, err error, logger lager.Logger
 	}
 }
 
-func writeResponse(resp http.ResponseWriter, dnsResponseStatus dnsmessage.RCode, requestedInfraName string, dnsType string, ips []string, logger lager.Logger) {
-	responseBody, err := buildResponseBody(dnsResponseStatus, requestedInfraName, dnsType, ips)
+func writeResponse(resp http.ResponseWriter, dnsResponseStatus dnsmessage.RCode, requestedInfraName string, dnsType string, answers, additional, authority []Answer, logger lager.Logger) {
+	responseBody, err := buildResponseBody(dnsResponseStatus, requestedInfraName, dnsType, answers, additional, authority)
 	if err != nil {
 		logger.Error("Error building response", err)
 		return
@@ -209,22 +413,378 @@ type Answer struct {
 	Data   string `json:"data"`
 }
 
-func buildResponseBody(dnsResponseStatus dnsmessage.RCode, requestedInfraName string, dnsType string, ips []string) (string, error) {
+const (
+	typeA    = "1"
+	typePTR  = "12"
+	typeAAAA = "28"
+	typeSRV  = "33"
+)
+
+// supportedTypes maps the record types that can be queried over HTTP to
+// their DNS types.
+var supportedTypes = map[string]dnsmessage.Type{
+	typeA:    dnsmessage.TypeA,
+	typePTR:  dnsmessage.TypePTR,
+	typeAAAA: dnsmessage.TypeAAAA,
+	typeSRV:  dnsmessage.TypeSRV,
+}
+
+func supportedType(rrType dnsmessage.Type) bool {
+	for _, supported := range supportedTypes {
+		if rrType == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceDiscoveryControllerURLs returns a URL per configured controller
+// address, and the name that their certificates are verified against. When
+// no addresses are configured, the controller address is used directly.
+func serviceDiscoveryControllerURLs(config *config.Config) ([]string, string) {
+	if len(config.ServiceDiscoveryControllerAddresses) == 0 {
+		return []string{fmt.Sprintf("https://%s:%s",
+			config.ServiceDiscoveryControllerAddress,
+			config.ServiceDiscoveryControllerPort,
+		)}, ""
+	}
+
+	urls := []string{}
+	for _, address := range config.ServiceDiscoveryControllerAddresses {
+		urls = append(urls, fmt.Sprintf("https://%s", net.JoinHostPort(address, config.ServiceDiscoveryControllerPort)))
+	}
+	return urls, config.ServiceDiscoveryControllerAddress
+}
+
+// parseOverlayNetwork returns the network whose IPs are answered in PTR
+// queries, or nil when reverse lookups are disabled. The config has already
+// validated the CIDR.
+func parseOverlayNetwork(config *config.Config) *net.IPNet {
+	if config.OverlayCIDR == "" {
+		return nil
+	}
+	_, network, _ := net.ParseCIDR(config.OverlayCIDR)
+	return network
+}
+
+// settings are the parts of the config that queries are answered with, and
+// that are applied again when the config is reloaded.
+type settings struct {
+	answerStrategy answers.Strategy
+	ttlPolicy      ttl.Policy
+	overlayNetwork *net.IPNet
+}
+
+func buildSettings(config *config.Config) settings {
+	overlayNetwork := parseOverlayNetwork(config)
+	return settings{
+		answerStrategy: answers.Strategy{
+			Order:          config.AnswerOrder,
+			Limit:          config.MaxAnswers,
+			PreferLocality: config.PreferLocality,
+			CellID:         config.CellID,
+			AZ:             config.AvailabilityZone,
+		},
+		ttlPolicy:      buildTTLPolicy(config, overlayNetwork),
+		overlayNetwork: overlayNetwork,
+	}
+}
+
+// currentSettings holds the settings that were loaded last. Each query is
+// answered with the settings at the time it was received.
+type currentSettings struct {
+	lock     sync.RWMutex
+	settings settings
+}
+
+func (c *currentSettings) get() settings {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.settings
+}
+
+func (c *currentSettings) set(settings settings) {
+	c.lock.Lock()
+	c.settings = settings
+	c.lock.Unlock()
+}
+
+func buildTTLPolicy(config *config.Config, overlayNetwork *net.IPNet) ttl.Policy {
+	domains := map[string]uint32{}
+	for domain, seconds := range config.DomainTTLSeconds {
+		domains[domain] = uint32(seconds)
+	}
+
+	zones := config.InternalDomains
+	if overlayNetwork != nil {
+		zones = append(append([]string{}, zones...), reverse.Zone(overlayNetwork))
+	}
+
+	return ttl.Policy{
+		Default:  uint32(config.TTLSeconds),
+		Domains:  domains,
+		Negative: uint32(config.NegativeTTLSeconds),
+		Zones:    zones,
+	}
+}
+
+// negativeAuthority returns an SOA record for empty answers, so that
+// resolvers can cache them for the negative TTL.
+func negativeAuthority(policy ttl.Policy, name string, answers []Answer) []Answer {
+	if len(answers) != 0 {
+		return nil
+	}
+
+	zone, data, ok := policy.SOA(name)
+	if !ok {
+		return nil
+	}
+
+	return []Answer{{
+		Name:   zone,
+		RRType: uint16(dnsmessage.TypeSOA),
+		TTL:    policy.Negative,
+		Data:   data,
+	}}
+}
+
+func addressRecords(name string, rrType dnsmessage.Type, ips []string, ttl uint32) []Answer {
 	answers := make([]Answer, len(ips), len(ips))
 	for i, ip := range ips {
 		answers[i] = Answer{
-			Name:   requestedInfraName,
-			RRType: uint16(dnsmessage.TypeA),
+			Name:   name,
+			RRType: uint16(rrType),
 			Data:   ip,
-			TTL:    0,
+			TTL:    ttl,
+		}
+	}
+	return answers
+}
+
+// ptrRecords returns a PTR answer per hostname registered for the IP that
+// name is the reverse lookup name of.
+func ptrRecords(name string, hostnames []string, ttl uint32) []Answer {
+	answers := make([]Answer, len(hostnames), len(hostnames))
+	for i, hostname := range hostnames {
+		answers[i] = Answer{
+			Name:   name,
+			RRType: uint16(dnsmessage.TypePTR),
+			Data:   fqdn(hostname),
+			TTL:    ttl,
+		}
+	}
+	return answers
+}
+
+// srvRecords returns an SRV answer per endpoint, and an A or AAAA record in
+// the additional section for each target. Targets are named after the IP,
+// e.g. 10-255-0-1.app.apps.internal. or
+// ip6-fd000000000000000000000000000001.app.apps.internal., and
+// A and AAAA queries for them are answered too.
+func srvRecords(name string, endpoints []sdcclient.Endpoint, ttl uint32) ([]Answer, []Answer) {
+	answers := []Answer{}
+	additional := []Answer{}
+	seenTargets := map[string]bool{}
+	for _, endpoint := range endpoints {
+		target := fmt.Sprintf("%s.%s", targetLabel(endpoint.IP), fqdn(serviceName(name)))
+		answers = append(answers, Answer{
+			Name:   name,
+			RRType: uint16(dnsmessage.TypeSRV),
+			Data:   fmt.Sprintf("0 0 %d %s", endpoint.Port, target),
+			TTL:    ttl,
+		})
+		if !seenTargets[target] {
+			seenTargets[target] = true
+			rrType := dnsmessage.TypeA
+			if strings.Contains(endpoint.IP, ":") {
+				rrType = dnsmessage.TypeAAAA
+			}
+			additional = append(additional, Answer{
+				Name:   target,
+				RRType: uint16(rrType),
+				Data:   endpoint.IP,
+				TTL:    ttl,
+			})
+		}
+	}
+	return answers, additional
+}
+
+// srvTarget parses a target name of srvRecords into the hostname and the
+// IP that it is named after.
+func srvTarget(name string) (string, net.IP, bool) {
+	dot := strings.Index(name, ".")
+	if dot == -1 {
+		return "", nil, false
+	}
+	label, host := strings.ToLower(name[:dot]), name[dot+1:]
+	if host == "" || host == "." {
+		return "", nil, false
+	}
+
+	if strings.HasPrefix(label, ipv6TargetPrefix) {
+		nibbles := strings.TrimPrefix(label, ipv6TargetPrefix)
+		if len(nibbles) != 2*net.IPv6len {
+			return "", nil, false
+		}
+		ip, err := hex.DecodeString(nibbles)
+		if err != nil || net.IP(ip).To4() != nil {
+			return "", nil, false
+		}
+		return host, net.IP(ip), true
+	}
+
+	if strings.Count(label, "-") != 3 {
+		return "", nil, false
+	}
+	ip := net.ParseIP(strings.Replace(label, "-", ".", -1))
+	if ip == nil || ip.To4() == nil {
+		return "", nil, false
+	}
+	return host, ip, true
+}
+
+// withIP returns the endpoints with the given IP.
+func withIP(endpoints []sdcclient.Endpoint, ip net.IP) []sdcclient.Endpoint {
+	matching := []sdcclient.Endpoint{}
+	for _, endpoint := range endpoints {
+		if ip.Equal(net.ParseIP(endpoint.IP)) {
+			matching = append(matching, endpoint)
 		}
 	}
+	return matching
+}
+
+// withPorts returns the endpoints that can be used in SRV records.
+func withPorts(endpoints []sdcclient.Endpoint) []sdcclient.Endpoint {
+	ported := []sdcclient.Endpoint{}
+	for _, endpoint := range endpoints {
+		if endpoint.Port != 0 {
+			ported = append(ported, endpoint)
+		}
+	}
+	return ported
+}
+
+// servedTTL returns the TTL that the controller set for the hostname of
+// endpoints, or 0 when it set none.
+func servedTTL(endpoints []sdcclient.Endpoint) uint32 {
+	if len(endpoints) == 0 {
+		return 0
+	}
+	return endpoints[0].TTL
+}
+
+// uniqueIPs returns the first endpoint for each IP, so that an IP
+// registered with several ports is answered once.
+func uniqueIPs(endpoints []sdcclient.Endpoint) []sdcclient.Endpoint {
+	unique := []sdcclient.Endpoint{}
+	seen := map[string]bool{}
+	for _, endpoint := range endpoints {
+		if !seen[endpoint.IP] {
+			seen[endpoint.IP] = true
+			unique = append(unique, endpoint)
+		}
+	}
+	return unique
+}
+
+// dnsRecords converts answers for the native DNS server.
+func dnsRecords(answers []Answer) []dnsserver.Record {
+	records := []dnsserver.Record{}
+	for _, answer := range answers {
+		records = append(records, dnsserver.Record{
+			Name: answer.Name,
+			Type: dnsmessage.Type(answer.RRType),
+			TTL:  answer.TTL,
+			Data: answer.Data,
+		})
+	}
+	return records
+}
+
+func dataOf(answers []Answer) []string {
+	data := make([]string, len(answers))
+	for i, answer := range answers {
+		data[i] = answer.Data
+	}
+	return data
+}
+
+func ipsOf(endpoints []sdcclient.Endpoint) []string {
+	ips := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		ips[i] = endpoint.IP
+	}
+	return ips
+}
+
+// ipv6TargetPrefix starts the SRV target label of an IPv6 address.
+const ipv6TargetPrefix = "ip6-"
+
+// targetLabel names the SRV target of an IP. IPv4 addresses have their dots
+// replaced by hyphens. IPv6 addresses are written as ip6- and their 32 hex
+// digits, because hyphens in place of the colons could start or end the
+// label, and would not say how many zeros a :: stands for.
+func targetLabel(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return strings.Replace(ip, ".", "-", -1)
+	}
+	if ipv4 := parsed.To4(); ipv4 != nil {
+		return strings.Replace(ipv4.String(), ".", "-", -1)
+	}
+	return ipv6TargetPrefix + hex.EncodeToString(parsed)
+}
+
+// serviceName strips the _service._proto labels from an SRV query name.
+func serviceName(name string) string {
+	for strings.HasPrefix(name, "_") {
+		dot := strings.Index(name, ".")
+		if dot == -1 {
+			return name
+		}
+		name = name[dot+1:]
+	}
+	return name
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+func buildResponseBody(dnsResponseStatus dnsmessage.RCode, requestedInfraName string, dnsType string, answers, additional, authority []Answer) (string, error) {
+	if answers == nil {
+		answers = []Answer{}
+	}
+	if additional == nil {
+		additional = []Answer{}
+	}
 
 	bytes, err := json.Marshal(answers)
 	if err != nil {
 		return "", err // not tested
 	}
 
+	additionalBytes, err := json.Marshal(additional)
+	if err != nil {
+		return "", err // not tested
+	}
+
+	// Authority is only included when there is a record to cache an empty
+	// answer with.
+	authoritySection := ""
+	if len(authority) > 0 {
+		authorityBytes, err := json.Marshal(authority)
+		if err != nil {
+			return "", err // not tested
+		}
+		authoritySection = fmt.Sprintf(`"Authority": %s,`, string(authorityBytes))
+	}
+
 	template := `{
 		"Status": %d,
 		"TC": false,
@@ -240,9 +800,10 @@ func buildResponseBody(dnsResponseStatus dnsmessage.RCode, requestedInfraName st
 			}
 		],
 		"Answer": %s,
-		"Additional": [ ],
+		"Additional": %s,
+		%s
 		"edns_client_subnet": "0.0.0.0/0"
 	}`
 
-	return fmt.Sprintf(template, dnsResponseStatus, requestedInfraName, dnsType, string(bytes)), nil
+	return fmt.Sprintf(template, dnsResponseStatus, requestedInfraName, dnsType, string(bytes), string(additionalBytes), authoritySection), nil
 }
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"strings"
+	"syscall"
 	"test-helpers"
 	"time"
 
@@ -20,6 +22,7 @@ import (
 	"github.com/onsi/gomega/gexec"
 	"github.com/onsi/gomega/ghttp"
 	"github.com/onsi/gomega/types"
+	"golang.org/x/net/dns/dnsmessage"
 )
 
 var _ = Describe("Main", func() {
@@ -33,6 +36,10 @@ var _ = Describe("Main", func() {
 		dnsAdapterPort                         string
 		fakeMetron                             metrics.FakeMetron
 		logLevelPort                           int
+		extraConfig                            string
+		caFileName                             string
+		clientCertFileName                     string
+		clientKeyFileName                      string
 	)
 
 	BeforeEach(func() {
@@ -59,11 +66,15 @@ var _ = Describe("Main", func() {
 
 		dnsAdapterPort = fmt.Sprintf("%d", ports.PickAPort())
 		logLevelPort = ports.PickAPort()
+		extraConfig = ""
 	})
 
 	JustBeforeEach(func() {
-		var err error
-		caFileName, clientCertFileName, clientKeyFileName, serverCert := testhelpers.GenerateCaAndMutualTlsCerts()
+		var (
+			err        error
+			serverCert tls.Certificate
+		)
+		caFileName, clientCertFileName, clientKeyFileName, serverCert = testhelpers.GenerateCaAndMutualTlsCerts()
 
 		fakeServiceDiscoveryControllerServer = ghttp.NewUnstartedServer()
 		fakeServiceDiscoveryControllerServer.HTTPTestServer.TLS = &tls.Config{}
@@ -90,7 +101,7 @@ var _ = Describe("Main", func() {
 			"metron_port": %d,
 			"metrics_emit_seconds": 2,
 			"log_level_port": %d,
-			"log_level_address": "127.0.0.1"
+			"log_level_address": "127.0.0.1"%s
 		}`, dnsAdapterAddress,
 			dnsAdapterPort,
 			strings.TrimPrefix(urlParts[1], "//"),
@@ -100,6 +111,7 @@ var _ = Describe("Main", func() {
 			caFileName,
 			fakeMetron.Port(),
 			logLevelPort,
+			extraConfig,
 		)
 
 		tempConfigFile, err = ioutil.TempFile(os.TempDir(), "sd")
@@ -171,6 +183,81 @@ var _ = Describe("Main", func() {
 		Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-stopped"))
 	})
 
+	Context("when it receives SIGHUP", func() {
+		It("queries the service discovery controller with the new certificates", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=1&name=app-id.internal.local.", dnsAdapterPort)
+			makeDNSRequest(url, 200)
+
+			newCAFileName, newClientCertFileName, newClientKeyFileName, _ := testhelpers.GenerateCaAndMutualTlsCerts()
+			defer os.Remove(newCAFileName)
+			defer os.Remove(newClientCertFileName)
+			defer os.Remove(newClientKeyFileName)
+
+			newConfigFileContents := strings.NewReplacer(
+				caFileName, newCAFileName,
+				clientCertFileName, newClientCertFileName,
+				clientKeyFileName, newClientKeyFileName,
+			).Replace(configFileContents)
+			Expect(ioutil.WriteFile(tempConfigFile.Name(), []byte(newConfigFileContents), os.ModePerm)).To(Succeed())
+
+			session.Signal(syscall.SIGHUP)
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.reload.reloaded-config"))
+
+			makeDNSRequest(url, 500)
+			Eventually(session).Should(gbytes.Say("could not connect to service discovery controller"))
+			Expect(session).NotTo(gexec.Exit())
+		})
+
+		It("answers with the new TTLs", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+			fakeServiceDiscoveryControllerServer.RouteToHandler("GET", "/v1/registration/app-id.internal.local.",
+				ghttp.RespondWith(200, `{ "hosts": [ { "ip_address": "192.168.0.1", "port": 0, "tags": {} } ] }`))
+
+			newConfigFileContents := strings.Replace(configFileContents,
+				`"log_level_address": "127.0.0.1"`,
+				`"log_level_address": "127.0.0.1", "ttl_seconds": 42`, 1)
+			Expect(ioutil.WriteFile(tempConfigFile.Name(), []byte(newConfigFileContents), os.ModePerm)).To(Succeed())
+
+			session.Signal(syscall.SIGHUP)
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.reload.reloaded-config"))
+
+			resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%s?type=1&name=app-id.internal.local.", dnsAdapterPort))
+			Expect(err).NotTo(HaveOccurred())
+			respBody, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(respBody)).To(ContainSubstring(`"TTL":42`))
+		})
+
+		It("logs an error when a field that is only read on start changes", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			newConfigFileContents := strings.Replace(configFileContents,
+				`"metrics_emit_seconds": 2`,
+				`"metrics_emit_seconds": 3`, 1)
+			Expect(ioutil.WriteFile(tempConfigFile.Name(), []byte(newConfigFileContents), os.ModePerm)).To(Succeed())
+
+			session.Signal(syscall.SIGHUP)
+			Eventually(session).Should(gbytes.Say(`bosh-dns-adapter.reload.restart-required.*metrics_emit_seconds`))
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.reload.reloaded-config"))
+			Expect(session).NotTo(gexec.Exit())
+		})
+
+		Context("when the config file is invalid", func() {
+			It("logs and keeps the current certificates", func() {
+				Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+				Expect(ioutil.WriteFile(tempConfigFile.Name(), []byte("garbage"), os.ModePerm)).To(Succeed())
+
+				session.Signal(syscall.SIGHUP)
+				Eventually(session).Should(gbytes.Say("bosh-dns-adapter.reload.parse-config"))
+
+				url := fmt.Sprintf("http://127.0.0.1:%s?type=1&name=app-id.internal.local.", dnsAdapterPort)
+				makeDNSRequest(url, 200)
+				Expect(session).NotTo(gexec.Exit())
+			})
+		})
+	})
+
 	Describe("emitting metrics", func() {
 		Context("when things are going well", func() {
 			JustBeforeEach(func() {
@@ -187,6 +274,13 @@ var _ = Describe("Main", func() {
 				)))
 			})
 
+			It("emits a healthy service discovery controllers metric", func() {
+				Eventually(fakeMetron.AllEvents, "5s").Should(ContainElement(SatisfyAll(
+					metricWithName("healthyServiceDiscoveryControllers"),
+					metricWithOrigin("bosh-dns-adapter"),
+				)))
+			})
+
 			It("emits an request metrics", func() {
 				Eventually(fakeMetron.AllEvents, "5s").Should(ContainElement(SatisfyAll(
 					metricWithName("GetIPsRequestTime"),
@@ -381,7 +475,7 @@ var _ = Describe("Main", func() {
 		})
 	})
 
-	Context("when requesting anything but an A record", func() {
+	Context("when requesting an unsupported record type", func() {
 		It("should return a successful response with no answers", func() {
 			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
 			url := fmt.Sprintf("http://127.0.0.1:%s?type=16&name=app-id.internal.local.", dnsAdapterPort)
@@ -417,24 +511,483 @@ var _ = Describe("Main", func() {
 		})
 	})
 
+	Context("when the service discovery controller has ipv4 and ipv6 addresses", func() {
+		BeforeEach(func() {
+			fakeServiceDiscoveryControllerResponse = []http.HandlerFunc{ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
+				ghttp.RespondWith(200, `{
+					"env": "",
+					"hosts": [
+					{ "ip_address": "192.168.0.1", "port": 0, "tags": {} },
+					{ "ip_address": "fd00::1", "port": 0, "tags": {} }
+					],
+					"service": ""
+				}`),
+			)}
+		})
+
+		getAnswers := func(dnsType string) string {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=%s&name=app-id.internal.local.", dnsAdapterPort, dnsType)
+			resp, err := http.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			all, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return string(all)
+		}
+
+		It("answers AAAA queries with ipv6 addresses", func() {
+			Expect(getAnswers("28")).To(MatchJSON(`{
+					"Status": 0,
+					"TC": false,
+					"RD": false,
+					"RA": false,
+					"AD": false,
+					"CD": false,
+					"Question": [ { "name": "app-id.internal.local.", "type": 28 } ],
+					"Answer": [ { "name": "app-id.internal.local.", "type": 28, "TTL": 0, "data": "fd00::1" } ],
+					"Additional": [ ],
+					"edns_client_subnet": "0.0.0.0/0"
+				}`))
+			Expect(fakeServiceDiscoveryControllerServer.ReceivedRequests()[0].URL.RawQuery).To(Equal("family=ipv6"))
+		})
+
+		It("answers A queries with ipv4 addresses only", func() {
+			Expect(getAnswers("1")).To(MatchJSON(`{
+					"Status": 0,
+					"TC": false,
+					"RD": false,
+					"RA": false,
+					"AD": false,
+					"CD": false,
+					"Question": [ { "name": "app-id.internal.local.", "type": 1 } ],
+					"Answer": [ { "name": "app-id.internal.local.", "type": 1, "TTL": 0, "data": "192.168.0.1" } ],
+					"Additional": [ ],
+					"edns_client_subnet": "0.0.0.0/0"
+				}`))
+		})
+	})
+
+	Context("when requesting an SRV record", func() {
+		BeforeEach(func() {
+			fakeServiceDiscoveryControllerResponse = []http.HandlerFunc{ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
+				ghttp.RespondWith(200, `{
+					"env": "",
+					"hosts": [
+					{ "ip_address": "192.168.0.1", "port": 8080, "tags": {} },
+					{ "ip_address": "192.168.0.2", "port": 0, "tags": {} }
+					],
+					"service": ""
+				}`),
+			)}
+		})
+
+		It("returns the port and target of each endpoint", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=33&name=_http._tcp.app-id.internal.local.", dnsAdapterPort)
+			resp, err := http.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			all, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(string(all)).To(MatchJSON(`{
+					"Status": 0,
+					"TC": false,
+					"RD": false,
+					"RA": false,
+					"AD": false,
+					"CD": false,
+					"Question":
+					[
+						{
+							"name": "_http._tcp.app-id.internal.local.",
+							"type": 33
+						}
+					],
+					"Answer":
+					[
+						{
+							"name": "_http._tcp.app-id.internal.local.",
+							"type": 33,
+							"TTL": 0,
+							"data": "0 0 8080 192-168-0-1.app-id.internal.local."
+						}
+					],
+					"Additional":
+					[
+						{
+							"name": "192-168-0-1.app-id.internal.local.",
+							"type": 1,
+							"TTL": 0,
+							"data": "192.168.0.1"
+						}
+					],
+					"edns_client_subnet": "0.0.0.0/0"
+				}`))
+		})
+	})
+
+	Context("when requesting an A record for the target of an SRV record", func() {
+		BeforeEach(func() {
+			fakeServiceDiscoveryControllerResponse = []http.HandlerFunc{
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/registration/192-168-0-2.app-id.internal.local."),
+					ghttp.RespondWith(200, `{ "env": "", "hosts": [], "service": "" }`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
+					ghttp.RespondWith(200, `{
+					"env": "",
+					"hosts": [
+					{ "ip_address": "192.168.0.1", "port": 8080, "tags": {} },
+					{ "ip_address": "192.168.0.2", "port": 8080, "tags": {} }
+					],
+					"service": ""
+				}`),
+				),
+			}
+		})
+
+		It("answers with the IP the target is named after", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=1&name=192-168-0-2.app-id.internal.local.", dnsAdapterPort)
+			resp, err := http.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			all, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(string(all)).To(MatchJSON(`{
+					"Status": 0,
+					"TC": false,
+					"RD": false,
+					"RA": false,
+					"AD": false,
+					"CD": false,
+					"Question": [ { "name": "192-168-0-2.app-id.internal.local.", "type": 1 } ],
+					"Answer": [ { "name": "192-168-0-2.app-id.internal.local.", "type": 1, "TTL": 0, "data": "192.168.0.2" } ],
+					"Additional": [ ],
+					"edns_client_subnet": "0.0.0.0/0"
+				}`))
+		})
+	})
+
+	Context("when requesting an AAAA record for the IPv6 target of an SRV record", func() {
+		BeforeEach(func() {
+			fakeServiceDiscoveryControllerResponse = []http.HandlerFunc{
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/registration/ip6-fd000000000000000000000000000002.app-id.internal.local."),
+					ghttp.RespondWith(200, `{ "env": "", "hosts": [], "service": "" }`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
+					ghttp.RespondWith(200, `{
+					"env": "",
+					"hosts": [
+					{ "ip_address": "fd00::1", "port": 8080, "tags": {} },
+					{ "ip_address": "fd00::2", "port": 8080, "tags": {} }
+					],
+					"service": ""
+				}`),
+				),
+			}
+		})
+
+		It("answers with the IP the target is named after", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=28&name=ip6-fd000000000000000000000000000002.app-id.internal.local.", dnsAdapterPort)
+			resp, err := http.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			all, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(string(all)).To(MatchJSON(`{
+					"Status": 0,
+					"TC": false,
+					"RD": false,
+					"RA": false,
+					"AD": false,
+					"CD": false,
+					"Question": [ { "name": "ip6-fd000000000000000000000000000002.app-id.internal.local.", "type": 28 } ],
+					"Answer": [ { "name": "ip6-fd000000000000000000000000000002.app-id.internal.local.", "type": 28, "TTL": 0, "data": "fd00::2" } ],
+					"Additional": [ ],
+					"edns_client_subnet": "0.0.0.0/0"
+				}`))
+		})
+	})
+
+	Context("when configured to prefer the local cell and limit answers", func() {
+		BeforeEach(func() {
+			extraConfig = `,
+			"max_answers": 1,
+			"prefer_locality": "cell",
+			"cell_id": "cell-2",
+			"availability_zone": "z1"`
+
+			fakeServiceDiscoveryControllerResponse = []http.HandlerFunc{ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
+				ghttp.RespondWith(200, `{
+					"env": "",
+					"hosts": [
+					{ "ip_address": "192.168.0.1", "port": 0, "tags": {"cell_id": "cell-1", "availability_zone": "z1"} },
+					{ "ip_address": "192.168.0.2", "port": 0, "tags": {"cell_id": "cell-2", "availability_zone": "z1"} },
+					{ "ip_address": "192.168.0.3", "port": 0, "tags": {} }
+					],
+					"service": ""
+				}`),
+			)}
+		})
+
+		It("answers with the instance on the same cell", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=1&name=app-id.internal.local.", dnsAdapterPort)
+			resp, err := http.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			all, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(all)).To(MatchJSON(`{
+					"Status": 0,
+					"TC": false,
+					"RD": false,
+					"RA": false,
+					"AD": false,
+					"CD": false,
+					"Question": [ { "name": "app-id.internal.local.", "type": 1 } ],
+					"Answer": [ { "name": "app-id.internal.local.", "type": 1, "TTL": 0, "data": "192.168.0.2" } ],
+					"Additional": [ ],
+					"edns_client_subnet": "0.0.0.0/0"
+				}`))
+		})
+	})
+
+	Context("when configured with TTLs", func() {
+		BeforeEach(func() {
+			extraConfig = `,
+			"ttl_seconds": 30,
+			"domain_ttl_seconds": {"internal.local.": 10},
+			"negative_ttl_seconds": 5,
+			"internal_domains": ["internal.local."]`
+
+			fakeServiceDiscoveryControllerResponse = append(fakeServiceDiscoveryControllerResponse, ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v1/registration/missing.internal.local."),
+				ghttp.RespondWith(200, `{ "env": "", "hosts": [], "service": "" }`),
+			))
+		})
+
+		getAnswers := func(name string) string {
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=1&name=%s", dnsAdapterPort, name)
+			resp, err := http.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			all, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return string(all)
+		}
+
+		It("answers with the TTL of the domain, and lets empty answers be cached", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			Expect(getAnswers("app-id.internal.local.")).To(MatchJSON(`{
+					"Status": 0,
+					"TC": false,
+					"RD": false,
+					"RA": false,
+					"AD": false,
+					"CD": false,
+					"Question": [ { "name": "app-id.internal.local.", "type": 1 } ],
+					"Answer": [ { "name": "app-id.internal.local.", "type": 1, "TTL": 10, "data": "192.168.0.1" } ],
+					"Additional": [ ],
+					"edns_client_subnet": "0.0.0.0/0"
+				}`))
+
+			Expect(getAnswers("missing.internal.local.")).To(MatchJSON(`{
+					"Status": 0,
+					"TC": false,
+					"RD": false,
+					"RA": false,
+					"AD": false,
+					"CD": false,
+					"Question": [ { "name": "missing.internal.local.", "type": 1 } ],
+					"Answer": [ ],
+					"Additional": [ ],
+					"Authority": [ {
+						"name": "internal.local.",
+						"type": 6,
+						"TTL": 5,
+						"data": "internal.local. hostmaster.internal.local. 1 3600 600 86400 5"
+					} ],
+					"edns_client_subnet": "0.0.0.0/0"
+				}`))
+		})
+	})
+
+	Context("when the service discovery controller sets a TTL", func() {
+		BeforeEach(func() {
+			extraConfig = `,
+			"ttl_seconds": 30`
+
+			fakeServiceDiscoveryControllerResponse = []http.HandlerFunc{ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
+				ghttp.RespondWith(200, `{
+					"env": "",
+					"hosts": [ { "ip_address": "192.168.0.1", "port": 0, "tags": {} } ],
+					"service": "",
+					"ttl_seconds": 15
+				}`),
+			)}
+		})
+
+		It("answers with the TTL of the controller", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=1&name=app-id.internal.local.", dnsAdapterPort)
+			resp, err := http.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			all, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(all)).To(ContainSubstring(`"TTL":15`))
+		})
+	})
+
+	Context("when reverse lookups of the overlay network are enabled", func() {
+		BeforeEach(func() {
+			extraConfig = `,
+			"ttl_seconds": 30,
+			"negative_ttl_seconds": 5,
+			"overlay_cidr": "10.255.0.0/16"`
+
+			fakeServiceDiscoveryControllerResponse = []http.HandlerFunc{ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v1/reverse/10.255.3.4"),
+				ghttp.RespondWith(200, `{
+					"ip": "10.255.3.4",
+					"hostnames": [ "app-id.internal.local.", "other-app.internal.local." ]
+				}`),
+			)}
+		})
+
+		getAnswers := func(name string) string {
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=12&name=%s", dnsAdapterPort, name)
+			resp, err := http.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			all, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return string(all)
+		}
+
+		It("answers PTR queries with the hostnames of the IP", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			Expect(getAnswers("4.3.255.10.in-addr.arpa.")).To(MatchJSON(`{
+					"Status": 0,
+					"TC": false,
+					"RD": false,
+					"RA": false,
+					"AD": false,
+					"CD": false,
+					"Question": [ { "name": "4.3.255.10.in-addr.arpa.", "type": 12 } ],
+					"Answer": [
+						{ "name": "4.3.255.10.in-addr.arpa.", "type": 12, "TTL": 30, "data": "app-id.internal.local." },
+						{ "name": "4.3.255.10.in-addr.arpa.", "type": 12, "TTL": 30, "data": "other-app.internal.local." }
+					],
+					"Additional": [ ],
+					"edns_client_subnet": "0.0.0.0/0"
+				}`))
+		})
+
+		It("does not look up IPs outside the overlay network", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			Expect(getAnswers("4.3.2.192.in-addr.arpa.")).To(ContainSubstring(`"Answer": [],`))
+			Expect(fakeServiceDiscoveryControllerServer.ReceivedRequests()).To(BeEmpty())
+		})
+
+		It("lets empty answers in the overlay zone be cached", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			fakeServiceDiscoveryControllerServer.SetHandler(0, ghttp.RespondWith(200, `{ "ip": "10.255.3.4", "hostnames": [] }`))
+			Expect(getAnswers("4.3.255.10.in-addr.arpa.")).To(ContainSubstring(`"data":"255.10.in-addr.arpa. hostmaster.255.10.in-addr.arpa. 1 3600 600 86400 5"`))
+		})
+	})
+
+	Context("when the service discovery controller fails after answering", func() {
+		BeforeEach(func() {
+			extraConfig = `,
+			"max_stale_seconds": 60`
+
+			for i := 0; i < 4; i++ {
+				fakeServiceDiscoveryControllerResponse = append(fakeServiceDiscoveryControllerResponse, ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
+					ghttp.RespondWith(500, `{ }`),
+				))
+			}
+		})
+
+		getStatusAndAnswers := func() (int, string) {
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=1&name=app-id.internal.local.", dnsAdapterPort)
+			resp, err := http.Get(url)
+			Expect(err).ToNot(HaveOccurred())
+
+			all, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return resp.StatusCode, string(all)
+		}
+
+		It("serves the last answer as stale", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			status, fresh := getStatusAndAnswers()
+			Expect(status).To(Equal(http.StatusOK))
+
+			status, stale := getStatusAndAnswers()
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(stale).To(MatchJSON(fresh))
+
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.serve-request.serving-stale-answer"))
+			Eventually(fakeMetron.AllEvents, "5s").Should(ContainElement(SatisfyAll(
+				metricWithName("DNSStaleAnswers"),
+				metricWithOrigin("bosh-dns-adapter"),
+			)))
+		})
+	})
+
 	Context("when the service discovery controller returns non-successful", func() {
 		BeforeEach(func() {
 			fakeServiceDiscoveryControllerResponse = []http.HandlerFunc{
 				ghttp.CombineHandlers(
 					ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
-					ghttp.RespondWith(404, `{ }`),
+					ghttp.RespondWith(500, `{ }`),
 				),
 				ghttp.CombineHandlers(
 					ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
-					ghttp.RespondWith(404, `{ }`),
+					ghttp.RespondWith(500, `{ }`),
 				),
 				ghttp.CombineHandlers(
 					ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
-					ghttp.RespondWith(404, `{ }`),
+					ghttp.RespondWith(500, `{ }`),
 				),
 				ghttp.CombineHandlers(
 					ghttp.VerifyRequest("GET", "/v1/registration/app-id.internal.local."),
-					ghttp.RespondWith(404, `{ }`),
+					ghttp.RespondWith(500, `{ }`),
 				),
 			}
 		})
@@ -459,6 +1012,117 @@ var _ = Describe("Main", func() {
 		})
 	})
 
+	Context("when the name is outside the domains of the service discovery controller", func() {
+		var dnsPort int
+
+		BeforeEach(func() {
+			fakeServiceDiscoveryControllerResponse = []http.HandlerFunc{
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/registration/app-id.unknown."),
+					ghttp.RespondWith(http.StatusNotFound, "hostname is outside the configured domains"),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/registration/app-id.unknown."),
+					ghttp.RespondWith(http.StatusNotFound, "hostname is outside the configured domains"),
+				),
+			}
+			dnsPort = ports.PickAPort()
+			extraConfig = fmt.Sprintf(`,
+				"dns_address": "127.0.0.1",
+				"dns_port": %d`, dnsPort)
+		})
+
+		It("refuses the query", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			url := fmt.Sprintf("http://127.0.0.1:%s?type=1&name=app-id.unknown.", dnsAdapterPort)
+			resp, err := http.Get(url)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(respBody)).To(ContainSubstring(`"Status": 5`))
+			Expect(string(respBody)).To(ContainSubstring(`"Answer": []`))
+			Expect(fakeServiceDiscoveryControllerServer.ReceivedRequests()).To(HaveLen(1))
+		})
+
+		It("refuses the query over the native DNS server", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			query, err := (&dnsmessage.Message{
+				Header: dnsmessage.Header{ID: 42},
+				Questions: []dnsmessage.Question{{
+					Name:  dnsmessage.MustNewName("app-id.unknown."),
+					Type:  dnsmessage.TypeA,
+					Class: dnsmessage.ClassINET,
+				}},
+			}).Pack()
+			Expect(err).NotTo(HaveOccurred())
+
+			conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", dnsPort))
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			_, err = conn.Write(query)
+			Expect(err).NotTo(HaveOccurred())
+
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			buf := make([]byte, 512)
+			n, err := conn.Read(buf)
+			Expect(err).NotTo(HaveOccurred())
+
+			var response dnsmessage.Message
+			Expect(response.Unpack(buf[:n])).To(Succeed())
+			Expect(response.Header.RCode).To(Equal(dnsmessage.RCodeRefused))
+			Expect(response.Answers).To(BeEmpty())
+		})
+	})
+
+	Context("when the native DNS server is enabled", func() {
+		var dnsPort int
+
+		BeforeEach(func() {
+			dnsPort = ports.PickAPort()
+			extraConfig = fmt.Sprintf(`,
+				"dns_address": "127.0.0.1",
+				"dns_port": %d`, dnsPort)
+		})
+
+		It("answers DNS queries over UDP", func() {
+			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
+
+			query, err := (&dnsmessage.Message{
+				Header: dnsmessage.Header{ID: 42},
+				Questions: []dnsmessage.Question{{
+					Name:  dnsmessage.MustNewName("app-id.internal.local."),
+					Type:  dnsmessage.TypeA,
+					Class: dnsmessage.ClassINET,
+				}},
+			}).Pack()
+			Expect(err).NotTo(HaveOccurred())
+
+			conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", dnsPort))
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+
+			_, err = conn.Write(query)
+			Expect(err).NotTo(HaveOccurred())
+
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			buf := make([]byte, 512)
+			n, err := conn.Read(buf)
+			Expect(err).NotTo(HaveOccurred())
+
+			var response dnsmessage.Message
+			Expect(response.Unpack(buf[:n])).To(Succeed())
+			Expect(response.Header.ID).To(Equal(uint16(42)))
+			Expect(response.Header.RCode).To(Equal(dnsmessage.RCodeSuccess))
+			Expect(response.Answers).To(HaveLen(1))
+			Expect(response.Answers[0].Body).To(Equal(&dnsmessage.AResource{A: [4]byte{192, 168, 0, 1}}))
+		})
+	})
+
 	Context("logging", func() {
 		JustBeforeEach(func() {
 			Eventually(session).Should(gbytes.Say("bosh-dns-adapter.server-started"))
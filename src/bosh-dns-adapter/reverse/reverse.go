@@ -0,0 +1,60 @@
+package reverse
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const arpaSuffix = ".in-addr.arpa."
+
+// IP returns the IPv4 address that a PTR query name such as
+// 4.3.255.10.in-addr.arpa. is for, or false when name is not the name of a
+// single IPv4 address.
+func IP(name string) (net.IP, bool) {
+	name = strings.ToLower(fqdn(name))
+	if !strings.HasSuffix(name, arpaSuffix) {
+		return nil, false
+	}
+
+	labels := strings.Split(strings.TrimSuffix(name, arpaSuffix), ".")
+	if len(labels) != net.IPv4len {
+		return nil, false
+	}
+
+	ip := make(net.IP, net.IPv4len)
+	for i, label := range labels {
+		octet, err := strconv.ParseUint(label, 10, 8)
+		if err != nil {
+			return nil, false
+		}
+		ip[net.IPv4len-1-i] = byte(octet)
+	}
+	return ip, true
+}
+
+// Zone returns the in-addr.arpa. zone that holds the PTR names of network,
+// e.g. 255.10.in-addr.arpa. for 10.255.0.0/16. Networks that do not end on
+// an octet boundary get the zone of the enclosing octet, so 10.255.0.0/20
+// also gets 255.10.in-addr.arpa.
+func Zone(network *net.IPNet) string {
+	ip := network.IP.To4()
+	ones, _ := network.Mask.Size()
+	if ip == nil || ones == 0 {
+		return "in-addr.arpa."
+	}
+
+	labels := []string{}
+	for i := ones/8 - 1; i >= 0; i-- {
+		labels = append(labels, strconv.Itoa(int(ip[i])))
+	}
+	return fmt.Sprintf("%s%s", strings.Join(labels, "."), arpaSuffix)
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
@@ -0,0 +1,13 @@
+package reverse_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestReverse(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Reverse Suite")
+}
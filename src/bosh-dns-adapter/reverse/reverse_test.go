@@ -0,0 +1,55 @@
+package reverse_test
+
+import (
+	"bosh-dns-adapter/reverse"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IP", func() {
+	It("returns the IP of a PTR name", func() {
+		ip, ok := reverse.IP("4.3.255.10.in-addr.arpa.")
+		Expect(ok).To(BeTrue())
+		Expect(ip.String()).To(Equal("10.255.3.4"))
+
+		ip, ok = reverse.IP("4.3.255.10.IN-ADDR.ARPA")
+		Expect(ok).To(BeTrue())
+		Expect(ip.String()).To(Equal("10.255.3.4"))
+	})
+
+	It("rejects names that are not for a single IPv4 address", func() {
+		for _, name := range []string{
+			"3.255.10.in-addr.arpa.",
+			"5.4.3.255.10.in-addr.arpa.",
+			"256.3.255.10.in-addr.arpa.",
+			"a.3.255.10.in-addr.arpa.",
+			"4.3.255.10.ip6.arpa.",
+			"app.apps.internal.",
+		} {
+			_, ok := reverse.IP(name)
+			Expect(ok).To(BeFalse(), name)
+		}
+	})
+})
+
+var _ = Describe("Zone", func() {
+	zoneOf := func(cidr string) string {
+		_, network, err := net.ParseCIDR(cidr)
+		Expect(err).NotTo(HaveOccurred())
+		return reverse.Zone(network)
+	}
+
+	It("returns the in-addr.arpa. zone of the network", func() {
+		Expect(zoneOf("10.255.0.0/16")).To(Equal("255.10.in-addr.arpa."))
+		Expect(zoneOf("10.0.0.0/8")).To(Equal("10.in-addr.arpa."))
+		Expect(zoneOf("192.168.1.0/24")).To(Equal("1.168.192.in-addr.arpa."))
+	})
+
+	It("rounds down to an octet boundary", func() {
+		Expect(zoneOf("10.255.0.0/20")).To(Equal("255.10.in-addr.arpa."))
+		Expect(zoneOf("10.240.0.0/12")).To(Equal("10.in-addr.arpa."))
+		Expect(zoneOf("0.0.0.0/0")).To(Equal("in-addr.arpa."))
+	})
+})
@@ -8,26 +8,141 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"math/rand"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 )
 
+// ServiceDiscoveryClient queries a list of service discovery controllers.
+// A controller that fails a query is marked unhealthy, and is only queried
+// again after the others once the unhealthy duration has passed.
 type ServiceDiscoveryClient struct {
-	serverURL string
-	client    *http.Client
+	servers           []*server
+	unhealthyDuration time.Duration
+	serverName        string
+	client            *http.Client
+	lock              sync.Mutex
+}
+
+type server struct {
+	url      string
+	failedAt time.Time
 }
 
 type serverResponse struct {
-	Hosts []host `json:"Hosts"`
+	Hosts      []host `json:"Hosts"`
+	TTLSeconds uint32 `json:"ttl_seconds"`
+}
+
+type reverseResponse struct {
+	Hostnames []string `json:"hostnames"`
 }
 
 type host struct {
-	IPAddress string `json:"ip_address"`
+	IPAddress string   `json:"ip_address"`
+	Port      uint16   `json:"port"`
+	Tags      hostTags `json:"tags"`
+	ttl       uint32
+}
+
+type hostTags struct {
+	CellID string `json:"cell_id"`
+	AZ     string `json:"availability_zone"`
+}
+
+// Endpoint is a registered address. Port is 0 when the registration did not
+// include one. CellID and AZ are empty when the registration did not
+// include them. TTL is the TTL in seconds that the server set for answers
+// for the hostname, or 0 when it set none.
+type Endpoint struct {
+	IP     string
+	Port   uint16
+	CellID string
+	AZ     string
+	TTL    uint32
+}
+
+// Address families accepted by EndpointsForSource. An empty family means any.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// ErrNotServed is returned for a hostname outside every domain that the
+// servers serve. It is not a failure of the server that answered.
+var ErrNotServed = errors.New("hostname is outside the served domains")
+
+// SourceIPHeader carries the IP of the container that sent a query, for
+// controllers that only answer with the apps it may reach.
+const SourceIPHeader = "X-Source-IP"
+
+// NewServiceDiscoveryClient returns a client that queries the servers in
+// order. serverName is the name verified in their certificates, or empty to
+// use the host of each URL.
+func NewServiceDiscoveryClient(serverURLs []string, serverName, caPath, clientCertPath, clientKeyPath string, unhealthyDuration time.Duration) (*ServiceDiscoveryClient, error) {
+	if len(serverURLs) == 0 {
+		return nil, errors.New("no server urls")
+	}
+
+	client, err := newHTTPClient(serverName, caPath, clientCertPath, clientKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := []*server{}
+	for _, serverURL := range serverURLs {
+		servers = append(servers, &server{url: serverURL})
+	}
+
+	return &ServiceDiscoveryClient{
+		servers:           servers,
+		unhealthyDuration: unhealthyDuration,
+		serverName:        serverName,
+		client:            client,
+	}, nil
+}
+
+// Reload queries serverURLs from then on, verifying serverName in their
+// certificates, with the CA and client key pair loaded again. Servers that
+// were queried before keep their health. If any of the files cannot be read,
+// nothing is changed.
+func (s *ServiceDiscoveryClient) Reload(serverURLs []string, serverName, caPath, clientCertPath, clientKeyPath string) error {
+	if len(serverURLs) == 0 {
+		return errors.New("no server urls")
+	}
+
+	client, err := newHTTPClient(serverName, caPath, clientCertPath, clientKeyPath)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	known := map[string]*server{}
+	for _, server := range s.servers {
+		known[server.url] = server
+	}
+	servers := []*server{}
+	for _, serverURL := range serverURLs {
+		if existing, ok := known[serverURL]; ok {
+			servers = append(servers, existing)
+		} else {
+			servers = append(servers, &server{url: serverURL})
+		}
+	}
+	previous := s.client
+	s.servers = servers
+	s.serverName = serverName
+	s.client = client
+	s.lock.Unlock()
+
+	if transport, ok := previous.Transport.(*http.Transport); ok {
+		transport.CloseIdleConnections()
+	}
+	return nil
 }
 
-func NewServiceDiscoveryClient(serverURL, caPath, clientCertPath, clientKeyPath string) (*ServiceDiscoveryClient, error) {
+func newHTTPClient(serverName, caPath, clientCertPath, clientKeyPath string) (*http.Client, error) {
 	caPemBytes, err := ioutil.ReadFile(caPath)
 	if err != nil {
 		return nil, fmt.Errorf("read CA file: %s", err)
@@ -47,6 +162,7 @@ func NewServiceDiscoveryClient(serverURL, caPath, clientCertPath, clientKeyPath
 		ClientCAs:    caCertPool,
 		RootCAs:      caCertPool,
 		Certificates: []tls.Certificate{cert},
+		ServerName:   serverName,
 	}
 
 	tlsConfig.BuildNameToCertificate()
@@ -63,33 +179,198 @@ func NewServiceDiscoveryClient(serverURL, caPath, clientCertPath, clientKeyPath
 		ExpectContinueTimeout: 1 * time.Second,
 	}
 
-	client := &http.Client{
+	return &http.Client{
 		Transport: tr,
 		Timeout:   time.Second * 10,
+	}, nil
+}
+
+// Healthy returns the number of servers that are not marked unhealthy. It
+// can be used as a metric getter.
+func (s *ServiceDiscoveryClient) Healthy() (float64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	healthy := 0
+	now := time.Now()
+	for _, server := range s.servers {
+		if server.healthy(now, s.unhealthyDuration) {
+			healthy++
+		}
+	}
+	return float64(healthy), nil
+}
+
+// EndpointsForSource returns every registered endpoint of the family, in
+// the order the server returned them, so that the caller can choose how to
+// order its answers. An empty family means any. A sourceIP tells the server
+// that the query was sent by the container with that IP.
+func (s *ServiceDiscoveryClient) EndpointsForSource(infrastructureName, family, sourceIP string) ([]Endpoint, error) {
+	hosts, err := s.hosts(infrastructureName, family, sourceIP)
+	if err != nil {
+		return []Endpoint{}, err
 	}
 
-	return &ServiceDiscoveryClient{
-		serverURL: serverURL,
-		client:    client,
-	}, nil
+	endpoints := []Endpoint{}
+	for _, host := range hosts {
+		endpoints = append(endpoints, Endpoint{
+			IP:     host.IPAddress,
+			Port:   host.Port,
+			CellID: host.Tags.CellID,
+			AZ:     host.Tags.AZ,
+			TTL:    host.ttl,
+		})
+	}
+
+	return endpoints, nil
 }
 
-func (s *ServiceDiscoveryClient) IPs(infrastructureName string) ([]string, error) {
-	requestUrl := fmt.Sprintf("%s/v1/registration/%s", s.serverURL, infrastructureName)
+// HostnamesForSource returns the hostnames registered for ip, telling the
+// server that the query was sent by the container with sourceIP. Servers are
+// queried in the same order as for EndpointsForSource.
+func (s *ServiceDiscoveryClient) HostnamesForSource(ip, sourceIP string) ([]string, error) {
+	var err error
+	for _, server := range s.candidates() {
+		var hostnames []string
+		hostnames, err = s.hostnamesFrom(server.url, ip, sourceIP)
+		s.record(server, err)
+		if err == nil {
+			return hostnames, nil
+		}
+	}
+	return []string{}, err
+}
 
-	var (
-		err      error
-		httpResp *http.Response
-	)
+// hosts queries the healthy servers first, and the unhealthy ones only when
+// all healthy servers fail. The error of the last server queried is
+// returned when every server fails.
+func (s *ServiceDiscoveryClient) hosts(infrastructureName, family, sourceIP string) ([]host, error) {
+	var err error
+	for _, server := range s.candidates() {
+		var hosts []host
+		hosts, err = s.hostsFrom(server.url, infrastructureName, family, sourceIP)
+		if err == ErrNotServed {
+			s.record(server, nil)
+			return nil, err
+		}
+		s.record(server, err)
+		if err == nil {
+			return hosts, nil
+		}
+	}
+	return nil, err
+}
 
+func (s *ServiceDiscoveryClient) candidates() []*server {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	healthy := []*server{}
+	unhealthy := []*server{}
+	now := time.Now()
+	for _, server := range s.servers {
+		if server.healthy(now, s.unhealthyDuration) {
+			healthy = append(healthy, server)
+		} else {
+			unhealthy = append(unhealthy, server)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (s *ServiceDiscoveryClient) record(server *server, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err != nil {
+		server.failedAt = time.Now()
+	} else {
+		server.failedAt = time.Time{}
+	}
+}
+
+func (s *server) healthy(now time.Time, unhealthyDuration time.Duration) bool {
+	return s.failedAt.IsZero() || now.Sub(s.failedAt) >= unhealthyDuration
+}
+
+func (s *ServiceDiscoveryClient) hostsFrom(serverURL, infrastructureName, family, sourceIP string) ([]host, error) {
+	requestUrl := fmt.Sprintf("%s/v1/registration/%s", serverURL, infrastructureName)
+	if family != "" {
+		requestUrl = fmt.Sprintf("%s?family=%s", requestUrl, family)
+	}
+
+	bytes, err := s.get(requestUrl, sourceIP)
+	if err != nil {
+		return nil, err
+	}
+
+	var serverResponse *serverResponse
+	err = json.Unmarshal(bytes, &serverResponse)
+	if err != nil {
+		return nil, err
+	}
+	for i := range serverResponse.Hosts {
+		serverResponse.Hosts[i].ttl = serverResponse.TTLSeconds
+	}
+
+	if family == "" {
+		return serverResponse.Hosts, nil
+	}
+
+	// Filter here as well, in case the server does not support the family parameter.
+	hosts := []host{}
+	for _, host := range serverResponse.Hosts {
+		if familyOf(host.IPAddress) == family {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, nil
+}
+
+func (s *ServiceDiscoveryClient) hostnamesFrom(serverURL, ip, sourceIP string) ([]string, error) {
+	bytes, err := s.get(fmt.Sprintf("%s/v1/reverse/%s", serverURL, ip), sourceIP)
+	if err != nil {
+		return nil, err
+	}
+
+	var response reverseResponse
+	err = json.Unmarshal(bytes, &response)
+	if err != nil {
+		return nil, err
+	}
+	if response.Hostnames == nil {
+		return []string{}, nil
+	}
+	return response.Hostnames, nil
+}
+
+// get returns the body of a successful response to a GET of requestUrl,
+// retrying responses that are not successful. A 404 is not retried, and
+// returns ErrNotServed.
+func (s *ServiceDiscoveryClient) get(requestUrl, sourceIP string) ([]byte, error) {
+	request, err := http.NewRequest("GET", requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if sourceIP != "" {
+		request.Header.Set(SourceIPHeader, sourceIP)
+	}
+
+	client := s.httpClient()
+
+	var httpResp *http.Response
 	for i := 0; i < 4; i++ {
-		httpResp, err = s.client.Get(requestUrl)
+		httpResp, err = client.Do(request)
 		if err != nil {
-			return []string{}, err
+			return nil, err
 		}
 
 		if httpResp.StatusCode == http.StatusOK {
 			break
+		} else if httpResp.StatusCode == http.StatusNotFound {
+			io.Copy(ioutil.Discard, httpResp.Body)
+			httpResp.Body.Close()
+			return nil, ErrNotServed
 		} else {
 			defer func(httpResp *http.Response) {
 				io.Copy(ioutil.Discard, httpResp.Body)
@@ -99,38 +380,27 @@ func (s *ServiceDiscoveryClient) IPs(infrastructureName string) ([]string, error
 	}
 
 	if httpResp.StatusCode != http.StatusOK {
-		return []string{}, errors.New(fmt.Sprintf("Received non successful response from server: %+v", httpResp))
+		return nil, errors.New(fmt.Sprintf("Received non successful response from server: %+v", httpResp))
 	}
 
 	bytes, err := ioutil.ReadAll(httpResp.Body)
 	httpResp.Body.Close()
-	if err != nil {
-		return []string{}, err
-	}
-
-	var serverResponse *serverResponse
-	err = json.Unmarshal(bytes, &serverResponse)
-	if err != nil {
-		return []string{}, err
-	}
-
-	numHosts := len(serverResponse.Hosts)
-	ips := make([]string, numHosts, numHosts)
-	for i, host := range serverResponse.Hosts {
-		ips[i] = host.IPAddress
-	}
-
-	shuffle(ips)
+	return bytes, err
+}
 
-	return ips, nil
+func (s *ServiceDiscoveryClient) httpClient() *http.Client {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.client
 }
 
-func shuffle(vals []string) {
-	r := rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
-	for len(vals) > 0 {
-		n := len(vals)
-		randIndex := r.Intn(n)
-		vals[n-1], vals[randIndex] = vals[randIndex], vals[n-1]
-		vals = vals[:n-1]
+func familyOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if parsed.To4() != nil {
+		return FamilyIPv4
 	}
+	return FamilyIPv6
 }
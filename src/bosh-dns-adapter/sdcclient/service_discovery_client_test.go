@@ -9,6 +9,7 @@ import (
 	"crypto/tls"
 	"io/ioutil"
 	"os"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -31,6 +32,14 @@ var _ = Describe("ServiceDiscoveryClient", func() {
 		caFileName, clientCertFileName, clientKeyFileName, serverCert = testhelpers.GenerateCaAndMutualTlsCerts()
 	})
 
+	ipsOf := func(endpoints []Endpoint) []string {
+		ips := []string{}
+		for _, endpoint := range endpoints {
+			ips = append(ips, endpoint.IP)
+		}
+		return ips
+	}
+
 	Describe("NewServiceDiscoveryClient", func() {
 		Context("when the client has a misconfigured CA path", func() {
 			BeforeEach(func() {
@@ -39,7 +48,7 @@ var _ = Describe("ServiceDiscoveryClient", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := NewServiceDiscoveryClient("app-id.apps.internal.", caFileName, clientCertFileName, clientKeyFileName)
+				_, err := NewServiceDiscoveryClient([]string{"https://sdc.example.com"}, "", caFileName, clientCertFileName, clientKeyFileName, time.Second)
 				Expect(err).To(MatchError("read CA file: open non-existent: no such file or directory"))
 
 			})
@@ -51,25 +60,32 @@ var _ = Describe("ServiceDiscoveryClient", func() {
 			})
 
 			It("returns an error", func() {
-				_, err := NewServiceDiscoveryClient("app-id.apps.internal.", caFileName, clientCertFileName, clientKeyFileName)
+				_, err := NewServiceDiscoveryClient([]string{"https://sdc.example.com"}, "", caFileName, clientCertFileName, clientKeyFileName, time.Second)
 				Expect(err).To(MatchError("load CA file into cert pool"))
 			})
 		})
 
+		Context("when there are no server urls", func() {
+			It("returns an error", func() {
+				_, err := NewServiceDiscoveryClient([]string{}, "", caFileName, clientCertFileName, clientKeyFileName, time.Second)
+				Expect(err).To(MatchError("no server urls"))
+			})
+		})
+
 		Context("when the client has a misconfigured client/key", func() {
 			BeforeEach(func() {
 				os.Remove(clientCertFileName)
 				clientCertFileName = "non-existent"
 			})
 			It("returns an error", func() {
-				_, err := NewServiceDiscoveryClient("app-id.apps.internal.", caFileName, clientCertFileName, clientKeyFileName)
+				_, err := NewServiceDiscoveryClient([]string{"https://sdc.example.com"}, "", caFileName, clientCertFileName, clientKeyFileName, time.Second)
 				Expect(err).To(MatchError("load client key pair: open non-existent: no such file or directory"))
 			})
 		})
 
 	})
 
-	Describe("IPs", func() {
+	Describe("EndpointsForSource", func() {
 		BeforeEach(func() {
 			fakeServer = ghttp.NewUnstartedServer()
 			fakeServer.HTTPTestServer.TLS = &tls.Config{}
@@ -84,7 +100,7 @@ var _ = Describe("ServiceDiscoveryClient", func() {
 		JustBeforeEach(func() {
 			var err error
 			fakeServer.HTTPTestServer.StartTLS()
-			client, err = NewServiceDiscoveryClient(fakeServer.URL(), caFileName, clientCertFileName, clientKeyFileName)
+			client, err = NewServiceDiscoveryClient([]string{fakeServer.URL()}, "", caFileName, clientCertFileName, clientKeyFileName, time.Second)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
@@ -98,7 +114,7 @@ var _ = Describe("ServiceDiscoveryClient", func() {
 		Context("when the server responds successfully", func() {
 			BeforeEach(func() {
 				fakeServerResponse = ghttp.CombineHandlers(
-					ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", ""),
+					ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", "family=ipv4"),
 					ghttp.RespondWith(http.StatusOK, `{
 							"env": "",
 							"Hosts": [
@@ -126,72 +142,24 @@ var _ = Describe("ServiceDiscoveryClient", func() {
 			})
 
 			It("returns the ips in the server response", func() {
-				actualIPs, err := client.IPs("app-id.apps.internal.")
+				endpoints, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(actualIPs).To(ConsistOf("192.168.0.1", "192.168.0.2"))
+				Expect(ipsOf(endpoints)).To(ConsistOf("192.168.0.1", "192.168.0.2"))
 			})
 
 		})
 
-		Context("returned ips order", func() {
-			BeforeEach(func() {
-				fakeServer.RouteToHandler("GET", "/v1/registration/app-id.apps.internal.", func(writer http.ResponseWriter, request *http.Request) {
-					_, err := writer.Write([]byte(`{
-							"env": "",
-							"Hosts": [
-							{
-								"ip_address": "192.168.0.1",
-								"last_check_in": "",
-								"port": 0,
-								"revision": "",
-								"service": "",
-								"service_repo_name": "",
-								"tags": {}
-							},
-							{
-								"ip_address": "192.168.0.2",
-								"last_check_in": "",
-								"port": 0,
-								"revision": "",
-								"service": "",
-								"service_repo_name": "",
-								"tags": {}
-							},
-							{
-								"ip_address": "192.168.0.3",
-								"last_check_in": "",
-								"port": 0,
-								"revision": "",
-								"service": "",
-								"service_repo_name": "",
-								"tags": {}
-							}],
-							"service": ""
-						}`))
-					Expect(err).ToNot(HaveOccurred())
-				})
-			})
-
-			It("shuffles them to return them in random order", func() {
-				Eventually(func() []string {
-					ips, err := client.IPs("app-id.apps.internal.")
-					Expect(err).ToNot(HaveOccurred())
-					return ips
-				}).Should(Equal([]string{"192.168.0.3", "192.168.0.1", "192.168.0.2"}))
-			})
-		})
-
 		Context("when the server responds with malformed JSON", func() {
 			BeforeEach(func() {
 				fakeServerResponse = ghttp.CombineHandlers(
-					ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", ""),
+					ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", "family=ipv4"),
 					ghttp.RespondWith(http.StatusOK, `garbage`))
 				fakeServer.AppendHandlers(fakeServerResponse)
 			})
 
 			It("returns an error", func() {
-				_, err := client.IPs("app-id.apps.internal.")
+				_, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
 				Expect(err).To(HaveOccurred())
 			})
 		})
@@ -200,16 +168,16 @@ var _ = Describe("ServiceDiscoveryClient", func() {
 			BeforeEach(func() {
 				fakeServer.AppendHandlers(
 					ghttp.CombineHandlers(
-						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", ""),
+						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", "family=ipv4"),
 						ghttp.RespondWith(http.StatusBadRequest, `{}`)),
 					ghttp.CombineHandlers(
-						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", ""),
+						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", "family=ipv4"),
 						ghttp.RespondWith(http.StatusBadRequest, `{}`)),
 					ghttp.CombineHandlers(
-						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", ""),
+						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", "family=ipv4"),
 						ghttp.RespondWith(http.StatusBadRequest, `{}`)),
 					ghttp.CombineHandlers(
-						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", ""),
+						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", "family=ipv4"),
 						ghttp.RespondWith(http.StatusOK, `{
 							"env": "",
 							"Hosts": [
@@ -237,10 +205,10 @@ var _ = Describe("ServiceDiscoveryClient", func() {
 			})
 
 			It("retries and returns the successful response", func() {
-				actualIPs, err := client.IPs("app-id.apps.internal.")
+				endpoints, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(actualIPs).To(ConsistOf("192.168.0.1", "192.168.0.2"))
+				Expect(ipsOf(endpoints)).To(ConsistOf("192.168.0.1", "192.168.0.2"))
 			})
 		})
 
@@ -248,26 +216,361 @@ var _ = Describe("ServiceDiscoveryClient", func() {
 			BeforeEach(func() {
 				fakeServer.AppendHandlers(
 					ghttp.CombineHandlers(
-						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", ""),
+						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", "family=ipv4"),
 						ghttp.RespondWith(http.StatusBadRequest, `{}`)),
 					ghttp.CombineHandlers(
-						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", ""),
+						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", "family=ipv4"),
 						ghttp.RespondWith(http.StatusBadRequest, `{}`)),
 					ghttp.CombineHandlers(
-						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", ""),
+						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", "family=ipv4"),
 						ghttp.RespondWith(http.StatusBadRequest, `{}`)),
 					ghttp.CombineHandlers(
-						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", ""),
+						ghttp.VerifyRequest("GET", "/v1/registration/app-id.apps.internal.", "family=ipv4"),
 						ghttp.RespondWith(http.StatusBadRequest, `{}`)),
 				)
 			})
 
 			It("returns an error", func() {
-				_, err := client.IPs("app-id.apps.internal.")
+				_, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("Received non successful response from server:"))
 			})
 		})
+
+		Context("when the hostname is outside the domains of the server", func() {
+			BeforeEach(func() {
+				fakeServer.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", "/v1/registration/app-id.unknown.", "family=ipv4"),
+						ghttp.RespondWith(http.StatusNotFound, "hostname is outside the configured domains")),
+				)
+			})
+
+			It("returns ErrNotServed without retrying", func() {
+				_, err := client.EndpointsForSource("app-id.unknown.", FamilyIPv4, "")
+				Expect(err).To(Equal(ErrNotServed))
+				Expect(fakeServer.ReceivedRequests()).To(HaveLen(1))
+			})
+
+			It("does not mark the server unhealthy", func() {
+				_, err := client.EndpointsForSource("app-id.unknown.", FamilyIPv4, "")
+				Expect(err).To(Equal(ErrNotServed))
+				Expect(client.Healthy()).To(Equal(float64(1)))
+			})
+		})
+
+		Context("when the server responds with several address families", func() {
+			BeforeEach(func() {
+				fakeServer.RouteToHandler("GET", "/v1/registration/app-id.apps.internal.", ghttp.RespondWith(http.StatusOK, `{
+					"env": "",
+					"Hosts": [
+					{ "ip_address": "192.168.0.1", "port": 0, "tags": {} },
+					{ "ip_address": "fd00::1", "port": 0, "tags": {} }
+					],
+					"service": ""
+				}`))
+			})
+
+			It("returns only ips of the requested family", func() {
+				endpoints, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv6, "")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipsOf(endpoints)).To(ConsistOf("fd00::1"))
+
+				endpoints, err = client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipsOf(endpoints)).To(ConsistOf("192.168.0.1"))
+			})
+
+			It("requests the family from the server", func() {
+				_, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv6, "")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeServer.ReceivedRequests()[0].URL.RawQuery).To(Equal("family=ipv6"))
+			})
+		})
+
+		Context("when the server responds with ports", func() {
+			BeforeEach(func() {
+				fakeServer.RouteToHandler("GET", "/v1/registration/app-id.apps.internal.", ghttp.RespondWith(http.StatusOK, `{
+					"env": "",
+					"Hosts": [
+					{ "ip_address": "192.168.0.1", "port": 8080, "tags": {} },
+					{ "ip_address": "192.168.0.1", "port": 9090, "tags": {"cell_id": "cell-1", "availability_zone": "z1"} },
+					{ "ip_address": "192.168.0.2", "port": 0, "tags": {} }
+					],
+					"service": ""
+				}`))
+			})
+
+			It("returns every endpoint in the order the server returned them", func() {
+				endpoints, err := client.EndpointsForSource("app-id.apps.internal.", "", "")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(endpoints).To(Equal([]Endpoint{
+					{IP: "192.168.0.1", Port: 8080},
+					{IP: "192.168.0.1", Port: 9090, CellID: "cell-1", AZ: "z1"},
+					{IP: "192.168.0.2"},
+				}))
+				Expect(fakeServer.ReceivedRequests()[0].Header.Get(SourceIPHeader)).To(BeEmpty())
+			})
+
+			It("returns the TTL the server set for the hostname", func() {
+				fakeServer.RouteToHandler("GET", "/v1/registration/app-id.apps.internal.", ghttp.RespondWith(http.StatusOK, `{
+					"hosts": [{ "ip_address": "192.168.0.1", "port": 8080, "tags": {} }],
+					"ttl_seconds": 5
+				}`))
+
+				endpoints, err := client.EndpointsForSource("app-id.apps.internal.", "", "")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(endpoints).To(Equal([]Endpoint{{IP: "192.168.0.1", Port: 8080, TTL: 5}}))
+			})
+
+			It("sends the IP of the querying container", func() {
+				_, err := client.EndpointsForSource("app-id.apps.internal.", "", "10.255.0.9")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeServer.ReceivedRequests()[0].Header.Get(SourceIPHeader)).To(Equal("10.255.0.9"))
+			})
+		})
+
+		Context("when an IP is looked up in reverse", func() {
+			It("returns the hostnames registered for the IP", func() {
+				fakeServer.AppendHandlers(ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/v1/reverse/10.255.3.4"),
+					ghttp.VerifyHeaderKV(SourceIPHeader, "10.255.0.9"),
+					ghttp.RespondWith(http.StatusOK, `{"ip": "10.255.3.4", "hostnames": ["app-a.apps.internal.", "app-b.apps.internal."]}`),
+				))
+
+				hostnames, err := client.HostnamesForSource("10.255.3.4", "10.255.0.9")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hostnames).To(Equal([]string{"app-a.apps.internal.", "app-b.apps.internal."}))
+			})
+
+			It("returns no hostnames when none are registered", func() {
+				fakeServer.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"ip": "10.255.3.4", "hostnames": null}`))
+
+				hostnames, err := client.HostnamesForSource("10.255.3.4", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(hostnames).To(BeEmpty())
+			})
+
+			It("returns an error when the server responds with malformed JSON", func() {
+				fakeServer.AppendHandlers(ghttp.RespondWith(http.StatusOK, `garbage`))
+
+				_, err := client.HostnamesForSource("10.255.3.4", "")
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Reload", func() {
+		var (
+			newCAFileName         string
+			newClientCertFileName string
+			newClientKeyFileName  string
+			newServerCert         tls.Certificate
+		)
+
+		BeforeEach(func() {
+			newCAFileName, newClientCertFileName, newClientKeyFileName, newServerCert = testhelpers.GenerateCaAndMutualTlsCerts()
+
+			fakeServer = ghttp.NewUnstartedServer()
+			fakeServer.HTTPTestServer.TLS = &tls.Config{}
+			fakeServer.HTTPTestServer.TLS.ClientCAs = testhelpers.CertPool(newCAFileName)
+			fakeServer.HTTPTestServer.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+			fakeServer.HTTPTestServer.TLS.Certificates = []tls.Certificate{newServerCert}
+			fakeServer.HTTPTestServer.StartTLS()
+			fakeServer.AllowUnhandledRequests = true
+			fakeServer.RouteToHandler("GET", "/v1/registration/app-id.apps.internal.", ghttp.RespondWith(http.StatusOK, `{"Hosts":[{"ip_address":"192.168.0.1"}]}`))
+
+			var err error
+			client, err = NewServiceDiscoveryClient([]string{fakeServer.URL()}, "", caFileName, clientCertFileName, clientKeyFileName, time.Second)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			fakeServer.Close()
+			os.Remove(caFileName)
+			os.Remove(clientCertFileName)
+			os.Remove(clientKeyFileName)
+			os.Remove(newCAFileName)
+			os.Remove(newClientCertFileName)
+			os.Remove(newClientKeyFileName)
+		})
+
+		It("queries the servers with the new certificates", func() {
+			_, err := client.EndpointsForSource("app-id.apps.internal.", "", "")
+			Expect(err).To(HaveOccurred())
+
+			Expect(client.Reload([]string{fakeServer.URL()}, "", newCAFileName, newClientCertFileName, newClientKeyFileName)).To(Succeed())
+
+			Expect(client.EndpointsForSource("app-id.apps.internal.", "", "")).To(Equal([]Endpoint{{IP: "192.168.0.1"}}))
+		})
+
+		Context("when the new certificates cannot be loaded", func() {
+			It("returns an error and keeps the previous certificates", func() {
+				Expect(client.Reload([]string{fakeServer.URL()}, "", newCAFileName, newClientCertFileName, newClientKeyFileName)).To(Succeed())
+
+				err := client.Reload([]string{fakeServer.URL()}, "", "non-existent", newClientCertFileName, newClientKeyFileName)
+				Expect(err).To(MatchError("read CA file: open non-existent: no such file or directory"))
+
+				Expect(client.EndpointsForSource("app-id.apps.internal.", "", "")).To(Equal([]Endpoint{{IP: "192.168.0.1"}}))
+			})
+		})
+
+		Context("when there are no server urls", func() {
+			It("returns an error and keeps the servers and certificates", func() {
+				Expect(client.Reload([]string{fakeServer.URL()}, "", newCAFileName, newClientCertFileName, newClientKeyFileName)).To(Succeed())
+
+				err := client.Reload([]string{}, "", caFileName, clientCertFileName, clientKeyFileName)
+				Expect(err).To(MatchError("no server urls"))
+
+				Expect(client.EndpointsForSource("app-id.apps.internal.", "", "")).To(Equal([]Endpoint{{IP: "192.168.0.1"}}))
+			})
+		})
+
+		Context("when the servers change", func() {
+			var otherServer *ghttp.Server
+
+			BeforeEach(func() {
+				otherServer = ghttp.NewUnstartedServer()
+				otherServer.HTTPTestServer.TLS = &tls.Config{}
+				otherServer.HTTPTestServer.TLS.ClientCAs = testhelpers.CertPool(newCAFileName)
+				otherServer.HTTPTestServer.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+				otherServer.HTTPTestServer.TLS.Certificates = []tls.Certificate{newServerCert}
+				otherServer.HTTPTestServer.StartTLS()
+				otherServer.RouteToHandler("GET", "/v1/registration/app-id.apps.internal.", ghttp.RespondWith(http.StatusOK, `{"Hosts":[{"ip_address":"192.168.0.2"}]}`))
+			})
+
+			AfterEach(func() {
+				otherServer.Close()
+			})
+
+			It("queries the new servers", func() {
+				Expect(client.Reload([]string{otherServer.URL()}, "", newCAFileName, newClientCertFileName, newClientKeyFileName)).To(Succeed())
+
+				Expect(client.EndpointsForSource("app-id.apps.internal.", "", "")).To(Equal([]Endpoint{{IP: "192.168.0.2"}}))
+				Expect(fakeServer.ReceivedRequests()).To(BeEmpty())
+				Expect(client.Healthy()).To(Equal(float64(1)))
+			})
+		})
 	})
 
+	Describe("failover", func() {
+		var (
+			failingServer *ghttp.Server
+			healthyServer *ghttp.Server
+			unhealthyFor  time.Duration
+		)
+
+		newServer := func() *ghttp.Server {
+			server := ghttp.NewUnstartedServer()
+			server.HTTPTestServer.TLS = &tls.Config{}
+			server.HTTPTestServer.TLS.ClientCAs = testhelpers.CertPool(caFileName)
+			server.HTTPTestServer.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+			server.HTTPTestServer.TLS.Certificates = []tls.Certificate{serverCert}
+			server.HTTPTestServer.StartTLS()
+			return server
+		}
+
+		BeforeEach(func() {
+			failingServer = newServer()
+			failingServer.RouteToHandler("GET", "/v1/registration/app-id.apps.internal.", ghttp.RespondWith(http.StatusInternalServerError, `{}`))
+			failingServer.SetAllowUnhandledRequests(true)
+
+			healthyServer = newServer()
+			healthyServer.RouteToHandler("GET", "/v1/registration/app-id.apps.internal.", ghttp.RespondWith(http.StatusOK, `{
+				"Hosts": [{ "ip_address": "192.168.0.1", "port": 0, "tags": {} }]
+			}`))
+
+			unhealthyFor = time.Minute
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			client, err = NewServiceDiscoveryClient([]string{failingServer.URL(), healthyServer.URL()}, "", caFileName, clientCertFileName, clientKeyFileName, unhealthyFor)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			failingServer.Close()
+			healthyServer.Close()
+			os.Remove(caFileName)
+			os.Remove(clientCertFileName)
+			os.Remove(clientKeyFileName)
+		})
+
+		It("queries the next server when one fails", func() {
+			endpoints, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipsOf(endpoints)).To(ConsistOf("192.168.0.1"))
+		})
+
+		It("stops querying a failed server while it is unhealthy", func() {
+			_, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
+			Expect(err).NotTo(HaveOccurred())
+			failedRequests := len(failingServer.ReceivedRequests())
+
+			_, err = client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(failingServer.ReceivedRequests()).To(HaveLen(failedRequests))
+			Expect(healthyServer.ReceivedRequests()).To(HaveLen(2))
+		})
+
+		It("reports the number of healthy servers", func() {
+			Expect(client.Healthy()).To(Equal(float64(2)))
+
+			_, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.Healthy()).To(Equal(float64(1)))
+		})
+
+		Context("when the unhealthy duration has passed", func() {
+			BeforeEach(func() {
+				unhealthyFor = 0
+			})
+
+			It("queries the failed server again", func() {
+				_, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
+				Expect(err).NotTo(HaveOccurred())
+				failedRequests := len(failingServer.ReceivedRequests())
+
+				_, err = client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(failingServer.ReceivedRequests())).To(BeNumerically(">", failedRequests))
+			})
+		})
+
+		It("queries the next server when a reverse lookup fails", func() {
+			failingServer.RouteToHandler("GET", "/v1/reverse/192.168.0.1", ghttp.RespondWith(http.StatusInternalServerError, `{}`))
+			healthyServer.RouteToHandler("GET", "/v1/reverse/192.168.0.1", ghttp.RespondWith(http.StatusOK, `{
+				"ip": "192.168.0.1", "hostnames": ["app-id.apps.internal."]
+			}`))
+
+			hostnames, err := client.HostnamesForSource("192.168.0.1", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hostnames).To(Equal([]string{"app-id.apps.internal."}))
+			Expect(client.Healthy()).To(Equal(float64(1)))
+		})
+
+		Context("when every server fails", func() {
+			BeforeEach(func() {
+				healthyServer.RouteToHandler("GET", "/v1/registration/app-id.apps.internal.", ghttp.RespondWith(http.StatusInternalServerError, `{}`))
+			})
+
+			It("returns the error of the last server", func() {
+				_, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
+				Expect(err).To(MatchError(ContainSubstring("Received non successful response from server:")))
+				Expect(client.Healthy()).To(Equal(float64(0)))
+			})
+
+			It("still queries every server", func() {
+				_, err := client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
+				Expect(err).To(HaveOccurred())
+
+				_, err = client.EndpointsForSource("app-id.apps.internal.", FamilyIPv4, "")
+				Expect(err).To(HaveOccurred())
+				Expect(len(healthyServer.ReceivedRequests())).To(BeNumerically(">", 4))
+			})
+		})
+	})
 })
@@ -0,0 +1,95 @@
+package ttl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SOA timers other than the minimum are not used by resolvers caching a
+// negative answer, so they are fixed.
+const (
+	soaSerial  = 1
+	soaRefresh = 3600
+	soaRetry   = 600
+	soaExpire  = 86400
+)
+
+// Policy decides how long resolvers may cache answers.
+type Policy struct {
+	// Default is the TTL of answers in domains without their own TTL.
+	Default uint32
+	// Domains maps a domain, such as apps.internal., to the TTL of answers
+	// for names in it. The longest matching domain wins.
+	Domains map[string]uint32
+	// Negative is how long an empty answer may be cached. When it is 0, no
+	// SOA record is returned.
+	Negative uint32
+	// Zones are the domains served, used to name the SOA record.
+	Zones []string
+}
+
+// For returns the TTL of answers for name.
+func (p Policy) For(name string) uint32 {
+	domain := longestMatch(name, keys(p.Domains))
+	if domain == "" {
+		return p.Default
+	}
+	return p.Domains[domain]
+}
+
+// ForServed returns the TTL of answers for name like For, except that names
+// in no configured domain get the TTL served by the controller, when it
+// sent one.
+func (p Policy) ForServed(name string, served uint32) uint32 {
+	if served > 0 && longestMatch(name, keys(p.Domains)) == "" {
+		return served
+	}
+	return p.For(name)
+}
+
+// SOA returns the zone and data of the SOA record that lets resolvers cache
+// an empty answer for name, or false when negative caching is disabled.
+func (p Policy) SOA(name string) (string, string, bool) {
+	if p.Negative == 0 {
+		return "", "", false
+	}
+
+	zones := append(keys(p.Domains), p.Zones...)
+	zone := fqdn(name)
+	if match := longestMatch(name, zones); match != "" {
+		zone = fqdn(match)
+	}
+
+	data := fmt.Sprintf("%s hostmaster.%s %d %d %d %d %d",
+		zone, zone, soaSerial, soaRefresh, soaRetry, soaExpire, p.Negative)
+	return zone, data, true
+}
+
+// longestMatch returns the most specific domain that name is in, as it is
+// written in domains, or "" when there is none.
+func longestMatch(name string, domains []string) string {
+	name = fqdn(name)
+	match := ""
+	for _, domain := range domains {
+		qualified := fqdn(domain)
+		if (name == qualified || strings.HasSuffix(name, "."+qualified)) && len(qualified) > len(fqdn(match)) {
+			match = domain
+		}
+	}
+	return match
+}
+
+func keys(domains map[string]uint32) []string {
+	names := []string{}
+	for domain := range domains {
+		names = append(names, domain)
+	}
+	return names
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
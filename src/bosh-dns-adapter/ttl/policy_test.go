@@ -0,0 +1,80 @@
+package ttl_test
+
+import (
+	"bosh-dns-adapter/ttl"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Policy", func() {
+	var policy ttl.Policy
+
+	BeforeEach(func() {
+		policy = ttl.Policy{
+			Default: 30,
+			Domains: map[string]uint32{
+				"apps.internal.":   10,
+				"my.apps.internal": 5,
+			},
+			Negative: 3,
+			Zones:    []string{"apps.internal.", "other.internal."},
+		}
+	})
+
+	Describe("For", func() {
+		It("returns the TTL of the most specific domain", func() {
+			Expect(policy.For("app.apps.internal.")).To(Equal(uint32(10)))
+			Expect(policy.For("app.my.apps.internal.")).To(Equal(uint32(5)))
+			Expect(policy.For("my.apps.internal")).To(Equal(uint32(5)))
+		})
+
+		It("returns the default TTL for other names", func() {
+			Expect(policy.For("app.other.internal.")).To(Equal(uint32(30)))
+			Expect(policy.For("app.notapps.internal.")).To(Equal(uint32(30)))
+		})
+	})
+
+	Describe("ForServed", func() {
+		It("prefers the TTL of a configured domain", func() {
+			Expect(policy.ForServed("app.apps.internal.", 60)).To(Equal(uint32(10)))
+		})
+
+		It("uses the served TTL for other names", func() {
+			Expect(policy.ForServed("app.other.internal.", 60)).To(Equal(uint32(60)))
+		})
+
+		It("returns the default TTL when none was served", func() {
+			Expect(policy.ForServed("app.other.internal.", 0)).To(Equal(uint32(30)))
+		})
+	})
+
+	Describe("SOA", func() {
+		It("returns an SOA record for the zone with the negative TTL as its minimum", func() {
+			zone, data, ok := policy.SOA("_http._tcp.app.other.internal.")
+			Expect(ok).To(BeTrue())
+			Expect(zone).To(Equal("other.internal."))
+			Expect(data).To(Equal("other.internal. hostmaster.other.internal. 1 3600 600 86400 3"))
+		})
+
+		It("uses the most specific zone", func() {
+			zone, _, _ := policy.SOA("app.my.apps.internal")
+			Expect(zone).To(Equal("my.apps.internal."))
+		})
+
+		Context("when the name is in no zone", func() {
+			It("uses the name", func() {
+				zone, _, _ := policy.SOA("app.example.com")
+				Expect(zone).To(Equal("app.example.com."))
+			})
+		})
+
+		Context("when negative caching is disabled", func() {
+			It("returns false", func() {
+				policy.Negative = 0
+				_, _, ok := policy.SOA("app.apps.internal.")
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package ttl_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestTTL(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TTL Suite")
+}
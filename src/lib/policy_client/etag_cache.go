@@ -0,0 +1,125 @@
+package policy_client
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"code.cloudfoundry.org/cf-networking-helpers/json_client"
+)
+
+// etagCacheEntries is the number of distinct queries whose responses are
+// kept by the internal client.
+const etagCacheEntries = 64
+
+var errNotModifiedWithoutCache = errors.New("server answered 304 Not Modified to a request without a cached response")
+
+// etagCache is a json_client.HttpClient that makes GET requests conditional.
+// It keeps the body of the last maxEntries responses that carried an ETag,
+// keyed on the request URL, and replays the kept body as a 200 when the
+// server answers 304 Not Modified. The least recently used entry is evicted
+// first.
+type etagCache struct {
+	client     json_client.HttpClient
+	maxEntries int
+
+	lock    sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type etagCacheEntry struct {
+	url    string
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+func newETagCache(client json_client.HttpClient, maxEntries int) *etagCache {
+	return &etagCache{
+		client:     client,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+func (c *etagCache) Do(request *http.Request) (*http.Response, error) {
+	if request.Method != "GET" {
+		return c.client.Do(request)
+	}
+
+	url := request.URL.String()
+	cached, haveCached := c.get(url)
+	if haveCached {
+		request.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		resp.Body.Close()
+		if !haveCached {
+			return nil, errNotModifiedWithoutCache
+		}
+		return &http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Proto:      resp.Proto,
+			ProtoMajor: resp.ProtoMajor,
+			ProtoMinor: resp.ProtoMinor,
+			Header:     cached.header,
+			Body:       ioutil.NopCloser(bytes.NewReader(cached.body)),
+			Request:    request,
+		}, nil
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "":
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read body: %s", err)
+		}
+		c.put(&etagCacheEntry{
+			url:    url,
+			etag:   resp.Header.Get("ETag"),
+			header: resp.Header,
+			body:   body,
+		})
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return resp, nil
+}
+
+func (c *etagCache) get(url string) (*etagCacheEntry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, ok := c.entries[url]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*etagCacheEntry), true
+}
+
+func (c *etagCache) put(entry *etagCacheEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if element, ok := c.entries[entry.url]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[entry.url] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*etagCacheEntry).url)
+	}
+}
@@ -3,6 +3,7 @@ package policy_client
 import (
 	"errors"
 	"policy-server/api"
+	"sort"
 	"strings"
 
 	"code.cloudfoundry.org/cf-networking-helpers/json_client"
@@ -13,9 +14,12 @@ type InternalClient struct {
 	JsonClient json_client.JsonClient
 }
 
+// NewInternal returns a client whose policy requests are conditional: the
+// responses of the most recent queries are kept along with their ETags and
+// reused when the policy server answers 304 Not Modified.
 func NewInternal(logger lager.Logger, httpClient json_client.HttpClient, baseURL string) *InternalClient {
 	return &InternalClient{
-		JsonClient: json_client.New(logger, httpClient, baseURL),
+		JsonClient: json_client.New(logger, newETagCache(httpClient, etagCacheEntries), baseURL),
 	}
 }
 
@@ -37,7 +41,10 @@ func (c *InternalClient) GetPoliciesByID(ids ...string) ([]api.Policy, error) {
 	if len(ids) == 0 {
 		return nil, errors.New("ids cannot be empty")
 	}
-	err := c.JsonClient.Do("GET", "/networking/v1/internal/policies?id="+strings.Join(ids, ","), nil, &policies, "")
+	// sorted so that the same set of ids is always the same query
+	sortedIDs := append([]string(nil), ids...)
+	sort.Strings(sortedIDs)
+	err := c.JsonClient.Do("GET", "/networking/v1/internal/policies?id="+strings.Join(sortedIDs, ","), nil, &policies, "")
 	if err != nil {
 		return nil, err
 	}
@@ -3,13 +3,18 @@ package policy_client_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"lib/policy_client"
+	"net/http"
 	"policy-server/api"
 
 	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
+	"code.cloudfoundry.org/cf-networking-helpers/json_client"
+	"code.cloudfoundry.org/lager/lagertest"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
 )
 
 var _ = Describe("InternalClient", func() {
@@ -112,6 +117,14 @@ var _ = Describe("InternalClient", func() {
 			Expect(token).To(BeEmpty())
 		})
 
+		It("sorts the ids so that the same ids make the same query", func() {
+			_, err := client.GetPoliciesByID("some-other-app-guid", "some-app-guid")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, route, _, _, _ := jsonClient.DoArgsForCall(0)
+			Expect(route).To(Equal("/networking/v1/internal/policies?id=some-app-guid,some-other-app-guid"))
+		})
+
 		Context("when the json client fails", func() {
 			BeforeEach(func() {
 				jsonClient.DoReturns(errors.New("banana"))
@@ -165,4 +178,130 @@ var _ = Describe("InternalClient", func() {
 			})
 		})
 	})
+
+	Describe("NewInternal", func() {
+		var (
+			server       *ghttp.Server
+			policiesJSON string
+		)
+
+		BeforeEach(func() {
+			server = ghttp.NewServer()
+			client = policy_client.NewInternal(lagertest.NewTestLogger("test"), http.DefaultClient, server.URL())
+			policiesJSON = `{ "policies": [ {"source": { "id": "some-app-guid", "tag": "BEEF" }, "destination": { "id": "some-other-app-guid", "protocol": "tcp", "ports": { "start": 8090, "end": 8090 } } } ] }`
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		noETag := func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Header.Get("If-None-Match")).To(BeEmpty())
+		}
+
+		It("sends the ETag of the last response and reuses its policies on 304", func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/networking/v1/internal/policies"),
+					noETag,
+					ghttp.RespondWith(http.StatusOK, policiesJSON, http.Header{"ETag": []string{`"some-etag"`}}),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/networking/v1/internal/policies"),
+					ghttp.VerifyHeaderKV("If-None-Match", `"some-etag"`),
+					ghttp.RespondWith(http.StatusNotModified, nil),
+				),
+			)
+
+			first, err := client.GetPolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first).To(HaveLen(1))
+
+			second, err := client.GetPolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second).To(Equal(first))
+			Expect(server.ReceivedRequests()).To(HaveLen(2))
+		})
+
+		It("keeps an ETag per query", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusOK, policiesJSON, http.Header{"ETag": []string{`"etag-all"`}}),
+				ghttp.RespondWith(http.StatusOK, policiesJSON, http.Header{"ETag": []string{`"etag-a-b"`}}),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/networking/v1/internal/policies"),
+					ghttp.VerifyHeaderKV("If-None-Match", `"etag-all"`),
+					ghttp.RespondWith(http.StatusNotModified, nil),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/networking/v1/internal/policies", "id=a,b"),
+					ghttp.VerifyHeaderKV("If-None-Match", `"etag-a-b"`),
+					ghttp.RespondWith(http.StatusNotModified, nil),
+				),
+			)
+
+			_, err := client.GetPolicies()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = client.GetPoliciesByID("a", "b")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = client.GetPolicies()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = client.GetPoliciesByID("b", "a")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(server.ReceivedRequests()).To(HaveLen(4))
+		})
+
+		It("forgets the least recently used queries", func() {
+			server.RouteToHandler("GET", "/networking/v1/internal/policies", func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Query().Get("id") == "app-0" {
+					noETag(w, req)
+				}
+				w.Header().Set("ETag", `"`+req.URL.RawQuery+`"`)
+				w.Write([]byte(policiesJSON))
+			})
+
+			for i := 0; i <= 64; i++ {
+				_, err := client.GetPoliciesByID(fmt.Sprintf("app-%d", i))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			_, err := client.GetPoliciesByID("app-0")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("does not cache responses without an ETag", func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusOK, policiesJSON),
+				ghttp.CombineHandlers(noETag, ghttp.RespondWith(http.StatusOK, policiesJSON)),
+			)
+
+			_, err := client.GetPolicies()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = client.GetPolicies()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the server answers 304 without a cached response", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(ghttp.RespondWith(http.StatusNotModified, nil))
+			})
+
+			It("returns an error", func() {
+				_, err := client.GetPolicies()
+				Expect(err).To(MatchError(ContainSubstring("server answered 304 Not Modified to a request without a cached response")))
+			})
+		})
+
+		Context("when the server responds with a non-2xx status", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(ghttp.RespondWith(http.StatusInternalServerError, `{"error": "banana"}`))
+			})
+
+			It("returns the error", func() {
+				_, err := client.GetPolicies()
+				Expect(err).To(Equal(&json_client.HttpResponseCodeError{
+					StatusCode: http.StatusInternalServerError,
+					Message:    `{"error": "banana"}`,
+				}))
+			})
+		})
+	})
 })
@@ -0,0 +1,84 @@
+package policy_client
+
+import (
+	"fmt"
+	"policy-server/api"
+	"policy-server/api/api_grpc"
+	"sort"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+type InternalWatchClient struct {
+	Client api_grpc.InternalPoliciesClient
+}
+
+func NewInternalWatch(conn *grpc.ClientConn) *InternalWatchClient {
+	return &InternalWatchClient{
+		Client: api_grpc.NewInternalPoliciesClient(conn),
+	}
+}
+
+// WatchPolicies streams policy changes from the policy server, calling
+// onChange with the full set of matching policies after the initial
+// snapshot and after every delta. It blocks until ctx is cancelled or
+// the stream fails; callers should reconnect on error.
+func (c *InternalWatchClient) WatchPolicies(ctx context.Context, onChange func([]api.Policy), ids ...string) error {
+	stream, err := c.Client.WatchPolicies(ctx, &api_grpc.WatchPoliciesRequest{AppIds: ids})
+	if err != nil {
+		return fmt.Errorf("watch policies: %s", err)
+	}
+
+	current := map[api.Policy]struct{}{}
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("receive policy update: %s", err)
+		}
+
+		if update.Type == api_grpc.PolicyUpdate_SNAPSHOT {
+			current = map[api.Policy]struct{}{}
+		}
+		for _, policy := range update.Removed {
+			delete(current, asAPIPolicy(policy))
+		}
+		for _, policy := range update.Added {
+			current[asAPIPolicy(policy)] = struct{}{}
+		}
+
+		onChange(sortedAPIPolicies(current))
+	}
+}
+
+func asAPIPolicy(policy *api_grpc.Policy) api.Policy {
+	return api.Policy{
+		Source: api.Source{
+			ID:  policy.GetSource().GetId(),
+			Tag: policy.GetSource().GetTag(),
+		},
+		Destination: api.Destination{
+			ID:       policy.GetDestination().GetId(),
+			Tag:      policy.GetDestination().GetTag(),
+			Protocol: policy.GetDestination().GetProtocol(),
+			Ports: api.Ports{
+				Start: int(policy.GetDestination().GetPorts().GetStart()),
+				End:   int(policy.GetDestination().GetPorts().GetEnd()),
+			},
+		},
+	}
+}
+
+func sortedAPIPolicies(policies map[api.Policy]struct{}) []api.Policy {
+	sorted := []api.Policy{}
+	for policy := range policies {
+		sorted = append(sorted, policy)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return fmt.Sprintf("%v", sorted[i]) < fmt.Sprintf("%v", sorted[j])
+	})
+	return sorted
+}
@@ -0,0 +1,153 @@
+package policy_client_test
+
+import (
+	"lib/policy_client"
+	"net"
+	"policy-server/api"
+	"policy-server/grpc_server"
+	"policy-server/store"
+	storeFakes "policy-server/store/fakes"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"policy-server/api/api_grpc"
+)
+
+var _ = Describe("InternalWatchClient", func() {
+	var (
+		client      *policy_client.InternalWatchClient
+		conn        *grpc.ClientConn
+		server      *grpc.Server
+		broadcaster *grpc_server.PolicyBroadcaster
+		fakeStore   *storeFakes.Store
+		ctx         context.Context
+		cancel      context.CancelFunc
+		received    chan []api.Policy
+		watchErr    chan error
+		policyA     store.Policy
+		policyB     store.Policy
+	)
+
+	BeforeEach(func() {
+		policyA = store.Policy{
+			Source: store.Source{ID: "app-a", Tag: "0001"},
+			Destination: store.Destination{
+				ID:       "app-b",
+				Tag:      "0002",
+				Protocol: "tcp",
+				Ports:    store.Ports{Start: 8080, End: 8080},
+			},
+		}
+		policyB = store.Policy{
+			Source: store.Source{ID: "app-c", Tag: "0003"},
+			Destination: store.Destination{
+				ID:       "app-a",
+				Tag:      "0001",
+				Protocol: "udp",
+				Ports:    store.Ports{Start: 5000, End: 6000},
+			},
+		}
+
+		fakeStore = &storeFakes.Store{}
+		fakeStore.AllReturns([]store.Policy{policyA}, nil)
+		logger := lagertest.NewTestLogger("test")
+		broadcaster = &grpc_server.PolicyBroadcaster{
+			Store:             fakeStore,
+			Logger:            logger,
+			SubscriberBacklog: 10,
+		}
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		server = grpc.NewServer()
+		api_grpc.RegisterInternalPoliciesServer(server, &grpc_server.PolicyWatchServer{
+			Broadcaster: broadcaster,
+			Logger:      logger,
+		})
+		go server.Serve(listener)
+
+		conn, err = grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+		Expect(err).NotTo(HaveOccurred())
+		client = policy_client.NewInternalWatch(conn)
+
+		ctx, cancel = context.WithCancel(context.Background())
+		received = make(chan []api.Policy, 10)
+		watchErr = make(chan error, 1)
+	})
+
+	AfterEach(func() {
+		cancel()
+		conn.Close()
+		server.Stop()
+	})
+
+	watch := func(ids ...string) {
+		client, ctx, received, watchErr := client, ctx, received, watchErr
+		go func() {
+			watchErr <- client.WatchPolicies(ctx, func(policies []api.Policy) {
+				received <- policies
+			}, ids...)
+		}()
+	}
+
+	It("receives the snapshot and then applies deltas", func() {
+		watch()
+
+		Eventually(received).Should(Receive(Equal([]api.Policy{{
+			Source: api.Source{ID: "app-a", Tag: "0001"},
+			Destination: api.Destination{
+				ID:       "app-b",
+				Tag:      "0002",
+				Protocol: "tcp",
+				Ports:    api.Ports{Start: 8080, End: 8080},
+			},
+		}})))
+
+		fakeStore.AllReturns([]store.Policy{policyB}, nil)
+		Expect(broadcaster.Refresh()).To(Succeed())
+
+		Eventually(received).Should(Receive(Equal([]api.Policy{{
+			Source: api.Source{ID: "app-c", Tag: "0003"},
+			Destination: api.Destination{
+				ID:       "app-a",
+				Tag:      "0001",
+				Protocol: "udp",
+				Ports:    api.Ports{Start: 5000, End: 6000},
+			},
+		}})))
+	})
+
+	It("only receives policies matching the requested ids", func() {
+		fakeStore.AllReturns([]store.Policy{policyA, policyB}, nil)
+		watch("app-c")
+
+		var policies []api.Policy
+		Eventually(received).Should(Receive(&policies))
+		Expect(policies).To(HaveLen(1))
+		Expect(policies[0].Source.ID).To(Equal("app-c"))
+	})
+
+	It("returns nil when the context is cancelled", func() {
+		watch()
+		Eventually(received).Should(Receive())
+
+		cancel()
+		Eventually(watchErr).Should(Receive(BeNil()))
+	})
+
+	Context("when the server goes away", func() {
+		It("returns an error", func() {
+			watch()
+			Eventually(received).Should(Receive())
+
+			server.Stop()
+			var err error
+			Eventually(watchErr).Should(Receive(&err))
+			Expect(err).To(MatchError(HavePrefix("receive policy update:")))
+		})
+	})
+})
@@ -30,7 +30,7 @@ func (p *policyMapper) AsStorePolicy(bytes []byte) ([]store.Policy, error) {
 
 	err = p.Validator.ValidatePolicies(payload.Policies)
 	if err != nil {
-		return nil, fmt.Errorf("validate policies: %s", err)
+		return nil, WrapValidationError("validate policies", err)
 	}
 
 	storePolicies := []store.Policy{}
@@ -139,6 +139,26 @@ var _ = Describe("ApiPolicyMapper", func() {
 				Expect(err).To(MatchError(errors.New("validate policies: banana")))
 			})
 		})
+
+		Context("when the validator returns a validation error", func() {
+			var failures []api.ValidationFailure
+
+			BeforeEach(func() {
+				failures = []api.ValidationFailure{
+					{Index: 0, Field: "source.id", Code: "missing_source_id", Reason: "missing source id"},
+				}
+				fakeValidator.ValidatePoliciesReturns(api.NewValidationError(failures))
+			})
+
+			It("keeps the failures", func() {
+				_, err := mapper.AsStorePolicy([]byte(`{}`))
+				Expect(err).To(MatchError("validate policies: missing source id"))
+
+				validationErr, ok := err.(*api.ValidationError)
+				Expect(ok).To(BeTrue())
+				Expect(validationErr.Failures).To(Equal(failures))
+			})
+		})
 	})
 
 	Describe("AsBytes", func() {
@@ -31,7 +31,7 @@ func (p *policyMapper) AsStorePolicy(bytes []byte) ([]store.Policy, error) {
 
 	err = p.Validator.ValidatePolicies(payload.Policies)
 	if err != nil {
-		return nil, fmt.Errorf("validate policies: %s", err)
+		return nil, api.WrapValidationError("validate policies", err)
 	}
 
 	storePolicies := []store.Policy{}
@@ -1,8 +1,8 @@
 package api_v0
 
 import (
-	"errors"
 	"fmt"
+	"policy-server/api"
 )
 
 //go:generate counterfeiter -o fakes/validator.go --fake-name Validator . validator
@@ -12,30 +12,45 @@ type validator interface {
 
 type Validator struct{}
 
+// ValidatePolicies returns an *api.ValidationError listing every invalid
+// policy.
 func (v *Validator) ValidatePolicies(policies []Policy) error {
 	if len(policies) == 0 {
-		return errors.New("missing policies")
+		return api.NewValidationError([]api.ValidationFailure{{
+			Index:  api.NoPolicyIndex,
+			Field:  "policies",
+			Code:   api.CodeMissingPolicies,
+			Reason: "missing policies",
+		}})
 	}
 
-	for _, policy := range policies {
+	failures := []api.ValidationFailure{}
+	for i, policy := range policies {
+		fail := func(field, code, reason string) {
+			failures = append(failures, api.ValidationFailure{Index: i, Field: field, Code: code, Reason: reason})
+		}
+
 		if policy.Source.ID == "" {
-			return errors.New("missing source id")
+			fail("source.id", api.CodeMissingSourceID, "missing source id")
 		}
 		if policy.Destination.ID == "" {
-			return errors.New("missing destination id")
+			fail("destination.id", api.CodeMissingDestID, "missing destination id")
 		}
 		if policy.Destination.Protocol != "udp" && policy.Destination.Protocol != "tcp" {
-			return errors.New("invalid destination protocol, specify either udp or tcp")
+			fail("destination.protocol", api.CodeInvalidProtocol, "invalid destination protocol, specify either udp or tcp")
 		}
 		if policy.Destination.Port < 0 {
-			return fmt.Errorf("invalid port %d, must be in range 1-65535", policy.Destination.Port)
+			fail("destination.port", api.CodeInvalidPort,
+				fmt.Sprintf("invalid port %d, must be in range 1-65535", policy.Destination.Port))
 		}
 		if policy.Destination.Port == 0 {
-			return fmt.Errorf("missing port")
+			fail("destination.port", api.CodeMissingPort, "missing port")
 		}
-		if policy.Source.Tag != "" || policy.Destination.Tag != "" {
-			return errors.New("tags may not be specified")
+		if policy.Source.Tag != "" {
+			fail("source.tag", api.CodeTagsNotAllowed, "tags may not be specified")
+		} else if policy.Destination.Tag != "" {
+			fail("destination.tag", api.CodeTagsNotAllowed, "tags may not be specified")
 		}
 	}
-	return nil
+	return api.NewValidationError(failures)
 }
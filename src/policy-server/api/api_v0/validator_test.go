@@ -1,6 +1,7 @@
 package api_v0_test
 
 import (
+	"policy-server/api"
 	"policy-server/api/api_v0"
 
 	. "github.com/onsi/ginkgo"
@@ -178,5 +179,38 @@ var _ = Describe("Validator", func() {
 				Expect(err).To(MatchError("tags may not be specified"))
 			})
 		})
+
+		Context("when several policies are invalid", func() {
+			It("returns every failure with its index, field and code", func() {
+				policies := []api_v0.Policy{
+					api_v0.Policy{
+						Source: api_v0.Source{ID: "some-source-id"},
+						Destination: api_v0.Destination{
+							ID:       "",
+							Protocol: "tcp",
+							Port:     42,
+						},
+					},
+					api_v0.Policy{
+						Source: api_v0.Source{ID: "some-source-id"},
+						Destination: api_v0.Destination{
+							ID:       "some-destination-id",
+							Protocol: "tcp",
+							Port:     -42,
+						},
+					},
+				}
+
+				err := validator.ValidatePolicies(policies)
+				Expect(err).To(MatchError("missing destination id; invalid port -42, must be in range 1-65535"))
+
+				validationErr, ok := err.(*api.ValidationError)
+				Expect(ok).To(BeTrue())
+				Expect(validationErr.Failures).To(Equal([]api.ValidationFailure{
+					{Index: 0, Field: "destination.id", Code: "missing_destination_id", Reason: "missing destination id"},
+					{Index: 1, Field: "destination.port", Code: "invalid_port", Reason: "invalid port -42, must be in range 1-65535"},
+				}))
+			})
+		})
 	})
 })
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Codes identifying why a policy failed validation. Clients match on these,
+// so they must not change.
+const (
+	CodeMissingPolicies  = "missing_policies"
+	CodeMissingSourceID  = "missing_source_id"
+	CodeMissingDestID    = "missing_destination_id"
+	CodeInvalidProtocol  = "invalid_protocol"
+	CodeInvalidPortRange = "invalid_port_range"
+	CodeInvalidStartPort = "invalid_start_port"
+	CodeMissingStartPort = "missing_start_port"
+	CodeInvalidEndPort   = "invalid_end_port"
+	CodeInvalidPort      = "invalid_port"
+	CodeMissingPort      = "missing_port"
+	CodeTagsNotAllowed   = "tags_not_allowed"
+)
+
+// NoPolicyIndex is the Index of a failure about the request as a whole.
+const NoPolicyIndex = -1
+
+type ValidationFailure struct {
+	Index  int    `json:"index"`
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// ValidationError lists every failure found in a batch of policies, so that
+// a client can fix all of them in one round-trip.
+type ValidationError struct {
+	Message  string
+	Failures []ValidationFailure
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// NewValidationError returns nil when there are no failures.
+func NewValidationError(failures []ValidationFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	reasons := []string{}
+	for _, failure := range failures {
+		reasons = append(reasons, failure.Reason)
+	}
+	return &ValidationError{
+		Message:  strings.Join(reasons, "; "),
+		Failures: failures,
+	}
+}
+
+// WrapValidationError prefixes the message of err like fmt.Errorf, but keeps
+// the failures when err is a *ValidationError.
+func WrapValidationError(prefix string, err error) error {
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		return fmt.Errorf("%s: %s", prefix, err)
+	}
+	return &ValidationError{
+		Message:  fmt.Sprintf("%s: %s", prefix, validationErr.Message),
+		Failures: validationErr.Failures,
+	}
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"errors"
 	"fmt"
 )
 
@@ -12,36 +11,52 @@ type validator interface {
 
 type Validator struct{}
 
+// ValidatePolicies returns a *ValidationError listing every invalid policy.
 func (v *Validator) ValidatePolicies(policies []Policy) error {
 	if len(policies) == 0 {
-		return errors.New("missing policies")
+		return NewValidationError([]ValidationFailure{{
+			Index:  NoPolicyIndex,
+			Field:  "policies",
+			Code:   CodeMissingPolicies,
+			Reason: "missing policies",
+		}})
 	}
 
-	for _, policy := range policies {
+	failures := []ValidationFailure{}
+	for i, policy := range policies {
+		fail := func(field, code, reason string) {
+			failures = append(failures, ValidationFailure{Index: i, Field: field, Code: code, Reason: reason})
+		}
+
 		if policy.Source.ID == "" {
-			return errors.New("missing source id")
+			fail("source.id", CodeMissingSourceID, "missing source id")
 		}
 		if policy.Destination.ID == "" {
-			return errors.New("missing destination id")
+			fail("destination.id", CodeMissingDestID, "missing destination id")
 		}
 		if policy.Destination.Protocol != "udp" && policy.Destination.Protocol != "tcp" {
-			return errors.New("invalid destination protocol, specify either udp or tcp")
+			fail("destination.protocol", CodeInvalidProtocol, "invalid destination protocol, specify either udp or tcp")
 		}
 		if policy.Destination.Ports.Start > policy.Destination.Ports.End {
-			return fmt.Errorf("invalid port range %d-%d, start must be less than or equal to end", policy.Destination.Ports.Start, policy.Destination.Ports.End)
+			fail("destination.ports", CodeInvalidPortRange,
+				fmt.Sprintf("invalid port range %d-%d, start must be less than or equal to end", policy.Destination.Ports.Start, policy.Destination.Ports.End))
 		}
 		if policy.Destination.Ports.Start < 0 {
-			return fmt.Errorf("invalid start port %d, must be in range 1-65535", policy.Destination.Ports.Start)
+			fail("destination.ports.start", CodeInvalidStartPort,
+				fmt.Sprintf("invalid start port %d, must be in range 1-65535", policy.Destination.Ports.Start))
 		}
 		if policy.Destination.Ports.Start == 0 {
-			return fmt.Errorf("missing start port")
+			fail("destination.ports.start", CodeMissingStartPort, "missing start port")
 		}
 		if policy.Destination.Ports.End > 65535 {
-			return fmt.Errorf("invalid end port %d, must be in range 1-65535", policy.Destination.Ports.End)
+			fail("destination.ports.end", CodeInvalidEndPort,
+				fmt.Sprintf("invalid end port %d, must be in range 1-65535", policy.Destination.Ports.End))
 		}
-		if policy.Source.Tag != "" || policy.Destination.Tag != "" {
-			return errors.New("tags may not be specified")
+		if policy.Source.Tag != "" {
+			fail("source.tag", CodeTagsNotAllowed, "tags may not be specified")
+		} else if policy.Destination.Tag != "" {
+			fail("destination.tag", CodeTagsNotAllowed, "tags may not be specified")
 		}
 	}
-	return nil
+	return NewValidationError(failures)
 }
@@ -249,5 +249,59 @@ var _ = Describe("Validator", func() {
 				Expect(err).To(MatchError("tags may not be specified"))
 			})
 		})
+
+		Context("when several policies are invalid", func() {
+			It("returns every failure with its index, field and code", func() {
+				policies := []api.Policy{
+					api.Policy{
+						Source: api.Source{ID: "some-source-id"},
+						Destination: api.Destination{
+							ID:       "some-destination-id",
+							Protocol: "tcp",
+							Ports:    api.Ports{Start: 42, End: 42},
+						},
+					},
+					api.Policy{
+						Source: api.Source{ID: ""},
+						Destination: api.Destination{
+							ID:       "some-destination-id",
+							Protocol: "icmp",
+							Ports:    api.Ports{Start: 42, End: 42},
+						},
+					},
+					api.Policy{
+						Source: api.Source{ID: "some-source-id"},
+						Destination: api.Destination{
+							ID:       "some-destination-id",
+							Protocol: "udp",
+							Ports:    api.Ports{Start: 0, End: 42},
+						},
+					},
+				}
+
+				err := validator.ValidatePolicies(policies)
+				Expect(err).To(MatchError("missing source id; invalid destination protocol, specify either udp or tcp; missing start port"))
+
+				validationErr, ok := err.(*api.ValidationError)
+				Expect(ok).To(BeTrue())
+				Expect(validationErr.Failures).To(Equal([]api.ValidationFailure{
+					{Index: 1, Field: "source.id", Code: "missing_source_id", Reason: "missing source id"},
+					{Index: 1, Field: "destination.protocol", Code: "invalid_protocol", Reason: "invalid destination protocol, specify either udp or tcp"},
+					{Index: 2, Field: "destination.ports.start", Code: "missing_start_port", Reason: "missing start port"},
+				}))
+			})
+		})
+
+		Context("when the policies list is empty", func() {
+			It("reports a failure that is not about any one policy", func() {
+				err := validator.ValidatePolicies(nil)
+
+				validationErr, ok := err.(*api.ValidationError)
+				Expect(ok).To(BeTrue())
+				Expect(validationErr.Failures).To(Equal([]api.ValidationFailure{
+					{Index: -1, Field: "policies", Code: "missing_policies", Reason: "missing policies"},
+				}))
+			})
+		})
 	})
 })
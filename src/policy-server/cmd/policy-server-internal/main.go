@@ -9,11 +9,13 @@ import (
 	"os"
 	"time"
 
+	"lib/poller"
 	"policy-server/adapter"
 	"policy-server/api"
 	"policy-server/api/api_v0_internal"
 	"policy-server/cmd/common"
 	"policy-server/config"
+	"policy-server/grpc_server"
 	"policy-server/handlers"
 	"policy-server/store"
 
@@ -37,6 +39,8 @@ import (
 
 const (
 	jobPrefix = "policy-server-internal"
+
+	watchSubscriberBacklog = 16
 )
 
 var (
@@ -205,6 +209,31 @@ func main() {
 		{"health-check-server", healthCheckServer},
 	}
 
+	if conf.InternalGRPCListenPort != 0 {
+		broadcaster := &grpc_server.PolicyBroadcaster{
+			Store:             wrappedStore,
+			Logger:            logger.Session("policy-broadcaster"),
+			SubscriberBacklog: watchSubscriberBacklog,
+		}
+		broadcastPoller := &poller.Poller{
+			Logger:          logger.Session("policy-broadcast-poller"),
+			PollInterval:    time.Duration(conf.WatchPollIntervalSeconds) * time.Second,
+			SingleCycleFunc: broadcaster.Refresh,
+		}
+		grpcServer := &grpc_server.Runner{
+			Address:   fmt.Sprintf("%s:%d", conf.ListenHost, conf.InternalGRPCListenPort),
+			TLSConfig: tlsConfig,
+			WatchServer: &grpc_server.PolicyWatchServer{
+				Broadcaster: broadcaster,
+				Logger:      logger.Session("watch-policies"),
+			},
+		}
+		members = append(members,
+			grouper.Member{Name: "policy-broadcast-poller", Runner: broadcastPoller},
+			grouper.Member{Name: "internal-grpc-server", Runner: grpcServer},
+		)
+	}
+
 	logger.Info("starting internal server", lager.Data{"listen-address": conf.ListenHost, "port": conf.InternalListenPort})
 
 	group := grouper.NewOrdered(os.Interrupt, members)
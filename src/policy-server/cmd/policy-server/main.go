@@ -33,6 +33,7 @@ import (
 	"code.cloudfoundry.org/cf-networking-helpers/metrics"
 	"code.cloudfoundry.org/cf-networking-helpers/middleware"
 	middlewareAdapter "code.cloudfoundry.org/cf-networking-helpers/middleware/adapter"
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/debugserver"
 	"code.cloudfoundry.org/lager"
 	"github.com/cloudfoundry/dropsonde"
@@ -183,15 +184,19 @@ func main() {
 	policyMapperV0 := api_v0.NewMapper(marshal.UnmarshalFunc(json.Unmarshal), marshal.MarshalFunc(json.Marshal), &api_v0.Validator{})
 	policyMapperV1 := api.NewMapper(marshal.UnmarshalFunc(json.Unmarshal), marshal.MarshalFunc(json.Marshal), &api.Validator{})
 
+	validationErrorResponse := &handlers.ValidationErrorResponse{
+		ErrorResponse: errorResponse,
+	}
+
 	createPolicyHandlerV1 := handlers.NewPoliciesCreate(wrappedStore, policyMapperV1,
-		policyGuard, quotaGuard, errorResponse)
+		policyGuard, quotaGuard, validationErrorResponse)
 	createPolicyHandlerV0 := handlers.NewPoliciesCreate(wrappedStore, policyMapperV0,
-		policyGuard, quotaGuard, errorResponse)
+		policyGuard, quotaGuard, validationErrorResponse)
 
 	deletePolicyHandlerV1 := handlers.NewPoliciesDelete(wrappedStore, policyMapperV1,
-		policyGuard, errorResponse)
+		policyGuard, validationErrorResponse)
 	deletePolicyHandlerV0 := handlers.NewPoliciesDelete(wrappedStore, policyMapperV0,
-		policyGuard, errorResponse)
+		policyGuard, validationErrorResponse)
 
 	policiesIndexHandlerV1 := handlers.NewPoliciesIndex(wrappedStore, policyMapperV1, policyFilter, errorResponse)
 	policiesIndexHandlerV0 := handlers.NewPoliciesIndex(wrappedStore, policyMapperV0, policyFilter, errorResponse)
@@ -233,6 +238,42 @@ func main() {
 		})
 	}
 
+	rateLimiters := map[string]*handlers.RateLimiter{}
+	for route, limit := range conf.RateLimits {
+		rateLimiters[route] = &handlers.RateLimiter{
+			Name:              route,
+			RequestsPerSecond: limit.RequestsPerSecond,
+			Burst:             limit.Burst,
+			Clock:             clock.NewClock(),
+			MetricsSender:     metricsSender,
+		}
+	}
+
+	rateLimitWrap := func(route string, handler http.Handler) http.Handler {
+		rateLimiter, ok := rateLimiters[route]
+		if !ok {
+			return handler
+		}
+		return rateLimiter.Wrap(handler)
+	}
+
+	idempotencyStore := store.NewIdempotencyStore(connectionPool)
+	idempotencyWindow := time.Duration(conf.IdempotencyKeyWindowSeconds) * time.Second
+
+	idempotencyWrap := func(route string, handler http.Handler) http.Handler {
+		if idempotencyWindow == 0 {
+			return handler
+		}
+		idempotency := &handlers.Idempotency{
+			Name:          route,
+			Store:         idempotencyStore,
+			Window:        idempotencyWindow,
+			Clock:         clock.NewClock(),
+			ErrorResponse: errorResponse,
+		}
+		return idempotency.Wrap(handler)
+	}
+
 	authAdminWrap := func(handler http.Handler) http.Handler {
 		networkAdminAuthenticator := handlers.Authenticator{
 			Client:        uaaClient,
@@ -283,22 +324,28 @@ func main() {
 		"health": corsOptionsWrapper(metricsWrap("Health", logWrap(healthHandler))),
 
 		"create_policies": corsOptionsWrapper(metricsWrap("CreatePolicies",
-			logWrap(versionWrap(authWriteWrap(createPolicyHandlerV1), authWriteWrap(createPolicyHandlerV0))))),
+			logWrap(versionWrap(authWriteWrap(rateLimitWrap("create_policies", idempotencyWrap("create_policies", createPolicyHandlerV1))),
+				authWriteWrap(rateLimitWrap("create_policies", idempotencyWrap("create_policies", createPolicyHandlerV0))))))),
 
 		"delete_policies": corsOptionsWrapper(metricsWrap("DeletePolicies",
-			logWrap(versionWrap(authWriteWrap(deletePolicyHandlerV1), authWriteWrap(deletePolicyHandlerV0))))),
+			logWrap(versionWrap(authWriteWrap(rateLimitWrap("delete_policies", idempotencyWrap("delete_policies", deletePolicyHandlerV1))),
+				authWriteWrap(rateLimitWrap("delete_policies", idempotencyWrap("delete_policies", deletePolicyHandlerV0))))))),
 
 		"policies_index": corsOptionsWrapper(metricsWrap("PoliciesIndex",
-			logWrap(versionWrap(authWriteWrap(policiesIndexHandlerV1), authWriteWrap(policiesIndexHandlerV0))))),
+			logWrap(versionWrap(authWriteWrap(rateLimitWrap("policies_index", policiesIndexHandlerV1)),
+				authWriteWrap(rateLimitWrap("policies_index", policiesIndexHandlerV0)))))),
 
 		"cleanup": corsOptionsWrapper(metricsWrap("Cleanup",
-			logWrap(versionWrap(authAdminWrap(policiesCleanupHandler), authAdminWrap(policiesCleanupHandler))))),
+			logWrap(versionWrap(authAdminWrap(rateLimitWrap("cleanup", policiesCleanupHandler)),
+				authAdminWrap(rateLimitWrap("cleanup", policiesCleanupHandler)))))),
 
 		"tags_index": corsOptionsWrapper(metricsWrap("TagsIndex",
-			logWrap(versionWrap(authAdminWrap(tagsIndexHandler), authAdminWrap(tagsIndexHandler))))),
+			logWrap(versionWrap(authAdminWrap(rateLimitWrap("tags_index", tagsIndexHandler)),
+				authAdminWrap(rateLimitWrap("tags_index", tagsIndexHandler)))))),
 
 		"whoami": corsOptionsWrapper(metricsWrap("WhoAmI",
-			logWrap(versionWrap(authAdminWrap(whoamiHandler), authAdminWrap(whoamiHandler))))),
+			logWrap(versionWrap(authAdminWrap(rateLimitWrap("whoami", whoamiHandler)),
+				authAdminWrap(rateLimitWrap("whoami", whoamiHandler)))))),
 	}
 
 	err = dropsonde.Initialize(conf.MetronAddress, dropsondeOrigin)
@@ -318,6 +365,11 @@ func main() {
 		{"debug-server", debugServer},
 	}
 
+	if idempotencyWindow != 0 {
+		idempotencyKeyPoller := initIdempotencyKeyPoller(logger, idempotencyStore, idempotencyWindow)
+		members = append(members, grouper.Member{Name: "idempotency-key-cleaner-poller", Runner: idempotencyKeyPoller})
+	}
+
 	logger.Info("starting external server", lager.Data{"listen-address": conf.ListenHost, "port": conf.ListenPort})
 
 	group := grouper.NewOrdered(os.Interrupt, members)
@@ -344,3 +396,13 @@ func initPoller(logger lager.Logger, conf *config.Config, policyCleaner *cleaner
 		SingleCycleFunc: policyCleaner.DeleteStalePoliciesWrapper,
 	}
 }
+
+func initIdempotencyKeyPoller(logger lager.Logger, idempotencyStore store.IdempotencyStore, window time.Duration) ifrit.Runner {
+	return &poller.Poller{
+		Logger:       logger.Session("idempotency-key-cleaner-poller"),
+		PollInterval: window,
+		SingleCycleFunc: func() error {
+			return idempotencyStore.DeleteExpired(time.Now().Add(-window))
+		},
+	}
+}
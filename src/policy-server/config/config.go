@@ -35,10 +35,55 @@ type Config struct {
 	AllowedCORSDomains              []string  `json:"allowed_cors_domains"`
 	MaxIdleConnections              int       `json:"max_idle_connections" validate:"min=0"`
 	MaxOpenConnections              int       `json:"max_open_connections" validate:"min=0"`
+
+	RateLimits                  map[string]RateLimit `json:"rate_limits"`
+	IdempotencyKeyWindowSeconds int                  `json:"idempotency_key_window_seconds" validate:"min=0"`
+}
+
+// RateLimit configures the token bucket for a single route, keyed in
+// RateLimits by the route name.
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"`
+}
+
+// RateLimitedRoutes are the names of the external routes that can be rate
+// limited. Limits for any other name are rejected, so that a typo does not
+// silently leave a route unlimited.
+var RateLimitedRoutes = []string{
+	"create_policies",
+	"delete_policies",
+	"policies_index",
+	"cleanup",
+	"tags_index",
+	"whoami",
 }
 
 func (c *Config) Validate() error {
-	return validator.Validate(c)
+	if err := validator.Validate(c); err != nil {
+		return err
+	}
+	for route, limit := range c.RateLimits {
+		if !isRateLimitedRoute(route) {
+			return fmt.Errorf("RateLimits.%s: unknown route", route)
+		}
+		if limit.RequestsPerSecond <= 0 {
+			return fmt.Errorf("RateLimits.%s.RequestsPerSecond: less than min", route)
+		}
+		if limit.Burst < 1 {
+			return fmt.Errorf("RateLimits.%s.Burst: less than min", route)
+		}
+	}
+	return nil
+}
+
+func isRateLimitedRoute(route string) bool {
+	for _, name := range RateLimitedRoutes {
+		if name == route {
+			return true
+		}
+	}
+	return false
 }
 
 func New(path string) (*Config, error) {
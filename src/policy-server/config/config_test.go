@@ -60,7 +60,11 @@ var _ = Describe("Config", func() {
 					"request_timeout": 5,
 					"max_policies": 3,
 					"enable_space_developer_self_service": true,
-					"allowed_cors_domains": ["https://foo.bar", "https://bar.foo"]
+					"allowed_cors_domains": ["https://foo.bar", "https://bar.foo"],
+					"rate_limits": {
+						"create_policies": {"requests_per_second": 0.5, "burst": 10}
+					},
+					"idempotency_key_window_seconds": 3600
 				}`)
 				c, err := config.New(file.Name())
 				Expect(err).NotTo(HaveOccurred())
@@ -98,6 +102,10 @@ var _ = Describe("Config", func() {
 					"https://foo.bar",
 					"https://bar.foo",
 				}))
+				Expect(c.RateLimits).To(Equal(map[string]config.RateLimit{
+					"create_policies": {RequestsPerSecond: 0.5, Burst: 10},
+				}))
+				Expect(c.IdempotencyKeyWindowSeconds).To(Equal(3600))
 			})
 		})
 
@@ -182,6 +190,50 @@ var _ = Describe("Config", func() {
 			Entry("missing max policies", "max_policies", "MaxPolicies: less than min"),
 		)
 
+		DescribeTable("when a rate limit is invalid",
+			func(route string, rateLimit map[string]interface{}, errorMsg string) {
+				allData := map[string]interface{}{
+					"listen_host":       "http://1.2.3.4",
+					"listen_port":       1234,
+					"log_prefix":        "cfnetworking",
+					"debug_server_host": "http://4.4.4.4",
+					"debug_server_port": 3333,
+					"uaa_client":        "some-uaa-client",
+					"uaa_client_secret": "some-uaa-client-secret",
+					"uaa_url":           "http://uaa.example.com",
+					"uaa_port":          5555,
+					"cc_url":            "http://ccapi.example.com",
+					"database": map[string]interface{}{
+						"type":    "mysql",
+						"user":    "root",
+						"host":    "127.0.0.1",
+						"port":    3306,
+						"timeout": 5,
+					},
+					"tag_length":       2,
+					"metron_address":   "http://1.2.3.4:9999",
+					"cleanup_interval": 2,
+					"request_timeout":  5,
+					"max_policies":     3,
+					"rate_limits": map[string]interface{}{
+						route: rateLimit,
+					},
+				}
+				Expect(json.NewEncoder(file).Encode(allData)).To(Succeed())
+
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError(fmt.Sprintf("invalid config: %s", errorMsg)))
+			},
+			Entry("missing requests per second", "create_policies", map[string]interface{}{"burst": 1},
+				"RateLimits.create_policies.RequestsPerSecond: less than min"),
+			Entry("negative requests per second", "create_policies", map[string]interface{}{"requests_per_second": -1, "burst": 1},
+				"RateLimits.create_policies.RequestsPerSecond: less than min"),
+			Entry("missing burst", "create_policies", map[string]interface{}{"requests_per_second": 1},
+				"RateLimits.create_policies.Burst: less than min"),
+			Entry("unknown route", "create_policy", map[string]interface{}{"requests_per_second": 1, "burst": 1},
+				"RateLimits.create_policy: unknown route"),
+		)
+
 		Describe("database config", func() {
 			var allData map[string]interface{}
 			BeforeEach(func() {
@@ -309,6 +361,18 @@ var _ = Describe("Config", func() {
 				})
 			})
 
+			Context("when the idempotency key window is less than 0", func() {
+				BeforeEach(func() {
+					allData["idempotency_key_window_seconds"] = -1
+					Expect(json.NewEncoder(file).Encode(allData)).To(Succeed())
+				})
+
+				It("returns an error", func() {
+					_, err = config.New(file.Name())
+					Expect(err).To(MatchError("invalid config: IdempotencyKeyWindowSeconds: less than min"))
+				})
+			})
+
 			Context("when the config file is missing a database_name", func() {
 				BeforeEach(func() {
 					delete(allData["database"].(map[string]interface{}), "database_name")
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 
@@ -27,10 +28,19 @@ type InternalConfig struct {
 	RequestTimeout     int       `json:"request_timeout" validate:"min=1"`
 	MaxIdleConnections int       `json:"max_idle_connections" validate:"min=0"`
 	MaxOpenConnections int       `json:"max_open_connections" validate:"min=0"`
+
+	InternalGRPCListenPort   int `json:"internal_grpc_listen_port" validate:"min=0"`
+	WatchPollIntervalSeconds int `json:"watch_poll_interval_seconds" validate:"min=0"`
 }
 
 func (c *InternalConfig) Validate() error {
-	return validator.Validate(c)
+	if err := validator.Validate(c); err != nil {
+		return err
+	}
+	if c.InternalGRPCListenPort != 0 && c.WatchPollIntervalSeconds < 1 {
+		return errors.New("WatchPollIntervalSeconds: less than min")
+	}
+	return nil
 }
 
 func NewInternal(path string) (*InternalConfig, error) {
@@ -50,7 +50,9 @@ var _ = Describe("InternalConfig", func() {
 					"tag_length": 2,
 					"metron_address": "http://1.2.3.4:9999",
 					"log_level": "debug",
-					"request_timeout": 5
+					"request_timeout": 5,
+					"internal_grpc_listen_port": 3333,
+					"watch_poll_interval_seconds": 2
 				}`)
 				c, err := config.NewInternal(file.Name())
 				Expect(err).NotTo(HaveOccurred())
@@ -76,6 +78,8 @@ var _ = Describe("InternalConfig", func() {
 				Expect(c.RequestTimeout).To(Equal(5))
 				Expect(c.MaxIdleConnections).To(Equal(4))
 				Expect(c.MaxOpenConnections).To(Equal(5))
+				Expect(c.InternalGRPCListenPort).To(Equal(3333))
+				Expect(c.WatchPollIntervalSeconds).To(Equal(2))
 			})
 		})
 
@@ -281,6 +285,18 @@ var _ = Describe("InternalConfig", func() {
 				})
 			})
 
+			Context("when the grpc listen port is set without a watch poll interval", func() {
+				BeforeEach(func() {
+					allData["internal_grpc_listen_port"] = 3333
+					Expect(json.NewEncoder(file).Encode(allData)).To(Succeed())
+				})
+
+				It("returns an error", func() {
+					_, err = config.NewInternal(file.Name())
+					Expect(err).To(MatchError("invalid config: WatchPollIntervalSeconds: less than min"))
+				})
+			})
+
 			Context("when the config file is missing a database_name", func() {
 				BeforeEach(func() {
 					delete(allData["database"].(map[string]interface{}), "database_name")
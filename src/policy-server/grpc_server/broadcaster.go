@@ -0,0 +1,196 @@
+package grpc_server
+
+import (
+	"fmt"
+	"policy-server/api/api_grpc"
+	"policy-server/store"
+	"sort"
+	"sync"
+
+	"code.cloudfoundry.org/lager"
+)
+
+type policyStore interface {
+	All() ([]store.Policy, error)
+}
+
+type PolicyBroadcaster struct {
+	Store             policyStore
+	Logger            lager.Logger
+	SubscriberBacklog int
+
+	mutex       sync.Mutex
+	current     map[store.Policy]struct{}
+	subscribers map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	ids     map[string]struct{}
+	updates chan *api_grpc.PolicyUpdate
+}
+
+// Updates is closed when the subscriber falls more than SubscriberBacklog
+// updates behind, so that it can reconnect and start from a fresh snapshot.
+func (s *Subscription) Updates() <-chan *api_grpc.PolicyUpdate {
+	return s.updates
+}
+
+func (b *PolicyBroadcaster) Refresh() error {
+	policies, err := b.Store.All()
+	if err != nil {
+		return fmt.Errorf("store all: %s", err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.publish(policies)
+	return nil
+}
+
+func (b *PolicyBroadcaster) Subscribe(ids []string) (*Subscription, *api_grpc.PolicyUpdate, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.current == nil {
+		policies, err := b.Store.All()
+		if err != nil {
+			return nil, nil, fmt.Errorf("store all: %s", err)
+		}
+		b.publish(policies)
+	}
+
+	subscription := &Subscription{
+		ids:     map[string]struct{}{},
+		updates: make(chan *api_grpc.PolicyUpdate, b.SubscriberBacklog),
+	}
+	for _, id := range ids {
+		subscription.ids[id] = struct{}{}
+	}
+
+	if b.subscribers == nil {
+		b.subscribers = map[*Subscription]struct{}{}
+	}
+	b.subscribers[subscription] = struct{}{}
+
+	snapshot := &api_grpc.PolicyUpdate{
+		Type:  api_grpc.PolicyUpdate_SNAPSHOT,
+		Added: subscription.filter(sortedPolicies(b.current)),
+	}
+	return subscription, snapshot, nil
+}
+
+func (b *PolicyBroadcaster) Unsubscribe(subscription *Subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.subscribers[subscription]; ok {
+		delete(b.subscribers, subscription)
+		close(subscription.updates)
+	}
+}
+
+func (b *PolicyBroadcaster) publish(policies []store.Policy) {
+	next := map[store.Policy]struct{}{}
+	for _, policy := range policies {
+		next[policy] = struct{}{}
+	}
+
+	previous := b.current
+	b.current = next
+	if previous == nil {
+		return
+	}
+
+	added := sortedPolicies(difference(next, previous))
+	removed := sortedPolicies(difference(previous, next))
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	b.Logger.Debug("policies-changed", lager.Data{"added": len(added), "removed": len(removed)})
+
+	for subscription := range b.subscribers {
+		update := &api_grpc.PolicyUpdate{
+			Type:    api_grpc.PolicyUpdate_DELTA,
+			Added:   subscription.filter(added),
+			Removed: subscription.filter(removed),
+		}
+		if len(update.Added) == 0 && len(update.Removed) == 0 {
+			continue
+		}
+
+		select {
+		case subscription.updates <- update:
+		default:
+			b.Logger.Info("subscriber-backlog-full")
+			delete(b.subscribers, subscription)
+			close(subscription.updates)
+		}
+	}
+}
+
+func (s *Subscription) filter(policies []store.Policy) []*api_grpc.Policy {
+	filtered := []*api_grpc.Policy{}
+	for _, policy := range policies {
+		if len(s.ids) > 0 {
+			_, sourceMatches := s.ids[policy.Source.ID]
+			_, destinationMatches := s.ids[policy.Destination.ID]
+			if !sourceMatches && !destinationMatches {
+				continue
+			}
+		}
+		filtered = append(filtered, asGRPCPolicy(policy))
+	}
+	return filtered
+}
+
+func difference(a, b map[store.Policy]struct{}) map[store.Policy]struct{} {
+	diff := map[store.Policy]struct{}{}
+	for policy := range a {
+		if _, ok := b[policy]; !ok {
+			diff[policy] = struct{}{}
+		}
+	}
+	return diff
+}
+
+func sortedPolicies(policies map[store.Policy]struct{}) []store.Policy {
+	sorted := []store.Policy{}
+	for policy := range policies {
+		sorted = append(sorted, policy)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return policyKey(sorted[i]) < policyKey(sorted[j])
+	})
+	return sorted
+}
+
+func policyKey(policy store.Policy) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%d|%d",
+		policy.Source.ID,
+		policy.Source.Tag,
+		policy.Destination.ID,
+		policy.Destination.Tag,
+		policy.Destination.Protocol,
+		policy.Destination.Ports.Start,
+		policy.Destination.Ports.End,
+	)
+}
+
+func asGRPCPolicy(policy store.Policy) *api_grpc.Policy {
+	return &api_grpc.Policy{
+		Source: &api_grpc.Source{
+			Id:  policy.Source.ID,
+			Tag: policy.Source.Tag,
+		},
+		Destination: &api_grpc.Destination{
+			Id:       policy.Destination.ID,
+			Tag:      policy.Destination.Tag,
+			Protocol: policy.Destination.Protocol,
+			Ports: &api_grpc.Ports{
+				Start: int32(policy.Destination.Ports.Start),
+				End:   int32(policy.Destination.Ports.End),
+			},
+		},
+	}
+}
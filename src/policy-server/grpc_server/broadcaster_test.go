@@ -0,0 +1,218 @@
+package grpc_server_test
+
+import (
+	"errors"
+	"policy-server/api/api_grpc"
+	"policy-server/grpc_server"
+	"policy-server/store"
+	storeFakes "policy-server/store/fakes"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("PolicyBroadcaster", func() {
+	var (
+		broadcaster *grpc_server.PolicyBroadcaster
+		fakeStore   *storeFakes.Store
+		logger      *lagertest.TestLogger
+		policyA     store.Policy
+		policyB     store.Policy
+		policyC     store.Policy
+	)
+
+	BeforeEach(func() {
+		policyA = store.Policy{
+			Source: store.Source{ID: "app-a", Tag: "0001"},
+			Destination: store.Destination{
+				ID:       "app-b",
+				Tag:      "0002",
+				Protocol: "tcp",
+				Ports:    store.Ports{Start: 8080, End: 8080},
+			},
+		}
+		policyB = store.Policy{
+			Source: store.Source{ID: "app-c", Tag: "0003"},
+			Destination: store.Destination{
+				ID:       "app-d",
+				Tag:      "0004",
+				Protocol: "udp",
+				Ports:    store.Ports{Start: 5000, End: 6000},
+			},
+		}
+		policyC = store.Policy{
+			Source: store.Source{ID: "app-b", Tag: "0002"},
+			Destination: store.Destination{
+				ID:       "app-d",
+				Tag:      "0004",
+				Protocol: "tcp",
+				Ports:    store.Ports{Start: 9000, End: 9000},
+			},
+		}
+
+		fakeStore = &storeFakes.Store{}
+		fakeStore.AllReturns([]store.Policy{policyA, policyB}, nil)
+		logger = lagertest.NewTestLogger("test")
+
+		broadcaster = &grpc_server.PolicyBroadcaster{
+			Store:             fakeStore,
+			Logger:            logger,
+			SubscriberBacklog: 2,
+		}
+	})
+
+	Describe("Subscribe", func() {
+		It("returns a snapshot of all policies", func() {
+			_, snapshot, err := broadcaster.Subscribe(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeStore.AllCallCount()).To(Equal(1))
+			Expect(snapshot.Type).To(Equal(api_grpc.PolicyUpdate_SNAPSHOT))
+			Expect(snapshot.Added).To(Equal([]*api_grpc.Policy{
+				{
+					Source: &api_grpc.Source{Id: "app-a", Tag: "0001"},
+					Destination: &api_grpc.Destination{
+						Id:       "app-b",
+						Tag:      "0002",
+						Protocol: "tcp",
+						Ports:    &api_grpc.Ports{Start: 8080, End: 8080},
+					},
+				},
+				{
+					Source: &api_grpc.Source{Id: "app-c", Tag: "0003"},
+					Destination: &api_grpc.Destination{
+						Id:       "app-d",
+						Tag:      "0004",
+						Protocol: "udp",
+						Ports:    &api_grpc.Ports{Start: 5000, End: 6000},
+					},
+				},
+			}))
+			Expect(snapshot.Removed).To(BeEmpty())
+		})
+
+		It("filters the snapshot by source or destination id", func() {
+			_, snapshot, err := broadcaster.Subscribe([]string{"app-d"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(snapshot.Added).To(HaveLen(1))
+			Expect(snapshot.Added[0].Source.Id).To(Equal("app-c"))
+		})
+
+		It("does not reload the policies once they have been loaded", func() {
+			Expect(broadcaster.Refresh()).To(Succeed())
+			_, _, err := broadcaster.Subscribe(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeStore.AllCallCount()).To(Equal(1))
+		})
+
+		Context("when the store fails", func() {
+			BeforeEach(func() {
+				fakeStore.AllReturns(nil, errors.New("banana"))
+			})
+
+			It("returns an error", func() {
+				_, _, err := broadcaster.Subscribe(nil)
+				Expect(err).To(MatchError("store all: banana"))
+			})
+		})
+	})
+
+	Describe("Refresh", func() {
+		var subscription *grpc_server.Subscription
+
+		BeforeEach(func() {
+			var err error
+			subscription, _, err = broadcaster.Subscribe(nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("sends a delta of the added and removed policies", func() {
+			fakeStore.AllReturns([]store.Policy{policyB, policyC}, nil)
+			Expect(broadcaster.Refresh()).To(Succeed())
+
+			var update *api_grpc.PolicyUpdate
+			Eventually(subscription.Updates()).Should(Receive(&update))
+			Expect(update.Type).To(Equal(api_grpc.PolicyUpdate_DELTA))
+			Expect(update.Added).To(HaveLen(1))
+			Expect(update.Added[0].Source.Id).To(Equal("app-b"))
+			Expect(update.Added[0].Destination.Ports.Start).To(Equal(int32(9000)))
+			Expect(update.Removed).To(HaveLen(1))
+			Expect(update.Removed[0].Source.Id).To(Equal("app-a"))
+		})
+
+		It("does not send anything when the policies are unchanged", func() {
+			Expect(broadcaster.Refresh()).To(Succeed())
+			Consistently(subscription.Updates()).ShouldNot(Receive())
+		})
+
+		Context("when the subscriber is filtering by id", func() {
+			var filtered *grpc_server.Subscription
+
+			BeforeEach(func() {
+				var err error
+				filtered, _, err = broadcaster.Subscribe([]string{"app-a"})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("only sends the matching changes", func() {
+				fakeStore.AllReturns([]store.Policy{policyA, policyB, policyC}, nil)
+				Expect(broadcaster.Refresh()).To(Succeed())
+
+				Eventually(subscription.Updates()).Should(Receive())
+				Consistently(filtered.Updates()).ShouldNot(Receive())
+
+				fakeStore.AllReturns([]store.Policy{policyB, policyC}, nil)
+				Expect(broadcaster.Refresh()).To(Succeed())
+
+				var update *api_grpc.PolicyUpdate
+				Eventually(filtered.Updates()).Should(Receive(&update))
+				Expect(update.Added).To(BeEmpty())
+				Expect(update.Removed).To(HaveLen(1))
+				Expect(update.Removed[0].Source.Id).To(Equal("app-a"))
+			})
+		})
+
+		Context("when a subscriber falls behind", func() {
+			It("closes its updates and drops it", func() {
+				fakeStore.AllReturns([]store.Policy{policyA}, nil)
+				Expect(broadcaster.Refresh()).To(Succeed())
+				fakeStore.AllReturns([]store.Policy{policyB}, nil)
+				Expect(broadcaster.Refresh()).To(Succeed())
+				fakeStore.AllReturns([]store.Policy{policyC}, nil)
+				Expect(broadcaster.Refresh()).To(Succeed())
+
+				Eventually(subscription.Updates()).Should(Receive())
+				Eventually(subscription.Updates()).Should(Receive())
+				Eventually(subscription.Updates()).Should(BeClosed())
+				Expect(logger).To(gbytes.Say("subscriber-backlog-full"))
+			})
+		})
+
+		Context("when the store fails", func() {
+			BeforeEach(func() {
+				fakeStore.AllReturns(nil, errors.New("banana"))
+			})
+
+			It("returns an error", func() {
+				Expect(broadcaster.Refresh()).To(MatchError("store all: banana"))
+			})
+		})
+	})
+
+	Describe("Unsubscribe", func() {
+		It("closes the updates and stops sending to the subscriber", func() {
+			subscription, _, err := broadcaster.Subscribe(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			broadcaster.Unsubscribe(subscription)
+			Eventually(subscription.Updates()).Should(BeClosed())
+
+			fakeStore.AllReturns([]store.Policy{policyC}, nil)
+			Expect(broadcaster.Refresh()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package grpc_server_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestGrpcServer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GrpcServer Suite")
+}
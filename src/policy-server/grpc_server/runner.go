@@ -0,0 +1,42 @@
+package grpc_server
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"policy-server/api/api_grpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type Runner struct {
+	Address     string
+	TLSConfig   *tls.Config
+	WatchServer api_grpc.InternalPoliciesServer
+}
+
+func (r *Runner) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	listener, err := net.Listen("tcp", r.Address)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(r.TLSConfig)))
+	api_grpc.RegisterInternalPoliciesServer(server, r.WatchServer)
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- server.Serve(listener)
+	}()
+
+	close(ready)
+
+	select {
+	case <-signals:
+		server.Stop()
+		return nil
+	case err := <-exited:
+		return err
+	}
+}
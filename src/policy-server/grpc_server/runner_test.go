@@ -0,0 +1,121 @@
+package grpc_server_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"policy-server/api/api_grpc"
+	"policy-server/grpc_server"
+	"policy-server/store"
+	storeFakes "policy-server/store/fakes"
+	"test-helpers"
+
+	"code.cloudfoundry.org/cf-networking-helpers/testsupport/ports"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+var _ = Describe("Runner", func() {
+	var (
+		runner     *grpc_server.Runner
+		process    ifrit.Process
+		address    string
+		caFile     string
+		certFile   string
+		keyFile    string
+		clientCert tls.Certificate
+	)
+
+	BeforeEach(func() {
+		caFile, certFile, keyFile, clientCert = testhelpers.GenerateCaAndMutualTlsCerts()
+		serverCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeStore := &storeFakes.Store{}
+		fakeStore.AllReturns([]store.Policy{{
+			Source:      store.Source{ID: "app-a", Tag: "0001"},
+			Destination: store.Destination{ID: "app-b", Tag: "0002", Protocol: "tcp", Ports: store.Ports{Start: 8080, End: 8080}},
+		}}, nil)
+		logger := lagertest.NewTestLogger("test")
+
+		address = fmt.Sprintf("127.0.0.1:%d", ports.PickAPort())
+		runner = &grpc_server.Runner{
+			Address: address,
+			TLSConfig: &tls.Config{
+				Certificates: []tls.Certificate{serverCert},
+				ClientCAs:    testhelpers.CertPool(caFile),
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+			},
+			WatchServer: &grpc_server.PolicyWatchServer{
+				Broadcaster: &grpc_server.PolicyBroadcaster{
+					Store:             fakeStore,
+					Logger:            logger,
+					SubscriberBacklog: 10,
+				},
+				Logger: logger,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+		}
+		os.Remove(caFile)
+		os.Remove(certFile)
+		os.Remove(keyFile)
+	})
+
+	dial := func() *grpc.ClientConn {
+		creds := credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{clientCert},
+			RootCAs:      testhelpers.CertPool(caFile),
+		})
+		conn, err := grpc.Dial(address, grpc.WithTransportCredentials(creds))
+		Expect(err).NotTo(HaveOccurred())
+		return conn
+	}
+
+	It("serves WatchPolicies over mutual TLS", func() {
+		process = ifrit.Invoke(runner)
+
+		conn := dial()
+		defer conn.Close()
+
+		stream, err := api_grpc.NewInternalPoliciesClient(conn).WatchPolicies(context.Background(), &api_grpc.WatchPoliciesRequest{})
+		Expect(err).NotTo(HaveOccurred())
+
+		snapshot, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot.Type).To(Equal(api_grpc.PolicyUpdate_SNAPSHOT))
+		Expect(snapshot.Added).To(HaveLen(1))
+	})
+
+	It("stops serving when signalled", func() {
+		process = ifrit.Invoke(runner)
+
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		process = nil
+
+		_, err := net.Dial("tcp", address)
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("when the address is in use", func() {
+		It("returns an error", func() {
+			listener, err := net.Listen("tcp", address)
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			Expect(runner.Run(make(chan os.Signal), make(chan struct{}))).To(HaveOccurred())
+		})
+	})
+})
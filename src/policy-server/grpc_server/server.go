@@ -0,0 +1,51 @@
+package grpc_server
+
+import (
+	"errors"
+	"policy-server/api/api_grpc"
+
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o fakes/watch_policies_stream.go --fake-name WatchPoliciesStream . watchPoliciesStream
+type watchPoliciesStream interface {
+	api_grpc.InternalPolicies_WatchPoliciesServer
+}
+
+type PolicyWatchServer struct {
+	Broadcaster *PolicyBroadcaster
+	Logger      lager.Logger
+}
+
+func (s *PolicyWatchServer) WatchPolicies(req *api_grpc.WatchPoliciesRequest, stream api_grpc.InternalPolicies_WatchPoliciesServer) error {
+	logger := s.Logger.Session("watch-policies", lager.Data{"ids": req.AppIds})
+
+	subscription, snapshot, err := s.Broadcaster.Subscribe(req.AppIds)
+	if err != nil {
+		logger.Error("subscribe", err)
+		return err
+	}
+	defer s.Broadcaster.Unsubscribe(subscription)
+
+	err = stream.Send(snapshot)
+	if err != nil {
+		logger.Error("send-snapshot", err)
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case update, ok := <-subscription.Updates():
+			if !ok {
+				return errors.New("subscriber fell behind, reconnect for a new snapshot")
+			}
+			err = stream.Send(update)
+			if err != nil {
+				logger.Error("send-update", err)
+				return err
+			}
+		}
+	}
+}
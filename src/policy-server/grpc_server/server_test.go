@@ -0,0 +1,144 @@
+package grpc_server_test
+
+import (
+	"context"
+	"errors"
+	"policy-server/api/api_grpc"
+	"policy-server/grpc_server"
+	"policy-server/grpc_server/fakes"
+	"policy-server/store"
+	storeFakes "policy-server/store/fakes"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PolicyWatchServer", func() {
+	var (
+		watchServer *grpc_server.PolicyWatchServer
+		broadcaster *grpc_server.PolicyBroadcaster
+		fakeStore   *storeFakes.Store
+		stream      *fakes.WatchPoliciesStream
+		ctx         context.Context
+		cancel      context.CancelFunc
+		watchErr    chan error
+		policyA     store.Policy
+		policyB     store.Policy
+	)
+
+	BeforeEach(func() {
+		policyA = store.Policy{
+			Source: store.Source{ID: "app-a", Tag: "0001"},
+			Destination: store.Destination{
+				ID:       "app-b",
+				Tag:      "0002",
+				Protocol: "tcp",
+				Ports:    store.Ports{Start: 8080, End: 8080},
+			},
+		}
+		policyB = store.Policy{
+			Source: store.Source{ID: "app-c", Tag: "0003"},
+			Destination: store.Destination{
+				ID:       "app-a",
+				Tag:      "0001",
+				Protocol: "udp",
+				Ports:    store.Ports{Start: 5000, End: 6000},
+			},
+		}
+
+		fakeStore = &storeFakes.Store{}
+		fakeStore.AllReturns([]store.Policy{policyA}, nil)
+		logger := lagertest.NewTestLogger("test")
+		broadcaster = &grpc_server.PolicyBroadcaster{
+			Store:             fakeStore,
+			Logger:            logger,
+			SubscriberBacklog: 10,
+		}
+		watchServer = &grpc_server.PolicyWatchServer{
+			Broadcaster: broadcaster,
+			Logger:      logger,
+		}
+
+		ctx, cancel = context.WithCancel(context.Background())
+		stream = &fakes.WatchPoliciesStream{}
+		stream.ContextReturns(ctx)
+		watchErr = make(chan error, 1)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	watch := func(ids ...string) {
+		stream, watchErr := stream, watchErr
+		go func() {
+			watchErr <- watchServer.WatchPolicies(&api_grpc.WatchPoliciesRequest{AppIds: ids}, stream)
+		}()
+	}
+
+	sent := func(i int) *api_grpc.PolicyUpdate {
+		Eventually(stream.SendCallCount).Should(BeNumerically(">", i))
+		return stream.SendArgsForCall(i)
+	}
+
+	It("sends a snapshot of the policies of the requested apps first", func() {
+		fakeStore.AllReturns([]store.Policy{policyA, policyB}, nil)
+		watch("app-b")
+
+		snapshot := sent(0)
+		Expect(snapshot.Type).To(Equal(api_grpc.PolicyUpdate_SNAPSHOT))
+		Expect(snapshot.Added).To(HaveLen(1))
+		Expect(snapshot.Added[0].Source.Id).To(Equal("app-a"))
+		Expect(snapshot.Added[0].Destination.Id).To(Equal("app-b"))
+		Expect(snapshot.Removed).To(BeEmpty())
+	})
+
+	It("pushes the changes after the snapshot", func() {
+		watch()
+		sent(0)
+
+		fakeStore.AllReturns([]store.Policy{policyB}, nil)
+		Expect(broadcaster.Refresh()).To(Succeed())
+
+		update := sent(1)
+		Expect(update.Type).To(Equal(api_grpc.PolicyUpdate_DELTA))
+		Expect(update.Added).To(HaveLen(1))
+		Expect(update.Added[0].Source.Id).To(Equal("app-c"))
+		Expect(update.Removed).To(HaveLen(1))
+		Expect(update.Removed[0].Source.Id).To(Equal("app-a"))
+	})
+
+	Context("when the stream context is cancelled", func() {
+		It("returns without an error and stops pushing changes", func() {
+			watch()
+			sent(0)
+
+			cancel()
+			Eventually(watchErr).Should(Receive(BeNil()))
+
+			fakeStore.AllReturns([]store.Policy{policyB}, nil)
+			Expect(broadcaster.Refresh()).To(Succeed())
+			Consistently(stream.SendCallCount).Should(Equal(1))
+		})
+	})
+
+	Context("when the snapshot cannot be sent", func() {
+		It("returns the error", func() {
+			stream.SendReturns(errors.New("potato"))
+			watch()
+
+			Eventually(watchErr).Should(Receive(MatchError("potato")))
+		})
+	})
+
+	Context("when the store cannot be read", func() {
+		It("returns the error without sending", func() {
+			fakeStore.AllReturns(nil, errors.New("potato"))
+			watch()
+
+			Eventually(watchErr).Should(Receive(MatchError("store all: potato")))
+			Expect(stream.SendCallCount()).To(Equal(0))
+		})
+	})
+})
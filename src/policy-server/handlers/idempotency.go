@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"policy-server/store"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotencyStore interface {
+	Get(keyHash string, notBefore time.Time) (store.IdempotencyRecord, bool, error)
+	Reserve(keyHash, requestHash string, createdAt, notBefore time.Time) (bool, error)
+	Complete(store.IdempotencyRecord) error
+	Release(keyHash string) error
+}
+
+// Idempotency replays the stored response when a request is retried with
+// the same Idempotency-Key header. Keys are scoped to the route and to the
+// UAA user or client, so it must wrap a handler that is already behind an
+// Authenticator.
+//
+// The key is reserved before the handler runs, so that only one of several
+// concurrent requests with the same key runs it. The others get a 409
+// Conflict until it completes. A key whose request fails with a server
+// error is released so that it can be retried. A key whose server stopped
+// while handling the request stays reserved until the window passes.
+type Idempotency struct {
+	Name          string
+	Store         idempotencyStore
+	Window        time.Duration
+	Clock         clock.Clock
+	ErrorResponse errorResponse
+}
+
+func (i *Idempotency) Wrap(handle http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			handle.ServeHTTP(w, req)
+			return
+		}
+
+		logger := getLogger(req).Session("idempotency")
+
+		bodyBytes, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			i.ErrorResponse.BadRequest(logger, w, err, "failed reading request body")
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+
+		keyHash := hashOf(i.Name, rateLimitKey(getTokenData(req)), key)
+		requestHash := hashOf(string(bodyBytes))
+		now := i.Clock.Now()
+
+		notBefore := now.Add(-i.Window)
+
+		reserved, err := i.Store.Reserve(keyHash, requestHash, now, notBefore)
+		if err != nil {
+			i.ErrorResponse.InternalServerError(logger, w, err, "idempotency key reservation failed")
+			return
+		}
+
+		if !reserved {
+			record, found, err := i.Store.Get(keyHash, notBefore)
+			if err != nil {
+				i.ErrorResponse.InternalServerError(logger, w, err, "idempotency key lookup failed")
+				return
+			}
+			i.respondWithExisting(logger, w, record, found, requestHash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, code: http.StatusOK}
+		handle.ServeHTTP(recorder, req)
+
+		// Server errors are not stored so that the client can retry them.
+		if recorder.code >= http.StatusInternalServerError {
+			err = i.Store.Release(keyHash)
+			if err != nil {
+				logger.Error("releasing-idempotency-key", err)
+			}
+			return
+		}
+
+		err = i.Store.Complete(store.IdempotencyRecord{
+			KeyHash:        keyHash,
+			RequestHash:    requestHash,
+			ResponseCode:   recorder.code,
+			ResponseHeader: recorder.header,
+			ResponseBody:   recorder.body.Bytes(),
+			CreatedAt:      now,
+		})
+		if err != nil {
+			logger.Error("storing-idempotency-key", err)
+		}
+	})
+}
+
+// respondWithExisting answers a request whose key was already reserved:
+// with the stored response once the first request completed, or with a
+// conflict while it is in progress.
+func (i *Idempotency) respondWithExisting(logger lager.Logger, w http.ResponseWriter, record store.IdempotencyRecord, found bool, requestHash string) {
+	if found && record.RequestHash != requestHash {
+		err := errors.New("idempotency key was already used with a different request body")
+		logger.Error("idempotency-key-reused", err)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(fmt.Sprintf(`{"error": "%s"}`, err)))
+		return
+	}
+
+	if !found || record.Pending() {
+		err := errors.New("a request with this idempotency key is in progress")
+		logger.Error("idempotency-key-in-progress", err)
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(fmt.Sprintf(`{"error": "%s"}`, err)))
+		return
+	}
+
+	logger.Info("replaying-response", lager.Data{"response-code": record.ResponseCode})
+	for name, values := range record.ResponseHeader {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.ResponseCode)
+	w.Write(record.ResponseBody)
+}
+
+// responseRecorder records the response of the wrapped handler, with the
+// headers as they were when it was written.
+type responseRecorder struct {
+	http.ResponseWriter
+	code   int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.code = code
+	r.recordHeader()
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.recordHeader()
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) recordHeader() {
+	if r.header != nil {
+		return
+	}
+	r.header = http.Header{}
+	for name, values := range r.ResponseWriter.Header() {
+		r.header[name] = append([]string{}, values...)
+	}
+}
+
+func hashOf(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
@@ -0,0 +1,278 @@
+package handlers_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+	"policy-server/store"
+	storeFakes "policy-server/store/fakes"
+	"policy-server/uaa_client"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Idempotency", func() {
+	var (
+		idempotency       *handlers.Idempotency
+		fakeStore         *storeFakes.IdempotencyStore
+		fakeClock         *fakeclock.FakeClock
+		fakeErrorResponse *fakes.ErrorResponse
+		logger            *lagertest.TestLogger
+		protected         http.Handler
+		handlerCallCount  int
+		handlerBody       []byte
+		handlerCode       int
+		resp              *httptest.ResponseRecorder
+		tokenData         uaa_client.CheckTokenResponse
+	)
+
+	BeforeEach(func() {
+		fakeStore = &storeFakes.IdempotencyStore{}
+		fakeClock = fakeclock.NewFakeClock(time.Unix(1000000, 0))
+		fakeErrorResponse = &fakes.ErrorResponse{}
+		logger = lagertest.NewTestLogger("test")
+		idempotency = &handlers.Idempotency{
+			Name:          "create_policies",
+			Store:         fakeStore,
+			Window:        time.Hour,
+			Clock:         fakeClock,
+			ErrorResponse: fakeErrorResponse,
+		}
+
+		handlerCallCount = 0
+		handlerCode = http.StatusOK
+		protected = idempotency.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlerCallCount++
+			var err error
+			handlerBody, err = ioutil.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(handlerCode)
+			w.Write([]byte("{}"))
+		}))
+
+		tokenData = uaa_client.CheckTokenResponse{UserID: "some-user-id"}
+		resp = httptest.NewRecorder()
+	})
+
+	makeRequest := func(key, body string) {
+		request, err := http.NewRequest("POST", "/networking/v1/external/policies", bytes.NewBufferString(body))
+		Expect(err).NotTo(HaveOccurred())
+		if key != "" {
+			request.Header.Set("Idempotency-Key", key)
+		}
+		MakeRequestWithLoggerAndAuth(protected.ServeHTTP, resp, request, logger, tokenData)
+	}
+
+	Context("when there is no Idempotency-Key header", func() {
+		It("calls the handler without touching the store", func() {
+			makeRequest("", "some-body")
+
+			Expect(handlerCallCount).To(Equal(1))
+			Expect(handlerBody).To(Equal([]byte("some-body")))
+			Expect(fakeStore.ReserveCallCount()).To(Equal(0))
+			Expect(fakeStore.CompleteCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the key is reserved", func() {
+		BeforeEach(func() {
+			fakeStore.ReserveReturns(true, nil)
+		})
+
+		It("calls the handler and stores the response", func() {
+			makeRequest("some-key", "some-body")
+
+			Expect(handlerCallCount).To(Equal(1))
+			Expect(handlerBody).To(Equal([]byte("some-body")))
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(Equal("{}"))
+
+			Expect(fakeStore.ReserveCallCount()).To(Equal(1))
+			keyHash, requestHash, createdAt, notBefore := fakeStore.ReserveArgsForCall(0)
+			Expect(requestHash).NotTo(BeEmpty())
+			Expect(createdAt).To(Equal(time.Unix(1000000, 0)))
+			Expect(notBefore).To(Equal(time.Unix(1000000, 0).Add(-time.Hour)))
+			Expect(fakeStore.GetCallCount()).To(Equal(0))
+
+			Expect(fakeStore.CompleteCallCount()).To(Equal(1))
+			record := fakeStore.CompleteArgsForCall(0)
+			Expect(record.KeyHash).To(Equal(keyHash))
+			Expect(record.RequestHash).To(Equal(requestHash))
+			Expect(record.ResponseCode).To(Equal(http.StatusOK))
+			Expect(record.ResponseHeader.Get("Content-Type")).To(Equal("application/json"))
+			Expect(record.ResponseBody).To(Equal([]byte("{}")))
+			Expect(record.CreatedAt).To(Equal(time.Unix(1000000, 0)))
+		})
+
+		It("scopes the key to the user", func() {
+			makeRequest("some-key", "some-body")
+			tokenData = uaa_client.CheckTokenResponse{UserID: "other-user-id"}
+			makeRequest("some-key", "some-body")
+
+			firstKeyHash, _, _, _ := fakeStore.ReserveArgsForCall(0)
+			secondKeyHash, _, _, _ := fakeStore.ReserveArgsForCall(1)
+			Expect(firstKeyHash).NotTo(Equal(secondKeyHash))
+		})
+
+		Context("when the handler fails with a server error", func() {
+			BeforeEach(func() {
+				handlerCode = http.StatusInternalServerError
+			})
+
+			It("releases the key instead of storing the response", func() {
+				makeRequest("some-key", "some-body")
+
+				Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+				Expect(fakeStore.CompleteCallCount()).To(Equal(0))
+				Expect(fakeStore.ReleaseCallCount()).To(Equal(1))
+				keyHash, _, _, _ := fakeStore.ReserveArgsForCall(0)
+				Expect(fakeStore.ReleaseArgsForCall(0)).To(Equal(keyHash))
+			})
+
+			Context("when releasing the key fails", func() {
+				BeforeEach(func() {
+					fakeStore.ReleaseReturns(errors.New("banana"))
+				})
+
+				It("still returns the response and logs the error", func() {
+					makeRequest("some-key", "some-body")
+
+					Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+					Expect(logger.Logs()).To(ContainElement(SatisfyAll(
+						LogsWith(lager.ERROR, "test.idempotency.releasing-idempotency-key"),
+						HaveLogData(HaveKeyWithValue("error", "banana")),
+					)))
+				})
+			})
+		})
+
+		Context("when storing the response fails", func() {
+			BeforeEach(func() {
+				fakeStore.CompleteReturns(errors.New("banana"))
+			})
+
+			It("still returns the response and logs the error", func() {
+				makeRequest("some-key", "some-body")
+
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(logger.Logs()).To(ContainElement(SatisfyAll(
+					LogsWith(lager.ERROR, "test.idempotency.storing-idempotency-key"),
+					HaveLogData(HaveKeyWithValue("error", "banana")),
+				)))
+			})
+		})
+	})
+
+	Context("when the key was already reserved", func() {
+		var storedRecord store.IdempotencyRecord
+
+		BeforeEach(func() {
+			fakeStore.ReserveReturns(true, nil)
+			makeRequest("some-key", "some-body")
+			storedRecord = fakeStore.CompleteArgsForCall(0)
+			storedRecord.ResponseCode = http.StatusCreated
+			storedRecord.ResponseHeader = http.Header{"Content-Type": {"application/json"}}
+			storedRecord.ResponseBody = []byte(`{"stored": true}`)
+
+			fakeStore.ReserveReturns(false, nil)
+			fakeStore.GetReturns(storedRecord, true, nil)
+			handlerCallCount = 0
+			resp = httptest.NewRecorder()
+		})
+
+		It("replays the stored response without calling the handler", func() {
+			makeRequest("some-key", "some-body")
+
+			Expect(handlerCallCount).To(Equal(0))
+			Expect(resp.Code).To(Equal(http.StatusCreated))
+			Expect(resp.Body.String()).To(Equal(`{"stored": true}`))
+			Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+			Expect(resp.Header().Get("Idempotent-Replayed")).To(Equal("true"))
+			Expect(fakeStore.CompleteCallCount()).To(Equal(1))
+
+			keyHash, notBefore := fakeStore.GetArgsForCall(0)
+			Expect(keyHash).To(Equal(storedRecord.KeyHash))
+			Expect(notBefore).To(Equal(time.Unix(1000000, 0).Add(-time.Hour)))
+		})
+
+		Context("when the request body is different", func() {
+			It("rejects the request", func() {
+				makeRequest("some-key", "some-other-body")
+
+				Expect(handlerCallCount).To(Equal(0))
+				Expect(resp.Code).To(Equal(http.StatusUnprocessableEntity))
+				Expect(resp.Body.String()).To(MatchJSON(`{"error": "idempotency key was already used with a different request body"}`))
+			})
+		})
+
+		Context("when the first request is still in progress", func() {
+			BeforeEach(func() {
+				storedRecord.ResponseCode = 0
+				fakeStore.GetReturns(storedRecord, true, nil)
+			})
+
+			It("responds with a conflict without calling the handler", func() {
+				makeRequest("some-key", "some-body")
+
+				Expect(handlerCallCount).To(Equal(0))
+				Expect(resp.Code).To(Equal(http.StatusConflict))
+				Expect(resp.Body.String()).To(MatchJSON(`{"error": "a request with this idempotency key is in progress"}`))
+			})
+		})
+
+		Context("when the reservation is gone by the time it is looked up", func() {
+			BeforeEach(func() {
+				fakeStore.GetReturns(store.IdempotencyRecord{}, false, nil)
+			})
+
+			It("responds with a conflict without calling the handler", func() {
+				makeRequest("some-key", "some-body")
+
+				Expect(handlerCallCount).To(Equal(0))
+				Expect(resp.Code).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("when the store lookup fails", func() {
+			BeforeEach(func() {
+				fakeStore.GetReturns(store.IdempotencyRecord{}, false, errors.New("banana"))
+			})
+
+			It("calls the internal server error handler", func() {
+				makeRequest("some-key", "some-body")
+
+				Expect(handlerCallCount).To(Equal(0))
+				Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+				_, _, err, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+				Expect(err).To(MatchError("banana"))
+				Expect(description).To(Equal("idempotency key lookup failed"))
+			})
+		})
+	})
+
+	Context("when reserving the key fails", func() {
+		BeforeEach(func() {
+			fakeStore.ReserveReturns(false, errors.New("banana"))
+		})
+
+		It("calls the internal server error handler", func() {
+			makeRequest("some-key", "some-body")
+
+			Expect(handlerCallCount).To(Equal(0))
+			Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+			_, _, err, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+			Expect(err).To(MatchError("banana"))
+			Expect(description).To(Equal("idempotency key reservation failed"))
+		})
+	})
+})
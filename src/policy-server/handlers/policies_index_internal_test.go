@@ -1,7 +1,9 @@
 package handlers_test
 
 import (
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"policy-server/handlers"
@@ -103,6 +105,57 @@ var _ = Describe("PoliciesIndexInternal", func() {
 		Expect(resp.Body.Bytes()).To(Equal(expectedResponseBody))
 	})
 
+	It("sets an ETag derived from the response body", func() {
+		request, err := http.NewRequest("GET", "/networking/v0/internal/policies?id=some-app-guid", nil)
+		Expect(err).NotTo(HaveOccurred())
+		MakeRequestWithLogger(handler.ServeHTTP, resp, request, logger)
+
+		Expect(resp.Header().Get("ETag")).To(Equal(`"` + fmt.Sprintf("%x", sha256.Sum256(expectedResponseBody)) + `"`))
+	})
+
+	Context("when the request has an If-None-Match header", func() {
+		var etag string
+
+		BeforeEach(func() {
+			etag = `"` + fmt.Sprintf("%x", sha256.Sum256(expectedResponseBody)) + `"`
+		})
+
+		Context("when the ETag matches", func() {
+			It("returns 304 Not Modified without a body", func() {
+				request, err := http.NewRequest("GET", "/networking/v0/internal/policies?id=some-app-guid", nil)
+				Expect(err).NotTo(HaveOccurred())
+				request.Header.Set("If-None-Match", etag)
+				MakeRequestWithLogger(handler.ServeHTTP, resp, request, logger)
+
+				Expect(resp.Code).To(Equal(http.StatusNotModified))
+				Expect(resp.Header().Get("ETag")).To(Equal(etag))
+				Expect(resp.Body.Bytes()).To(BeEmpty())
+			})
+
+			It("matches weak validators and lists of ETags", func() {
+				request, err := http.NewRequest("GET", "/networking/v0/internal/policies?id=some-app-guid", nil)
+				Expect(err).NotTo(HaveOccurred())
+				request.Header.Set("If-None-Match", `"some-other-etag", W/`+etag)
+				MakeRequestWithLogger(handler.ServeHTTP, resp, request, logger)
+
+				Expect(resp.Code).To(Equal(http.StatusNotModified))
+			})
+		})
+
+		Context("when the ETag does not match", func() {
+			It("returns the policies", func() {
+				request, err := http.NewRequest("GET", "/networking/v0/internal/policies?id=some-app-guid", nil)
+				Expect(err).NotTo(HaveOccurred())
+				request.Header.Set("If-None-Match", `"some-stale-etag"`)
+				MakeRequestWithLogger(handler.ServeHTTP, resp, request, logger)
+
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Header().Get("ETag")).To(Equal(etag))
+				Expect(resp.Body.Bytes()).To(Equal(expectedResponseBody))
+			})
+		})
+	})
+
 	Context("when the logger isn't on the request context", func() {
 		It("still works", func() {
 			request, err := http.NewRequest("GET", "/networking/v0/internal/policies?id=some-app-guid", nil)
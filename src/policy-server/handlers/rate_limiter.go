@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"policy-server/uaa_client"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+const rateLimitPruneInterval = time.Minute
+
+//go:generate counterfeiter -o fakes/counter_sender.go --fake-name CounterSender . counterSender
+type counterSender interface {
+	IncrementCounter(string)
+}
+
+// RateLimiter is a token bucket per UAA user or client. It must wrap a
+// handler that is already behind an Authenticator so that the token data
+// is on the request context.
+type RateLimiter struct {
+	Name              string
+	RequestsPerSecond float64
+	Burst             int
+	Clock             clock.Clock
+	MetricsSender     counterSender
+
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func (r *RateLimiter) Wrap(handle http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := rateLimitKey(getTokenData(req))
+
+		allowed, retryAfter := r.take(key)
+		if !allowed {
+			logger := getLogger(req).Session("rate-limit")
+			logger.Info("rate-limit-exceeded", lager.Data{"route": r.Name, "key": key})
+			r.MetricsSender.IncrementCounter("RateLimitExceeded")
+
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": "rate limit exceeded"}`))
+			return
+		}
+
+		handle.ServeHTTP(w, req)
+	})
+}
+
+func (r *RateLimiter) take(key string) (bool, time.Duration) {
+	now := r.Clock.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.buckets == nil {
+		r.buckets = map[string]*tokenBucket{}
+		r.lastPrune = now
+	}
+	if now.Sub(r.lastPrune) >= rateLimitPruneInterval {
+		r.prune(now)
+	}
+
+	bucket, ok := r.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(r.Burst), updated: now}
+		r.buckets[key] = bucket
+	}
+	bucket.tokens = r.refill(bucket, now)
+	bucket.updated = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := (1 - bucket.tokens) / r.RequestsPerSecond
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+func (r *RateLimiter) refill(bucket *tokenBucket, now time.Time) float64 {
+	tokens := bucket.tokens + now.Sub(bucket.updated).Seconds()*r.RequestsPerSecond
+	return math.Min(tokens, float64(r.Burst))
+}
+
+// prune forgets buckets that have refilled completely, since a new
+// bucket would behave identically.
+func (r *RateLimiter) prune(now time.Time) {
+	for key, bucket := range r.buckets {
+		if r.refill(bucket, now) >= float64(r.Burst) {
+			delete(r.buckets, key)
+		}
+	}
+	r.lastPrune = now
+}
+
+func rateLimitKey(tokenData uaa_client.CheckTokenResponse) string {
+	if tokenData.UserID != "" {
+		return "user:" + tokenData.UserID
+	}
+	return "client:" + tokenData.ClientID
+}
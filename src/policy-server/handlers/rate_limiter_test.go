@@ -0,0 +1,115 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+	"policy-server/uaa_client"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimiter", func() {
+	var (
+		rateLimiter       *handlers.RateLimiter
+		fakeClock         *fakeclock.FakeClock
+		fakeMetricsSender *fakes.CounterSender
+		protected         http.Handler
+		handlerCallCount  int
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeMetricsSender = &fakes.CounterSender{}
+		rateLimiter = &handlers.RateLimiter{
+			Name:              "create_policies",
+			RequestsPerSecond: 0.5,
+			Burst:             2,
+			Clock:             fakeClock,
+			MetricsSender:     fakeMetricsSender,
+		}
+
+		handlerCallCount = 0
+		protected = rateLimiter.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlerCallCount++
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	makeRequest := func(tokenData uaa_client.CheckTokenResponse) *httptest.ResponseRecorder {
+		request, err := http.NewRequest("POST", "/networking/v1/external/policies", nil)
+		Expect(err).NotTo(HaveOccurred())
+		request = request.WithContext(context.WithValue(request.Context(), handlers.TokenDataKey, tokenData))
+
+		resp := httptest.NewRecorder()
+		protected.ServeHTTP(resp, request)
+		return resp
+	}
+
+	user := uaa_client.CheckTokenResponse{UserID: "some-user-id", ClientID: "cf"}
+
+	It("allows requests up to the burst size", func() {
+		Expect(makeRequest(user).Code).To(Equal(http.StatusOK))
+		Expect(makeRequest(user).Code).To(Equal(http.StatusOK))
+		Expect(handlerCallCount).To(Equal(2))
+		Expect(fakeMetricsSender.IncrementCounterCallCount()).To(Equal(0))
+	})
+
+	Context("when the bucket is empty", func() {
+		BeforeEach(func() {
+			makeRequest(user)
+			makeRequest(user)
+		})
+
+		It("responds with 429 and a Retry-After header", func() {
+			resp := makeRequest(user)
+			Expect(resp.Code).To(Equal(http.StatusTooManyRequests))
+			Expect(resp.Header().Get("Retry-After")).To(Equal("2"))
+			Expect(resp.Body.String()).To(MatchJSON(`{"error": "rate limit exceeded"}`))
+			Expect(handlerCallCount).To(Equal(2))
+		})
+
+		It("counts the rejection", func() {
+			makeRequest(user)
+			Expect(fakeMetricsSender.IncrementCounterCallCount()).To(Equal(1))
+			Expect(fakeMetricsSender.IncrementCounterArgsForCall(0)).To(Equal("RateLimitExceeded"))
+		})
+
+		It("refills over time", func() {
+			fakeClock.Increment(1 * time.Second)
+			Expect(makeRequest(user).Code).To(Equal(http.StatusTooManyRequests))
+
+			fakeClock.Increment(1 * time.Second)
+			Expect(makeRequest(user).Code).To(Equal(http.StatusOK))
+		})
+
+		It("does not limit other users", func() {
+			otherUser := uaa_client.CheckTokenResponse{UserID: "other-user-id", ClientID: "cf"}
+			Expect(makeRequest(otherUser).Code).To(Equal(http.StatusOK))
+		})
+
+		It("limits clients without a user separately", func() {
+			client := uaa_client.CheckTokenResponse{ClientID: "some-client"}
+			Expect(makeRequest(client).Code).To(Equal(http.StatusOK))
+			Expect(makeRequest(client).Code).To(Equal(http.StatusOK))
+			Expect(makeRequest(client).Code).To(Equal(http.StatusTooManyRequests))
+		})
+	})
+
+	Context("when idle buckets are pruned", func() {
+		It("still limits active users", func() {
+			makeRequest(user)
+			makeRequest(user)
+
+			fakeClock.Increment(2 * time.Minute)
+			Expect(makeRequest(user).Code).To(Equal(http.StatusOK))
+			Expect(makeRequest(user).Code).To(Equal(http.StatusOK))
+			Expect(makeRequest(user).Code).To(Equal(http.StatusTooManyRequests))
+		})
+	})
+})
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"policy-server/api"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// ValidationErrorResponse responds to an *api.ValidationError with a JSON
+// body listing every failure, and passes all other errors through to
+// ErrorResponse. Validation errors still go through ErrorResponse, which
+// logs them, writes their status and counts them in its metric; only the
+// body it writes is replaced.
+type ValidationErrorResponse struct {
+	ErrorResponse errorResponse
+}
+
+type validationErrorBody struct {
+	Error    string                  `json:"error"`
+	Failures []api.ValidationFailure `json:"failures"`
+}
+
+// replacedBodyWriter passes the status and headers written to it through,
+// and discards the body so that another can be written in its place.
+type replacedBodyWriter struct {
+	http.ResponseWriter
+}
+
+func (w replacedBodyWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (e *ValidationErrorResponse) BadRequest(logger lager.Logger, w http.ResponseWriter, err error, description string) {
+	validationErr, ok := err.(*api.ValidationError)
+	if !ok {
+		e.ErrorResponse.BadRequest(logger, w, err, description)
+		return
+	}
+
+	body, marshalErr := json.Marshal(validationErrorBody{
+		Error:    description,
+		Failures: validationErr.Failures,
+	})
+	if marshalErr != nil {
+		e.ErrorResponse.BadRequest(logger, w, err, description)
+		return
+	}
+
+	e.ErrorResponse.BadRequest(logger, replacedBodyWriter{w}, err, description)
+	w.Write(body)
+}
+
+func (e *ValidationErrorResponse) InternalServerError(logger lager.Logger, w http.ResponseWriter, err error, description string) {
+	e.ErrorResponse.InternalServerError(logger, w, err, description)
+}
+
+func (e *ValidationErrorResponse) NotAcceptable(logger lager.Logger, w http.ResponseWriter, err error, description string) {
+	e.ErrorResponse.NotAcceptable(logger, w, err, description)
+}
+
+func (e *ValidationErrorResponse) Forbidden(logger lager.Logger, w http.ResponseWriter, err error, description string) {
+	e.ErrorResponse.Forbidden(logger, w, err, description)
+}
+
+func (e *ValidationErrorResponse) Unauthorized(logger lager.Logger, w http.ResponseWriter, err error, description string) {
+	e.ErrorResponse.Unauthorized(logger, w, err, description)
+}
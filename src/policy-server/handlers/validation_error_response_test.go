@@ -0,0 +1,92 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/api"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidationErrorResponse", func() {
+	var (
+		errorResponse     *handlers.ValidationErrorResponse
+		fakeErrorResponse *fakes.ErrorResponse
+		logger            *lagertest.TestLogger
+		resp              *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		fakeErrorResponse = &fakes.ErrorResponse{}
+		fakeErrorResponse.BadRequestStub = func(_ lager.Logger, w http.ResponseWriter, _ error, _ string) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "some-description"}`))
+		}
+		errorResponse = &handlers.ValidationErrorResponse{
+			ErrorResponse: fakeErrorResponse,
+		}
+		logger = lagertest.NewTestLogger("test")
+		resp = httptest.NewRecorder()
+	})
+
+	Context("when the error is a validation error", func() {
+		var err error
+
+		BeforeEach(func() {
+			err = api.NewValidationError([]api.ValidationFailure{
+				{Index: 0, Field: "source.id", Code: "missing_source_id", Reason: "missing source id"},
+				{Index: 3, Field: "destination.ports.start", Code: "missing_start_port", Reason: "missing start port"},
+			})
+		})
+
+		It("responds with every failure", func() {
+			errorResponse.BadRequest(logger, resp, err, "mapper: validate policies: missing source id; missing start port")
+
+			Expect(resp.Code).To(Equal(http.StatusBadRequest))
+			Expect(resp.Body.String()).To(MatchJSON(`{
+				"error": "mapper: validate policies: missing source id; missing start port",
+				"failures": [
+					{"index": 0, "field": "source.id", "code": "missing_source_id", "reason": "missing source id"},
+					{"index": 3, "field": "destination.ports.start", "code": "missing_start_port", "reason": "missing start port"}
+				]
+			}`))
+		})
+
+		It("lets the wrapped error response log, count and set the status and headers", func() {
+			errorResponse.BadRequest(logger, resp, err, "some-description")
+
+			Expect(fakeErrorResponse.BadRequestCallCount()).To(Equal(1))
+			l, _, wrappedErr, description := fakeErrorResponse.BadRequestArgsForCall(0)
+			Expect(l).To(Equal(logger))
+			Expect(wrappedErr).To(Equal(err))
+			Expect(description).To(Equal("some-description"))
+			Expect(resp.Header().Get("Content-Type")).To(Equal("application/json"))
+		})
+	})
+
+	Context("when the error is not a validation error", func() {
+		It("calls the wrapped error response", func() {
+			errorResponse.BadRequest(logger, resp, errors.New("banana"), "some-description")
+
+			Expect(fakeErrorResponse.BadRequestCallCount()).To(Equal(1))
+			l, w, err, description := fakeErrorResponse.BadRequestArgsForCall(0)
+			Expect(l).To(Equal(logger))
+			Expect(w).To(Equal(resp))
+			Expect(err).To(MatchError("banana"))
+			Expect(description).To(Equal("some-description"))
+			Expect(resp.Body.String()).To(MatchJSON(`{"error": "some-description"}`))
+		})
+	})
+
+	It("passes other responses through", func() {
+		errorResponse.Forbidden(logger, resp, errors.New("banana"), "some-description")
+		Expect(fakeErrorResponse.ForbiddenCallCount()).To(Equal(1))
+	})
+})
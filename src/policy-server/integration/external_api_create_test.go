@@ -108,9 +108,9 @@ var _ = Describe("External API Adding Policies", func() {
 		v0RequestMissingProtocol := `{ "policies": [ {"source": { "id": "some-app-guid" }, "destination": { "id": "some-other-app-guid", "port": 8080 } } ] }`
 		v0Response := `{ "total_policies": 1, "policies": [ { "source": { "id": "some-app-guid" }, "destination": { "id": "some-other-app-guid", "protocol": "tcp", "port": 8080 } } ]}`
 
-		missingStartPortResponse := `{ "error": "mapper: validate policies: missing start port" }`
-		missingPortResponse := `{ "error": "mapper: validate policies: missing port" }`
-		invalidProtocolResponse := `{ "error": "mapper: validate policies: invalid destination protocol, specify either udp or tcp" }`
+		missingStartPortResponse := `{ "error": "mapper: validate policies: missing start port", "failures": [ { "index": 0, "field": "destination.ports.start", "code": "missing_start_port", "reason": "missing start port" } ] }`
+		missingPortResponse := `{ "error": "mapper: validate policies: missing port", "failures": [ { "index": 0, "field": "destination.port", "code": "missing_port", "reason": "missing port" } ] }`
+		invalidProtocolResponse := `{ "error": "mapper: validate policies: invalid destination protocol, specify either udp or tcp", "failures": [ { "index": 0, "field": "destination.protocol", "code": "invalid_protocol", "reason": "invalid destination protocol, specify either udp or tcp" } ] }`
 
 		DescribeTable("adding policies succeeds", addPoliciesSucceeds,
 			Entry("v1", "v1", v1Request, v1Response),
@@ -144,10 +144,10 @@ var _ = Describe("External API Deleting Policies", func() {
 		  { "source": { "id": "some-app-guid" }, "destination": { "id": "some-other-app-guid", "protocol": "tcp", "port": 7777 } }
 		]}`
 
-		missingStartPortResponse := `{ "error": "mapper: validate policies: missing start port" }`
+		missingStartPortResponse := `{ "error": "mapper: validate policies: missing start port", "failures": [ { "index": 0, "field": "destination.ports.start", "code": "missing_start_port", "reason": "missing start port" } ] }`
 
-		missingPortResponse := `{ "error": "mapper: validate policies: missing port" }`
-		invalidProtocolResponse := `{ "error": "mapper: validate policies: invalid destination protocol, specify either udp or tcp" }`
+		missingPortResponse := `{ "error": "mapper: validate policies: missing port", "failures": [ { "index": 0, "field": "destination.port", "code": "missing_port", "reason": "missing port" } ] }`
+		invalidProtocolResponse := `{ "error": "mapper: validate policies: invalid destination protocol, specify either udp or tcp", "failures": [ { "index": 0, "field": "destination.protocol", "code": "invalid_protocol", "reason": "invalid destination protocol, specify either udp or tcp" } ] }`
 
 		DescribeTable("deleting policies succeeds", deletePoliciesSucceeds,
 			Entry("v1", "v1", v1Request, v1Response),
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"policy-server/store/helpers"
+	"time"
+)
+
+//go:generate counterfeiter -o fakes/idempotency_store.go --fake-name IdempotencyStore . IdempotencyStore
+type IdempotencyStore interface {
+	Get(keyHash string, notBefore time.Time) (IdempotencyRecord, bool, error)
+	Reserve(keyHash, requestHash string, createdAt, notBefore time.Time) (bool, error)
+	Complete(IdempotencyRecord) error
+	Release(keyHash string) error
+	DeleteExpired(notBefore time.Time) error
+}
+
+// IdempotencyRecord is the response to the first request made with a key.
+// A record is pending, with a zero ResponseCode, from when the key is
+// reserved until that request completes.
+type IdempotencyRecord struct {
+	KeyHash        string
+	RequestHash    string
+	ResponseCode   int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	CreatedAt      time.Time
+}
+
+// Pending returns whether the first request made with the key is still in
+// progress.
+func (r IdempotencyRecord) Pending() bool {
+	return r.ResponseCode == 0
+}
+
+type idempotencyStore struct {
+	conn database
+}
+
+// NewIdempotencyStore expects the idempotency_keys table to exist, which
+// is created by the migrations that New runs.
+func NewIdempotencyStore(dbConnectionPool database) IdempotencyStore {
+	return &idempotencyStore{
+		conn: dbConnectionPool,
+	}
+}
+
+func (s *idempotencyStore) Get(keyHash string, notBefore time.Time) (IdempotencyRecord, bool, error) {
+	var (
+		requestHash     string
+		responseCode    int
+		responseHeaders sql.NullString
+		responseBody    sql.NullString
+		createdAt       int64
+	)
+
+	err := s.conn.QueryRow(
+		helpers.RebindForSQLDialect(`
+		SELECT request_hash, response_code, response_headers, response_body, created_at
+		FROM idempotency_keys
+		WHERE key_hash = ? AND created_at >= ?
+		`, s.conn.DriverName()),
+		keyHash,
+		notBefore.Unix(),
+	).Scan(&requestHash, &responseCode, &responseHeaders, &responseBody, &createdAt)
+	if err == sql.ErrNoRows {
+		return IdempotencyRecord{}, false, nil
+	}
+	if err != nil {
+		return IdempotencyRecord{}, false, fmt.Errorf("getting idempotency key: %s", err)
+	}
+
+	header := http.Header{}
+	if responseHeaders.Valid && responseHeaders.String != "" {
+		err = json.Unmarshal([]byte(responseHeaders.String), &header)
+		if err != nil {
+			return IdempotencyRecord{}, false, fmt.Errorf("unmarshaling response headers: %s", err)
+		}
+	}
+
+	return IdempotencyRecord{
+		KeyHash:        keyHash,
+		RequestHash:    requestHash,
+		ResponseCode:   responseCode,
+		ResponseHeader: header,
+		ResponseBody:   []byte(responseBody.String),
+		CreatedAt:      time.Unix(createdAt, 0),
+	}, true, nil
+}
+
+// Reserve inserts a pending record for the key, replacing a record that
+// was created before notBefore. It returns false when the key already has
+// a record. The unique constraint on key_hash ensures that only one of
+// several concurrent reservations of a key succeeds.
+func (s *idempotencyStore) Reserve(keyHash, requestHash string, createdAt, notBefore time.Time) (bool, error) {
+	_, err := s.conn.Exec(
+		helpers.RebindForSQLDialect(`DELETE FROM idempotency_keys WHERE key_hash = ? AND created_at < ?`, s.conn.DriverName()),
+		keyHash,
+		notBefore.Unix(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("deleting expired idempotency key: %s", err)
+	}
+
+	_, err = s.conn.Exec(
+		helpers.RebindForSQLDialect(`
+		INSERT INTO idempotency_keys (key_hash, request_hash, response_code, created_at)
+		VALUES (?, ?, 0, ?)`, s.conn.DriverName()),
+		keyHash,
+		requestHash,
+		createdAt.Unix(),
+	)
+	if err == nil {
+		return true, nil
+	}
+
+	_, found, getErr := s.Get(keyHash, time.Unix(0, 0))
+	if getErr == nil && found {
+		return false, nil
+	}
+	return false, fmt.Errorf("inserting idempotency key: %s", err)
+}
+
+// Complete stores the response of the request that reserved the key.
+func (s *idempotencyStore) Complete(record IdempotencyRecord) error {
+	headers, err := json.Marshal(record.ResponseHeader)
+	if err != nil {
+		return fmt.Errorf("marshaling response headers: %s", err)
+	}
+
+	_, err = s.conn.Exec(
+		helpers.RebindForSQLDialect(`
+		UPDATE idempotency_keys
+		SET response_code = ?, response_headers = ?, response_body = ?
+		WHERE key_hash = ?`, s.conn.DriverName()),
+		record.ResponseCode,
+		string(headers),
+		string(record.ResponseBody),
+		record.KeyHash,
+	)
+	if err != nil {
+		return fmt.Errorf("completing idempotency key: %s", err)
+	}
+	return nil
+}
+
+// Release deletes a pending record, so that the request can be retried
+// with the same key.
+func (s *idempotencyStore) Release(keyHash string) error {
+	_, err := s.conn.Exec(
+		helpers.RebindForSQLDialect(`DELETE FROM idempotency_keys WHERE key_hash = ? AND response_code = 0`, s.conn.DriverName()),
+		keyHash,
+	)
+	if err != nil {
+		return fmt.Errorf("releasing idempotency key: %s", err)
+	}
+	return nil
+}
+
+func (s *idempotencyStore) DeleteExpired(notBefore time.Time) error {
+	_, err := s.conn.Exec(
+		helpers.RebindForSQLDialect(`DELETE FROM idempotency_keys WHERE created_at < ?`, s.conn.DriverName()),
+		notBefore.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("deleting expired idempotency keys: %s", err)
+	}
+	return nil
+}
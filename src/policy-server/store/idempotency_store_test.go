@@ -0,0 +1,205 @@
+package store_test
+
+import (
+	"fmt"
+	"net/http"
+	"policy-server/store"
+	"sync"
+	"time"
+
+	dbHelper "code.cloudfoundry.org/cf-networking-helpers/db"
+	"code.cloudfoundry.org/cf-networking-helpers/testsupport"
+
+	"policy-server/store/migrations"
+
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"policy-server/db"
+)
+
+var _ = Describe("IdempotencyStore", func() {
+	var (
+		idempotencyStore store.IdempotencyStore
+		dbConf           dbHelper.Config
+		realDb           *db.ConnWrapper
+		createdAt        time.Time
+	)
+
+	BeforeEach(func() {
+		dbConf = testsupport.GetDBConfig()
+		dbConf.DatabaseName = fmt.Sprintf("idempotency_store_test_node_%d", time.Now().UnixNano())
+
+		testsupport.CreateDatabase(dbConf)
+
+		logger := lager.NewLogger("Idempotency Store Test")
+		realDb = db.NewConnectionPool(dbConf, 200, 200, "Idempotency Store Test", "Idempotency Store Test", logger)
+
+		migrator := &migrations.Migrator{
+			MigrateAdapter: &migrations.MigrateAdapter{},
+		}
+		_, err := migrator.PerformMigrations(realDb.DriverName(), realDb, 0)
+		Expect(err).NotTo(HaveOccurred())
+
+		idempotencyStore = store.NewIdempotencyStore(realDb)
+		createdAt = time.Unix(1500000000, 0)
+	})
+
+	AfterEach(func() {
+		if realDb != nil {
+			Expect(realDb.Close()).To(Succeed())
+		}
+		testsupport.RemoveDatabase(dbConf)
+	})
+
+	record := func(keyHash, requestHash string, at time.Time) store.IdempotencyRecord {
+		return store.IdempotencyRecord{
+			KeyHash:        keyHash,
+			RequestHash:    requestHash,
+			ResponseCode:   200,
+			ResponseHeader: http.Header{"Content-Type": {"application/json"}},
+			ResponseBody:   []byte("{}"),
+			CreatedAt:      at,
+		}
+	}
+
+	reserveAndComplete := func(r store.IdempotencyRecord) {
+		reserved, err := idempotencyStore.Reserve(r.KeyHash, r.RequestHash, r.CreatedAt, r.CreatedAt.Add(-time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reserved).To(BeTrue())
+		Expect(idempotencyStore.Complete(r)).To(Succeed())
+	}
+
+	Describe("Reserve", func() {
+		It("stores a pending record", func() {
+			reserved, err := idempotencyStore.Reserve("some-key", "some-request", createdAt, createdAt.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reserved).To(BeTrue())
+
+			found, ok, err := idempotencyStore.Get("some-key", createdAt.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(found.Pending()).To(BeTrue())
+			Expect(found.RequestHash).To(Equal("some-request"))
+		})
+
+		It("does not reserve a key that is already reserved", func() {
+			reserved, err := idempotencyStore.Reserve("some-key", "some-request", createdAt, createdAt.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reserved).To(BeTrue())
+
+			reserved, err = idempotencyStore.Reserve("some-key", "some-other-request", createdAt, createdAt.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reserved).To(BeFalse())
+
+			found, _, err := idempotencyStore.Get("some-key", createdAt.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.RequestHash).To(Equal("some-request"))
+		})
+
+		It("lets only one of several concurrent reservations of a key succeed", func() {
+			results := make(chan bool, 10)
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					reserved, err := idempotencyStore.Reserve("some-key", fmt.Sprintf("request-%d", i), createdAt, createdAt.Add(-time.Hour))
+					Expect(err).NotTo(HaveOccurred())
+					results <- reserved
+				}(i)
+			}
+			wg.Wait()
+			close(results)
+
+			reservedCount := 0
+			for reserved := range results {
+				if reserved {
+					reservedCount++
+				}
+			}
+			Expect(reservedCount).To(Equal(1))
+		})
+
+		It("replaces a record created before notBefore", func() {
+			reserveAndComplete(record("some-key", "some-request", createdAt))
+
+			reserved, err := idempotencyStore.Reserve("some-key", "some-other-request", createdAt.Add(2*time.Hour), createdAt.Add(time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reserved).To(BeTrue())
+
+			found, ok, err := idempotencyStore.Get("some-key", createdAt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(found.RequestHash).To(Equal("some-other-request"))
+		})
+	})
+
+	Describe("Complete and Get", func() {
+		It("returns the stored record", func() {
+			reserveAndComplete(record("some-key", "some-request", createdAt))
+
+			found, ok, err := idempotencyStore.Get("some-key", createdAt.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(found).To(Equal(record("some-key", "some-request", createdAt)))
+			Expect(found.Pending()).To(BeFalse())
+		})
+
+		It("does not return records older than notBefore", func() {
+			reserveAndComplete(record("some-key", "some-request", createdAt))
+
+			_, ok, err := idempotencyStore.Get("some-key", createdAt.Add(time.Second))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("does not find unknown keys", func() {
+			_, ok, err := idempotencyStore.Get("some-unknown-key", createdAt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Release", func() {
+		It("deletes a pending record so that the key can be reserved again", func() {
+			reserved, err := idempotencyStore.Reserve("some-key", "some-request", createdAt, createdAt.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reserved).To(BeTrue())
+
+			Expect(idempotencyStore.Release("some-key")).To(Succeed())
+
+			reserved, err = idempotencyStore.Reserve("some-key", "some-request", createdAt, createdAt.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reserved).To(BeTrue())
+		})
+
+		It("does not delete a completed record", func() {
+			reserveAndComplete(record("some-key", "some-request", createdAt))
+
+			Expect(idempotencyStore.Release("some-key")).To(Succeed())
+
+			_, ok, err := idempotencyStore.Get("some-key", createdAt.Add(-time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		})
+	})
+
+	Describe("DeleteExpired", func() {
+		It("deletes only records older than notBefore", func() {
+			reserveAndComplete(record("old-key", "some-request", createdAt))
+			reserveAndComplete(record("new-key", "some-request", createdAt.Add(time.Hour)))
+
+			Expect(idempotencyStore.DeleteExpired(createdAt.Add(time.Minute))).To(Succeed())
+
+			_, ok, err := idempotencyStore.Get("old-key", time.Unix(0, 0))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			_, ok, err = idempotencyStore.Get("new-key", time.Unix(0, 0))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		})
+	})
+})
@@ -406,6 +406,51 @@ var _ = Describe("migrations", func() {
 			})
 		})
 
+		Describe("V4", func() {
+			It("creates the idempotency_keys table", func() {
+				numMigrations, err := migrator.PerformMigrations(realDb.DriverName(), realDb, 4)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numMigrations).To(Equal(4))
+
+				By("inserting a key")
+				_, err = realDb.Exec(helpers.RebindForSQLDialect(`
+					INSERT INTO idempotency_keys (key_hash, request_hash, response_code, response_body, created_at)
+					VALUES (?, ?, ?, ?, ?)`, realDb.DriverName()),
+					"some-key-hash", "some-request-hash", 200, "{}", 1500000000)
+				Expect(err).NotTo(HaveOccurred())
+
+				By("rejecting a duplicate key")
+				_, err = realDb.Exec(helpers.RebindForSQLDialect(`
+					INSERT INTO idempotency_keys (key_hash, request_hash, response_code, response_body, created_at)
+					VALUES (?, ?, ?, ?, ?)`, realDb.DriverName()),
+					"some-key-hash", "some-other-request-hash", 200, "{}", 1500000001)
+				Expect(err).To(HaveOccurred())
+
+				rows, err := realDb.Query(`SELECT count(*) FROM idempotency_keys`)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(scanCountRow(rows)).To(Equal(1))
+			})
+
+			It("stores the response headers and allows keys without a response", func() {
+				numMigrations, err := migrator.PerformMigrations(realDb.DriverName(), realDb, 4)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numMigrations).To(Equal(4))
+
+				_, err = realDb.Exec(helpers.RebindForSQLDialect(`
+					INSERT INTO idempotency_keys (key_hash, request_hash, response_code, response_body, response_headers, created_at)
+					VALUES (?, ?, ?, ?, ?, ?)`, realDb.DriverName()),
+					"some-key-hash", "some-request-hash", 200, "{}", `{"Content-Type":["application/json"]}`, 1500000000)
+				Expect(err).NotTo(HaveOccurred())
+
+				By("allowing keys without a response")
+				_, err = realDb.Exec(helpers.RebindForSQLDialect(`
+					INSERT INTO idempotency_keys (key_hash, request_hash, response_code, created_at)
+					VALUES (?, ?, ?, ?)`, realDb.DriverName()),
+					"some-other-key-hash", "some-request-hash", 0, 1500000000)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
 		Context("when migrating in parallel", func() {
 			Context("mysql", func() {
 				BeforeEach(func() {
@@ -0,0 +1,31 @@
+package migrations
+
+var migration_v0004 = map[string][]string{
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+		id int NOT NULL AUTO_INCREMENT,
+		key_hash varchar(64) NOT NULL,
+		request_hash varchar(64) NOT NULL,
+		response_code int NOT NULL,
+		response_headers text,
+		response_body text,
+		created_at bigint NOT NULL,
+		UNIQUE (key_hash),
+		PRIMARY KEY (id)
+	);`,
+		`CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at)`,
+	},
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+		id SERIAL PRIMARY KEY,
+		key_hash text NOT NULL,
+		request_hash text NOT NULL,
+		response_code int NOT NULL,
+		response_headers text,
+		response_body text,
+		created_at bigint NOT NULL,
+		UNIQUE (key_hash)
+	);`,
+		`CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at)`,
+	},
+}
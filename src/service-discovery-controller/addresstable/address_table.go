@@ -2,6 +2,7 @@ package addresstable
 
 import (
 	"fmt"
+	"service-discovery-controller/domains"
 	"sync"
 	"time"
 
@@ -9,94 +10,321 @@ import (
 	"code.cloudfoundry.org/lager"
 )
 
+// AddressTable holds the endpoints registered for each hostname. Entries
+// are sharded by hostname, each shard with its own lock, so that
+// registrations and pruning only block lookups of the hostnames in the
+// shards they change. The table's mutex guards aliases, domains and the
+// pruning state, and is always taken before a shard's.
 type AddressTable struct {
-	addresses          map[string][]entry
+	shards             []*shard
+	reverse            *reverseIndex
+	versions           *versionCounter
+	epoch              string
 	clock              clock.Clock
 	stalenessThreshold time.Duration
+	pruningInterval    time.Duration
 	mutex              sync.RWMutex
 	ticker             clock.Ticker
+	tickerChanged      chan struct{}
+	shutdown           chan struct{}
+	shutdownOnce       sync.Once
 	pausedPruning      bool
 	logger             lager.Logger
 	lastResume         time.Time
 	resumePruningDelay time.Duration
 	warm               bool
+	restored           bool
 	warmMutex          sync.RWMutex
+	watchers           map[*watcher]struct{}
+	watchMutex         sync.Mutex
+	aliases            map[string]string
+	domains            *domains.Set
+}
+
+// Endpoint is an address registered for a hostname. Port is the container
+// port, or 0 when the registration did not include one. CellID and AZ
+// locate the instance, and AppID is the app it belongs to, when the
+// registration included them. Unhealthy endpoints are kept, but only served
+// when a hostname has no healthy ones.
+type Endpoint struct {
+	IP        string
+	Port      uint16
+	CellID    string
+	AZ        string
+	AppID     string
+	Unhealthy bool
 }
 
 type entry struct {
 	ip         string
+	port       uint16
+	cellID     string
+	az         string
+	appID      string
+	unhealthy  bool
+	family     Family
 	updateTime time.Time
+	restored   bool
+
+	// version is the version the entry last changed in, and versionTime
+	// when it was taken.
+	version     uint64
+	versionTime time.Time
 }
 
 func NewAddressTable(stalenessThreshold, pruningInterval, resumePruningDelay time.Duration, clock clock.Clock, logger lager.Logger) *AddressTable {
+	reverse := newReverseIndex()
+	versions := &versionCounter{}
 	table := &AddressTable{
-		addresses:          map[string][]entry{},
+		shards:             newShards(reverse, versions),
+		reverse:            reverse,
+		versions:           versions,
+		epoch:              newEpoch(),
 		clock:              clock,
 		stalenessThreshold: stalenessThreshold,
+		pruningInterval:    pruningInterval,
 		ticker:             clock.NewTicker(pruningInterval),
+		tickerChanged:      make(chan struct{}),
+		shutdown:           make(chan struct{}),
 		pausedPruning:      false,
 		logger:             logger,
 		resumePruningDelay: resumePruningDelay,
+		watchers:           map[*watcher]struct{}{},
+		aliases:            map[string]string{},
+		domains:            domains.NewSet(nil),
 	}
 
-	table.pruneStaleEntriesOnInterval(pruningInterval)
+	table.pruneStaleEntriesOnInterval()
 	return table
 }
 
-func (at *AddressTable) Add(hostnames []string, ip string) {
+// Reconfigure changes the staleness threshold of hostnames outside the
+// domains, and how often stale entries are pruned, without losing any
+// entries. When the interval changes, the ticker is replaced and the next
+// prune happens one new interval from now.
+func (at *AddressTable) Reconfigure(stalenessThreshold, pruningInterval time.Duration) {
+	at.mutex.Lock()
+	at.stalenessThreshold = stalenessThreshold
+	intervalChanged := at.pruningInterval != pruningInterval && !at.isShutdown()
+	if intervalChanged {
+		at.pruningInterval = pruningInterval
+		at.ticker.Stop()
+		at.ticker = at.clock.NewTicker(pruningInterval)
+	}
+	at.mutex.Unlock()
+
+	at.logger.Info("reconfigured", lager.Data{
+		"staleness-threshold": stalenessThreshold.String(),
+		"pruning-interval":    pruningInterval.String(),
+	})
+
+	if !intervalChanged {
+		return
+	}
+
+	select {
+	case at.tickerChanged <- struct{}{}:
+	case <-at.shutdown:
+	}
+}
+
+// SetDomains sets the internal domains whose staleness thresholds override
+// the table's for the hostnames in them.
+func (at *AddressTable) SetDomains(domainSet *domains.Set) {
 	at.mutex.Lock()
+	at.domains = domainSet
+	at.mutex.Unlock()
+}
+
+// stalenessThresholds returns the domains and the staleness threshold of
+// hostnames outside them, which can both change while the table is used.
+func (at *AddressTable) stalenessThresholds() (*domains.Set, time.Duration) {
+	at.mutex.RLock()
+	defer at.mutex.RUnlock()
+	return at.domains, at.stalenessThreshold
+}
+
+func (at *AddressTable) Add(hostnames []string, ip string) {
+	at.AddEndpoint(hostnames, Endpoint{IP: ip})
+}
+
+// AddEndpoint adds or refreshes the endpoint for each hostname. The same IP
+// may be registered with several ports. Refreshing an endpoint also updates
+// its locality and health, so registering it as unhealthy marks it
+// unhealthy without removing it.
+func (at *AddressTable) AddEndpoint(hostnames []string, endpoint Endpoint) {
+	changed := []string{}
+	domainSet, defaultThreshold := at.stalenessThresholds()
 	for _, hostname := range hostnames {
 		fqHostname := fqdn(hostname)
-		entries := at.entriesForHostname(fqHostname)
-		entryIndex := indexOf(entries, ip)
-		if entryIndex == -1 {
-			at.addresses[fqHostname] = append(entries, entry{ip: ip, updateTime: at.clock.Now()})
-		} else {
-			at.addresses[fqHostname][entryIndex].updateTime = at.clock.Now()
+		refreshAfter := refreshVersionAfter(domainSet.StalenessThreshold(fqHostname, defaultThreshold))
+		shard := at.shardFor(fqHostname)
+		shard.mutex.Lock()
+		if shard.addEndpoint(fqHostname, endpoint, at.clock.Now(), refreshAfter) {
+			changed = append(changed, fqHostname)
 		}
+		shard.mutex.Unlock()
 	}
-	at.mutex.Unlock()
+
+	at.notify(changed)
 }
 
 func (at *AddressTable) Remove(hostnames []string, ip string) {
-	at.mutex.Lock()
+	at.RemoveEndpoint(hostnames, Endpoint{IP: ip})
+}
+
+// RemoveEndpoint removes the endpoint from each hostname. An endpoint
+// without a port removes every port registered for its IP. The removal is
+// remembered for the staleness threshold, so that Merge does not add the
+// endpoint back from a peer that has not seen it removed.
+func (at *AddressTable) RemoveEndpoint(hostnames []string, endpoint Endpoint) {
+	changed := []string{}
 	for _, hostname := range hostnames {
 		fqHostname := fqdn(hostname)
-		entries := at.entriesForHostname(fqHostname)
-		index := indexOf(entries, ip)
-		if index > -1 {
-			if len(entries) == 1 {
-				delete(at.addresses, fqHostname)
-			} else {
-				at.addresses[fqHostname] = append(entries[:index], entries[index+1:]...)
-			}
+		shard := at.shardFor(fqHostname)
+		shard.mutex.Lock()
+		if shard.removeEndpoint(fqHostname, endpoint) {
+			changed = append(changed, fqHostname)
 		}
+		shard.recordRemoval(fqHostname, removal{ip: endpoint.IP, port: endpoint.Port, removeTime: at.clock.Now()})
+		shard.mutex.Unlock()
 	}
-	at.mutex.Unlock()
+
+	at.notify(changed)
 }
 
 func (at *AddressTable) Lookup(hostname string) []string {
-	at.mutex.RLock()
+	found := preferHealthy(at.lookupEntries(fqdn(hostname)))
+	return entriesToIPs(found)
+}
 
-	found := at.entriesForHostname(fqdn(hostname))
-	ips := entriesToIPs(found)
+func (at *AddressTable) LookupEndpoints(hostname string) []Endpoint {
+	found := preferHealthy(at.lookupEntries(fqdn(hostname)))
+	endpoints := make([]Endpoint, len(found))
+	for idx, entry := range found {
+		endpoints[idx] = entry.endpoint()
+	}
 
-	at.mutex.RUnlock()
+	return endpoints
+}
 
-	return ips
+// LookupFamily returns only the endpoints whose IP is in the given family.
+func (at *AddressTable) LookupFamily(hostname string, family Family) []Endpoint {
+	inFamily := []entry{}
+	for _, entry := range at.lookupEntries(fqdn(hostname)) {
+		if entry.family == family {
+			inFamily = append(inFamily, entry)
+		}
+	}
+
+	endpoints := []Endpoint{}
+	for _, entry := range preferHealthy(inFamily) {
+		endpoints = append(endpoints, entry.endpoint())
+	}
+
+	return endpoints
 }
 
+// GetAllAddresses returns the IPs of every hostname. The shards are read
+// one at a time, so changes made while it runs may be only partly seen.
 func (at *AddressTable) GetAllAddresses() map[string][]string {
-	at.mutex.RLock()
-
 	addresses := map[string][]string{}
-	for address, entries := range at.addresses {
-		addresses[address] = entriesToIPs(entries)
+	at.forEachShard(func(shard *shard) {
+		for address, entries := range shard.addresses {
+			addresses[address] = entriesToIPs(entries)
+		}
+	})
+
+	return addresses
+}
+
+// AllEndpoints returns every endpoint of every hostname, healthy or not.
+func (at *AddressTable) AllEndpoints() map[string][]Endpoint {
+	endpoints := map[string][]Endpoint{}
+	at.forEachShard(func(shard *shard) {
+		for hostname, entries := range shard.addresses {
+			hostEndpoints := make([]Endpoint, len(entries))
+			for idx, entry := range entries {
+				hostEndpoints[idx] = entry.endpoint()
+			}
+			endpoints[hostname] = hostEndpoints
+		}
+	})
+
+	return endpoints
+}
+
+// EntryCounts returns the number of entries of each hostname.
+func (at *AddressTable) EntryCounts() map[string]int {
+	counts := map[string]int{}
+	at.forEachShard(func(shard *shard) {
+		for hostname, entries := range shard.addresses {
+			if len(entries) > 0 {
+				counts[hostname] = len(entries)
+			}
+		}
+	})
+	return counts
+}
+
+// TakePruned returns the number of entries pruned from each hostname since
+// the last call.
+func (at *AddressTable) TakePruned() map[string]int {
+	pruned := map[string]int{}
+	for _, shard := range at.shards {
+		shard.mutex.Lock()
+		for hostname, count := range shard.pruned {
+			pruned[hostname] += count
+		}
+		shard.pruned = map[string]int{}
+		shard.mutex.Unlock()
+	}
+	return pruned
+}
+
+// TakePrunedByDomain returns the number of entries pruned from each domain
+// since the last call. Entries of hostnames in no domain are counted under
+// the empty key.
+func (at *AddressTable) TakePrunedByDomain() map[string]int {
+	pruned := map[string]int{}
+	for _, shard := range at.shards {
+		shard.mutex.Lock()
+		for domain, count := range shard.prunedByDomain {
+			pruned[domain] += count
+		}
+		shard.prunedByDomain = map[string]int{}
+		shard.mutex.Unlock()
 	}
+	return pruned
+}
 
-	at.mutex.RUnlock()
+// AppIDForIP returns the app that ip is registered for, or "" when no
+// registration of ip included an app.
+func (at *AddressTable) AppIDForIP(ip string) string {
+	for _, hostname := range at.reverse.lookup(ip) {
+		for _, entry := range at.shardFor(hostname).copyEntries(hostname) {
+			if entry.ip == ip && entry.appID != "" {
+				return entry.appID
+			}
+		}
+	}
+	return ""
+}
 
-	return addresses
+// ReverseLookup returns the hostnames that ip is registered for, sorted.
+// Aliases are not included.
+func (at *AddressTable) ReverseLookup(ip string) []string {
+	return at.reverse.lookup(ip)
+}
+
+// forEachShard calls f with each shard in turn, while holding its read
+// lock.
+func (at *AddressTable) forEachShard(f func(shard *shard)) {
+	for _, shard := range at.shards {
+		shard.mutex.RLock()
+		f(shard)
+		shard.mutex.RUnlock()
+	}
 }
 
 func (at *AddressTable) SetWarm() {
@@ -105,7 +333,17 @@ func (at *AddressTable) SetWarm() {
 	at.warmMutex.Unlock()
 }
 
+// IsWarm is true once SetWarm has been called, or once entries have been
+// restored from a snapshot.
 func (at *AddressTable) IsWarm() bool {
+	at.warmMutex.RLock()
+	warm := at.warm || at.restored
+	at.warmMutex.RUnlock()
+
+	return warm
+}
+
+func (at *AddressTable) isWarmFromNats() bool {
 	at.warmMutex.RLock()
 	warm := at.warm
 	at.warmMutex.RUnlock()
@@ -114,7 +352,12 @@ func (at *AddressTable) IsWarm() bool {
 }
 
 func (at *AddressTable) Shutdown() {
-	at.ticker.Stop()
+	at.shutdownOnce.Do(func() {
+		at.mutex.Lock()
+		at.ticker.Stop()
+		close(at.shutdown)
+		at.mutex.Unlock()
+	})
 }
 
 func (at *AddressTable) PausePruning() {
@@ -132,86 +375,202 @@ func (at *AddressTable) ResumePruning() {
 	at.mutex.Unlock()
 }
 
-func (at *AddressTable) entriesForHostname(hostname string) []entry {
-	if existing, ok := at.addresses[hostname]; ok {
-		return existing
-	} else {
-		return []entry{}
+func (e entry) endpoint() Endpoint {
+	return Endpoint{IP: e.ip, Port: e.port, CellID: e.cellID, AZ: e.az, AppID: e.appID, Unhealthy: e.unhealthy}
+}
+
+// preferHealthy returns the healthy entries, or every entry when none are
+// healthy, so that a hostname is still answered while all its instances
+// are failing their health checks.
+func preferHealthy(entries []entry) []entry {
+	unhealthy := 0
+	for _, entry := range entries {
+		if entry.unhealthy {
+			unhealthy++
+		}
+	}
+	if unhealthy == 0 || unhealthy == len(entries) {
+		return entries
 	}
+
+	healthy := make([]entry, 0, len(entries)-unhealthy)
+	for _, entry := range entries {
+		if !entry.unhealthy {
+			healthy = append(healthy, entry)
+		}
+	}
+	return healthy
 }
 
+// smallEntries is the number of entries up to which entriesToIPs finds
+// duplicate IPs by scanning, which is cheaper than a map.
+const smallEntries = 16
+
+// entriesToIPs returns each IP once, even when it is registered with
+// several ports.
 func entriesToIPs(entries []entry) []string {
-	ips := make([]string, len(entries))
-	for idx, entry := range entries {
-		ips[idx] = entry.ip
+	ips := make([]string, 0, len(entries))
+	if len(entries) <= smallEntries {
+		for _, entry := range entries {
+			if !containsIP(ips, entry.ip) {
+				ips = append(ips, entry.ip)
+			}
+		}
+		return ips
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if !seen[entry.ip] {
+			seen[entry.ip] = true
+			ips = append(ips, entry.ip)
+		}
 	}
 
 	return ips
 }
 
-func (at *AddressTable) pruneStaleEntriesOnInterval(pruningInterval time.Duration) {
+func containsIP(ips []string, ip string) bool {
+	for _, existing := range ips {
+		if existing == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneStaleEntriesOnInterval prunes on the table's ticker, switching to
+// the new one when the table is reconfigured, until the table is shut down.
+func (at *AddressTable) pruneStaleEntriesOnInterval() {
 	go func() {
-		defer at.ticker.Stop()
-		for _ = range at.ticker.C() {
-			at.mutex.RLock()
-			if at.pausedPruning || (at.clock.Since(at.lastResume) < at.resumePruningDelay) {
+		ticker := at.currentTicker()
+		for {
+			select {
+			case <-ticker.C():
+				at.mutex.RLock()
+				if at.pausedPruning || (at.clock.Since(at.lastResume) < at.resumePruningDelay) {
+					at.mutex.RUnlock()
+					continue
+				}
 				at.mutex.RUnlock()
-				continue
+				at.PruneStaleEntries()
+			case <-at.tickerChanged:
+				ticker = at.currentTicker()
+			case <-at.shutdown:
+				return
 			}
-			at.mutex.RUnlock()
-			staleAddresses := at.addressesWithStaleEntriesWithReadLock()
-			at.pruneStaleEntriesWithWriteLock(staleAddresses)
 		}
 	}()
 }
 
-func (at *AddressTable) pruneStaleEntriesWithWriteLock(candidateAddresses []string) {
-	if len(candidateAddresses) == 0 {
+// isShutdown returns whether the table was shut down. It must be called
+// while holding the table's mutex, so that no ticker is started after the
+// pruning goroutine has returned.
+func (at *AddressTable) isShutdown() bool {
+	select {
+	case <-at.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+func (at *AddressTable) currentTicker() clock.Ticker {
+	at.mutex.RLock()
+	defer at.mutex.RUnlock()
+	return at.ticker
+}
+
+// PruneStaleEntries prunes the stale entries and removals straight away,
+// even while pruning is paused. Restored entries are still kept until the
+// table is warm. Each shard is only locked for writing when it has stale
+// entries or any removals.
+func (at *AddressTable) PruneStaleEntries() {
+	at.pruneStaleRemovals()
+
+	warmFromNats := at.isWarmFromNats()
+	domainSet, defaultThreshold := at.stalenessThresholds()
+
+	var oldTotal, newTotal int
+	changed := []string{}
+	pruned := false
+	for _, shard := range at.shards {
+		staleAddresses := at.addressesWithStaleEntriesWithReadLock(shard, domainSet, defaultThreshold, warmFromNats)
+		if len(staleAddresses) == 0 {
+			continue
+		}
+		pruned = true
+
+		shardChanged, shardOldTotal, shardNewTotal := at.pruneStaleEntriesWithWriteLock(shard, domainSet, defaultThreshold, staleAddresses, warmFromNats)
+		changed = append(changed, shardChanged...)
+		oldTotal += shardOldTotal
+		newTotal += shardNewTotal
+	}
+
+	if !pruned {
 		return
 	}
+	at.logger.Info("pruned", lager.Data{"old-total": oldTotal, "new-total": newTotal})
+
+	at.notify(changed)
+}
 
+func (at *AddressTable) pruneStaleEntriesWithWriteLock(shard *shard, domainSet *domains.Set, defaultThreshold time.Duration, candidateAddresses []string, warmFromNats bool) ([]string, int, int) {
 	var oldTotal, newTotal int
-	at.mutex.Lock()
+	changed := []string{}
+	shard.mutex.Lock()
 	for _, staleAddr := range candidateAddresses {
-		entries, ok := at.addresses[staleAddr]
+		entries, ok := shard.addresses[staleAddr]
 		if ok {
+			stalenessThreshold := domainSet.StalenessThreshold(staleAddr, defaultThreshold)
 			oldCount := len(entries)
 			freshEntries := []entry{}
 			for _, entry := range entries {
-				if at.clock.Since(entry.updateTime) <= at.stalenessThreshold {
+				if at.clock.Since(entry.updateTime) <= stalenessThreshold || (entry.restored && !warmFromNats) {
 					freshEntries = append(freshEntries, entry)
 				} else {
 					at.logger.Debug(fmt.Sprintf("pruning address %s from %s", entry.ip, staleAddr))
+					shard.reverse.remove(entry.ip, staleAddr)
 				}
 			}
-			at.addresses[staleAddr] = freshEntries
+			shard.addresses[staleAddr] = freshEntries
 			newCount := len(freshEntries)
+			if newCount != oldCount {
+				changed = append(changed, staleAddr)
+				shard.pruned[staleAddr] += oldCount - newCount
+				shard.prunedByDomain[domainSet.NameOf(staleAddr)] += oldCount - newCount
+			}
 			oldTotal += oldCount
 			newTotal += newCount
 		}
 	}
-	at.mutex.Unlock()
-	at.logger.Info("pruned", lager.Data{"old-total": oldTotal, "new-total": newTotal})
+	shard.mutex.Unlock()
+
+	return changed, oldTotal, newTotal
 }
 
-func (at *AddressTable) addressesWithStaleEntriesWithReadLock() []string {
+func (at *AddressTable) addressesWithStaleEntriesWithReadLock(shard *shard, domainSet *domains.Set, defaultThreshold time.Duration, warmFromNats bool) []string {
 	staleAddresses := []string{}
-	at.mutex.RLock()
-	for address, entries := range at.addresses {
+	shard.mutex.RLock()
+	for address, entries := range shard.addresses {
+		stalenessThreshold := domainSet.StalenessThreshold(address, defaultThreshold)
 		for _, entry := range entries {
-			if at.clock.Since(entry.updateTime) > at.stalenessThreshold {
+			if entry.restored && !warmFromNats {
+				continue
+			}
+			if at.clock.Since(entry.updateTime) > stalenessThreshold {
 				staleAddresses = append(staleAddresses, address)
 				break
 			}
 		}
 	}
-	at.mutex.RUnlock()
+	shard.mutex.RUnlock()
 	return staleAddresses
 }
 
-func indexOf(entries []entry, value string) int {
+func indexOf(entries []entry, endpoint Endpoint) int {
 	for idx, entry := range entries {
-		if entry.ip == value {
+		if entry.ip == endpoint.IP && entry.port == endpoint.Port {
 			return idx
 		}
 	}
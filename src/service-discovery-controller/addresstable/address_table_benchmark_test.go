@@ -0,0 +1,156 @@
+package addresstable_test
+
+import (
+	"fmt"
+	"service-discovery-controller/addresstable"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	benchmarkHostnames      = 10000
+	benchmarkIPsPerHostname = 5
+)
+
+// newBenchmarkTable returns a table with benchmarkHostnames hostnames,
+// each with benchmarkIPsPerHostname IPs, that is not pruned on an
+// interval.
+func newBenchmarkTable() *addresstable.AddressTable {
+	table := addresstable.NewAddressTable(time.Hour, time.Hour, 0, clock.NewClock(), lager.NewLogger("benchmark"))
+	for h := 0; h < benchmarkHostnames; h++ {
+		for i := 0; i < benchmarkIPsPerHostname; i++ {
+			table.Add([]string{benchmarkHostname(h)}, benchmarkIP(h, i))
+		}
+	}
+	table.SetWarm()
+	return table
+}
+
+func benchmarkHostname(h int) string {
+	return fmt.Sprintf("app-%d.apps.internal.", h)
+}
+
+func benchmarkIP(h, i int) string {
+	n := h*benchmarkIPsPerHostname + i
+	return fmt.Sprintf("10.%d.%d.%d", n>>16&0xff, n>>8&0xff, n&0xff)
+}
+
+// runInBackground calls f in a loop on workers goroutines until the
+// returned function is called.
+func runInBackground(workers int, f func(n int)) func() {
+	var stopped int32
+	done := make(chan struct{})
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			for n := w; atomic.LoadInt32(&stopped) == 0; n += workers {
+				f(n)
+			}
+			done <- struct{}{}
+		}(w)
+	}
+
+	return func() {
+		atomic.StoreInt32(&stopped, 1)
+		for w := 0; w < workers; w++ {
+			<-done
+		}
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	table := newBenchmarkTable()
+	defer table.Shutdown()
+
+	var counter int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := atomic.AddInt64(&counter, 1)
+			table.Lookup(benchmarkHostname(int(n % benchmarkHostnames)))
+		}
+	})
+}
+
+func BenchmarkAddEndpoint(b *testing.B) {
+	table := newBenchmarkTable()
+	defer table.Shutdown()
+
+	var counter int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := int(atomic.AddInt64(&counter, 1))
+			h := n % benchmarkHostnames
+			table.Add([]string{benchmarkHostname(h)}, benchmarkIP(h, n%benchmarkIPsPerHostname))
+		}
+	})
+}
+
+// BenchmarkLookupDuringRegistrationStorm measures lookups while NATS
+// register messages refresh every entry as fast as they can.
+func BenchmarkLookupDuringRegistrationStorm(b *testing.B) {
+	table := newBenchmarkTable()
+	defer table.Shutdown()
+
+	stop := runInBackground(4, func(n int) {
+		h := n % benchmarkHostnames
+		table.Add([]string{benchmarkHostname(h)}, benchmarkIP(h, n%benchmarkIPsPerHostname))
+	})
+	defer stop()
+
+	var counter int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := atomic.AddInt64(&counter, 1)
+			table.Lookup(benchmarkHostname(int(n % benchmarkHostnames)))
+		}
+	})
+	b.StopTimer()
+}
+
+// BenchmarkLookupDuringPruning measures lookups while the whole table is
+// checked for stale entries over and over.
+func BenchmarkLookupDuringPruning(b *testing.B) {
+	table := newBenchmarkTable()
+	defer table.Shutdown()
+
+	stop := runInBackground(1, func(int) {
+		table.PruneStaleEntries()
+	})
+	defer stop()
+
+	var counter int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n := atomic.AddInt64(&counter, 1)
+			table.Lookup(benchmarkHostname(int(n % benchmarkHostnames)))
+		}
+	})
+	b.StopTimer()
+}
+
+// BenchmarkGetAllAddressesDuringRegistrationStorm measures reading the
+// whole table, as the routes endpoint and snapshots do, while entries are
+// refreshed.
+func BenchmarkGetAllAddressesDuringRegistrationStorm(b *testing.B) {
+	table := newBenchmarkTable()
+	defer table.Shutdown()
+
+	stop := runInBackground(4, func(n int) {
+		h := n % benchmarkHostnames
+		table.Add([]string{benchmarkHostname(h)}, benchmarkIP(h, n%benchmarkIPsPerHostname))
+	})
+	defer stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		table.GetAllAddresses()
+	}
+	b.StopTimer()
+}
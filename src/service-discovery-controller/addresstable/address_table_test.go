@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"service-discovery-controller/addresstable"
+	"service-discovery-controller/domains"
 	"sync"
 	"time"
 
@@ -140,6 +141,198 @@ var _ = Describe("AddressTable", func() {
 		})
 	})
 
+	Describe("Endpoints with ports", func() {
+		BeforeEach(func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 9090})
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.2", Port: 8080})
+		})
+
+		It("returns every port", func() {
+			Expect(table.LookupEndpoints("foo.com")).To(Equal([]addresstable.Endpoint{
+				{IP: "192.0.0.1", Port: 8080},
+				{IP: "192.0.0.1", Port: 9090},
+				{IP: "192.0.0.2", Port: 8080},
+			}))
+		})
+
+		It("returns each IP once from Lookup even with many ports", func() {
+			for port := uint16(10000); port < 10020; port++ {
+				table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.2", Port: port})
+			}
+			Expect(table.Lookup("foo.com")).To(Equal([]string{"192.0.0.1", "192.0.0.2"}))
+		})
+
+		It("returns each IP once from Lookup", func() {
+			Expect(table.Lookup("foo.com")).To(Equal([]string{"192.0.0.1", "192.0.0.2"}))
+			Expect(table.GetAllAddresses()).To(Equal(map[string][]string{
+				"foo.com.": {"192.0.0.1", "192.0.0.2"},
+			}))
+		})
+
+		It("removes only the given port", func() {
+			table.RemoveEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 9090})
+			Expect(table.LookupEndpoints("foo.com")).To(Equal([]addresstable.Endpoint{
+				{IP: "192.0.0.1", Port: 8080},
+				{IP: "192.0.0.2", Port: 8080},
+			}))
+		})
+
+		Context("when the endpoint to remove has no port", func() {
+			It("removes every port for the IP", func() {
+				table.Remove([]string{"foo.com"}, "192.0.0.1")
+				Expect(table.LookupEndpoints("foo.com")).To(Equal([]addresstable.Endpoint{
+					{IP: "192.0.0.2", Port: 8080},
+				}))
+			})
+		})
+	})
+
+	Describe("Endpoints with locality", func() {
+		It("returns the cell and AZ of the latest registration", func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", CellID: "cell-1", AZ: "z1"})
+			Expect(table.LookupEndpoints("foo.com")).To(Equal([]addresstable.Endpoint{
+				{IP: "192.0.0.1", CellID: "cell-1", AZ: "z1"},
+			}))
+
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", CellID: "cell-2", AZ: "z2"})
+			Expect(table.LookupFamily("foo.com", addresstable.IPv4)).To(Equal([]addresstable.Endpoint{
+				{IP: "192.0.0.1", CellID: "cell-2", AZ: "z2"},
+			}))
+			Expect(table.Snapshot()).To(Equal([]addresstable.SnapshotEntry{
+				{Hostname: "foo.com.", IP: "192.0.0.1", CellID: "cell-2", AZ: "z2", UpdateTime: fakeClock.Now()},
+			}))
+		})
+	})
+
+	Describe("AppIDForIP", func() {
+		It("returns the app that the IP is registered for", func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080, AppID: "app-1"})
+			table.AddEndpoint([]string{"bar.com"}, addresstable.Endpoint{IP: "192.0.0.2"})
+
+			Expect(table.AppIDForIP("192.0.0.1")).To(Equal("app-1"))
+			Expect(table.LookupEndpoints("foo.com")).To(Equal([]addresstable.Endpoint{{IP: "192.0.0.1", Port: 8080, AppID: "app-1"}}))
+		})
+
+		It("returns empty for IPs registered without an app", func() {
+			table.AddEndpoint([]string{"bar.com"}, addresstable.Endpoint{IP: "192.0.0.2"})
+
+			Expect(table.AppIDForIP("192.0.0.2")).To(BeEmpty())
+			Expect(table.AppIDForIP("192.0.0.3")).To(BeEmpty())
+		})
+	})
+
+	Describe("ReverseLookup", func() {
+		It("returns the hostnames that the IP is registered for", func() {
+			table.AddEndpoint([]string{"foo.com", "bar.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 9090})
+			table.AddEndpoint([]string{"baz.com"}, addresstable.Endpoint{IP: "192.0.0.2"})
+
+			Expect(table.ReverseLookup("192.0.0.1")).To(Equal([]string{"bar.com.", "foo.com."}))
+			Expect(table.ReverseLookup("192.0.0.3")).To(BeEmpty())
+		})
+
+		It("keeps a hostname until every port of the IP is removed", func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 9090})
+
+			table.RemoveEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			Expect(table.ReverseLookup("192.0.0.1")).To(Equal([]string{"foo.com."}))
+
+			table.RemoveEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 9090})
+			Expect(table.ReverseLookup("192.0.0.1")).To(BeEmpty())
+		})
+
+		It("matches IPv6 addresses however they are written", func() {
+			table.Add([]string{"foo.com"}, "fd00:0::1")
+
+			Expect(table.ReverseLookup("fd00::1")).To(Equal([]string{"foo.com."}))
+		})
+
+		It("forgets pruned entries", func() {
+			table.Add([]string{"foo.com"}, "192.0.0.1")
+			fakeClock.Increment(stalenessThreshold + time.Second)
+			table.PruneStaleEntries()
+
+			Expect(table.ReverseLookup("192.0.0.1")).To(BeEmpty())
+		})
+
+		It("includes restored and merged entries", func() {
+			table.Restore([]addresstable.SnapshotEntry{{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now()}})
+			table.Merge([]addresstable.SnapshotEntry{{Hostname: "bar.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now()}})
+
+			Expect(table.ReverseLookup("192.0.0.1")).To(Equal([]string{"bar.com.", "foo.com."}))
+		})
+	})
+
+	Describe("Endpoints with health", func() {
+		BeforeEach(func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.2", Port: 8080, Unhealthy: true})
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "fd00::1", Port: 8080, Unhealthy: true})
+		})
+
+		It("omits unhealthy endpoints from lookups", func() {
+			Expect(table.Lookup("foo.com")).To(Equal([]string{"192.0.0.1"}))
+			Expect(table.LookupEndpoints("foo.com")).To(Equal([]addresstable.Endpoint{{IP: "192.0.0.1", Port: 8080}}))
+			Expect(table.LookupFamily("foo.com", addresstable.IPv4)).To(Equal([]addresstable.Endpoint{{IP: "192.0.0.1", Port: 8080}}))
+		})
+
+		It("keeps unhealthy endpoints in the table", func() {
+			Expect(table.AllEndpoints()).To(Equal(map[string][]addresstable.Endpoint{
+				"foo.com.": {
+					{IP: "192.0.0.1", Port: 8080},
+					{IP: "192.0.0.2", Port: 8080, Unhealthy: true},
+					{IP: "fd00::1", Port: 8080, Unhealthy: true},
+				},
+			}))
+			Expect(table.GetAllAddresses()).To(Equal(map[string][]string{"foo.com.": {"192.0.0.1", "192.0.0.2", "fd00::1"}}))
+		})
+
+		It("returns unhealthy endpoints when none are healthy", func() {
+			Expect(table.LookupFamily("foo.com", addresstable.IPv6)).To(Equal([]addresstable.Endpoint{{IP: "fd00::1", Port: 8080, Unhealthy: true}}))
+
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080, Unhealthy: true})
+			Expect(table.Lookup("foo.com")).To(Equal([]string{"192.0.0.1", "192.0.0.2", "fd00::1"}))
+		})
+
+		It("serves an endpoint again once it is registered as healthy", func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.2", Port: 8080})
+			Expect(table.Lookup("foo.com")).To(Equal([]string{"192.0.0.1", "192.0.0.2"}))
+		})
+
+		It("includes the health in the snapshot", func() {
+			Expect(table.Snapshot()).To(ContainElement(addresstable.SnapshotEntry{
+				Hostname: "foo.com.", IP: "192.0.0.2", Port: 8080, Unhealthy: true, UpdateTime: fakeClock.Now(),
+			}))
+		})
+	})
+
+	Describe("LookupFamily", func() {
+		BeforeEach(func() {
+			table.Add([]string{"foo.com"}, "192.0.0.1")
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "fd00::1", Port: 8080})
+			table.Add([]string{"foo.com"}, "192.0.0.2")
+		})
+
+		It("returns only ipv4 endpoints for ipv4", func() {
+			Expect(table.LookupFamily("foo.com", addresstable.IPv4)).To(Equal([]addresstable.Endpoint{
+				{IP: "192.0.0.1"},
+				{IP: "192.0.0.2"},
+			}))
+		})
+
+		It("returns only ipv6 endpoints for ipv6", func() {
+			Expect(table.LookupFamily("foo.com", addresstable.IPv6)).To(Equal([]addresstable.Endpoint{
+				{IP: "fd00::1", Port: 8080},
+			}))
+		})
+
+		It("returns an empty array for an unknown hostname", func() {
+			Expect(table.LookupFamily("bar.com", addresstable.IPv6)).To(Equal([]addresstable.Endpoint{}))
+		})
+	})
+
 	Describe("Lookup", func() {
 		It("returns an empty array for an unknown hostname", func() {
 			Expect(table.Lookup("foo.com")).To(Equal([]string{}))
@@ -173,6 +366,419 @@ var _ = Describe("AddressTable", func() {
 		})
 	})
 
+	Describe("Snapshot", func() {
+		It("returns every entry with its update time", func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			fakeClock.Increment(time.Second)
+			table.Add([]string{"bar.com"}, "192.0.0.2")
+
+			Expect(table.Snapshot()).To(Equal([]addresstable.SnapshotEntry{
+				{Hostname: "bar.com.", IP: "192.0.0.2", UpdateTime: fakeClock.Now()},
+				{Hostname: "foo.com.", IP: "192.0.0.1", Port: 8080, UpdateTime: fakeClock.Now().Add(-time.Second)},
+			}))
+		})
+	})
+
+	Describe("Restore", func() {
+		var snapshotTime time.Time
+
+		BeforeEach(func() {
+			snapshotTime = fakeClock.Now().Add(-stalenessThreshold)
+			table.Restore([]addresstable.SnapshotEntry{
+				{Hostname: "foo.com.", IP: "192.0.0.1", Port: 8080, UpdateTime: snapshotTime},
+				{Hostname: "bar.com.", IP: "192.0.0.2", UpdateTime: snapshotTime},
+			})
+		})
+
+		It("serves the restored entries straight away", func() {
+			Expect(table.IsWarm()).To(BeTrue())
+			Expect(table.LookupEndpoints("foo.com")).To(Equal([]addresstable.Endpoint{{IP: "192.0.0.1", Port: 8080}}))
+			Expect(table.Snapshot()).To(ContainElement(
+				addresstable.SnapshotEntry{Hostname: "bar.com.", IP: "192.0.0.2", UpdateTime: snapshotTime},
+			))
+		})
+
+		It("does not prune the stale restored entries until the table is warm", func() {
+			fakeClock.Increment(pruningInterval)
+			Consistently(func() []string { return table.Lookup("foo.com") }).Should(Equal([]string{"192.0.0.1"}))
+
+			table.SetWarm()
+			fakeClock.Increment(pruningInterval)
+			Eventually(func() []string { return table.Lookup("foo.com") }).Should(Equal([]string{}))
+		})
+
+		Context("when a restored entry is registered again", func() {
+			It("is pruned like any other entry", func() {
+				table.Add([]string{"bar.com"}, "192.0.0.2")
+				table.SetWarm()
+
+				fakeClock.Increment(pruningInterval)
+				Eventually(func() []string { return table.Lookup("foo.com") }).Should(Equal([]string{}))
+				Consistently(func() []string { return table.Lookup("bar.com") }).Should(Equal([]string{"192.0.0.2"}))
+			})
+		})
+
+		Context("when an entry is already in the table", func() {
+			It("keeps the existing entry", func() {
+				table.Add([]string{"baz.com"}, "192.0.0.3")
+				table.Restore([]addresstable.SnapshotEntry{
+					{Hostname: "baz.com.", IP: "192.0.0.3", UpdateTime: snapshotTime},
+				})
+
+				Expect(table.Snapshot()).To(ContainElement(
+					addresstable.SnapshotEntry{Hostname: "baz.com.", IP: "192.0.0.3", UpdateTime: fakeClock.Now()},
+				))
+			})
+		})
+	})
+
+	Context("when restoring a snapshot older than the staleness threshold", func() {
+		BeforeEach(func() {
+			table.Restore([]addresstable.SnapshotEntry{
+				{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now().Add(-time.Hour)},
+			})
+		})
+
+		It("does not mark the table warm", func() {
+			Expect(table.IsWarm()).To(BeFalse())
+		})
+
+		It("prunes the restored entries once the table is warm", func() {
+			fakeClock.Increment(pruningInterval)
+			Consistently(func() []string { return table.Lookup("foo.com") }).Should(Equal([]string{"192.0.0.1"}))
+
+			table.SetWarm()
+			fakeClock.Increment(pruningInterval)
+			Eventually(func() []string { return table.Lookup("foo.com") }).Should(Equal([]string{}))
+		})
+	})
+
+	Context("when restoring an empty snapshot", func() {
+		It("does not mark the table warm", func() {
+			table.Restore([]addresstable.SnapshotEntry{})
+			Expect(table.IsWarm()).To(BeFalse())
+		})
+	})
+
+	Describe("Merge", func() {
+		BeforeEach(func() {
+			table.Add([]string{"foo.com"}, "192.0.0.1")
+			fakeClock.Increment(time.Second)
+		})
+
+		It("adds missing entries and refreshes older ones", func() {
+			merged := table.Merge([]addresstable.SnapshotEntry{
+				{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now()},
+				{Hostname: "bar.com.", IP: "192.0.0.2", Port: 8080, UpdateTime: fakeClock.Now()},
+			})
+
+			Expect(merged).To(Equal(2))
+			Expect(table.Snapshot()).To(Equal([]addresstable.SnapshotEntry{
+				{Hostname: "bar.com.", IP: "192.0.0.2", Port: 8080, UpdateTime: fakeClock.Now()},
+				{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now()},
+			}))
+		})
+
+		It("keeps entries that are newer than the peer's", func() {
+			merged := table.Merge([]addresstable.SnapshotEntry{
+				{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now().Add(-time.Minute)},
+			})
+
+			Expect(merged).To(Equal(0))
+			Expect(table.Snapshot()).To(Equal([]addresstable.SnapshotEntry{
+				{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now().Add(-time.Second)},
+			}))
+		})
+
+		It("takes the health of entries the peer has seen more recently", func() {
+			table.Merge([]addresstable.SnapshotEntry{
+				{Hostname: "foo.com.", IP: "192.0.0.1", Unhealthy: true, UpdateTime: fakeClock.Now()},
+				{Hostname: "foo.com.", IP: "192.0.0.2", UpdateTime: fakeClock.Now()},
+			})
+
+			Expect(table.Lookup("foo.com")).To(Equal([]string{"192.0.0.2"}))
+		})
+
+		It("ignores stale entries", func() {
+			merged := table.Merge([]addresstable.SnapshotEntry{
+				{Hostname: "bar.com.", IP: "192.0.0.2", UpdateTime: fakeClock.Now().Add(-stalenessThreshold - time.Second)},
+			})
+
+			Expect(merged).To(Equal(0))
+			Expect(table.Lookup("bar.com")).To(BeEmpty())
+		})
+
+		It("ignores entries that are stale in their domain", func() {
+			table.SetDomains(domains.NewSet([]domains.Domain{{Name: "short.com", StalenessThreshold: time.Second}}))
+			merged := table.Merge([]addresstable.SnapshotEntry{
+				{Hostname: "bar.short.com.", IP: "192.0.0.2", UpdateTime: fakeClock.Now().Add(-2 * time.Second)},
+			})
+
+			Expect(merged).To(Equal(0))
+			Expect(table.Lookup("bar.short.com")).To(BeEmpty())
+		})
+
+		Context("when an endpoint was removed after the peer saw it registered", func() {
+			var peerEntries []addresstable.SnapshotEntry
+
+			BeforeEach(func() {
+				peerEntries = table.Snapshot()
+				fakeClock.Increment(time.Second)
+				table.Remove([]string{"foo.com"}, "192.0.0.1")
+			})
+
+			It("does not add it back", func() {
+				merged := table.Merge(peerEntries)
+
+				Expect(merged).To(Equal(0))
+				Expect(table.Lookup("foo.com")).To(BeEmpty())
+			})
+
+			It("adds it back once the peer sees it registered again", func() {
+				fakeClock.Increment(time.Second)
+				merged := table.Merge([]addresstable.SnapshotEntry{
+					{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now()},
+				})
+
+				Expect(merged).To(Equal(1))
+				Expect(table.Lookup("foo.com")).To(Equal([]string{"192.0.0.1"}))
+			})
+		})
+	})
+
+	Describe("Removals", func() {
+		It("returns the endpoints removed within the staleness threshold", func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			table.RemoveEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			removeTime := fakeClock.Now()
+
+			Expect(table.Removals()).To(Equal([]addresstable.Removal{
+				{Hostname: "foo.com.", IP: "192.0.0.1", Port: 8080, RemoveTime: removeTime},
+			}))
+
+			fakeClock.Increment(stalenessThreshold + time.Second)
+			table.PruneStaleEntries()
+
+			Expect(table.Removals()).To(BeEmpty())
+		})
+	})
+
+	Describe("MergeRemovals", func() {
+		BeforeEach(func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 9090})
+			fakeClock.Increment(time.Second)
+		})
+
+		It("removes entries registered before the peer removed them", func() {
+			removed := table.MergeRemovals([]addresstable.Removal{
+				{Hostname: "foo.com.", IP: "192.0.0.1", Port: 8080, RemoveTime: fakeClock.Now()},
+			})
+
+			Expect(removed).To(Equal(1))
+			Expect(table.LookupEndpoints("foo.com")).To(Equal([]addresstable.Endpoint{{IP: "192.0.0.1", Port: 9090}}))
+		})
+
+		It("removes every port when the removal has none", func() {
+			removed := table.MergeRemovals([]addresstable.Removal{
+				{Hostname: "foo.com.", IP: "192.0.0.1", RemoveTime: fakeClock.Now()},
+			})
+
+			Expect(removed).To(Equal(2))
+			Expect(table.Lookup("foo.com")).To(BeEmpty())
+		})
+
+		It("keeps entries registered after the peer removed them", func() {
+			removed := table.MergeRemovals([]addresstable.Removal{
+				{Hostname: "foo.com.", IP: "192.0.0.1", RemoveTime: fakeClock.Now().Add(-time.Minute)},
+			})
+
+			Expect(removed).To(Equal(0))
+			Expect(table.Lookup("foo.com")).To(Equal([]string{"192.0.0.1"}))
+		})
+
+		It("ignores stale removals", func() {
+			removed := table.MergeRemovals([]addresstable.Removal{
+				{Hostname: "foo.com.", IP: "192.0.0.1", RemoveTime: fakeClock.Now().Add(-stalenessThreshold - time.Second)},
+			})
+
+			Expect(removed).To(Equal(0))
+			Expect(table.Removals()).To(BeEmpty())
+		})
+
+		It("keeps a peer from adding back what another peer removed", func() {
+			peerEntries := table.Snapshot()
+			table.MergeRemovals([]addresstable.Removal{
+				{Hostname: "foo.com.", IP: "192.0.0.1", RemoveTime: fakeClock.Now()},
+			})
+
+			merged := table.Merge(peerEntries)
+
+			Expect(merged).To(Equal(0))
+			Expect(table.Lookup("foo.com")).To(BeEmpty())
+		})
+	})
+
+	Describe("ChangesSince", func() {
+		It("returns every entry and removal for version 0", func() {
+			table.Add([]string{"foo.com"}, "192.0.0.1")
+			table.Remove([]string{"bar.com"}, "192.0.0.2")
+
+			changes := table.ChangesSince(table.Epoch(), 0)
+			Expect(changes.Full).To(BeTrue())
+			Expect(changes.Epoch).To(Equal(table.Epoch()))
+			Expect(changes.Entries).To(Equal([]addresstable.SnapshotEntry{
+				{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now()},
+			}))
+			Expect(changes.Removals).To(Equal([]addresstable.Removal{
+				{Hostname: "bar.com.", IP: "192.0.0.2", RemoveTime: fakeClock.Now()},
+			}))
+		})
+
+		It("returns only what changed after the version", func() {
+			table.Add([]string{"foo.com"}, "192.0.0.1")
+			version := table.ChangesSince(table.Epoch(), 0).Version
+
+			table.Add([]string{"bar.com"}, "192.0.0.2")
+			table.Remove([]string{"baz.com"}, "192.0.0.3")
+
+			changes := table.ChangesSince(table.Epoch(), version)
+			Expect(changes.Full).To(BeFalse())
+			Expect(changes.Version).To(BeNumerically(">", version))
+			Expect(changes.Entries).To(Equal([]addresstable.SnapshotEntry{
+				{Hostname: "bar.com.", IP: "192.0.0.2", UpdateTime: fakeClock.Now()},
+			}))
+			Expect(changes.Removals).To(Equal([]addresstable.Removal{
+				{Hostname: "baz.com.", IP: "192.0.0.3", RemoveTime: fakeClock.Now()},
+			}))
+
+			Expect(table.ChangesSince(table.Epoch(), changes.Version).Entries).To(BeEmpty())
+		})
+
+		It("includes entries whose health changed", func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1"})
+			version := table.ChangesSince(table.Epoch(), 0).Version
+
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Unhealthy: true})
+
+			Expect(table.ChangesSince(table.Epoch(), version).Entries).To(HaveLen(1))
+		})
+
+		It("only includes unchanged registrations once half the staleness threshold has passed", func() {
+			table.Add([]string{"foo.com"}, "192.0.0.1")
+			version := table.ChangesSince(table.Epoch(), 0).Version
+
+			fakeClock.Increment(stalenessThreshold/2 - time.Millisecond)
+			table.Add([]string{"foo.com"}, "192.0.0.1")
+			Expect(table.ChangesSince(table.Epoch(), version).Entries).To(BeEmpty())
+
+			fakeClock.Increment(time.Millisecond)
+			table.Add([]string{"foo.com"}, "192.0.0.1")
+			Expect(table.ChangesSince(table.Epoch(), version).Entries).To(Equal([]addresstable.SnapshotEntry{
+				{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now()},
+			}))
+		})
+
+		It("includes entries merged from peers", func() {
+			version := table.ChangesSince(table.Epoch(), 0).Version
+
+			table.Merge([]addresstable.SnapshotEntry{{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now()}})
+
+			Expect(table.ChangesSince(table.Epoch(), version).Entries).To(HaveLen(1))
+		})
+
+		Context("when the version is from another epoch", func() {
+			It("returns everything", func() {
+				table.Add([]string{"foo.com"}, "192.0.0.1")
+				version := table.ChangesSince(table.Epoch(), 0).Version
+
+				changes := table.ChangesSince("some-other-epoch", version)
+				Expect(changes.Full).To(BeTrue())
+				Expect(changes.Entries).To(HaveLen(1))
+			})
+		})
+
+		Context("when the version is ahead of the table", func() {
+			It("returns everything", func() {
+				table.Add([]string{"foo.com"}, "192.0.0.1")
+
+				changes := table.ChangesSince(table.Epoch(), 1000)
+				Expect(changes.Full).To(BeTrue())
+				Expect(changes.Entries).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("Watch", func() {
+		var (
+			changes <-chan struct{}
+			stop    func()
+		)
+
+		BeforeEach(func() {
+			table.AddEndpoint([]string{"watched.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			changes, stop = table.Watch([]string{"watched.com", "other-watched.com."})
+		})
+
+		AfterEach(func() {
+			stop()
+		})
+
+		It("notifies when an endpoint is added", func() {
+			table.AddEndpoint([]string{"watched.com"}, addresstable.Endpoint{IP: "192.0.0.2", Port: 8080})
+			Eventually(changes).Should(Receive())
+		})
+
+		It("notifies when an endpoint moves cell", func() {
+			table.AddEndpoint([]string{"watched.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080, CellID: "cell-1"})
+			Eventually(changes).Should(Receive())
+		})
+
+		It("notifies when an endpoint is removed", func() {
+			table.RemoveEndpoint([]string{"watched.com"}, addresstable.Endpoint{IP: "192.0.0.1"})
+			Eventually(changes).Should(Receive())
+		})
+
+		It("notifies when an endpoint is pruned", func() {
+			fakeClock.Increment(stalenessThreshold + time.Second)
+			Eventually(changes).Should(Receive())
+			Expect(table.Lookup("watched.com")).To(BeEmpty())
+		})
+
+		It("notifies when an endpoint is restored or merged", func() {
+			table.Restore([]addresstable.SnapshotEntry{{Hostname: "other-watched.com.", IP: "192.0.0.3", UpdateTime: fakeClock.Now()}})
+			Eventually(changes).Should(Receive())
+
+			table.Merge([]addresstable.SnapshotEntry{{Hostname: "other-watched.com.", IP: "192.0.0.4", UpdateTime: fakeClock.Now()}})
+			Eventually(changes).Should(Receive())
+		})
+
+		It("does not notify when an endpoint is only refreshed", func() {
+			table.AddEndpoint([]string{"watched.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			Consistently(changes).ShouldNot(Receive())
+		})
+
+		It("does not notify about other hostnames", func() {
+			table.Add([]string{"unwatched.com"}, "192.0.0.5")
+			Consistently(changes).ShouldNot(Receive())
+		})
+
+		It("coalesces changes that were not received yet", func() {
+			table.Add([]string{"watched.com"}, "192.0.0.6")
+			table.Add([]string{"watched.com"}, "192.0.0.7")
+			Eventually(changes).Should(Receive())
+			Consistently(changes).ShouldNot(Receive())
+		})
+
+		Context("when the watch is stopped", func() {
+			It("does not notify", func() {
+				stop()
+				table.Add([]string{"watched.com"}, "192.0.0.8")
+				Consistently(changes).ShouldNot(Receive())
+			})
+		})
+	})
+
 	Describe("PausePruning", func() {
 		BeforeEach(func() {
 			table.Add([]string{"stale.com"}, "192.0.0.1")
@@ -185,6 +791,119 @@ var _ = Describe("AddressTable", func() {
 		})
 	})
 
+	Describe("PruneStaleEntries", func() {
+		BeforeEach(func() {
+			table.Add([]string{"stale.com"}, "192.0.0.1")
+			table.PausePruning()
+			fakeClock.Increment(stalenessThreshold + 1*time.Second)
+			table.Add([]string{"stale.com"}, "192.0.0.2")
+		})
+
+		It("prunes stale routes even while pruning is paused", func() {
+			table.PruneStaleEntries()
+			Expect(table.Lookup("stale.com")).To(Equal([]string{"192.0.0.2"}))
+		})
+
+		Context("when hostnames are in domains with their own staleness threshold", func() {
+			BeforeEach(func() {
+				table.SetDomains(domains.NewSet([]domains.Domain{
+					{Name: "long.com", StalenessThreshold: time.Minute},
+					{Name: "short.com", StalenessThreshold: 2 * time.Second},
+					{Name: "default.com"},
+				}))
+				table.Add([]string{"a.long.com", "a.short.com", "a.default.com", "other.com"}, "192.0.0.3")
+				fakeClock.Increment(3 * time.Second)
+				table.Add([]string{"a.short.com"}, "192.0.0.4")
+				fakeClock.Increment(3 * time.Second)
+			})
+
+			It("prunes each hostname with the threshold of its domain", func() {
+				table.PruneStaleEntries()
+				Expect(table.Lookup("a.long.com")).To(Equal([]string{"192.0.0.3"}))
+				Expect(table.Lookup("a.short.com")).To(BeEmpty())
+				Expect(table.Lookup("a.default.com")).To(BeEmpty())
+				Expect(table.Lookup("other.com")).To(BeEmpty())
+			})
+
+			It("counts the pruned entries of each domain", func() {
+				table.PruneStaleEntries()
+				Expect(table.TakePrunedByDomain()).To(Equal(map[string]int{
+					"short.com":   2,
+					"default.com": 1,
+					"":            3,
+				}))
+				Expect(table.TakePrunedByDomain()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("TakePruned", func() {
+		It("returns the number of entries pruned from each hostname since the last call", func() {
+			table.Add([]string{"stale.com", "other.com"}, "192.0.0.1")
+			table.Add([]string{"stale.com"}, "192.0.0.2")
+			fakeClock.Increment(stalenessThreshold + 1*time.Second)
+
+			Eventually(func() []string { return table.Lookup("stale.com") }).Should(BeEmpty())
+			Expect(table.TakePruned()).To(Equal(map[string]int{"stale.com.": 2, "other.com.": 1}))
+			Expect(table.TakePruned()).To(BeEmpty())
+		})
+	})
+
+	Describe("EntryCounts", func() {
+		It("returns the number of entries of each hostname", func() {
+			table.Add([]string{"foo.com", "bar.com"}, "192.0.0.1")
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+
+			Expect(table.EntryCounts()).To(Equal(map[string]int{"foo.com.": 2, "bar.com.": 1}))
+		})
+	})
+
+	Describe("DebugEntries", func() {
+		BeforeEach(func() {
+			table.AddEndpoint([]string{"foo.com"}, addresstable.Endpoint{IP: "192.0.0.1", Port: 8080})
+			fakeClock.Increment(2 * time.Second)
+			table.Add([]string{"bar.com"}, "192.0.0.2")
+		})
+
+		It("returns every entry with its update time and age", func() {
+			entries := table.DebugEntries("")
+			Expect(entries).To(HaveLen(2))
+			Expect(entries["foo.com."]).To(Equal([]addresstable.DebugEntry{{
+				Endpoint:   addresstable.Endpoint{IP: "192.0.0.1", Port: 8080},
+				UpdateTime: fakeClock.Now().Add(-2 * time.Second),
+				Age:        2 * time.Second,
+			}}))
+			Expect(entries["bar.com."][0].Age).To(BeZero())
+		})
+
+		It("returns only the entries of the given hostname", func() {
+			entries := table.DebugEntries("foo.com")
+			Expect(entries).To(HaveLen(1))
+			Expect(entries).To(HaveKey("foo.com."))
+		})
+
+		It("returns nothing for an unknown hostname", func() {
+			Expect(table.DebugEntries("baz.com")).To(BeEmpty())
+		})
+	})
+
+	Describe("PruningState", func() {
+		It("returns whether pruning is paused and when it was last resumed", func() {
+			table.PausePruning()
+			Expect(table.PruningState()).To(Equal(addresstable.PruningState{
+				Paused:             true,
+				StalenessThreshold: stalenessThreshold,
+				PruningInterval:    pruningInterval,
+				ResumePruningDelay: resumePruningDelay,
+			}))
+
+			table.ResumePruning()
+			state := table.PruningState()
+			Expect(state.Paused).To(BeFalse())
+			Expect(state.LastResume).To(Equal(fakeClock.Now()))
+		})
+	})
+
 	Describe("ResumePruning", func() {
 		Context("when pruning is initially paused", func() {
 			BeforeEach(func() {
@@ -203,6 +922,55 @@ var _ = Describe("AddressTable", func() {
 		})
 	})
 
+	Describe("Reconfigure", func() {
+		BeforeEach(func() {
+			table.Add([]string{"stale.com"}, "192.0.0.1")
+		})
+
+		It("prunes with the new staleness threshold", func() {
+			table.Reconfigure(time.Minute, pruningInterval)
+			fakeClock.Increment(stalenessThreshold + time.Second)
+			Consistently(func() []string { return table.Lookup("stale.com") }).Should(Equal([]string{"192.0.0.1"}))
+
+			table.Reconfigure(2*time.Second, pruningInterval)
+			fakeClock.Increment(pruningInterval)
+			Eventually(func() []string { return table.Lookup("stale.com") }).Should(BeEmpty())
+		})
+
+		It("prunes on the new interval", func() {
+			table.Reconfigure(stalenessThreshold, 10*time.Second)
+			Expect(fakeClock.WatcherCount()).To(Equal(1))
+
+			fakeClock.Increment(stalenessThreshold + time.Second)
+			Consistently(func() []string { return table.Lookup("stale.com") }).Should(Equal([]string{"192.0.0.1"}))
+
+			fakeClock.Increment(4 * time.Second)
+			Eventually(func() []string { return table.Lookup("stale.com") }).Should(BeEmpty())
+		})
+
+		It("keeps the entries and reports the new state", func() {
+			table.Reconfigure(time.Minute, 10*time.Second)
+			Expect(table.Lookup("stale.com")).To(Equal([]string{"192.0.0.1"}))
+
+			state := table.PruningState()
+			Expect(state.StalenessThreshold).To(Equal(time.Minute))
+			Expect(state.PruningInterval).To(Equal(10 * time.Second))
+		})
+
+		Context("when the table is shut down", func() {
+			It("does not block or start pruning again", func() {
+				table.Shutdown()
+				done := make(chan struct{})
+				go func() {
+					table.Reconfigure(stalenessThreshold, 10*time.Second)
+					close(done)
+				}()
+				Eventually(done).Should(BeClosed())
+				Expect(fakeClock.WatcherCount()).To(Equal(0))
+			})
+		})
+	})
+
 	Describe("Shutdown", func() {
 		It("stops pruning", func() {
 			table.Add([]string{"foo.com"}, "192.0.0.1")
@@ -281,6 +1049,38 @@ var _ = Describe("AddressTable", func() {
 			}
 			wg.Wait()
 		})
+
+		It("keeps every hostname while they are registered, looked up and pruned concurrently", func() {
+			Expect(table.SetAlias("all.apps.internal", "*.apps.internal")).To(Succeed())
+
+			var wg sync.WaitGroup
+			const nHostnames = 200
+			wg.Add(nHostnames * 2)
+			for h := 0; h < nHostnames; h++ {
+				go func(i int) {
+					defer wg.Done()
+					table.Add([]string{fmt.Sprintf("app-%d.apps.internal", i)}, fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+				}(h)
+				go func(i int) {
+					defer wg.Done()
+					switch i % 4 {
+					case 0:
+						table.Lookup(fmt.Sprintf("app-%d.apps.internal", i))
+					case 1:
+						table.Lookup("all.apps.internal")
+					case 2:
+						table.PruneStaleEntries()
+					case 3:
+						table.Snapshot()
+					}
+				}(h)
+			}
+			wg.Wait()
+
+			Expect(table.GetAllAddresses()).To(HaveLen(nHostnames))
+			Expect(table.Lookup("all.apps.internal")).To(HaveLen(nHostnames))
+			Expect(table.Lookup("app-199.apps.internal")).To(Equal([]string{"10.0.0.199"}))
+		})
 	})
 
 	Describe("Warm Concurrency", func() {
@@ -0,0 +1,149 @@
+package addresstable
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// maxAliasDepth limits how many aliases are followed to resolve a hostname.
+const maxAliasDepth = 8
+
+// SetAlias makes lookups of alias return the endpoints of target. The
+// target may be another alias, or a wildcard such as *.tenant.apps.internal.
+// that matches every hostname under tenant.apps.internal. Aliases are
+// resolved before registered endpoints, so an alias hides any endpoints
+// registered for the same hostname.
+func (at *AddressTable) SetAlias(alias, target string) error {
+	alias, target = fqdn(alias), fqdn(target)
+	if strings.Contains(alias, "*") {
+		return fmt.Errorf("alias %s: must not be a wildcard", alias)
+	}
+	if strings.Contains(target, "*") && !isWildcard(target) {
+		return fmt.Errorf("alias %s: wildcard target %s must start with *.", alias, target)
+	}
+
+	at.mutex.Lock()
+	previous, existed := at.aliases[alias]
+	at.aliases[alias] = target
+	chain, err := at.resolveChain(alias)
+	if err != nil {
+		if existed {
+			at.aliases[alias] = previous
+		} else {
+			delete(at.aliases, alias)
+		}
+		at.mutex.Unlock()
+		return fmt.Errorf("alias %s: %s", alias, err)
+	}
+	at.mutex.Unlock()
+
+	at.logger.Info("alias-set", lager.Data{"alias": alias, "target": target, "resolves-to": chain[len(chain)-1]})
+	at.notify([]string{alias})
+	return nil
+}
+
+// RemoveAlias removes the alias, and returns false when there was none.
+func (at *AddressTable) RemoveAlias(alias string) bool {
+	alias = fqdn(alias)
+
+	at.mutex.Lock()
+	_, ok := at.aliases[alias]
+	delete(at.aliases, alias)
+	at.mutex.Unlock()
+
+	if ok {
+		at.logger.Info("alias-removed", lager.Data{"alias": alias})
+		at.notify([]string{alias})
+	}
+	return ok
+}
+
+// Aliases returns every alias and its target.
+func (at *AddressTable) Aliases() map[string]string {
+	at.mutex.RLock()
+	defer at.mutex.RUnlock()
+
+	aliases := map[string]string{}
+	for alias, target := range at.aliases {
+		aliases[alias] = target
+	}
+	return aliases
+}
+
+// resolveChain returns the hostname followed by the target of each alias
+// it goes through. The last hostname is the one to look up. It must be
+// called while holding the mutex.
+func (at *AddressTable) resolveChain(hostname string) ([]string, error) {
+	chain := []string{hostname}
+	for {
+		target, ok := at.aliases[hostname]
+		if !ok {
+			return chain, nil
+		}
+		for _, seen := range chain {
+			if seen == target {
+				return nil, fmt.Errorf("alias loop through %s", target)
+			}
+		}
+		if len(chain) > maxAliasDepth {
+			return nil, fmt.Errorf("more than %d aliases to resolve", maxAliasDepth)
+		}
+		chain = append(chain, target)
+		hostname = target
+	}
+}
+
+// lookupEntries returns a copy of the entries for hostname after resolving
+// aliases, merging the entries of every matching hostname for a wildcard.
+// It must not be called while holding the mutex or a shard's mutex.
+func (at *AddressTable) lookupEntries(hostname string) []entry {
+	at.mutex.RLock()
+	chain, err := at.resolveChain(hostname)
+	at.mutex.RUnlock()
+	if err != nil {
+		return []entry{}
+	}
+
+	resolved := chain[len(chain)-1]
+	if !isWildcard(resolved) {
+		return at.shardFor(resolved).copyEntries(resolved)
+	}
+
+	matched := map[string][]entry{}
+	at.forEachShard(func(shard *shard) {
+		for candidate, entries := range shard.addresses {
+			if matchesWildcard(resolved, candidate) {
+				matched[candidate] = append([]entry{}, entries...)
+			}
+		}
+	})
+
+	matches := []string{}
+	for match := range matched {
+		matches = append(matches, match)
+	}
+	sort.Strings(matches)
+
+	merged := []entry{}
+	for _, match := range matches {
+		for _, e := range matched[match] {
+			if indexOf(merged, e.endpoint()) == -1 {
+				merged = append(merged, e)
+			}
+		}
+	}
+	return merged
+}
+
+func isWildcard(hostname string) bool {
+	return strings.HasPrefix(hostname, "*.") && !strings.Contains(hostname[2:], "*")
+}
+
+// matchesWildcard is true for hostnames with at least one label in front
+// of the wildcard's suffix.
+func matchesWildcard(wildcard, hostname string) bool {
+	return strings.HasSuffix(hostname, wildcard[1:])
+}
@@ -0,0 +1,137 @@
+package addresstable_test
+
+import (
+	"service-discovery-controller/addresstable"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Aliases", func() {
+	var table *addresstable.AddressTable
+
+	BeforeEach(func() {
+		fakeClock := fakeclock.NewFakeClock(time.Now())
+		table = addresstable.NewAddressTable(5*time.Second, time.Second, 30*time.Second, fakeClock, lagertest.NewTestLogger("test"))
+		table.AddEndpoint([]string{"postgres-blue.apps.internal."}, addresstable.Endpoint{IP: "192.0.0.1", Port: 5432})
+		table.AddEndpoint([]string{"a.tenant.apps.internal."}, addresstable.Endpoint{IP: "192.0.0.2", Port: 8080})
+		table.AddEndpoint([]string{"b.tenant.apps.internal."}, addresstable.Endpoint{IP: "192.0.0.3", Port: 8080})
+		table.AddEndpoint([]string{"c.b.tenant.apps.internal."}, addresstable.Endpoint{IP: "192.0.0.2", Port: 8080})
+		table.AddEndpoint([]string{"tenant.apps.internal."}, addresstable.Endpoint{IP: "192.0.0.4", Port: 8080})
+	})
+
+	AfterEach(func() {
+		table.Shutdown()
+	})
+
+	Describe("SetAlias", func() {
+		It("resolves the alias to its target", func() {
+			Expect(table.SetAlias("db.apps.internal", "postgres-blue.apps.internal")).To(Succeed())
+
+			Expect(table.LookupEndpoints("db.apps.internal.")).To(Equal([]addresstable.Endpoint{{IP: "192.0.0.1", Port: 5432}}))
+			Expect(table.Lookup("db.apps.internal")).To(Equal([]string{"192.0.0.1"}))
+			Expect(table.LookupFamily("db.apps.internal.", addresstable.IPv4)).To(HaveLen(1))
+			Expect(table.Aliases()).To(Equal(map[string]string{"db.apps.internal.": "postgres-blue.apps.internal."}))
+		})
+
+		It("resolves aliases before registered endpoints", func() {
+			table.Add([]string{"db.apps.internal."}, "192.0.0.9")
+			Expect(table.SetAlias("db.apps.internal.", "postgres-blue.apps.internal.")).To(Succeed())
+
+			Expect(table.Lookup("db.apps.internal.")).To(Equal([]string{"192.0.0.1"}))
+		})
+
+		It("follows aliases of aliases", func() {
+			Expect(table.SetAlias("db.apps.internal.", "postgres.apps.internal.")).To(Succeed())
+			Expect(table.SetAlias("postgres.apps.internal.", "postgres-blue.apps.internal.")).To(Succeed())
+
+			Expect(table.Lookup("db.apps.internal.")).To(Equal([]string{"192.0.0.1"}))
+		})
+
+		It("replaces the target of an existing alias", func() {
+			Expect(table.SetAlias("db.apps.internal.", "postgres-blue.apps.internal.")).To(Succeed())
+			Expect(table.SetAlias("db.apps.internal.", "a.tenant.apps.internal.")).To(Succeed())
+
+			Expect(table.Lookup("db.apps.internal.")).To(Equal([]string{"192.0.0.2"}))
+		})
+
+		It("rejects an alias loop and keeps the previous target", func() {
+			Expect(table.SetAlias("a.apps.internal.", "b.apps.internal.")).To(Succeed())
+			Expect(table.SetAlias("b.apps.internal.", "postgres-blue.apps.internal.")).To(Succeed())
+
+			err := table.SetAlias("b.apps.internal.", "a.apps.internal.")
+			Expect(err).To(MatchError("alias b.apps.internal.: alias loop through b.apps.internal."))
+			Expect(table.Aliases()).To(HaveKeyWithValue("b.apps.internal.", "postgres-blue.apps.internal."))
+		})
+
+		It("rejects a wildcard alias", func() {
+			err := table.SetAlias("*.apps.internal.", "postgres-blue.apps.internal.")
+			Expect(err).To(MatchError("alias *.apps.internal.: must not be a wildcard"))
+		})
+
+		It("rejects a target with a wildcard that is not the first label", func() {
+			err := table.SetAlias("db.apps.internal.", "db.*.apps.internal.")
+			Expect(err).To(MatchError("alias db.apps.internal.: wildcard target db.*.apps.internal. must start with *."))
+		})
+	})
+
+	Describe("RemoveAlias", func() {
+		It("stops resolving the alias", func() {
+			Expect(table.SetAlias("db.apps.internal.", "postgres-blue.apps.internal.")).To(Succeed())
+
+			Expect(table.RemoveAlias("db.apps.internal")).To(BeTrue())
+			Expect(table.Lookup("db.apps.internal.")).To(BeEmpty())
+			Expect(table.Aliases()).To(BeEmpty())
+		})
+
+		It("returns false when there is no alias", func() {
+			Expect(table.RemoveAlias("db.apps.internal.")).To(BeFalse())
+		})
+	})
+
+	Describe("wildcards", func() {
+		It("merges the endpoints of every hostname under the wildcard", func() {
+			Expect(table.LookupEndpoints("*.tenant.apps.internal.")).To(Equal([]addresstable.Endpoint{
+				{IP: "192.0.0.2", Port: 8080},
+				{IP: "192.0.0.3", Port: 8080},
+			}))
+		})
+
+		It("can be the target of an alias", func() {
+			Expect(table.SetAlias("tenant-all.apps.internal.", "*.tenant.apps.internal.")).To(Succeed())
+			Expect(table.Lookup("tenant-all.apps.internal.")).To(ConsistOf("192.0.0.2", "192.0.0.3"))
+		})
+
+		It("matches nothing when no hostname is under it", func() {
+			Expect(table.LookupEndpoints("*.other.apps.internal.")).To(BeEmpty())
+		})
+	})
+
+	Describe("watching", func() {
+		It("notifies watchers of an alias when its target changes", func() {
+			Expect(table.SetAlias("db.apps.internal.", "postgres-blue.apps.internal.")).To(Succeed())
+			changes, stop := table.Watch([]string{"db.apps.internal."})
+			defer stop()
+
+			table.Add([]string{"postgres-blue.apps.internal."}, "192.0.0.5")
+			Eventually(changes).Should(Receive())
+
+			Expect(table.SetAlias("db.apps.internal.", "a.tenant.apps.internal.")).To(Succeed())
+			Eventually(changes).Should(Receive())
+		})
+
+		It("notifies watchers of a wildcard when a matching hostname changes", func() {
+			changes, stop := table.Watch([]string{"*.tenant.apps.internal."})
+			defer stop()
+
+			table.Add([]string{"new.tenant.apps.internal."}, "192.0.0.6")
+			Eventually(changes).Should(Receive())
+
+			table.Add([]string{"tenant.apps.internal."}, "192.0.0.7")
+			Consistently(changes).ShouldNot(Receive())
+		})
+	})
+})
@@ -0,0 +1,136 @@
+package addresstable
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// versionCounter numbers the changes made to the entries and removals of a
+// table, so that peers can ask for the changes after the last one they have
+// merged. It is shared by the shards, and versions are taken while holding
+// the mutex of the shard they are stored in.
+type versionCounter struct {
+	last uint64
+}
+
+func (v *versionCounter) next() uint64 {
+	return atomic.AddUint64(&v.last, 1)
+}
+
+func (v *versionCounter) current() uint64 {
+	return atomic.LoadUint64(&v.last)
+}
+
+// newEpoch returns a random identifier for the versions of a table. Versions
+// restart with the process, so a peer's cursor is only valid for the epoch
+// it was taken in.
+func newEpoch() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+	return hex.EncodeToString(b)
+}
+
+// refreshVersionAfter is how long an entry that is registered again without
+// changing keeps its version. Peers that only fetch changes learn of
+// registrations this often, which is enough to keep the entries they missed
+// NATS messages for from going stale.
+func refreshVersionAfter(stalenessThreshold time.Duration) time.Duration {
+	return stalenessThreshold / 2
+}
+
+// Changes are the entries and removals of a table that changed after a
+// version, ordered by hostname. Version is the version they bring a peer
+// up to. Full is set when every entry and removal is included, because the
+// requested version is not one of the table's.
+type Changes struct {
+	Epoch    string
+	Version  uint64
+	Full     bool
+	Entries  []SnapshotEntry
+	Removals []Removal
+}
+
+// Epoch identifies the versions of the table. It changes when the process
+// restarts.
+func (at *AddressTable) Epoch() string {
+	return at.epoch
+}
+
+// ChangesSince returns the entries and removals that changed after version
+// since of epoch. A since of 0, a since from another epoch, or a since that
+// is not a version of the table returns every entry and removal. An entry
+// that is registered again without changing is only included once in a
+// while, see refreshVersionAfter.
+func (at *AddressTable) ChangesSince(epoch string, since uint64) Changes {
+	// The version is read before the shards are, so that changes made while
+	// they are read are returned again next time rather than missed.
+	version := at.versions.current()
+	if epoch != at.epoch || since > version {
+		since = 0
+	}
+
+	return Changes{
+		Epoch:    at.epoch,
+		Version:  version,
+		Full:     since == 0,
+		Entries:  at.entriesAfter(since),
+		Removals: at.removalsAfter(since),
+	}
+}
+
+func (at *AddressTable) entriesAfter(since uint64) []SnapshotEntry {
+	entries := []SnapshotEntry{}
+	at.forEachShard(func(shard *shard) {
+		for hostname, hostEntries := range shard.addresses {
+			for _, entry := range hostEntries {
+				if entry.version <= since {
+					continue
+				}
+				entries = append(entries, SnapshotEntry{
+					Hostname:   hostname,
+					IP:         entry.ip,
+					Port:       entry.port,
+					CellID:     entry.cellID,
+					AZ:         entry.az,
+					AppID:      entry.appID,
+					Unhealthy:  entry.unhealthy,
+					UpdateTime: entry.updateTime,
+				})
+			}
+		}
+	})
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Hostname < entries[j].Hostname
+	})
+	return entries
+}
+
+func (at *AddressTable) removalsAfter(since uint64) []Removal {
+	removals := []Removal{}
+	at.forEachShard(func(shard *shard) {
+		for hostname, hostRemovals := range shard.removals {
+			for _, r := range hostRemovals {
+				if r.version <= since {
+					continue
+				}
+				removals = append(removals, Removal{
+					Hostname:   hostname,
+					IP:         r.ip,
+					Port:       r.port,
+					RemoveTime: r.removeTime,
+				})
+			}
+		}
+	})
+
+	sort.SliceStable(removals, func(i, j int) bool {
+		return removals[i].Hostname < removals[j].Hostname
+	})
+	return removals
+}
@@ -0,0 +1,69 @@
+package addresstable
+
+import "time"
+
+// DebugEntry is an entry in the table with what decides when it is pruned:
+// its age since it was last registered, and whether it was restored from a
+// snapshot and not registered since.
+type DebugEntry struct {
+	Endpoint
+	UpdateTime time.Time
+	Age        time.Duration
+	Restored   bool
+}
+
+// PruningState is what decides whether stale entries are pruned.
+// Entries are pruned every pruning interval once they are older than the
+// staleness threshold, unless pruning is paused or was resumed less than
+// the resume delay ago.
+type PruningState struct {
+	Paused             bool
+	LastResume         time.Time
+	StalenessThreshold time.Duration
+	PruningInterval    time.Duration
+	ResumePruningDelay time.Duration
+}
+
+// DebugEntries returns the entries of each hostname, or only of hostname
+// when it is not empty. Aliases are not followed.
+func (at *AddressTable) DebugEntries(hostname string) map[string][]DebugEntry {
+	debugEntries := map[string][]DebugEntry{}
+	addEntries := func(address string, entries []entry) {
+		for _, entry := range entries {
+			debugEntries[address] = append(debugEntries[address], DebugEntry{
+				Endpoint:   entry.endpoint(),
+				UpdateTime: entry.updateTime,
+				Age:        at.clock.Since(entry.updateTime),
+				Restored:   entry.restored,
+			})
+		}
+	}
+
+	if hostname != "" {
+		address := fqdn(hostname)
+		addEntries(address, at.shardFor(address).copyEntries(address))
+		return debugEntries
+	}
+
+	at.forEachShard(func(shard *shard) {
+		for address, entries := range shard.addresses {
+			addEntries(address, entries)
+		}
+	})
+	return debugEntries
+}
+
+// PruningState returns whether stale entries are currently pruned, and
+// why not.
+func (at *AddressTable) PruningState() PruningState {
+	at.mutex.RLock()
+	defer at.mutex.RUnlock()
+
+	return PruningState{
+		Paused:             at.pausedPruning,
+		LastResume:         at.lastResume,
+		StalenessThreshold: at.stalenessThreshold,
+		PruningInterval:    at.pruningInterval,
+		ResumePruningDelay: at.resumePruningDelay,
+	}
+}
@@ -0,0 +1,48 @@
+package addresstable
+
+import (
+	"fmt"
+	"net"
+)
+
+type Family int
+
+const (
+	UnknownFamily Family = iota
+	IPv4
+	IPv6
+)
+
+func FamilyOf(ip string) Family {
+	parsed := net.ParseIP(ip)
+	switch {
+	case parsed == nil:
+		return UnknownFamily
+	case parsed.To4() != nil:
+		return IPv4
+	default:
+		return IPv6
+	}
+}
+
+func ParseFamily(family string) (Family, error) {
+	switch family {
+	case "ipv4":
+		return IPv4, nil
+	case "ipv6":
+		return IPv6, nil
+	default:
+		return UnknownFamily, fmt.Errorf("invalid address family %q, specify either ipv4 or ipv6", family)
+	}
+}
+
+func (f Family) String() string {
+	switch f {
+	case IPv4:
+		return "ipv4"
+	case IPv6:
+		return "ipv6"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,33 @@
+package addresstable_test
+
+import (
+	"service-discovery-controller/addresstable"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Family", func() {
+	DescribeTable("FamilyOf",
+		func(ip string, family addresstable.Family) {
+			Expect(addresstable.FamilyOf(ip)).To(Equal(family))
+		},
+		Entry("ipv4", "192.0.0.1", addresstable.IPv4),
+		Entry("ipv6", "fd00::1", addresstable.IPv6),
+		Entry("ipv4-mapped ipv6", "::ffff:192.0.0.1", addresstable.IPv4),
+		Entry("garbage", "not-an-ip", addresstable.UnknownFamily),
+	)
+
+	Describe("ParseFamily", func() {
+		It("parses ipv4 and ipv6", func() {
+			Expect(addresstable.ParseFamily("ipv4")).To(Equal(addresstable.IPv4))
+			Expect(addresstable.ParseFamily("ipv6")).To(Equal(addresstable.IPv6))
+		})
+
+		It("returns an error for anything else", func() {
+			_, err := addresstable.ParseFamily("ipx")
+			Expect(err).To(MatchError(`invalid address family "ipx", specify either ipv4 or ipv6`))
+		})
+	})
+})
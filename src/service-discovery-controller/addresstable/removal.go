@@ -0,0 +1,143 @@
+package addresstable
+
+import (
+	"time"
+)
+
+// Removal is an endpoint that was unregistered from a hostname, with the
+// time it was unregistered. A Removal without a port covers every port of
+// its IP. Removals are shared with peers, so that a peer that has not seen
+// the unregister yet does not add the endpoint back.
+type Removal struct {
+	Hostname   string
+	IP         string
+	Port       uint16
+	RemoveTime time.Time
+}
+
+type removal struct {
+	ip         string
+	port       uint16
+	removeTime time.Time
+	version    uint64
+}
+
+// Removals returns the endpoints unregistered within the staleness
+// threshold, ordered by hostname.
+func (at *AddressTable) Removals() []Removal {
+	return at.removalsAfter(0)
+}
+
+// MergeRemovals removes the entries that a peer has seen unregistered
+// since they were last registered, and remembers the removals so that
+// Merge does not add them back. Removals that are already stale are
+// ignored. It returns the number of entries that were removed.
+func (at *AddressTable) MergeRemovals(removals []Removal) int {
+	removed := 0
+	changed := []string{}
+	domainSet, defaultThreshold := at.stalenessThresholds()
+
+	for _, r := range removals {
+		fqHostname := fqdn(r.Hostname)
+		if at.clock.Since(r.RemoveTime) > domainSet.StalenessThreshold(fqHostname, defaultThreshold) {
+			continue
+		}
+
+		shard := at.shardFor(fqHostname)
+		shard.mutex.Lock()
+		shard.recordRemoval(fqHostname, removal{ip: r.IP, port: r.Port, removeTime: r.RemoveTime})
+		count := shard.removeEntriesBefore(fqHostname, r.IP, r.Port, r.RemoveTime)
+		shard.mutex.Unlock()
+
+		if count > 0 {
+			changed = append(changed, fqHostname)
+			removed += count
+		}
+	}
+
+	at.notify(changed)
+	return removed
+}
+
+// pruneStaleRemovals forgets removals older than the staleness threshold.
+// By then Merge ignores every entry they could apply to.
+func (at *AddressTable) pruneStaleRemovals() {
+	domainSet, defaultThreshold := at.stalenessThresholds()
+	for _, shard := range at.shards {
+		shard.mutex.RLock()
+		hasRemovals := len(shard.removals) > 0
+		shard.mutex.RUnlock()
+		if !hasRemovals {
+			continue
+		}
+
+		shard.mutex.Lock()
+		for hostname, hostRemovals := range shard.removals {
+			stalenessThreshold := domainSet.StalenessThreshold(hostname, defaultThreshold)
+			fresh := []removal{}
+			for _, r := range hostRemovals {
+				if at.clock.Since(r.removeTime) <= stalenessThreshold {
+					fresh = append(fresh, r)
+				}
+			}
+			if len(fresh) == 0 {
+				delete(shard.removals, hostname)
+			} else {
+				shard.removals[hostname] = fresh
+			}
+		}
+		shard.mutex.Unlock()
+	}
+}
+
+// recordRemoval remembers that an endpoint was removed from hostname,
+// keeping the latest time for each endpoint, with the version it was last
+// changed in. It must be called while holding the shard's mutex.
+func (s *shard) recordRemoval(hostname string, r removal) {
+	hostRemovals := s.removals[hostname]
+	for i, existing := range hostRemovals {
+		if existing.ip == r.ip && existing.port == r.port {
+			if r.removeTime.After(existing.removeTime) {
+				hostRemovals[i].removeTime = r.removeTime
+				hostRemovals[i].version = s.versions.next()
+			}
+			return
+		}
+	}
+	r.version = s.versions.next()
+	s.removals[hostname] = append(hostRemovals, r)
+}
+
+// removedSince returns whether the endpoint was removed from hostname at
+// or after updateTime. It must be called while holding the shard's mutex.
+func (s *shard) removedSince(hostname string, endpoint Endpoint, updateTime time.Time) bool {
+	for _, r := range s.removals[hostname] {
+		if r.ip == endpoint.IP && (r.port == 0 || r.port == endpoint.Port) && !updateTime.After(r.removeTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeEntriesBefore removes the entries of hostname with the IP, and the
+// port unless it is 0, that were last updated at or before removeTime. It
+// returns the number of entries removed, and must be called while holding
+// the shard's mutex.
+func (s *shard) removeEntriesBefore(hostname, ip string, port uint16, removeTime time.Time) int {
+	entries := s.entriesForHostname(hostname)
+	remaining := []entry{}
+	for _, existing := range entries {
+		if existing.ip == ip && (port == 0 || existing.port == port) && !existing.updateTime.After(removeTime) {
+			s.reverse.remove(existing.ip, hostname)
+			continue
+		}
+		remaining = append(remaining, existing)
+	}
+
+	if len(remaining) == 0 {
+		delete(s.addresses, hostname)
+	} else {
+		s.addresses[hostname] = remaining
+	}
+	return len(entries) - len(remaining)
+}
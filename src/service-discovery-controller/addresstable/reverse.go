@@ -0,0 +1,77 @@
+package addresstable
+
+import (
+	"net"
+	"sort"
+	"sync"
+)
+
+// reverseIndex maps each registered IP to the hostnames it is registered
+// for, with the number of entries of each, so that an IP registered with
+// several ports is only unindexed once all of them are gone. Its mutex is
+// always taken after a shard's.
+type reverseIndex struct {
+	mutex     sync.RWMutex
+	hostnames map[string]map[string]int
+}
+
+func newReverseIndex() *reverseIndex {
+	return &reverseIndex{hostnames: map[string]map[string]int{}}
+}
+
+func (r *reverseIndex) add(ip, hostname string) {
+	ip = canonicalIP(ip)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	hostnames, ok := r.hostnames[ip]
+	if !ok {
+		hostnames = map[string]int{}
+		r.hostnames[ip] = hostnames
+	}
+	hostnames[hostname]++
+}
+
+func (r *reverseIndex) remove(ip, hostname string) {
+	ip = canonicalIP(ip)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	hostnames, ok := r.hostnames[ip]
+	if !ok {
+		return
+	}
+	hostnames[hostname]--
+	if hostnames[hostname] <= 0 {
+		delete(hostnames, hostname)
+	}
+	if len(hostnames) == 0 {
+		delete(r.hostnames, ip)
+	}
+}
+
+// lookup returns the hostnames that ip is registered for, sorted.
+func (r *reverseIndex) lookup(ip string) []string {
+	ip = canonicalIP(ip)
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	hostnames := make([]string, 0, len(r.hostnames[ip]))
+	for hostname := range r.hostnames[ip] {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// canonicalIP returns ip in its canonical form, so that IPv6 addresses
+// written differently are indexed together.
+func canonicalIP(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		return parsed.String()
+	}
+	return ip
+}
@@ -0,0 +1,150 @@
+package addresstable
+
+import (
+	"sync"
+	"time"
+)
+
+// shardCount is the number of shards that the entries of the table are
+// spread over by hostname. Registrations and pruning only lock the shards
+// of the hostnames they change, so that lookups of other hostnames do not
+// queue behind them.
+const shardCount = 64
+
+// shard holds the entries of the hostnames that hash to it, the endpoints
+// recently removed from them, and how many of them were pruned, by hostname
+// and by domain, since the last call to TakePruned and TakePrunedByDomain.
+// Entries are added to and removed from the reverse index shared by all
+// shards as they are added and removed, and are numbered by the version
+// counter shared by all shards as they change.
+type shard struct {
+	mutex          sync.RWMutex
+	addresses      map[string][]entry
+	removals       map[string][]removal
+	pruned         map[string]int
+	prunedByDomain map[string]int
+	reverse        *reverseIndex
+	versions       *versionCounter
+}
+
+func newShards(reverse *reverseIndex, versions *versionCounter) []*shard {
+	shards := make([]*shard, shardCount)
+	for i := range shards {
+		shards[i] = &shard{
+			addresses:      map[string][]entry{},
+			removals:       map[string][]removal{},
+			pruned:         map[string]int{},
+			prunedByDomain: map[string]int{},
+			reverse:        reverse,
+			versions:       versions,
+		}
+	}
+	return shards
+}
+
+// shardFor returns the shard of a fully qualified hostname, by its FNV-1a
+// hash.
+func (at *AddressTable) shardFor(hostname string) *shard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(hostname); i++ {
+		hash ^= uint32(hostname[i])
+		hash *= 16777619
+	}
+	return at.shards[hash%uint32(len(at.shards))]
+}
+
+// entriesForHostname returns the entries of hostname. It must be called
+// while holding the shard's mutex, and the entries must not be used after
+// it is released, as they are updated in place.
+func (s *shard) entriesForHostname(hostname string) []entry {
+	if existing, ok := s.addresses[hostname]; ok {
+		return existing
+	} else {
+		return []entry{}
+	}
+}
+
+// copyEntries returns a copy of the entries of hostname that can be used
+// after the shard's mutex is released.
+func (s *shard) copyEntries(hostname string) []entry {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entries := s.addresses[hostname]
+	copied := make([]entry, len(entries))
+	copy(copied, entries)
+	return copied
+}
+
+// addEndpoint adds or refreshes the endpoint for hostname, and returns
+// whether its endpoints changed. A refresh that changes nothing only takes
+// a new version once the entry has kept its version for refreshAfter. It
+// must be called while holding the shard's mutex.
+func (s *shard) addEndpoint(hostname string, endpoint Endpoint, now time.Time, refreshAfter time.Duration) bool {
+	entries := s.entriesForHostname(hostname)
+	entryIndex := indexOf(entries, endpoint)
+	if entryIndex == -1 {
+		s.insert(hostname, entry{
+			ip:         endpoint.IP,
+			port:       endpoint.Port,
+			cellID:     endpoint.CellID,
+			az:         endpoint.AZ,
+			appID:      endpoint.AppID,
+			unhealthy:  endpoint.Unhealthy,
+			family:     FamilyOf(endpoint.IP),
+			updateTime: now,
+		}, now)
+		return true
+	}
+
+	changed := entries[entryIndex].endpoint() != endpoint
+	entries[entryIndex].cellID = endpoint.CellID
+	entries[entryIndex].az = endpoint.AZ
+	entries[entryIndex].appID = endpoint.AppID
+	entries[entryIndex].unhealthy = endpoint.Unhealthy
+	entries[entryIndex].updateTime = now
+	entries[entryIndex].restored = false
+	s.refreshVersion(&entries[entryIndex], changed, now, refreshAfter)
+	return changed
+}
+
+// refreshVersion gives an entry that was updated a new version when it
+// changed, or when it has kept its version for refreshAfter. It must be
+// called while holding the shard's mutex.
+func (s *shard) refreshVersion(e *entry, changed bool, now time.Time, refreshAfter time.Duration) {
+	if changed || now.Sub(e.versionTime) >= refreshAfter {
+		e.version = s.versions.next()
+		e.versionTime = now
+	}
+}
+
+// removeEndpoint removes the endpoint from hostname, and returns whether
+// its endpoints changed. It must be called while holding the shard's
+// mutex.
+func (s *shard) removeEndpoint(hostname string, endpoint Endpoint) bool {
+	entries := s.entriesForHostname(hostname)
+	remaining := []entry{}
+	for _, existing := range entries {
+		if existing.ip == endpoint.IP && (endpoint.Port == 0 || existing.port == endpoint.Port) {
+			s.reverse.remove(existing.ip, hostname)
+			continue
+		}
+		remaining = append(remaining, existing)
+	}
+
+	if len(remaining) == 0 {
+		delete(s.addresses, hostname)
+	} else {
+		s.addresses[hostname] = remaining
+	}
+	return len(remaining) != len(entries)
+}
+
+// insert adds a new entry for hostname, with a new version. It must be
+// called while holding the shard's mutex.
+func (s *shard) insert(hostname string, e entry, now time.Time) {
+	e.version = s.versions.next()
+	e.versionTime = now
+	s.addresses[hostname] = append(s.entriesForHostname(hostname), e)
+	s.reverse.add(e.ip, hostname)
+}
@@ -0,0 +1,135 @@
+package addresstable
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// SnapshotEntry is an endpoint registered for a hostname, with the time it
+// was last registered.
+type SnapshotEntry struct {
+	Hostname   string
+	IP         string
+	Port       uint16
+	CellID     string
+	AZ         string
+	AppID      string
+	Unhealthy  bool
+	UpdateTime time.Time
+}
+
+// Snapshot returns every entry in the table, ordered by hostname.
+func (at *AddressTable) Snapshot() []SnapshotEntry {
+	return at.entriesAfter(0)
+}
+
+// Restore adds entries from a snapshot that are not already in the table,
+// keeping their update times. Restored entries are not pruned until SetWarm
+// is called, so that they can be served while NATS repopulates the table.
+// The table reports itself as warm straight away only when the snapshot is
+// recent, i.e. when one of its entries is within the staleness threshold.
+// An older snapshot is kept until then but not served early.
+func (at *AddressTable) Restore(entries []SnapshotEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	domainSet, defaultThreshold := at.stalenessThresholds()
+	recent := false
+	changed := []string{}
+	for _, snapshotEntry := range entries {
+		fqHostname := fqdn(snapshotEntry.Hostname)
+		if at.clock.Since(snapshotEntry.UpdateTime) <= domainSet.StalenessThreshold(fqHostname, defaultThreshold) {
+			recent = true
+		}
+
+		shard := at.shardFor(fqHostname)
+		shard.mutex.Lock()
+		existing := shard.entriesForHostname(fqHostname)
+		endpoint := Endpoint{IP: snapshotEntry.IP, Port: snapshotEntry.Port}
+		if indexOf(existing, endpoint) != -1 || shard.removedSince(fqHostname, endpoint, snapshotEntry.UpdateTime) {
+			shard.mutex.Unlock()
+			continue
+		}
+		changed = append(changed, fqHostname)
+		shard.insert(fqHostname, entry{
+			ip:         snapshotEntry.IP,
+			port:       snapshotEntry.Port,
+			cellID:     snapshotEntry.CellID,
+			az:         snapshotEntry.AZ,
+			appID:      snapshotEntry.AppID,
+			unhealthy:  snapshotEntry.Unhealthy,
+			family:     FamilyOf(snapshotEntry.IP),
+			updateTime: snapshotEntry.UpdateTime,
+			restored:   true,
+		}, at.clock.Now())
+		shard.mutex.Unlock()
+	}
+
+	if recent {
+		at.warmMutex.Lock()
+		at.restored = true
+		at.warmMutex.Unlock()
+	}
+
+	at.notify(changed)
+
+	at.logger.Info("restored-snapshot", lager.Data{"entries": len(entries), "warm": recent})
+}
+
+// Merge adds entries learned from a peer that are missing from the table,
+// and refreshes the update time and health of entries the peer has seen
+// registered more recently. Entries that are already stale, or that were
+// removed since the peer saw them registered, are ignored. It returns the
+// number of entries that were added or refreshed.
+func (at *AddressTable) Merge(entries []SnapshotEntry) int {
+	merged := 0
+	changed := []string{}
+	domainSet, defaultThreshold := at.stalenessThresholds()
+
+	for _, snapshotEntry := range entries {
+		fqHostname := fqdn(snapshotEntry.Hostname)
+		stalenessThreshold := domainSet.StalenessThreshold(fqHostname, defaultThreshold)
+		if at.clock.Since(snapshotEntry.UpdateTime) > stalenessThreshold {
+			continue
+		}
+
+		shard := at.shardFor(fqHostname)
+		shard.mutex.Lock()
+		existing := shard.entriesForHostname(fqHostname)
+		endpoint := Endpoint{IP: snapshotEntry.IP, Port: snapshotEntry.Port}
+		if shard.removedSince(fqHostname, endpoint, snapshotEntry.UpdateTime) {
+			shard.mutex.Unlock()
+			continue
+		}
+		entryIndex := indexOf(existing, endpoint)
+		if entryIndex == -1 {
+			shard.insert(fqHostname, entry{
+				ip:         snapshotEntry.IP,
+				port:       snapshotEntry.Port,
+				cellID:     snapshotEntry.CellID,
+				az:         snapshotEntry.AZ,
+				appID:      snapshotEntry.AppID,
+				unhealthy:  snapshotEntry.Unhealthy,
+				family:     FamilyOf(snapshotEntry.IP),
+				updateTime: snapshotEntry.UpdateTime,
+			}, at.clock.Now())
+			changed = append(changed, fqHostname)
+			merged++
+		} else if snapshotEntry.UpdateTime.After(existing[entryIndex].updateTime) {
+			healthChanged := existing[entryIndex].unhealthy != snapshotEntry.Unhealthy
+			if healthChanged {
+				changed = append(changed, fqHostname)
+			}
+			existing[entryIndex].updateTime = snapshotEntry.UpdateTime
+			existing[entryIndex].unhealthy = snapshotEntry.Unhealthy
+			shard.refreshVersion(&existing[entryIndex], healthChanged, at.clock.Now(), refreshVersionAfter(stalenessThreshold))
+			merged++
+		}
+		shard.mutex.Unlock()
+	}
+
+	at.notify(changed)
+	return merged
+}
@@ -0,0 +1,78 @@
+package addresstable
+
+type watcher struct {
+	hostnames map[string]bool
+	changes   chan struct{}
+}
+
+// Watch returns a channel that receives a value whenever the endpoints of
+// any of the hostnames change, and a function that stops the watch. Changes
+// made before the channel is read are coalesced into a single value, so the
+// watcher should look up every hostname it watches when it receives one.
+func (at *AddressTable) Watch(hostnames []string) (<-chan struct{}, func()) {
+	w := &watcher{
+		hostnames: map[string]bool{},
+		changes:   make(chan struct{}, 1),
+	}
+	for _, hostname := range hostnames {
+		w.hostnames[fqdn(hostname)] = true
+	}
+
+	at.watchMutex.Lock()
+	at.watchers[w] = struct{}{}
+	at.watchMutex.Unlock()
+
+	stop := func() {
+		at.watchMutex.Lock()
+		delete(at.watchers, w)
+		at.watchMutex.Unlock()
+	}
+	return w.changes, stop
+}
+
+// notify tells the watchers whose hostnames resolve through any of the
+// changed hostnames that their endpoints changed. It must not be called
+// while holding the table's mutex.
+func (at *AddressTable) notify(changed []string) {
+	if len(changed) == 0 {
+		return
+	}
+
+	at.mutex.RLock()
+	defer at.mutex.RUnlock()
+	at.watchMutex.Lock()
+	defer at.watchMutex.Unlock()
+
+	for w := range at.watchers {
+		if at.affects(w, changed) {
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// affects is true when a change to any of the hostnames changes the lookup
+// of a hostname the watcher watches, directly, through an alias or through
+// a wildcard.
+func (at *AddressTable) affects(w *watcher, changed []string) bool {
+	for watched := range w.hostnames {
+		chain, err := at.resolveChain(watched)
+		if err != nil {
+			continue
+		}
+		resolved := chain[len(chain)-1]
+		for _, hostname := range changed {
+			if isWildcard(resolved) && matchesWildcard(resolved, hostname) {
+				return true
+			}
+			for _, link := range chain {
+				if link == hostname {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
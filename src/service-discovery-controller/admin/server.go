@@ -0,0 +1,394 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"service-discovery-controller/addresstable"
+	"service-discovery-controller/certstore"
+	"service-discovery-controller/config"
+	"service-discovery-controller/mbus"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o fakes/alias_table.go --fake-name AliasTable . AliasTable
+type AliasTable interface {
+	SetAlias(alias, target string) error
+	RemoveAlias(alias string) bool
+	Aliases() map[string]string
+}
+
+//go:generate counterfeiter -o fakes/debug_table.go --fake-name DebugTable . DebugTable
+type DebugTable interface {
+	DebugEntries(hostname string) map[string][]addresstable.DebugEntry
+	PruningState() addresstable.PruningState
+	PruneStaleEntries()
+	IsWarm() bool
+	SetWarm()
+	AddEndpoint(hostnames []string, endpoint addresstable.Endpoint)
+	RemoveEndpoint(hostnames []string, endpoint addresstable.Endpoint)
+}
+
+//go:generate counterfeiter -o fakes/nats_status_reporter.go --fake-name NatsStatusReporter . NatsStatusReporter
+type NatsStatusReporter interface {
+	NatsStatus() mbus.NatsStatus
+}
+
+// Server serves the admin API, which lets operators change the address
+// table at runtime, and inspect it when a route misbehaves. It listens
+// separately from the routes server, and only accepts clients with a
+// certificate signed by the admin CA.
+type Server struct {
+	aliasTable AliasTable
+	debugTable DebugTable
+	nats       NatsStatusReporter
+	config     *config.Config
+	certs      *certstore.Store
+	logger     lager.Logger
+}
+
+type aliasesResponse struct {
+	Aliases map[string]string `json:"aliases"`
+}
+
+type aliasRequest struct {
+	Target string `json:"target"`
+}
+
+type debugEndpoint struct {
+	IP        string `json:"ip"`
+	Port      uint16 `json:"port,omitempty"`
+	CellID    string `json:"cell_id,omitempty"`
+	AZ        string `json:"availability_zone,omitempty"`
+	AppID     string `json:"app_id,omitempty"`
+	Unhealthy bool   `json:"unhealthy,omitempty"`
+}
+
+type debugEntry struct {
+	debugEndpoint
+	UpdateTime time.Time `json:"update_time"`
+	AgeSeconds float64   `json:"age_seconds"`
+	Restored   bool      `json:"restored"`
+}
+
+type entriesResponse struct {
+	Entries map[string][]debugEntry `json:"entries"`
+}
+
+type stateResponse struct {
+	Pruning pruningState `json:"pruning"`
+	Warm    bool         `json:"warm"`
+	Nats    natsState    `json:"nats"`
+}
+
+type pruningState struct {
+	Paused                    bool       `json:"paused"`
+	LastResume                *time.Time `json:"last_resume,omitempty"`
+	StalenessThresholdSeconds float64    `json:"staleness_threshold_seconds"`
+	PruningIntervalSeconds    float64    `json:"pruning_interval_seconds"`
+	ResumePruningDelaySeconds float64    `json:"resume_pruning_delay_seconds"`
+}
+
+type natsState struct {
+	Connected bool   `json:"connected"`
+	Server    string `json:"server,omitempty"`
+}
+
+func NewServer(aliasTable AliasTable, debugTable DebugTable, nats NatsStatusReporter, config *config.Config, logger lager.Logger) *Server {
+	return &Server{
+		aliasTable: aliasTable,
+		debugTable: debugTable,
+		nats:       nats,
+		config:     config,
+		certs:      &certstore.Store{},
+		logger:     logger,
+	}
+}
+
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/aliases", s.handleAliasesRequest)
+	mux.HandleFunc("/v1/aliases/", s.handleAliasRequest)
+	mux.HandleFunc("/v1/debug/entries", s.handleEntriesRequest)
+	mux.HandleFunc("/v1/debug/entries/", s.handleEntryRequest)
+	mux.HandleFunc("/v1/debug/state", s.handleStateRequest)
+	mux.HandleFunc("/v1/debug/prune", s.handlePruneRequest)
+	mux.HandleFunc("/v1/debug/warm", s.handleWarmRequest)
+
+	err := s.certs.Load(s.config.AdminServerCert, s.config.AdminServerKey, s.config.AdminCACert)
+	if err != nil {
+		return fmt.Errorf("load admin certificates: %s", err)
+	}
+
+	httpServer := &http.Server{
+		Addr:      fmt.Sprintf("%s:%d", s.config.AdminAddress, s.config.AdminPort),
+		Handler:   mux,
+		TLSConfig: s.certs.ServerConfig(),
+	}
+
+	exited := make(chan error)
+	go func() {
+		exited <- httpServer.ListenAndServeTLS("", "")
+	}()
+
+	time.Sleep(time.Microsecond)
+	close(ready)
+	s.logger.Info("server-started")
+
+	select {
+	case err := <-exited:
+		httpServer.Close()
+		s.logger.Info("server-exited", lager.Data{"error": fmt.Sprintf("%v", err)})
+		return err
+	case signal := <-signals:
+		httpServer.Close()
+		s.logger.Info("server-exited", lager.Data{"signal": signal.String()})
+		return nil
+	}
+}
+
+// ReloadCertificates loads the admin server certificate, key and CA of conf,
+// and answers new connections with them. Established connections are kept.
+func (s *Server) ReloadCertificates(conf *config.Config) error {
+	err := s.certs.Load(conf.AdminServerCert, conf.AdminServerKey, conf.AdminCACert)
+	if err != nil {
+		return fmt.Errorf("load admin certificates: %s", err)
+	}
+	return nil
+}
+
+func (s *Server) handleAliasesRequest(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.writeJSON(resp, aliasesResponse{Aliases: s.aliasTable.Aliases()})
+}
+
+// handleAliasRequest sets an alias on PUT, with the target in the body, and
+// removes it on DELETE.
+func (s *Server) handleAliasRequest(resp http.ResponseWriter, req *http.Request) {
+	alias := strings.TrimPrefix(req.URL.Path, "/v1/aliases/")
+	if alias == "" {
+		http.Error(resp, "missing alias", http.StatusBadRequest)
+		return
+	}
+
+	logger := s.logger.WithData(lager.Data{"alias": alias, "client": clientName(req)})
+
+	switch req.Method {
+	case http.MethodPut:
+		var body aliasRequest
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			http.Error(resp, fmt.Sprintf("invalid request body: %s", err), http.StatusBadRequest)
+			return
+		}
+		if body.Target == "" {
+			http.Error(resp, "missing target", http.StatusBadRequest)
+			return
+		}
+
+		err = s.aliasTable.SetAlias(alias, body.Target)
+		if err != nil {
+			http.Error(resp, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		logger.Info("set-alias", lager.Data{"target": body.Target})
+		resp.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		if !s.aliasTable.RemoveAlias(alias) {
+			http.Error(resp, "alias not found", http.StatusNotFound)
+			return
+		}
+		logger.Info("removed-alias")
+		resp.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleEntriesRequest(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.writeJSON(resp, entriesResponse{Entries: toDebugEntries(s.debugTable.DebugEntries(""))})
+}
+
+// handleEntryRequest returns the entries of a hostname on GET. During
+// incidents, it also lets operators inject an entry on PUT, with the
+// endpoint in the body, and remove one on DELETE, with the ip and an
+// optional port in the query. Injected entries are pruned like registered
+// ones once they go stale.
+func (s *Server) handleEntryRequest(resp http.ResponseWriter, req *http.Request) {
+	hostname := strings.TrimPrefix(req.URL.Path, "/v1/debug/entries/")
+	if hostname == "" {
+		http.Error(resp, "missing hostname", http.StatusBadRequest)
+		return
+	}
+
+	logger := s.logger.WithData(lager.Data{"hostname": hostname, "client": clientName(req)})
+
+	switch req.Method {
+	case http.MethodGet:
+		entries := s.debugTable.DebugEntries(hostname)
+		if len(entries) == 0 {
+			http.Error(resp, "hostname not found", http.StatusNotFound)
+			return
+		}
+		s.writeJSON(resp, entriesResponse{Entries: toDebugEntries(entries)})
+	case http.MethodPut:
+		var body debugEndpoint
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			http.Error(resp, fmt.Sprintf("invalid request body: %s", err), http.StatusBadRequest)
+			return
+		}
+		if net.ParseIP(body.IP) == nil {
+			http.Error(resp, "invalid ip", http.StatusBadRequest)
+			return
+		}
+
+		endpoint := addresstable.Endpoint{
+			IP:        body.IP,
+			Port:      body.Port,
+			CellID:    body.CellID,
+			AZ:        body.AZ,
+			AppID:     body.AppID,
+			Unhealthy: body.Unhealthy,
+		}
+		s.debugTable.AddEndpoint([]string{hostname}, endpoint)
+		logger.Info("injected-entry", lager.Data{"ip": endpoint.IP, "port": endpoint.Port})
+		resp.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		ip := req.URL.Query().Get("ip")
+		if net.ParseIP(ip) == nil {
+			http.Error(resp, "invalid ip", http.StatusBadRequest)
+			return
+		}
+
+		var port uint64
+		if portParam := req.URL.Query().Get("port"); portParam != "" {
+			var err error
+			port, err = strconv.ParseUint(portParam, 10, 16)
+			if err != nil {
+				http.Error(resp, "invalid port", http.StatusBadRequest)
+				return
+			}
+		}
+
+		s.debugTable.RemoveEndpoint([]string{hostname}, addresstable.Endpoint{IP: ip, Port: uint16(port)})
+		logger.Info("removed-entry", lager.Data{"ip": ip, "port": port})
+		resp.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleStateRequest(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pruning := s.debugTable.PruningState()
+	state := stateResponse{
+		Pruning: pruningState{
+			Paused:                    pruning.Paused,
+			StalenessThresholdSeconds: pruning.StalenessThreshold.Seconds(),
+			PruningIntervalSeconds:    pruning.PruningInterval.Seconds(),
+			ResumePruningDelaySeconds: pruning.ResumePruningDelay.Seconds(),
+		},
+		Warm: s.debugTable.IsWarm(),
+	}
+	if !pruning.LastResume.IsZero() {
+		state.Pruning.LastResume = &pruning.LastResume
+	}
+
+	natsStatus := s.nats.NatsStatus()
+	state.Nats = natsState{Connected: natsStatus.Connected, Server: natsStatus.Server}
+
+	s.writeJSON(resp, state)
+}
+
+// handlePruneRequest prunes stale entries straight away, even while pruning
+// is paused.
+func (s *Server) handlePruneRequest(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.debugTable.PruneStaleEntries()
+	s.logger.Info("forced-prune", lager.Data{"client": clientName(req)})
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// handleWarmRequest marks the table warm, so that it is served and restored
+// entries are pruned without waiting for the warm duration.
+func (s *Server) handleWarmRequest(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.debugTable.SetWarm()
+	s.logger.Info("forced-warm", lager.Data{"client": clientName(req)})
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func toDebugEntries(entries map[string][]addresstable.DebugEntry) map[string][]debugEntry {
+	converted := map[string][]debugEntry{}
+	for hostname, hostEntries := range entries {
+		for _, entry := range hostEntries {
+			converted[hostname] = append(converted[hostname], debugEntry{
+				debugEndpoint: debugEndpoint{
+					IP:        entry.IP,
+					Port:      entry.Port,
+					CellID:    entry.CellID,
+					AZ:        entry.AZ,
+					AppID:     entry.AppID,
+					Unhealthy: entry.Unhealthy,
+				},
+				UpdateTime: entry.UpdateTime,
+				AgeSeconds: entry.Age.Seconds(),
+				Restored:   entry.Restored,
+			})
+		}
+	}
+	return converted
+}
+
+func (s *Server) writeJSON(resp http.ResponseWriter, body interface{}) {
+	json, err := json.Marshal(body)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set("Content-Type", "application/json")
+	_, err = resp.Write(json)
+	if err != nil {
+		s.logger.Debug("Error writing to http response body")
+	}
+}
+
+// clientName identifies the operator in the logs by the common name of
+// their certificate.
+func clientName(req *http.Request) string {
+	if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	return req.TLS.PeerCertificates[0].Subject.CommonName
+}
@@ -0,0 +1,344 @@
+package admin_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"service-discovery-controller/addresstable"
+	"service-discovery-controller/admin"
+	"service-discovery-controller/admin/fakes"
+	"service-discovery-controller/config"
+	"service-discovery-controller/mbus"
+	"strings"
+	"test-helpers"
+	"time"
+
+	"code.cloudfoundry.org/cf-networking-helpers/testsupport/ports"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		aliasTable *fakes.AliasTable
+		debugTable *fakes.DebugTable
+		nats       *fakes.NatsStatusReporter
+		server     *admin.Server
+		caFile     string
+		serverCert string
+		serverKey  string
+		clientCert tls.Certificate
+		serverProc ifrit.Process
+		testLogger *lagertest.TestLogger
+		client     *http.Client
+		port       int
+	)
+
+	BeforeEach(func() {
+		caFile, serverCert, serverKey, clientCert = testhelpers.GenerateCaAndMutualTlsCerts()
+		port = ports.PickAPort()
+		testLogger = lagertest.NewTestLogger("test")
+		aliasTable = &fakes.AliasTable{}
+		debugTable = &fakes.DebugTable{}
+		nats = &fakes.NatsStatusReporter{}
+
+		server = admin.NewServer(aliasTable, debugTable, nats, &config.Config{
+			AdminAddress:    "127.0.0.1",
+			AdminPort:       port,
+			AdminServerCert: serverCert,
+			AdminServerKey:  serverKey,
+			AdminCACert:     caFile,
+		}, testLogger)
+		serverProc = ifrit.Invoke(server)
+		client = testhelpers.NewClient(testhelpers.CertPool(caFile), clientCert)
+	})
+
+	AfterEach(func() {
+		serverProc.Signal(os.Interrupt)
+		Eventually(serverProc.Wait()).Should(Receive())
+		os.Remove(caFile)
+		os.Remove(serverCert)
+		os.Remove(serverKey)
+	})
+
+	do := func(method, path, body string) *http.Response {
+		var resp *http.Response
+		Eventually(func() error {
+			req, err := http.NewRequest(method, fmt.Sprintf("https://127.0.0.1:%d%s", port, path), strings.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			resp, err = client.Do(req)
+			return err
+		}).Should(Succeed())
+		return resp
+	}
+
+	Describe("GET /v1/aliases", func() {
+		It("returns every alias", func() {
+			aliasTable.AliasesReturns(map[string]string{"db.apps.internal.": "postgres-blue.apps.internal."})
+
+			resp := do("GET", "/v1/aliases", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(body).To(MatchJSON(`{"aliases": {"db.apps.internal.": "postgres-blue.apps.internal."}}`))
+		})
+	})
+
+	Describe("PUT /v1/aliases/<alias>", func() {
+		It("sets the alias", func() {
+			resp := do("PUT", "/v1/aliases/db.apps.internal.", `{"target": "postgres-blue.apps.internal."}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			Expect(aliasTable.SetAliasCallCount()).To(Equal(1))
+			alias, target := aliasTable.SetAliasArgsForCall(0)
+			Expect(alias).To(Equal("db.apps.internal."))
+			Expect(target).To(Equal("postgres-blue.apps.internal."))
+			Expect(testLogger.LogMessages()).To(ContainElement("test.set-alias"))
+		})
+
+		It("rejects a missing target", func() {
+			resp := do("PUT", "/v1/aliases/db.apps.internal.", `{}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(aliasTable.SetAliasCallCount()).To(Equal(0))
+		})
+
+		Context("when the alias is invalid", func() {
+			It("returns the error", func() {
+				aliasTable.SetAliasReturns(errors.New("alias db.apps.internal.: alias loop through db.apps.internal."))
+
+				resp := do("PUT", "/v1/aliases/db.apps.internal.", `{"target": "db.apps.internal."}`)
+				Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+				body, err := ioutil.ReadAll(resp.Body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(body)).To(ContainSubstring("alias loop"))
+			})
+		})
+	})
+
+	Describe("DELETE /v1/aliases/<alias>", func() {
+		It("removes the alias", func() {
+			aliasTable.RemoveAliasReturns(true)
+
+			resp := do("DELETE", "/v1/aliases/db.apps.internal.", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+			Expect(aliasTable.RemoveAliasArgsForCall(0)).To(Equal("db.apps.internal."))
+		})
+
+		It("returns not found when there is no alias", func() {
+			resp := do("DELETE", "/v1/aliases/db.apps.internal.", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	readBody := func(resp *http.Response) []byte {
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return body
+	}
+
+	Describe("GET /v1/debug/entries", func() {
+		It("returns every entry with its update time and age", func() {
+			updateTime := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+			debugTable.DebugEntriesReturns(map[string][]addresstable.DebugEntry{
+				"app-id.apps.internal.": {{
+					Endpoint:   addresstable.Endpoint{IP: "192.168.0.1", Port: 8080, CellID: "cell-1"},
+					UpdateTime: updateTime,
+					Age:        90 * time.Second,
+				}},
+			})
+
+			resp := do("GET", "/v1/debug/entries", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(readBody(resp)).To(MatchJSON(`{
+				"entries": {
+					"app-id.apps.internal.": [{
+						"ip": "192.168.0.1",
+						"port": 8080,
+						"cell_id": "cell-1",
+						"update_time": "2018-01-01T00:00:00Z",
+						"age_seconds": 90,
+						"restored": false
+					}]
+				}
+			}`))
+			Expect(debugTable.DebugEntriesArgsForCall(0)).To(BeEmpty())
+		})
+	})
+
+	Describe("GET /v1/debug/entries/<hostname>", func() {
+		It("returns the entries of the hostname", func() {
+			debugTable.DebugEntriesReturns(map[string][]addresstable.DebugEntry{
+				"app-id.apps.internal.": {{Endpoint: addresstable.Endpoint{IP: "192.168.0.1"}}},
+			})
+
+			resp := do("GET", "/v1/debug/entries/app-id.apps.internal.", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(debugTable.DebugEntriesArgsForCall(0)).To(Equal("app-id.apps.internal."))
+		})
+
+		It("returns not found when the hostname has no entries", func() {
+			resp := do("GET", "/v1/debug/entries/app-id.apps.internal.", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("PUT /v1/debug/entries/<hostname>", func() {
+		It("injects the entry", func() {
+			resp := do("PUT", "/v1/debug/entries/app-id.apps.internal.", `{"ip": "192.168.0.1", "port": 8080, "availability_zone": "z1"}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			Expect(debugTable.AddEndpointCallCount()).To(Equal(1))
+			hostnames, endpoint := debugTable.AddEndpointArgsForCall(0)
+			Expect(hostnames).To(Equal([]string{"app-id.apps.internal."}))
+			Expect(endpoint).To(Equal(addresstable.Endpoint{IP: "192.168.0.1", Port: 8080, AZ: "z1"}))
+			Expect(testLogger.LogMessages()).To(ContainElement("test.injected-entry"))
+		})
+
+		It("rejects an invalid ip", func() {
+			resp := do("PUT", "/v1/debug/entries/app-id.apps.internal.", `{"ip": "potato"}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(debugTable.AddEndpointCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("DELETE /v1/debug/entries/<hostname>", func() {
+		It("removes the entry", func() {
+			resp := do("DELETE", "/v1/debug/entries/app-id.apps.internal.?ip=192.168.0.1&port=8080", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			hostnames, endpoint := debugTable.RemoveEndpointArgsForCall(0)
+			Expect(hostnames).To(Equal([]string{"app-id.apps.internal."}))
+			Expect(endpoint).To(Equal(addresstable.Endpoint{IP: "192.168.0.1", Port: 8080}))
+			Expect(testLogger.LogMessages()).To(ContainElement("test.removed-entry"))
+		})
+
+		It("rejects an invalid port", func() {
+			resp := do("DELETE", "/v1/debug/entries/app-id.apps.internal.?ip=192.168.0.1&port=99999", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(debugTable.RemoveEndpointCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("GET /v1/debug/state", func() {
+		It("returns the pruning, warm and NATS state", func() {
+			debugTable.PruningStateReturns(addresstable.PruningState{
+				Paused:             true,
+				LastResume:         time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+				StalenessThreshold: 180 * time.Second,
+				PruningInterval:    3 * time.Second,
+				ResumePruningDelay: 60 * time.Second,
+			})
+			debugTable.IsWarmReturns(true)
+			nats.NatsStatusReturns(mbus.NatsStatus{Connected: true, Server: "nats://10.0.0.1:4222"})
+
+			resp := do("GET", "/v1/debug/state", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(readBody(resp)).To(MatchJSON(`{
+				"pruning": {
+					"paused": true,
+					"last_resume": "2018-01-01T00:00:00Z",
+					"staleness_threshold_seconds": 180,
+					"pruning_interval_seconds": 3,
+					"resume_pruning_delay_seconds": 60
+				},
+				"warm": true,
+				"nats": {"connected": true, "server": "nats://10.0.0.1:4222"}
+			}`))
+		})
+	})
+
+	Describe("POST /v1/debug/prune", func() {
+		It("prunes stale entries", func() {
+			resp := do("POST", "/v1/debug/prune", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+			Expect(debugTable.PruneStaleEntriesCallCount()).To(Equal(1))
+			Expect(testLogger.LogMessages()).To(ContainElement("test.forced-prune"))
+		})
+
+		It("only accepts POST", func() {
+			resp := do("GET", "/v1/debug/prune", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+			Expect(debugTable.PruneStaleEntriesCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("POST /v1/debug/warm", func() {
+		It("marks the table warm", func() {
+			resp := do("POST", "/v1/debug/warm", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+			Expect(debugTable.SetWarmCallCount()).To(Equal(1))
+			Expect(testLogger.LogMessages()).To(ContainElement("test.forced-warm"))
+		})
+	})
+
+	Describe("ReloadCertificates", func() {
+		var (
+			newCAFile     string
+			newServerCert string
+			newServerKey  string
+			newClientCert tls.Certificate
+		)
+
+		BeforeEach(func() {
+			newCAFile, newServerCert, newServerKey, newClientCert = testhelpers.GenerateCaAndMutualTlsCerts()
+		})
+
+		AfterEach(func() {
+			os.Remove(newCAFile)
+			os.Remove(newServerCert)
+			os.Remove(newServerKey)
+		})
+
+		It("serves new connections with the new certificates", func() {
+			resp := do("GET", "/v1/aliases", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(server.ReloadCertificates(&config.Config{
+				AdminServerCert: newServerCert,
+				AdminServerKey:  newServerKey,
+				AdminCACert:     newCAFile,
+			})).To(Succeed())
+
+			_, err := testhelpers.NewClient(testhelpers.CertPool(caFile), clientCert).Get(fmt.Sprintf("https://127.0.0.1:%d/v1/aliases", port))
+			Expect(err).To(HaveOccurred())
+
+			client = testhelpers.NewClient(testhelpers.CertPool(newCAFile), newClientCert)
+			resp = do("GET", "/v1/aliases", "")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		Context("when the new certificates cannot be loaded", func() {
+			It("returns an error and keeps serving the previous certificates", func() {
+				err := server.ReloadCertificates(&config.Config{
+					AdminServerCert: "non-existent",
+					AdminServerKey:  newServerKey,
+					AdminCACert:     newCAFile,
+				})
+				Expect(err).To(MatchError("load admin certificates: load server key pair: open non-existent: no such file or directory"))
+
+				resp := do("GET", "/v1/aliases", "")
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Context("when the client has no certificate", func() {
+		It("rejects the connection", func() {
+			Eventually(func() error {
+				_, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/aliases", port))
+				return err
+			}).Should(Succeed())
+
+			unauthenticated := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs: testhelpers.CertPool(caFile),
+			}}}
+			_, err := unauthenticated.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/aliases", port))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
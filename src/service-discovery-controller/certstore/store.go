@@ -0,0 +1,87 @@
+package certstore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/pivotal-cf/paraphernalia/secure/tlsconfig"
+)
+
+// Store holds the mutual TLS configuration of a server, so that its
+// certificates can be rotated by loading them again without closing the
+// listener. Handshakes use the configuration that was loaded last.
+type Store struct {
+	mutex  sync.RWMutex
+	config *tls.Config
+}
+
+// Load reads the server key pair and the CA that client certificates must be
+// signed by. If any of them cannot be read, the previously loaded
+// configuration is kept.
+func (s *Store) Load(certPath, keyPath, caPath string) error {
+	caCert, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		return fmt.Errorf("read CA file: %s", err)
+	}
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caCert) {
+		return fmt.Errorf("load CA file into cert pool")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return fmt.Errorf("load server key pair: %s", err)
+	}
+
+	serverConfig := tlsconfig.Build(
+		tlsconfig.WithIdentity(cert),
+		tlsconfig.WithInternalServiceDefaults(),
+	).Server(tlsconfig.WithClientAuthentication(caCertPool))
+	serverConfig.BuildNameToCertificate()
+
+	s.mutex.Lock()
+	s.config = serverConfig
+	s.mutex.Unlock()
+	return nil
+}
+
+// ServerConfig returns the configuration to serve with. Each handshake is
+// answered with the certificates that are loaded at the time. It must not be
+// called before the store is loaded.
+func (s *Store) ServerConfig() *tls.Config {
+	current := s.current()
+	return &tls.Config{
+		Certificates: current.Certificates,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return s.current(), nil
+		},
+	}
+}
+
+func (s *Store) current() *tls.Config {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.config
+}
+
+// CommonName returns the common name of the first certificate in the PEM
+// file at certPath.
+func CommonName(certPath string) (string, error) {
+	certPem, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return "", fmt.Errorf("read certificate file: %s", err)
+	}
+	block, _ := pem.Decode(certPem)
+	if block == nil {
+		return "", fmt.Errorf("decode certificate file: no PEM data")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("parse certificate: %s", err)
+	}
+	return cert.Subject.CommonName, nil
+}
@@ -0,0 +1,109 @@
+package certstore_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"service-discovery-controller/certstore"
+	"test-helpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+	var (
+		store *certstore.Store
+
+		caFileName   string
+		certFileName string
+		keyFileName  string
+		cert         tls.Certificate
+	)
+
+	BeforeEach(func() {
+		store = &certstore.Store{}
+		caFileName, certFileName, keyFileName, cert = testhelpers.GenerateCaAndMutualTlsCerts()
+	})
+
+	AfterEach(func() {
+		os.Remove(caFileName)
+		os.Remove(certFileName)
+		os.Remove(keyFileName)
+	})
+
+	It("serves the certificates that were loaded last", func() {
+		Expect(store.Load(certFileName, keyFileName, caFileName)).To(Succeed())
+
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.TLS = store.ServerConfig()
+		server.StartTLS()
+		defer server.Close()
+
+		client := testhelpers.NewClient(testhelpers.CertPool(caFileName), cert)
+		resp, err := client.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+
+		newCAFileName, newCertFileName, newKeyFileName, newCert := testhelpers.GenerateCaAndMutualTlsCerts()
+		defer os.Remove(newCAFileName)
+		defer os.Remove(newCertFileName)
+		defer os.Remove(newKeyFileName)
+		Expect(store.Load(newCertFileName, newKeyFileName, newCAFileName)).To(Succeed())
+
+		client = testhelpers.NewClient(testhelpers.CertPool(caFileName), cert)
+		_, err = client.Get(server.URL)
+		Expect(err).To(HaveOccurred())
+
+		client = testhelpers.NewClient(testhelpers.CertPool(newCAFileName), newCert)
+		resp, err = client.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+	})
+
+	Context("when the CA file does not exist", func() {
+		It("returns an error and keeps the loaded certificates", func() {
+			Expect(store.Load(certFileName, keyFileName, caFileName)).To(Succeed())
+
+			err := store.Load(certFileName, keyFileName, "non-existent")
+			Expect(err).To(MatchError("read CA file: open non-existent: no such file or directory"))
+
+			Expect(store.ServerConfig().Certificates).To(HaveLen(1))
+		})
+	})
+
+	Context("when the server key pair does not exist", func() {
+		It("returns an error", func() {
+			err := store.Load("non-existent", keyFileName, caFileName)
+			Expect(err).To(MatchError("load server key pair: open non-existent: no such file or directory"))
+		})
+	})
+})
+
+var _ = Describe("CommonName", func() {
+	It("returns the common name of the certificate", func() {
+		caFileName, certFileName, keyFileName, named := testhelpers.GenerateCaAndNamedMutualTlsCerts("some-name")
+		defer os.Remove(caFileName)
+		defer os.Remove(certFileName)
+		defer os.Remove(keyFileName)
+		defer os.Remove(named["some-name"].CertFileName)
+		defer os.Remove(named["some-name"].PrivateKeyFileName)
+
+		Expect(certstore.CommonName(named["some-name"].CertFileName)).To(Equal("some-name"))
+	})
+
+	Context("when the file does not exist", func() {
+		It("returns an error", func() {
+			_, err := certstore.CommonName("non-existent")
+			Expect(err).To(MatchError("read certificate file: open non-existent: no such file or directory"))
+		})
+	})
+
+	Context("when the file is not PEM", func() {
+		It("returns an error", func() {
+			_, err := certstore.CommonName("store_test.go")
+			Expect(err).To(MatchError("decode certificate file: no PEM data"))
+		})
+	})
+})
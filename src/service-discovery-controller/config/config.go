@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"service-discovery-controller/domains"
+	"strings"
+	"time"
 
 	"gopkg.in/validator.v2"
 )
@@ -22,8 +25,41 @@ type Config struct {
 	StalenessThresholdSeconds int          `json:"staleness_threshold_seconds" validate:"min=1"`
 	PruningIntervalSeconds    int          `json:"pruning_interval_seconds" validate:"min=1"`
 	MetricsEmitSeconds        int          `json:"metrics_emit_seconds" validate:"min=1"`
+	MetricsTopHostnames       int          `json:"metrics_top_hostnames" validate:"min=0"`
 	ResumePruningDelaySeconds int          `json:"resume_pruning_delay_seconds" validate:"min=0"`
 	WarmDurationSeconds       int          `json:"warm_duration_seconds" validate:"min=0"`
+
+	SnapshotPath            string `json:"snapshot_path"`
+	SnapshotIntervalSeconds int    `json:"snapshot_interval_seconds" validate:"min=0"`
+
+	Peers                   []string `json:"peers"`
+	PeerSyncIntervalSeconds int      `json:"peer_sync_interval_seconds" validate:"min=0"`
+	PeerClientCert          string   `json:"peer_client_cert"`
+	PeerClientKey           string   `json:"peer_client_key"`
+	PeerServerName          string   `json:"peer_server_name"`
+
+	AdminAddress    string `json:"admin_address"`
+	AdminPort       int    `json:"admin_port" validate:"min=0"`
+	AdminServerCert string `json:"admin_server_cert"`
+	AdminServerKey  string `json:"admin_server_key"`
+	AdminCACert     string `json:"admin_ca_cert"`
+
+	Aliases map[string]string `json:"aliases"`
+
+	PolicyServerURL           string `json:"policy_server_url"`
+	PolicyServerCACert        string `json:"policy_server_ca_cert"`
+	PolicyClientCert          string `json:"policy_client_cert"`
+	PolicyClientKey           string `json:"policy_client_key"`
+	PolicyPollIntervalSeconds int    `json:"policy_poll_interval_seconds" validate:"min=0"`
+
+	SourceIPClientNames []string `json:"source_ip_client_names"`
+
+	NatsCACert     string `json:"nats_ca_cert"`
+	NatsClientCert string `json:"nats_client_cert"`
+	NatsClientKey  string `json:"nats_client_key"`
+	NatsCredsFile  string `json:"nats_creds_file"`
+
+	Domains []DomainConfig `json:"domains"`
 }
 
 type NatsConfig struct {
@@ -33,6 +69,13 @@ type NatsConfig struct {
 	Pass string `json:"pass"`
 }
 
+type DomainConfig struct {
+	Name                      string   `json:"name" validate:"nonzero"`
+	StalenessThresholdSeconds int      `json:"staleness_threshold_seconds" validate:"min=0"`
+	TTLSeconds                int      `json:"ttl_seconds" validate:"min=0"`
+	AllowedRegistrants        []string `json:"allowed_registrants"`
+}
+
 func NewConfig(configJSON []byte) (*Config, error) {
 	sdcConfig := &Config{}
 	err := json.Unmarshal(configJSON, sdcConfig)
@@ -43,21 +86,114 @@ func NewConfig(configJSON []byte) (*Config, error) {
 	if err = validator.Validate(sdcConfig); err != nil {
 		return nil, fmt.Errorf("invalid config: %s", err)
 	}
+
+	if sdcConfig.SnapshotPath != "" && sdcConfig.SnapshotIntervalSeconds < 1 {
+		return nil, fmt.Errorf("invalid config: SnapshotIntervalSeconds: less than min")
+	}
+
+	if len(sdcConfig.Peers) > 0 {
+		switch {
+		case sdcConfig.PeerSyncIntervalSeconds < 1:
+			return nil, fmt.Errorf("invalid config: PeerSyncIntervalSeconds: less than min")
+		case sdcConfig.PeerClientCert == "":
+			return nil, fmt.Errorf("invalid config: PeerClientCert: zero value")
+		case sdcConfig.PeerClientKey == "":
+			return nil, fmt.Errorf("invalid config: PeerClientKey: zero value")
+		}
+	}
+
+	if sdcConfig.AdminPort > 0 {
+		switch {
+		case sdcConfig.AdminAddress == "":
+			return nil, fmt.Errorf("invalid config: AdminAddress: zero value")
+		case sdcConfig.AdminServerCert == "":
+			return nil, fmt.Errorf("invalid config: AdminServerCert: zero value")
+		case sdcConfig.AdminServerKey == "":
+			return nil, fmt.Errorf("invalid config: AdminServerKey: zero value")
+		case sdcConfig.AdminCACert == "":
+			return nil, fmt.Errorf("invalid config: AdminCACert: zero value")
+		}
+	}
+
+	if sdcConfig.PolicyServerURL != "" {
+		switch {
+		case sdcConfig.PolicyServerCACert == "":
+			return nil, fmt.Errorf("invalid config: PolicyServerCACert: zero value")
+		case sdcConfig.PolicyClientCert == "":
+			return nil, fmt.Errorf("invalid config: PolicyClientCert: zero value")
+		case sdcConfig.PolicyClientKey == "":
+			return nil, fmt.Errorf("invalid config: PolicyClientKey: zero value")
+		case sdcConfig.PolicyPollIntervalSeconds < 1:
+			return nil, fmt.Errorf("invalid config: PolicyPollIntervalSeconds: less than min")
+		case len(sdcConfig.SourceIPClientNames) == 0:
+			return nil, fmt.Errorf("invalid config: SourceIPClientNames: zero value")
+		}
+	}
+
+	if sdcConfig.NatsClientCert != "" || sdcConfig.NatsClientKey != "" {
+		switch {
+		case sdcConfig.NatsCACert == "":
+			return nil, fmt.Errorf("invalid config: NatsCACert: zero value")
+		case sdcConfig.NatsClientCert == "":
+			return nil, fmt.Errorf("invalid config: NatsClientCert: zero value")
+		case sdcConfig.NatsClientKey == "":
+			return nil, fmt.Errorf("invalid config: NatsClientKey: zero value")
+		}
+	}
+
+	domainNames := map[string]bool{}
+	for _, domain := range sdcConfig.Domains {
+		name := strings.TrimSuffix(domain.Name, ".")
+		if domainNames[name] {
+			return nil, fmt.Errorf("invalid config: Domains: duplicate domain %s", name)
+		}
+		domainNames[name] = true
+	}
 	return sdcConfig, err
 }
 
+// NatsTLSEnabled returns whether NATS servers are connected to over TLS,
+// which is the case when a CA to verify them is configured.
+func (c *Config) NatsTLSEnabled() bool {
+	return c.NatsCACert != ""
+}
+
+// NatsServers returns a URI per NATS server. Credentials are only included
+// for servers with a user, so that a creds file can be used instead.
 func (c *Config) NatsServers() []string {
+	scheme := "nats"
+	if c.NatsTLSEnabled() {
+		scheme = "tls"
+	}
+
 	var natsServers []string
 	for _, info := range c.Nats {
 		uri :=
 			url.URL{
-				Scheme: "nats",
-				User:   url.UserPassword(info.User, info.Pass),
+				Scheme: scheme,
 				Host:   fmt.Sprintf("%s:%d", info.Host, info.Port),
 			}
+		if info.User != "" {
+			uri.User = url.UserPassword(info.User, info.Pass)
+		}
 		natsServers = append(natsServers, uri.String())
 
 	}
 
 	return natsServers
 }
+
+// DomainSet returns the configured internal domains. Without any, every
+// hostname is served with the default policies.
+func (c *Config) DomainSet() *domains.Set {
+	var set []domains.Domain
+	for _, domain := range c.Domains {
+		set = append(set, domains.Domain{
+			Name:               domain.Name,
+			StalenessThreshold: time.Duration(domain.StalenessThresholdSeconds) * time.Second,
+			TTL:                time.Duration(domain.TTLSeconds) * time.Second,
+			AllowedRegistrants: domain.AllowedRegistrants,
+		})
+	}
+	return domains.NewSet(set)
+}
@@ -0,0 +1,121 @@
+package domains
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Domain is an internal domain, such as apps.internal., and the policies
+// for the hostnames in it. A zero StalenessThreshold or TTL means the
+// default of the controller or of its clients. An empty AllowedRegistrants
+// lets every app register hostnames in the domain.
+type Domain struct {
+	Name               string
+	StalenessThreshold time.Duration
+	TTL                time.Duration
+	AllowedRegistrants []string
+}
+
+// Set is the internal domains that the controller serves. A hostname is in
+// the most specific domain that it is in or under. A set without domains
+// serves every hostname, with the defaults.
+type Set struct {
+	domains []Domain
+}
+
+func NewSet(domains []Domain) *Set {
+	sorted := []Domain{}
+	for _, domain := range domains {
+		domain.Name = fqdn(domain.Name)
+		sorted = append(sorted, domain)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Name) > len(sorted[j].Name)
+	})
+	return &Set{domains: sorted}
+}
+
+// Match returns the domain of hostname, or false when it is in none.
+func (s *Set) Match(hostname string) (Domain, bool) {
+	hostname = fqdn(hostname)
+	for _, domain := range s.domains {
+		if hostname == domain.Name || strings.HasSuffix(hostname, "."+domain.Name) {
+			return domain, true
+		}
+	}
+	return Domain{}, false
+}
+
+// Serves is true when hostname is in one of the domains, or when there are
+// no domains.
+func (s *Set) Serves(hostname string) bool {
+	if len(s.domains) == 0 {
+		return true
+	}
+	_, ok := s.Match(hostname)
+	return ok
+}
+
+// AllowsRegistration is true when the app with appID may register
+// hostname. Registrations without an app are only allowed in domains
+// without allowed registrants.
+func (s *Set) AllowsRegistration(hostname, appID string) bool {
+	domain, ok := s.Match(hostname)
+	if !ok || len(domain.AllowedRegistrants) == 0 {
+		return true
+	}
+
+	for _, registrant := range domain.AllowedRegistrants {
+		if registrant == appID && appID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// StalenessThreshold returns the staleness threshold of the domain of
+// hostname, or defaultThreshold when it has none.
+func (s *Set) StalenessThreshold(hostname string, defaultThreshold time.Duration) time.Duration {
+	if domain, ok := s.Match(hostname); ok && domain.StalenessThreshold > 0 {
+		return domain.StalenessThreshold
+	}
+	return defaultThreshold
+}
+
+// TTL returns the TTL of answers for hostname, or 0 when its domain has
+// none.
+func (s *Set) TTL(hostname string) time.Duration {
+	domain, _ := s.Match(hostname)
+	return domain.TTL
+}
+
+// NameOf returns the name of the domain of hostname without the trailing
+// dot, as metrics are keyed by it, or the empty string when it is in none.
+func (s *Set) NameOf(hostname string) string {
+	domain, _ := s.Match(hostname)
+	return strings.TrimSuffix(domain.Name, ".")
+}
+
+// GroupByDomain adds up counts by hostname into counts by the name of
+// their domain. Hostnames in no domain are counted under the empty key.
+func (s *Set) GroupByDomain(counts map[string]int) map[string]int {
+	grouped := map[string]int{}
+	for hostname, count := range counts {
+		grouped[s.NameOf(hostname)] += count
+	}
+	return grouped
+}
+
+// Len returns the number of domains.
+func (s *Set) Len() int {
+	return len(s.domains)
+}
+
+func fqdn(hostname string) string {
+	if strings.HasSuffix(hostname, ".") {
+		return hostname
+	}
+	return hostname + "."
+}
@@ -0,0 +1,76 @@
+package domains_test
+
+import (
+	"service-discovery-controller/domains"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Set", func() {
+	var set *domains.Set
+
+	BeforeEach(func() {
+		set = domains.NewSet([]domains.Domain{
+			{Name: "apps.internal", StalenessThreshold: time.Minute, TTL: 5 * time.Second},
+			{Name: "tenant-a.apps.internal.", AllowedRegistrants: []string{"app-a"}},
+		})
+	})
+
+	It("matches hostnames to their most specific domain", func() {
+		domain, ok := set.Match("db.tenant-a.apps.internal")
+		Expect(ok).To(BeTrue())
+		Expect(domain.Name).To(Equal("tenant-a.apps.internal."))
+
+		domain, ok = set.Match("db.apps.internal.")
+		Expect(ok).To(BeTrue())
+		Expect(domain.Name).To(Equal("apps.internal."))
+
+		_, ok = set.Match("db.notapps.internal.")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("serves only hostnames in its domains", func() {
+		Expect(set.Serves("db.apps.internal.")).To(BeTrue())
+		Expect(set.Serves("apps.internal.")).To(BeTrue())
+		Expect(set.Serves("db.other.internal.")).To(BeFalse())
+	})
+
+	It("serves every hostname without domains", func() {
+		Expect(domains.NewSet(nil).Serves("db.other.internal.")).To(BeTrue())
+	})
+
+	It("only allows the allowed registrants of a domain", func() {
+		Expect(set.AllowsRegistration("db.tenant-a.apps.internal.", "app-a")).To(BeTrue())
+		Expect(set.AllowsRegistration("db.tenant-a.apps.internal.", "app-b")).To(BeFalse())
+		Expect(set.AllowsRegistration("db.tenant-a.apps.internal.", "")).To(BeFalse())
+		Expect(set.AllowsRegistration("db.apps.internal.", "app-b")).To(BeTrue())
+		Expect(set.AllowsRegistration("db.other.internal.", "")).To(BeTrue())
+	})
+
+	It("returns the staleness threshold and TTL of the domain", func() {
+		Expect(set.StalenessThreshold("db.apps.internal.", time.Hour)).To(Equal(time.Minute))
+		Expect(set.StalenessThreshold("db.tenant-a.apps.internal.", time.Hour)).To(Equal(time.Hour))
+		Expect(set.TTL("db.apps.internal.")).To(Equal(5 * time.Second))
+		Expect(set.TTL("db.other.internal.")).To(Equal(time.Duration(0)))
+	})
+
+	It("names the domain of hostnames", func() {
+		Expect(set.NameOf("db.tenant-a.apps.internal")).To(Equal("tenant-a.apps.internal"))
+		Expect(set.NameOf("db.other.internal.")).To(Equal(""))
+	})
+
+	It("groups counts by domain", func() {
+		Expect(set.GroupByDomain(map[string]int{
+			"a.apps.internal.":          1,
+			"b.apps.internal.":          2,
+			"a.tenant-a.apps.internal.": 3,
+			"a.other.internal.":         4,
+		})).To(Equal(map[string]int{
+			"apps.internal":          3,
+			"tenant-a.apps.internal": 3,
+			"":                       4,
+		}))
+	})
+})
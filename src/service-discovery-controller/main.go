@@ -4,11 +4,18 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"lib/policy_client"
+	"net/http"
 	"os"
 	"os/signal"
 	"service-discovery-controller/addresstable"
+	"service-discovery-controller/admin"
 	"service-discovery-controller/config"
 	"service-discovery-controller/mbus"
+	"service-discovery-controller/peer"
+	"service-discovery-controller/policyfilter"
+	"service-discovery-controller/snapshot"
+	"service-discovery-controller/topmetrics"
 	"syscall"
 	"time"
 
@@ -20,9 +27,11 @@ import (
 	"code.cloudfoundry.org/cf-networking-helpers/lagerlevel"
 	"code.cloudfoundry.org/cf-networking-helpers/metrics"
 	"code.cloudfoundry.org/cf-networking-helpers/middleware/adapter"
+	"code.cloudfoundry.org/cf-networking-helpers/mutualtls"
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
 	"github.com/cloudfoundry/dropsonde"
+	"github.com/nats-io/go-nats"
 	"github.com/tedsuo/ifrit"
 	"github.com/tedsuo/ifrit/grouper"
 	"github.com/tedsuo/ifrit/sigmon"
@@ -38,6 +47,8 @@ func main() {
 func mainWithError() error {
 	signalChannel := make(chan os.Signal, 1)
 	signal.Notify(signalChannel, syscall.SIGTERM, os.Interrupt)
+	reloadChannel := make(chan os.Signal, 1)
+	signal.Notify(reloadChannel, syscall.SIGHUP)
 	configPath := flag.String("c", "", "path to config file")
 	flag.Parse()
 
@@ -48,6 +59,12 @@ func mainWithError() error {
 	}
 
 	addressTable := buildAddressTable(conf, logger)
+	restoreSnapshot(conf, addressTable, logger)
+
+	err = setAliases(conf, addressTable, logger)
+	if err != nil {
+		return err
+	}
 
 	metronAddress := fmt.Sprintf("127.0.0.1:%d", conf.MetronPort)
 	err = dropsonde.Initialize(metronAddress, "service-discovery-controller")
@@ -64,7 +81,8 @@ func mainWithError() error {
 		return err
 	}
 
-	dnsRequestRecorder := &routes.MetricsRecorder{}
+	domainSet := conf.DomainSet()
+	dnsRequestRecorder := routes.NewMetricsRecorder(domainSet)
 
 	dnsRequestSource := metrics.MetricSource{
 		Name:   "dnsRequest",
@@ -84,19 +102,74 @@ func mainWithError() error {
 		Getter: routeMessageRecorder.GetRegisterMessagesReceived,
 	}
 
-	metricsEmitter := metrics.NewMetricsEmitter(
-		logger,
-		time.Duration(conf.MetricsEmitSeconds)*time.Second,
+	registerMessagesPerIntervalSource := metrics.MetricSource{
+		Name:   "registerMessagesPerInterval",
+		Unit:   "message",
+		Getter: routeMessageRecorder.GetRegisterMessagesSinceLastInterval,
+	}
+
+	unregisterMessagesPerIntervalSource := metrics.MetricSource{
+		Name:   "unregisterMessagesPerInterval",
+		Unit:   "message",
+		Getter: routeMessageRecorder.GetUnregisterMessagesSinceLastInterval,
+	}
+
+	metricsSender := &metrics.MetricsSender{
+		Logger: logger.Session("time-metric-emitter"),
+	}
+
+	metricSources := []metrics.MetricSource{
 		metrics.NewUptimeSource(),
 		dnsRequestSource,
 		routeMessageSource,
 		registerMessagesReceivedSource,
-	)
+		registerMessagesPerIntervalSource,
+		unregisterMessagesPerIntervalSource,
+		topmetrics.Source("lookupsPerInterval", "request", dnsRequestRecorder.TakeLookups, conf.MetricsTopHostnames, metricsSender),
+		topmetrics.Source("emptyAnswersPerInterval", "request", dnsRequestRecorder.TakeEmptyAnswers, conf.MetricsTopHostnames, metricsSender),
+		topmetrics.Source("lookupsBySourcePerInterval", "request", dnsRequestRecorder.TakeLookupsBySource, conf.MetricsTopHostnames, metricsSender),
+		topmetrics.Source("prunedEntriesPerInterval", "entry", addressTable.TakePruned, conf.MetricsTopHostnames, metricsSender),
+		topmetrics.DistributionSource("entriesPerHostname", "entry", func() []int {
+			counts := []int{}
+			for _, count := range addressTable.EntryCounts() {
+				counts = append(counts, count)
+			}
+			return counts
+		}, metricsSender),
+	}
 
-	metricsSender := &metrics.MetricsSender{
-		Logger: logger.Session("time-metric-emitter"),
+	if domainSet.Len() > 0 {
+		metricSources = append(metricSources,
+			topmetrics.Source("lookupsPerDomainPerInterval", "request", dnsRequestRecorder.TakeLookupsByDomain, domainSet.Len(), metricsSender),
+			topmetrics.Source("rejectedLookupsPerInterval", "request", dnsRequestRecorder.TakeRejectedLookups, conf.MetricsTopHostnames, metricsSender),
+			topmetrics.Source("prunedEntriesPerDomainPerInterval", "entry", addressTable.TakePrunedByDomain, domainSet.Len(), metricsSender),
+			topmetrics.Source("entriesPerDomain", "entry", func() map[string]int {
+				return domainSet.GroupByDomain(addressTable.EntryCounts())
+			}, domainSet.Len(), metricsSender),
+		)
 	}
 
+	var replicator *peer.Replicator
+	if len(conf.Peers) > 0 {
+		replicator, err = buildReplicator(conf, addressTable, logger)
+		if err != nil {
+			logger.Error("Failed to build peer replicator", err)
+			return err
+		}
+
+		metricSources = append(metricSources, metrics.MetricSource{
+			Name:   "peerTableDivergence",
+			Unit:   "entry",
+			Getter: replicator.GetDivergence,
+		})
+	}
+
+	metricsEmitter := metrics.NewMetricsEmitter(
+		logger,
+		time.Duration(conf.MetricsEmitSeconds)*time.Second,
+		metricSources...,
+	)
+
 	logLevelServer := lagerlevel.NewServer(
 		conf.LogLevelAddress,
 		conf.LogLevelPort,
@@ -104,11 +177,25 @@ func mainWithError() error {
 		logger.Session("log-level-server"),
 	)
 
+	// The routes server only filters lookups when it is given a filter, so
+	// the interface is left nil when policies are not enforced.
+	var policyFilter *policyfilter.Filter
+	var routesPolicyFilter routes.PolicyFilter
+	if conf.PolicyServerURL != "" {
+		policyFilter, err = buildPolicyFilter(conf, addressTable, logger)
+		if err != nil {
+			logger.Error("Failed to build policy filter", err)
+			return err
+		}
+		routesPolicyFilter = policyFilter
+	}
+
 	routesServer := routes.NewServer(
 		addressTable,
 		conf,
 		dnsRequestRecorder,
 		metricsSender,
+		routesPolicyFilter,
 		logger.Session("routes-server"),
 	)
 
@@ -116,7 +203,38 @@ func mainWithError() error {
 		{"subscriber", subscriber},
 		{"metrics-emitter", metricsEmitter},
 		{"log-level-server", logLevelServer},
-		{"routes-server", routesServer},
+	}
+
+	if replicator != nil {
+		members = append(members, grouper.Member{Name: "peer-replicator", Runner: replicator})
+	}
+
+	if policyFilter != nil {
+		members = append(members, grouper.Member{Name: "policy-filter", Runner: policyFilter})
+	}
+
+	members = append(members, grouper.Member{Name: "routes-server", Runner: routesServer})
+
+	var adminServer *admin.Server
+	if conf.AdminPort > 0 {
+		adminServer = admin.NewServer(
+			addressTable,
+			addressTable,
+			subscriber,
+			conf,
+			logger.Session("admin-server"),
+		)
+		members = append(members, grouper.Member{Name: "admin-server", Runner: adminServer})
+	}
+
+	if conf.SnapshotPath != "" {
+		members = append(members, grouper.Member{Name: "snapshotter", Runner: &snapshot.Snapshotter{
+			Table:    addressTable,
+			Path:     conf.SnapshotPath,
+			Interval: time.Duration(conf.SnapshotIntervalSeconds) * time.Second,
+			Clock:    clock.NewClock(),
+			Logger:   logger.Session("snapshotter"),
+		}})
 	}
 
 	group := grouper.NewOrdered(os.Interrupt, members)
@@ -131,23 +249,182 @@ func mainWithError() error {
 
 	logger.Info("server-started")
 
-	select {
-	case stopSignal := <-signalChannel:
-		subscriber.Close()
-		addressTable.Shutdown()
-		monitor.Signal(stopSignal)
-		logger.Info("server-stopped")
-		return nil
+	reloader := &configReloader{
+		configPath:         configPath,
+		natsServers:        strings.Join(conf.NatsServers(), ","),
+		addressTable:       addressTable,
+		subscriber:         subscriber,
+		routesServer:       routesServer,
+		dnsRequestRecorder: dnsRequestRecorder,
+		adminServer:        adminServer,
+		logger:             logger.Session("reload"),
+	}
+
+	for {
+		select {
+		case <-reloadChannel:
+			reloader.reload()
+		case stopSignal := <-signalChannel:
+			subscriber.Close()
+			addressTable.Shutdown()
+			monitor.Signal(stopSignal)
+			logger.Info("server-stopped")
+			return nil
+		}
 	}
 }
 
+// configReloader applies the settings of the config file that can change
+// without a restart when the controller receives SIGHUP: the staleness
+// threshold and pruning interval of the address table, the internal
+// domains, the NATS servers, and the certificates of the routes and admin
+// servers. Changes to other settings take effect on the next restart.
+type configReloader struct {
+	configPath         *string
+	natsServers        string
+	addressTable       *addresstable.AddressTable
+	subscriber         *mbus.Subscriber
+	routesServer       *routes.Server
+	dnsRequestRecorder *routes.MetricsRecorder
+	adminServer        *admin.Server
+	logger             lager.Logger
+}
+
+// reload reads the config file and applies it. A config that cannot be read
+// is logged and the current settings are kept. The subscriber only
+// reconnects when the NATS servers changed, so that the table is not
+// re-warmed.
+func (r *configReloader) reload() {
+	r.logger.Info("reloading", lager.Data{"path": *r.configPath})
+
+	conf, err := readConfig(r.configPath, r.logger)
+	if err != nil {
+		return
+	}
+
+	r.addressTable.Reconfigure(
+		time.Duration(conf.StalenessThresholdSeconds)*time.Second,
+		time.Duration(conf.PruningIntervalSeconds)*time.Second,
+	)
+
+	domainSet := conf.DomainSet()
+	r.addressTable.SetDomains(domainSet)
+	r.subscriber.SetRegistrationPolicy(domainSet)
+	r.routesServer.SetDomains(domainSet)
+	r.dnsRequestRecorder.SetDomains(domainSet)
+
+	natsServers := strings.Join(conf.NatsServers(), ",")
+	if natsServers != r.natsServers {
+		err = r.subscriber.Reconnect(&mbus.NatsConnWithUrlProvider{
+			Url:     natsServers,
+			Options: natsOptions(conf),
+		})
+		if err != nil {
+			r.logger.Error("reconnect-nats", err)
+		} else {
+			r.natsServers = natsServers
+			r.logger.Info("reconnected-nats", lager.Data{"servers": len(conf.Nats)})
+		}
+	}
+
+	err = r.routesServer.ReloadCertificates(conf)
+	if err != nil {
+		r.logger.Error("reload-server-certificates", err)
+	}
+
+	if r.adminServer != nil {
+		err = r.adminServer.ReloadCertificates(conf)
+		if err != nil {
+			r.logger.Error("reload-admin-certificates", err)
+		}
+	}
+
+	r.logger.Info("reloaded")
+}
+
 func buildAddressTable(conf *config.Config, logger lager.Logger) *addresstable.AddressTable {
-	return addresstable.NewAddressTable(
+	table := addresstable.NewAddressTable(
 		time.Duration(conf.StalenessThresholdSeconds)*time.Second,
 		time.Duration(conf.PruningIntervalSeconds)*time.Second,
 		time.Duration(conf.ResumePruningDelaySeconds)*time.Second,
 		clock.NewClock(),
 		logger.Session("address-table"))
+	table.SetDomains(conf.DomainSet())
+	return table
+}
+
+// restoreSnapshot lets the address table serve the routes it had before a
+// restart while NATS repopulates it. A bad snapshot is logged and ignored.
+func restoreSnapshot(conf *config.Config, addressTable *addresstable.AddressTable, logger lager.Logger) {
+	if conf.SnapshotPath == "" {
+		return
+	}
+
+	entries, err := snapshot.Load(conf.SnapshotPath)
+	if err != nil {
+		logger.Error("load-snapshot", err)
+		return
+	}
+
+	addressTable.Restore(entries)
+}
+
+// setAliases sets the aliases from the config. Aliases set through the
+// admin API are not saved, so aliases that should survive a restart belong
+// in the config.
+func setAliases(conf *config.Config, addressTable *addresstable.AddressTable, logger lager.Logger) error {
+	for alias, target := range conf.Aliases {
+		err := addressTable.SetAlias(alias, target)
+		if err != nil {
+			logger.Error("set-alias", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// buildPolicyFilter polls the internal API of the policy server, so that
+// lookups only return the apps that the querying container's app has a
+// policy to. The source of a lookup is only known when it comes through
+// the native DNS server of the bosh-dns-adapter, which its job template
+// requires while the filter is enabled.
+func buildPolicyFilter(conf *config.Config, addressTable *addresstable.AddressTable, logger lager.Logger) (*policyfilter.Filter, error) {
+	tlsConfig, err := mutualtls.NewClientTLSConfig(conf.PolicyClientCert, conf.PolicyClientKey, conf.PolicyServerCACert)
+	if err != nil {
+		return nil, fmt.Errorf("policy client tls config: %s", err)
+	}
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		Timeout:   10 * time.Second,
+	}
+
+	return &policyfilter.Filter{
+		Client:   policy_client.NewInternal(logger.Session("policy-client"), httpClient, conf.PolicyServerURL),
+		Table:    addressTable,
+		Interval: time.Duration(conf.PolicyPollIntervalSeconds) * time.Second,
+		Clock:    clock.NewClock(),
+		Logger:   logger.Session("policy-filter"),
+	}, nil
+}
+
+// buildReplicator replicates the address table from the other instances.
+// It runs before the routes server starts, so that a new instance can
+// bootstrap from a warm peer before it answers lookups.
+func buildReplicator(conf *config.Config, addressTable *addresstable.AddressTable, logger lager.Logger) (*peer.Replicator, error) {
+	client, err := peer.NewClient(conf.CACert, conf.PeerClientCert, conf.PeerClientKey, conf.PeerServerName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &peer.Replicator{
+		Table:    addressTable,
+		Client:   client,
+		Peers:    conf.Peers,
+		Interval: time.Duration(conf.PeerSyncIntervalSeconds) * time.Second,
+		Clock:    clock.NewClock(),
+		Logger:   logger.Session("peer-replicator"),
+	}, nil
 }
 
 func buildLogger() (lager.Logger, *lager.ReconfigurableSink) {
@@ -191,7 +468,8 @@ func buildSubscriber(conf *config.Config, addressTable *addresstable.AddressTabl
 	}
 
 	provider := &mbus.NatsConnWithUrlProvider{
-		Url: strings.Join(conf.NatsServers(), ","),
+		Url:     strings.Join(conf.NatsServers(), ","),
+		Options: natsOptions(conf),
 	}
 
 	localIP, err := localip.LocalIP()
@@ -203,6 +481,22 @@ func buildSubscriber(conf *config.Config, addressTable *addresstable.AddressTabl
 	warmDuration := time.Duration(conf.WarmDurationSeconds) * time.Second
 
 	subscriber := mbus.NewSubscriber(provider, subOpts, warmDuration, addressTable,
-		localIP, routeMessageRecorder, logger.Session("mbus"), newClock)
+		localIP, routeMessageRecorder, conf.DomainSet(), logger.Session("mbus"), newClock)
 	return subscriber, nil
 }
+
+// natsOptions returns the connection options for the NATS TLS and
+// credentials in conf.
+func natsOptions(conf *config.Config) []nats.Option {
+	options := []nats.Option{}
+	if conf.NatsTLSEnabled() {
+		options = append(options, nats.RootCAs(conf.NatsCACert))
+	}
+	if conf.NatsClientCert != "" {
+		options = append(options, nats.ClientCert(conf.NatsClientCert, conf.NatsClientKey))
+	}
+	if conf.NatsCredsFile != "" {
+		options = append(options, nats.UserCredentials(conf.NatsCredsFile))
+	}
+	return options
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"syscall"
 
 	"code.cloudfoundry.org/cf-networking-helpers/testsupport/metrics"
 
@@ -622,6 +623,153 @@ var _ = Describe("Service Discovery Controller process", func() {
 			})
 		})
 
+		Context("when it receives SIGHUP", func() {
+			var (
+				newCAFile     string
+				newServerCert string
+				newServerKey  string
+				newClientCert tls.Certificate
+				extraConfig   string
+			)
+
+			writeConfig := func(caFile, serverCert, serverKey string, natsPort int) {
+				err := ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`{
+					"address":"127.0.0.1",
+					"port":"%d",
+					"ca_cert": "%s",
+					"server_cert": "%s",
+					"server_key": "%s",
+					"nats":[
+						{
+							"host":"localhost",
+							"port":%d,
+							"user":"",
+							"pass":""
+						}
+					],
+					"staleness_threshold_seconds": 60,
+					"pruning_interval_seconds": 1,
+					"log_level_address": "%s",
+					"log_level_port": %d,
+					"metron_port": %d,
+					"metrics_emit_seconds": 2,
+					"resume_pruning_delay_seconds": 1,
+					"warm_duration_seconds": 0%s
+				}`, port, caFile, serverCert, serverKey, natsPort, logLevelEndpointAddress, logLevelEndpointPort, fakeMetron.Port(), extraConfig)), os.ModePerm)
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			lookup := func(client *http.Client) ([]byte, error) {
+				resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/registration/app-id.internal.local.", port))
+				if err != nil {
+					return nil, err
+				}
+				defer resp.Body.Close()
+				return ioutil.ReadAll(resp.Body)
+			}
+
+			BeforeEach(func() {
+				newCAFile, newServerCert, newServerKey, newClientCert = testhelpers.GenerateCaAndMutualTlsCerts()
+				extraConfig = ""
+			})
+
+			AfterEach(func() {
+				os.Remove(newCAFile)
+				os.Remove(newServerCert)
+				os.Remove(newServerKey)
+			})
+
+			It("serves with the new certificates without restarting", func() {
+				writeConfig(newCAFile, newServerCert, newServerKey, natsServerPort)
+				session.Signal(syscall.SIGHUP)
+				Eventually(session).Should(gbytes.Say("service-discovery-controller.reload.reloaded"))
+
+				_, err := lookup(testhelpers.NewClient(testhelpers.CertPool(caFile), clientCert))
+				Expect(err).To(HaveOccurred())
+
+				body, err := lookup(testhelpers.NewClient(testhelpers.CertPool(newCAFile), newClientCert))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(body).To(ContainSubstring("192.168.0.1"))
+				Expect(session).NotTo(gexec.Exit())
+			})
+
+			It("keeps the registered routes past the previous staleness threshold", func() {
+				writeConfig(caFile, serverCert, serverKey, natsServerPort)
+				session.Signal(syscall.SIGHUP)
+				Eventually(session).Should(gbytes.Say("service-discovery-controller.reload.reloaded"))
+
+				client := testhelpers.NewClient(testhelpers.CertPool(caFile), clientCert)
+				Consistently(func() []byte {
+					body, err := lookup(client)
+					Expect(err).NotTo(HaveOccurred())
+					return body
+				}, time.Duration(stalenessThresholdSeconds+pruningIntervalSeconds+1)*time.Second).Should(ContainSubstring("192.168.0.1"))
+			})
+
+			It("answers lookups with the new domains", func() {
+				extraConfig = `,
+					"domains": [{"name": "other.local"}]`
+				writeConfig(caFile, serverCert, serverKey, natsServerPort)
+				session.Signal(syscall.SIGHUP)
+				Eventually(session).Should(gbytes.Say("service-discovery-controller.reload.reloaded"))
+
+				client := testhelpers.NewClient(testhelpers.CertPool(caFile), clientCert)
+				resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/registration/app-id.internal.local.", port))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+			})
+
+			Context("when the nats servers changed", func() {
+				var (
+					otherNatsServer     *server.Server
+					otherRouteEmitter   *nats.Conn
+					otherNatsServerPort int
+				)
+
+				BeforeEach(func() {
+					otherNatsServerPort = ports.PickAPort()
+					otherNatsServer = RunNatsServerOnPort(otherNatsServerPort)
+				})
+
+				AfterEach(func() {
+					if otherRouteEmitter != nil {
+						otherRouteEmitter.Close()
+					}
+					otherNatsServer.Shutdown()
+				})
+
+				It("reconnects to the new servers and keeps the table", func() {
+					writeConfig(caFile, serverCert, serverKey, otherNatsServerPort)
+					session.Signal(syscall.SIGHUP)
+					Eventually(session).Should(gbytes.Say("service-discovery-controller.reload.reconnected-nats"))
+
+					otherRouteEmitter = newFakeRouteEmitter("nats://" + otherNatsServer.Addr().String())
+					register(otherRouteEmitter, "192.168.0.99", "app-id.internal.local.")
+					Expect(otherRouteEmitter.Flush()).ToNot(HaveOccurred())
+
+					client := testhelpers.NewClient(testhelpers.CertPool(caFile), clientCert)
+					Eventually(func() []byte {
+						body, err := lookup(client)
+						Expect(err).NotTo(HaveOccurred())
+						return body
+					}).Should(And(ContainSubstring("192.168.0.1"), ContainSubstring("192.168.0.99")))
+				})
+			})
+
+			Context("when the config is invalid", func() {
+				It("logs and keeps running with the current config", func() {
+					Expect(ioutil.WriteFile(configPath, []byte("garbage"), os.ModePerm)).To(Succeed())
+					session.Signal(syscall.SIGHUP)
+					Eventually(session).Should(gbytes.Say("Could not parse config file"))
+
+					body, err := lookup(testhelpers.NewClient(testhelpers.CertPool(caFile), clientCert))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(body).To(ContainSubstring("192.168.0.1"))
+					Expect(session).NotTo(gexec.Exit())
+				})
+			})
+		})
+
 		Context("Attempting to adjust log level", func() {
 			It("it accepts the debug request", func() {
 				response := requestLogChange("debug", logLevelEndpointPort)
@@ -2,102 +2,86 @@
 package fakes
 
 import (
+	"service-discovery-controller/addresstable"
 	"service-discovery-controller/mbus"
 	"sync"
 )
 
 type AddressTable struct {
-	AddStub        func(infraNames []string, ip string)
-	addMutex       sync.RWMutex
-	addArgsForCall []struct {
-		infraNames []string
-		ip         string
+	AddEndpointStub        func([]string, addresstable.Endpoint)
+	addEndpointMutex       sync.RWMutex
+	addEndpointArgsForCall []struct {
+		arg1 []string
+		arg2 addresstable.Endpoint
 	}
-	RemoveStub        func(infraNames []string, ip string)
-	removeMutex       sync.RWMutex
-	removeArgsForCall []struct {
-		infraNames []string
-		ip         string
+	PausePruningStub        func()
+	pausePruningMutex       sync.RWMutex
+	pausePruningArgsForCall []struct {
+	}
+	RemoveEndpointStub        func([]string, addresstable.Endpoint)
+	removeEndpointMutex       sync.RWMutex
+	removeEndpointArgsForCall []struct {
+		arg1 []string
+		arg2 addresstable.Endpoint
 	}
-	PausePruningStub         func()
-	pausePruningMutex        sync.RWMutex
-	pausePruningArgsForCall  []struct{}
 	ResumePruningStub        func()
 	resumePruningMutex       sync.RWMutex
-	resumePruningArgsForCall []struct{}
-	SetWarmStub              func()
-	setWarmMutex             sync.RWMutex
-	setWarmArgsForCall       []struct{}
-	invocations              map[string][][]interface{}
-	invocationsMutex         sync.RWMutex
-}
-
-func (fake *AddressTable) Add(infraNames []string, ip string) {
-	var infraNamesCopy []string
-	if infraNames != nil {
-		infraNamesCopy = make([]string, len(infraNames))
-		copy(infraNamesCopy, infraNames)
+	resumePruningArgsForCall []struct {
 	}
-	fake.addMutex.Lock()
-	fake.addArgsForCall = append(fake.addArgsForCall, struct {
-		infraNames []string
-		ip         string
-	}{infraNamesCopy, ip})
-	fake.recordInvocation("Add", []interface{}{infraNamesCopy, ip})
-	fake.addMutex.Unlock()
-	if fake.AddStub != nil {
-		fake.AddStub(infraNames, ip)
+	SetWarmStub        func()
+	setWarmMutex       sync.RWMutex
+	setWarmArgsForCall []struct {
 	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
 }
 
-func (fake *AddressTable) AddCallCount() int {
-	fake.addMutex.RLock()
-	defer fake.addMutex.RUnlock()
-	return len(fake.addArgsForCall)
-}
-
-func (fake *AddressTable) AddArgsForCall(i int) ([]string, string) {
-	fake.addMutex.RLock()
-	defer fake.addMutex.RUnlock()
-	return fake.addArgsForCall[i].infraNames, fake.addArgsForCall[i].ip
-}
-
-func (fake *AddressTable) Remove(infraNames []string, ip string) {
-	var infraNamesCopy []string
-	if infraNames != nil {
-		infraNamesCopy = make([]string, len(infraNames))
-		copy(infraNamesCopy, infraNames)
+func (fake *AddressTable) AddEndpoint(arg1 []string, arg2 addresstable.Endpoint) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
 	}
-	fake.removeMutex.Lock()
-	fake.removeArgsForCall = append(fake.removeArgsForCall, struct {
-		infraNames []string
-		ip         string
-	}{infraNamesCopy, ip})
-	fake.recordInvocation("Remove", []interface{}{infraNamesCopy, ip})
-	fake.removeMutex.Unlock()
-	if fake.RemoveStub != nil {
-		fake.RemoveStub(infraNames, ip)
+	fake.addEndpointMutex.Lock()
+	fake.addEndpointArgsForCall = append(fake.addEndpointArgsForCall, struct {
+		arg1 []string
+		arg2 addresstable.Endpoint
+	}{arg1Copy, arg2})
+	stub := fake.AddEndpointStub
+	fake.recordInvocation("AddEndpoint", []interface{}{arg1Copy, arg2})
+	fake.addEndpointMutex.Unlock()
+	if stub != nil {
+		fake.AddEndpointStub(arg1, arg2)
 	}
 }
 
-func (fake *AddressTable) RemoveCallCount() int {
-	fake.removeMutex.RLock()
-	defer fake.removeMutex.RUnlock()
-	return len(fake.removeArgsForCall)
+func (fake *AddressTable) AddEndpointCallCount() int {
+	fake.addEndpointMutex.RLock()
+	defer fake.addEndpointMutex.RUnlock()
+	return len(fake.addEndpointArgsForCall)
+}
+
+func (fake *AddressTable) AddEndpointCalls(stub func([]string, addresstable.Endpoint)) {
+	fake.addEndpointMutex.Lock()
+	defer fake.addEndpointMutex.Unlock()
+	fake.AddEndpointStub = stub
 }
 
-func (fake *AddressTable) RemoveArgsForCall(i int) ([]string, string) {
-	fake.removeMutex.RLock()
-	defer fake.removeMutex.RUnlock()
-	return fake.removeArgsForCall[i].infraNames, fake.removeArgsForCall[i].ip
+func (fake *AddressTable) AddEndpointArgsForCall(i int) ([]string, addresstable.Endpoint) {
+	fake.addEndpointMutex.RLock()
+	defer fake.addEndpointMutex.RUnlock()
+	argsForCall := fake.addEndpointArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *AddressTable) PausePruning() {
 	fake.pausePruningMutex.Lock()
-	fake.pausePruningArgsForCall = append(fake.pausePruningArgsForCall, struct{}{})
+	fake.pausePruningArgsForCall = append(fake.pausePruningArgsForCall, struct {
+	}{})
+	stub := fake.PausePruningStub
 	fake.recordInvocation("PausePruning", []interface{}{})
 	fake.pausePruningMutex.Unlock()
-	if fake.PausePruningStub != nil {
+	if stub != nil {
 		fake.PausePruningStub()
 	}
 }
@@ -108,12 +92,58 @@ func (fake *AddressTable) PausePruningCallCount() int {
 	return len(fake.pausePruningArgsForCall)
 }
 
+func (fake *AddressTable) PausePruningCalls(stub func()) {
+	fake.pausePruningMutex.Lock()
+	defer fake.pausePruningMutex.Unlock()
+	fake.PausePruningStub = stub
+}
+
+func (fake *AddressTable) RemoveEndpoint(arg1 []string, arg2 addresstable.Endpoint) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.removeEndpointMutex.Lock()
+	fake.removeEndpointArgsForCall = append(fake.removeEndpointArgsForCall, struct {
+		arg1 []string
+		arg2 addresstable.Endpoint
+	}{arg1Copy, arg2})
+	stub := fake.RemoveEndpointStub
+	fake.recordInvocation("RemoveEndpoint", []interface{}{arg1Copy, arg2})
+	fake.removeEndpointMutex.Unlock()
+	if stub != nil {
+		fake.RemoveEndpointStub(arg1, arg2)
+	}
+}
+
+func (fake *AddressTable) RemoveEndpointCallCount() int {
+	fake.removeEndpointMutex.RLock()
+	defer fake.removeEndpointMutex.RUnlock()
+	return len(fake.removeEndpointArgsForCall)
+}
+
+func (fake *AddressTable) RemoveEndpointCalls(stub func([]string, addresstable.Endpoint)) {
+	fake.removeEndpointMutex.Lock()
+	defer fake.removeEndpointMutex.Unlock()
+	fake.RemoveEndpointStub = stub
+}
+
+func (fake *AddressTable) RemoveEndpointArgsForCall(i int) ([]string, addresstable.Endpoint) {
+	fake.removeEndpointMutex.RLock()
+	defer fake.removeEndpointMutex.RUnlock()
+	argsForCall := fake.removeEndpointArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
 func (fake *AddressTable) ResumePruning() {
 	fake.resumePruningMutex.Lock()
-	fake.resumePruningArgsForCall = append(fake.resumePruningArgsForCall, struct{}{})
+	fake.resumePruningArgsForCall = append(fake.resumePruningArgsForCall, struct {
+	}{})
+	stub := fake.ResumePruningStub
 	fake.recordInvocation("ResumePruning", []interface{}{})
 	fake.resumePruningMutex.Unlock()
-	if fake.ResumePruningStub != nil {
+	if stub != nil {
 		fake.ResumePruningStub()
 	}
 }
@@ -124,12 +154,20 @@ func (fake *AddressTable) ResumePruningCallCount() int {
 	return len(fake.resumePruningArgsForCall)
 }
 
+func (fake *AddressTable) ResumePruningCalls(stub func()) {
+	fake.resumePruningMutex.Lock()
+	defer fake.resumePruningMutex.Unlock()
+	fake.ResumePruningStub = stub
+}
+
 func (fake *AddressTable) SetWarm() {
 	fake.setWarmMutex.Lock()
-	fake.setWarmArgsForCall = append(fake.setWarmArgsForCall, struct{}{})
+	fake.setWarmArgsForCall = append(fake.setWarmArgsForCall, struct {
+	}{})
+	stub := fake.SetWarmStub
 	fake.recordInvocation("SetWarm", []interface{}{})
 	fake.setWarmMutex.Unlock()
-	if fake.SetWarmStub != nil {
+	if stub != nil {
 		fake.SetWarmStub()
 	}
 }
@@ -140,15 +178,21 @@ func (fake *AddressTable) SetWarmCallCount() int {
 	return len(fake.setWarmArgsForCall)
 }
 
+func (fake *AddressTable) SetWarmCalls(stub func()) {
+	fake.setWarmMutex.Lock()
+	defer fake.setWarmMutex.Unlock()
+	fake.SetWarmStub = stub
+}
+
 func (fake *AddressTable) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.addMutex.RLock()
-	defer fake.addMutex.RUnlock()
-	fake.removeMutex.RLock()
-	defer fake.removeMutex.RUnlock()
+	fake.addEndpointMutex.RLock()
+	defer fake.addEndpointMutex.RUnlock()
 	fake.pausePruningMutex.RLock()
 	defer fake.pausePruningMutex.RUnlock()
+	fake.removeEndpointMutex.RLock()
+	defer fake.removeEndpointMutex.RUnlock()
 	fake.resumePruningMutex.RLock()
 	defer fake.resumePruningMutex.RUnlock()
 	fake.setWarmMutex.RLock()
@@ -6,27 +6,33 @@ import (
 )
 
 type RouteMessageRecorder struct {
-	RecordMessageTransitTimeStub        func(time int64)
+	RecordMessageTransitTimeStub        func(int64)
 	recordMessageTransitTimeMutex       sync.RWMutex
 	recordMessageTransitTimeArgsForCall []struct {
-		time int64
+		arg1 int64
 	}
 	RecordRegisterMessageReceivedStub        func()
 	recordRegisterMessageReceivedMutex       sync.RWMutex
-	recordRegisterMessageReceivedArgsForCall []struct{}
-	invocations                              map[string][][]interface{}
-	invocationsMutex                         sync.RWMutex
+	recordRegisterMessageReceivedArgsForCall []struct {
+	}
+	RecordUnregisterMessageReceivedStub        func()
+	recordUnregisterMessageReceivedMutex       sync.RWMutex
+	recordUnregisterMessageReceivedArgsForCall []struct {
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
 }
 
-func (fake *RouteMessageRecorder) RecordMessageTransitTime(time int64) {
+func (fake *RouteMessageRecorder) RecordMessageTransitTime(arg1 int64) {
 	fake.recordMessageTransitTimeMutex.Lock()
 	fake.recordMessageTransitTimeArgsForCall = append(fake.recordMessageTransitTimeArgsForCall, struct {
-		time int64
-	}{time})
-	fake.recordInvocation("RecordMessageTransitTime", []interface{}{time})
+		arg1 int64
+	}{arg1})
+	stub := fake.RecordMessageTransitTimeStub
+	fake.recordInvocation("RecordMessageTransitTime", []interface{}{arg1})
 	fake.recordMessageTransitTimeMutex.Unlock()
-	if fake.RecordMessageTransitTimeStub != nil {
-		fake.RecordMessageTransitTimeStub(time)
+	if stub != nil {
+		fake.RecordMessageTransitTimeStub(arg1)
 	}
 }
 
@@ -36,18 +42,27 @@ func (fake *RouteMessageRecorder) RecordMessageTransitTimeCallCount() int {
 	return len(fake.recordMessageTransitTimeArgsForCall)
 }
 
+func (fake *RouteMessageRecorder) RecordMessageTransitTimeCalls(stub func(int64)) {
+	fake.recordMessageTransitTimeMutex.Lock()
+	defer fake.recordMessageTransitTimeMutex.Unlock()
+	fake.RecordMessageTransitTimeStub = stub
+}
+
 func (fake *RouteMessageRecorder) RecordMessageTransitTimeArgsForCall(i int) int64 {
 	fake.recordMessageTransitTimeMutex.RLock()
 	defer fake.recordMessageTransitTimeMutex.RUnlock()
-	return fake.recordMessageTransitTimeArgsForCall[i].time
+	argsForCall := fake.recordMessageTransitTimeArgsForCall[i]
+	return argsForCall.arg1
 }
 
 func (fake *RouteMessageRecorder) RecordRegisterMessageReceived() {
 	fake.recordRegisterMessageReceivedMutex.Lock()
-	fake.recordRegisterMessageReceivedArgsForCall = append(fake.recordRegisterMessageReceivedArgsForCall, struct{}{})
+	fake.recordRegisterMessageReceivedArgsForCall = append(fake.recordRegisterMessageReceivedArgsForCall, struct {
+	}{})
+	stub := fake.RecordRegisterMessageReceivedStub
 	fake.recordInvocation("RecordRegisterMessageReceived", []interface{}{})
 	fake.recordRegisterMessageReceivedMutex.Unlock()
-	if fake.RecordRegisterMessageReceivedStub != nil {
+	if stub != nil {
 		fake.RecordRegisterMessageReceivedStub()
 	}
 }
@@ -58,6 +73,36 @@ func (fake *RouteMessageRecorder) RecordRegisterMessageReceivedCallCount() int {
 	return len(fake.recordRegisterMessageReceivedArgsForCall)
 }
 
+func (fake *RouteMessageRecorder) RecordRegisterMessageReceivedCalls(stub func()) {
+	fake.recordRegisterMessageReceivedMutex.Lock()
+	defer fake.recordRegisterMessageReceivedMutex.Unlock()
+	fake.RecordRegisterMessageReceivedStub = stub
+}
+
+func (fake *RouteMessageRecorder) RecordUnregisterMessageReceived() {
+	fake.recordUnregisterMessageReceivedMutex.Lock()
+	fake.recordUnregisterMessageReceivedArgsForCall = append(fake.recordUnregisterMessageReceivedArgsForCall, struct {
+	}{})
+	stub := fake.RecordUnregisterMessageReceivedStub
+	fake.recordInvocation("RecordUnregisterMessageReceived", []interface{}{})
+	fake.recordUnregisterMessageReceivedMutex.Unlock()
+	if stub != nil {
+		fake.RecordUnregisterMessageReceivedStub()
+	}
+}
+
+func (fake *RouteMessageRecorder) RecordUnregisterMessageReceivedCallCount() int {
+	fake.recordUnregisterMessageReceivedMutex.RLock()
+	defer fake.recordUnregisterMessageReceivedMutex.RUnlock()
+	return len(fake.recordUnregisterMessageReceivedArgsForCall)
+}
+
+func (fake *RouteMessageRecorder) RecordUnregisterMessageReceivedCalls(stub func()) {
+	fake.recordUnregisterMessageReceivedMutex.Lock()
+	defer fake.recordUnregisterMessageReceivedMutex.Unlock()
+	fake.RecordUnregisterMessageReceivedStub = stub
+}
+
 func (fake *RouteMessageRecorder) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -65,6 +110,8 @@ func (fake *RouteMessageRecorder) Invocations() map[string][][]interface{} {
 	defer fake.recordMessageTransitTimeMutex.RUnlock()
 	fake.recordRegisterMessageReceivedMutex.RLock()
 	defer fake.recordRegisterMessageReceivedMutex.RUnlock()
+	fake.recordUnregisterMessageReceivedMutex.RLock()
+	defer fake.recordUnregisterMessageReceivedMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
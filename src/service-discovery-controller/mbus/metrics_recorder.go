@@ -12,6 +12,9 @@ type MetricsRecorder struct {
 	currentMax               time.Duration
 	Clock                    clock.Clock
 	registerMessagesReceived int
+
+	registerMessagesSinceLastInterval   int
+	unregisterMessagesSinceLastInterval int
 }
 
 func NewMetricsRecorder(clock clock.Clock) *MetricsRecorder {
@@ -41,8 +44,39 @@ func (r *MetricsRecorder) RecordMessageTransitTime(unixTimeNS int64) {
 	r.Unlock()
 }
 func (r *MetricsRecorder) RecordRegisterMessageReceived() {
+	r.Lock()
 	r.registerMessagesReceived++
+	r.registerMessagesSinceLastInterval++
+	r.Unlock()
 }
 func (r *MetricsRecorder) GetRegisterMessagesReceived() (float64, error) {
+	r.RLock()
+	defer r.RUnlock()
 	return float64(r.registerMessagesReceived), nil
 }
+
+func (r *MetricsRecorder) RecordUnregisterMessageReceived() {
+	r.Lock()
+	r.unregisterMessagesSinceLastInterval++
+	r.Unlock()
+}
+
+// GetRegisterMessagesSinceLastInterval returns the register messages
+// received since it was last called, which is their rate per interval.
+func (r *MetricsRecorder) GetRegisterMessagesSinceLastInterval() (float64, error) {
+	r.Lock()
+	count := r.registerMessagesSinceLastInterval
+	r.registerMessagesSinceLastInterval = 0
+	r.Unlock()
+	return float64(count), nil
+}
+
+// GetUnregisterMessagesSinceLastInterval returns the unregister messages
+// received since it was last called.
+func (r *MetricsRecorder) GetUnregisterMessagesSinceLastInterval() (float64, error) {
+	r.Lock()
+	count := r.unregisterMessagesSinceLastInterval
+	r.unregisterMessagesSinceLastInterval = 0
+	r.Unlock()
+	return float64(count), nil
+}
@@ -49,6 +49,19 @@ var _ = Describe("MetricsRecorder", func() {
 		recorder.RecordRegisterMessageReceived()
 		Expect(recorder.GetRegisterMessagesReceived()).To(Equal(float64(4)))
 	})
+
+	It("should return the register and unregister messages received since the last time it was asked", func() {
+		recorder.RecordRegisterMessageReceived()
+		recorder.RecordRegisterMessageReceived()
+		recorder.RecordUnregisterMessageReceived()
+		Expect(recorder.GetRegisterMessagesSinceLastInterval()).To(Equal(float64(2)))
+		Expect(recorder.GetUnregisterMessagesSinceLastInterval()).To(Equal(float64(1)))
+
+		recorder.RecordRegisterMessageReceived()
+		Expect(recorder.GetRegisterMessagesSinceLastInterval()).To(Equal(float64(1)))
+		Expect(recorder.GetUnregisterMessagesSinceLastInterval()).To(Equal(float64(0)))
+		Expect(recorder.GetRegisterMessagesReceived()).To(Equal(float64(3)))
+	})
 })
 
 func secondToNanosecond(sec int) int64 {
@@ -2,10 +2,15 @@ package mbus
 
 import "github.com/nats-io/go-nats"
 
+// NatsConnWithUrlProvider connects to the NATS servers in Url, a comma
+// separated list. Options, such as TLS or credentials, are applied to every
+// connection along with the options passed to Connection.
 type NatsConnWithUrlProvider struct {
-	Url string
+	Url     string
+	Options []nats.Option
 }
 
 func (p *NatsConnWithUrlProvider) Connection(opts ...nats.Option) (NatsConn, error) {
-	return nats.Connect(p.Url, opts...)
+	options := append([]nats.Option{}, p.Options...)
+	return nats.Connect(p.Url, append(options, opts...)...)
 }
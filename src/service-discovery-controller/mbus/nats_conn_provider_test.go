@@ -47,4 +47,19 @@ var _ = Describe("NatsConnProvider", func() {
 
 		Expect(natsCon.Opts.Timeout).To(Equal(42 * time.Second))
 	})
+
+	Context("when the provider has options", func() {
+		BeforeEach(func() {
+			provider.(*NatsConnWithUrlProvider).Options = []nats.Option{nats.Name("service-discovery-controller")}
+		})
+
+		It("applies them along with the options passed in", func() {
+			conn, err := provider.Connection(nats.Timeout(42 * time.Second))
+			Expect(err).NotTo(HaveOccurred())
+			natsCon = conn.(*nats.Conn)
+
+			Expect(natsCon.Opts.Name).To(Equal("service-discovery-controller"))
+			Expect(natsCon.Opts.Timeout).To(Equal(42 * time.Second))
+		})
+	})
 })
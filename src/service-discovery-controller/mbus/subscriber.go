@@ -12,6 +12,8 @@ import (
 
 	"os"
 
+	"service-discovery-controller/addresstable"
+
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/lager"
 	"github.com/nats-io/go-nats"
@@ -34,19 +36,41 @@ type SubscriberOpts struct {
 
 type RegistryMessage struct {
 	IP                string   `json:"host"`
+	Port              uint16   `json:"port"`
 	InfraNames        []string `json:"uris"`
 	EndpointUpdatedAt int64    `json:"endpoint_updated_at_ns"`
+	CellID            string   `json:"cell_id"`
+	AvailabilityZone  string   `json:"availability_zone"`
+	App               string   `json:"app"`
+	Healthy           *bool    `json:"healthy"`
+}
+
+func (m *RegistryMessage) endpoint() addresstable.Endpoint {
+	return addresstable.Endpoint{
+		IP:     m.IP,
+		Port:   m.Port,
+		CellID: m.CellID,
+		AZ:     m.AvailabilityZone,
+		AppID:  m.App,
+		// Emitters that do not report health are taken to be healthy.
+		Unhealthy: m.Healthy != nil && !*m.Healthy,
+	}
 }
 
 //go:generate counterfeiter -o fakes/address_table.go --fake-name AddressTable . AddressTable
 type AddressTable interface {
-	Add(infraNames []string, ip string)
-	Remove(infraNames []string, ip string)
+	AddEndpoint(infraNames []string, endpoint addresstable.Endpoint)
+	RemoveEndpoint(infraNames []string, endpoint addresstable.Endpoint)
 	PausePruning()
 	ResumePruning()
 	SetWarm()
 }
 
+//go:generate counterfeiter -o fakes/registration_policy.go --fake-name RegistrationPolicy . RegistrationPolicy
+type RegistrationPolicy interface {
+	AllowsRegistration(hostname, appID string) bool
+}
+
 type Subscriber struct {
 	natsConnProvider NatsConnProvider
 	subOpts          SubscriberOpts
@@ -54,8 +78,11 @@ type Subscriber struct {
 	table            AddressTable
 	logger           lager.Logger
 	recorder         routeMessageRecorder
+	policy           RegistrationPolicy
 	localIP          string
 	natsClient       NatsConn
+	retired          map[NatsConn]bool
+	mutex            sync.Mutex
 	once             sync.Once
 	clock            clock.Clock
 }
@@ -78,6 +105,7 @@ type NatsConnProvider interface {
 type routeMessageRecorder interface {
 	RecordMessageTransitTime(time int64)
 	RecordRegisterMessageReceived()
+	RecordUnregisterMessageReceived()
 }
 
 func NewSubscriber(
@@ -87,6 +115,7 @@ func NewSubscriber(
 	table AddressTable,
 	localIP string,
 	recorder routeMessageRecorder,
+	policy RegistrationPolicy,
 	logger lager.Logger,
 	clock clock.Clock,
 ) *Subscriber {
@@ -96,8 +125,10 @@ func NewSubscriber(
 		warmingDuration:  warmingDuration,
 		table:            table,
 		recorder:         recorder,
+		policy:           policy,
 		logger:           logger,
 		localIP:          localIP,
+		retired:          map[NatsConn]bool{},
 		clock:            clock,
 	}
 }
@@ -123,70 +154,14 @@ func (s *Subscriber) RunOnce() error {
 	var err error
 	s.once.Do(func() {
 		var natsClient NatsConn
-		natsClient, err = s.natsConnProvider.Connection(
-			nats.ReconnectHandler(nats.ConnHandler(func(conn *nats.Conn) {
-				{
-					connectedUrl, err := url.Parse(conn.ConnectedUrl())
-					if err == nil {
-						s.logger.Info(
-							"ReconnectHandler reconnected to nats server",
-							lager.Data{"nats_host": connectedUrl.Scheme + "://" + connectedUrl.Host}, //don't leak creds
-						)
-					}
-				}
-
-				s.table.ResumePruning()
-
-				s.sendStartMessage()
-			})),
-			nats.DisconnectHandler(nats.ConnHandler(func(conn *nats.Conn) {
-				s.logger.Info(
-					"DisconnectHandler disconnected from nats server",
-					lager.Data{"last_error": conn.LastError()},
-				)
-
-				s.table.PausePruning()
-			})),
-			nats.ClosedHandler(nats.ConnHandler(func(conn *nats.Conn) {
-				s.logger.Info(
-					"ClosedHandler unexpected close of nats connection",
-					lager.Data{"last_error": conn.LastError()},
-				)
-			})),
-			nats.MaxReconnects(-1),
-		)
-
+		natsClient, err = s.connect(s.natsConnProvider)
 		if err != nil {
-			err = errors.Wrap(err, "unable to create nats connection")
 			return
 		}
 
+		s.mutex.Lock()
 		s.natsClient = natsClient
-
-		{
-			connectedUrl, err := url.Parse(natsClient.ConnectedUrl())
-			if err == nil {
-				s.logger.Info(
-					"Connected to NATS server",
-					lager.Data{"nats_host": connectedUrl.Scheme + "://" + connectedUrl.Host},
-				)
-			}
-		}
-
-		err = s.sendStartMessage()
-		if err != nil {
-			return
-		}
-
-		err = s.setupGreetMsgHandler()
-		if err != nil {
-			return
-		}
-
-		err = s.setupAddressMessageHandler()
-		if err != nil {
-			return
-		}
+		s.mutex.Unlock()
 
 		go func() {
 			<-s.clock.After(s.warmingDuration)
@@ -201,13 +176,157 @@ func (s *Subscriber) RunOnce() error {
 	return err
 }
 
-func (s *Subscriber) sendStartMessage() error {
+// Reconnect connects to the NATS servers of provider, subscribes there and
+// sends a start message, and only then closes the current connection, so
+// that route emitters register with the new servers before the old ones
+// are left. The current connection is kept when connecting fails. The
+// table stays warm, and closing the old connection does not pause pruning.
+func (s *Subscriber) Reconnect(provider NatsConnProvider) error {
+	natsClient, err := s.connect(provider)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	previous := s.natsClient
+	s.natsClient = natsClient
+	s.natsConnProvider = provider
+	if previous != nil {
+		s.retired[previous] = true
+	}
+	s.mutex.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+	return nil
+}
+
+// connect returns a connection to the NATS servers of provider that is
+// subscribed to greet, register and unregister messages, after sending a
+// start message on it. The connection is closed when any of these fail.
+func (s *Subscriber) connect(provider NatsConnProvider) (NatsConn, error) {
+	natsClient, err := provider.Connection(
+		nats.ReconnectHandler(nats.ConnHandler(func(conn *nats.Conn) {
+			if s.isRetired(conn) {
+				return
+			}
+
+			{
+				connectedUrl, err := url.Parse(conn.ConnectedUrl())
+				if err == nil {
+					s.logger.Info(
+						"ReconnectHandler reconnected to nats server",
+						lager.Data{"nats_host": connectedUrl.Scheme + "://" + connectedUrl.Host}, //don't leak creds
+					)
+				}
+			}
+
+			s.table.ResumePruning()
+
+			s.sendStartMessage(conn)
+		})),
+		nats.DisconnectHandler(nats.ConnHandler(func(conn *nats.Conn) {
+			if s.isRetired(conn) {
+				return
+			}
+
+			s.logger.Info(
+				"DisconnectHandler disconnected from nats server",
+				lager.Data{"last_error": conn.LastError()},
+			)
+
+			s.table.PausePruning()
+		})),
+		nats.ClosedHandler(nats.ConnHandler(func(conn *nats.Conn) {
+			if s.forgetRetired(conn) {
+				s.logger.Info("ClosedHandler closed previous nats connection")
+				return
+			}
+
+			s.logger.Info(
+				"ClosedHandler unexpected close of nats connection",
+				lager.Data{"last_error": conn.LastError()},
+			)
+		})),
+		nats.MaxReconnects(-1),
+	)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create nats connection")
+	}
+
+	{
+		connectedUrl, err := url.Parse(natsClient.ConnectedUrl())
+		if err == nil {
+			s.logger.Info(
+				"Connected to NATS server",
+				lager.Data{"nats_host": connectedUrl.Scheme + "://" + connectedUrl.Host},
+			)
+		}
+	}
+
+	err = s.sendStartMessage(natsClient)
+	if err == nil {
+		err = s.setupGreetMsgHandler(natsClient)
+	}
+	if err == nil {
+		err = s.setupAddressMessageHandler(natsClient)
+	}
+	if err != nil {
+		natsClient.Close()
+		return nil, err
+	}
+
+	return natsClient, nil
+}
+
+// isRetired returns whether conn was replaced by Reconnect, so that its
+// handlers leave the table alone.
+func (s *Subscriber) isRetired(conn *nats.Conn) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.retired[conn]
+}
+
+// forgetRetired returns whether conn was replaced by Reconnect, and stops
+// tracking it. It is called when conn is closed, after which its handlers
+// are not called again.
+func (s *Subscriber) forgetRetired(conn *nats.Conn) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	retired := s.retired[conn]
+	delete(s.retired, conn)
+	return retired
+}
+
+// SetRegistrationPolicy sets the policy that decides which hostnames an app
+// may register and unregister, for the messages received from then on.
+func (s *Subscriber) SetRegistrationPolicy(policy RegistrationPolicy) {
+	s.mutex.Lock()
+	s.policy = policy
+	s.mutex.Unlock()
+}
+
+func (s *Subscriber) registrationPolicy() RegistrationPolicy {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.policy
+}
+
+func (s *Subscriber) client() NatsConn {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.natsClient
+}
+
+func (s *Subscriber) sendStartMessage(natsClient NatsConn) error {
 	msg := &nats.Msg{
 		Subject: "service-discovery.start",
 		Data:    s.subscriptionOptionsJSON(),
 	}
 
-	err := s.natsClient.PublishMsg(msg)
+	err := natsClient.PublishMsg(msg)
 	if err != nil {
 		return errors.Wrap(err, "unable to publish a start message")
 	}
@@ -219,17 +338,43 @@ func (s *Subscriber) sendStartMessage() error {
 	return nil
 }
 
+// NatsStatus is the state of the connection to NATS. Server is the URL of
+// the server connected to, without credentials.
+type NatsStatus struct {
+	Connected bool
+	Server    string
+}
+
+// NatsStatus returns the state of the connection to NATS. It is only
+// connected once the subscriber has run.
+func (s *Subscriber) NatsStatus() NatsStatus {
+	natsClient := s.client()
+	if natsClient == nil {
+		return NatsStatus{}
+	}
+
+	connectedUrl, err := url.Parse(natsClient.ConnectedUrl())
+	if err != nil || connectedUrl.Host == "" {
+		return NatsStatus{}
+	}
+	return NatsStatus{
+		Connected: true,
+		Server:    connectedUrl.Scheme + "://" + connectedUrl.Host,
+	}
+}
+
 func (s *Subscriber) Close() {
-	if s.natsClient != nil {
-		s.natsClient.Close()
+	natsClient := s.client()
+	if natsClient != nil {
+		natsClient.Close()
 	}
 }
 
-func (s *Subscriber) setupGreetMsgHandler() error {
+func (s *Subscriber) setupGreetMsgHandler(natsClient NatsConn) error {
 	discoveryMessageJson := s.subscriptionOptionsJSON()
 
-	_, err := s.natsClient.Subscribe("service-discovery.greet", nats.MsgHandler(func(greetMsg *nats.Msg) {
-		err := s.natsClient.PublishMsg(&nats.Msg{
+	_, err := natsClient.Subscribe("service-discovery.greet", nats.MsgHandler(func(greetMsg *nats.Msg) {
+		err := natsClient.PublishMsg(&nats.Msg{
 			Subject: greetMsg.Reply,
 			Data:    discoveryMessageJson,
 		})
@@ -246,7 +391,7 @@ func (s *Subscriber) setupGreetMsgHandler() error {
 		return err
 	}
 
-	err = s.natsClient.Flush()
+	err = natsClient.Flush()
 	if err != nil {
 		s.logger.Error("setupGreetMsgHandler unable to flush subscribe greet message", err)
 		return err
@@ -255,8 +400,31 @@ func (s *Subscriber) setupGreetMsgHandler() error {
 	return nil
 }
 
-func (s *Subscriber) setupAddressMessageHandler() error {
-	_, err := s.natsClient.Subscribe("service-discovery.register", nats.MsgHandler(func(msg *nats.Msg) {
+// allowedInfraNames returns the hostnames of the message that its app may
+// register or unregister, and logs the rest.
+func (s *Subscriber) allowedInfraNames(registryMessage *RegistryMessage) []string {
+	policy := s.registrationPolicy()
+	allowed := []string{}
+	rejected := []string{}
+	for _, infraName := range registryMessage.InfraNames {
+		if policy.AllowsRegistration(infraName, registryMessage.App) {
+			allowed = append(allowed, infraName)
+		} else {
+			rejected = append(rejected, infraName)
+		}
+	}
+
+	if len(rejected) > 0 {
+		s.logger.Info("AddressMessageHandler rejected hostnames the app may not register", lager.Data(map[string]interface{}{
+			"app":       registryMessage.App,
+			"hostnames": rejected,
+		}))
+	}
+	return allowed
+}
+
+func (s *Subscriber) setupAddressMessageHandler(natsClient NatsConn) error {
+	_, err := natsClient.Subscribe("service-discovery.register", nats.MsgHandler(func(msg *nats.Msg) {
 		registryMessage := &RegistryMessage{}
 		err := json.Unmarshal(msg.Data, registryMessage)
 		if err != nil || registryMessage.IP == "" || len(registryMessage.InfraNames) == 0 {
@@ -272,7 +440,11 @@ func (s *Subscriber) setupAddressMessageHandler() error {
 		s.logger.Debug("AddressMessageHandler register msg received", lager.Data(map[string]interface{}{
 			"msgJson": string(msg.Data),
 		}))
-		s.table.Add(registryMessage.InfraNames, registryMessage.IP)
+		infraNames := s.allowedInfraNames(registryMessage)
+		if len(infraNames) == 0 {
+			return
+		}
+		s.table.AddEndpoint(infraNames, registryMessage.endpoint())
 	}))
 
 	if err != nil {
@@ -280,7 +452,7 @@ func (s *Subscriber) setupAddressMessageHandler() error {
 		return err
 	}
 
-	_, err = s.natsClient.Subscribe("service-discovery.unregister", nats.MsgHandler(func(msg *nats.Msg) {
+	_, err = natsClient.Subscribe("service-discovery.unregister", nats.MsgHandler(func(msg *nats.Msg) {
 		registryMessage := &RegistryMessage{}
 		err := json.Unmarshal(msg.Data, registryMessage)
 		if err != nil || len(registryMessage.InfraNames) == 0 {
@@ -289,10 +461,15 @@ func (s *Subscriber) setupAddressMessageHandler() error {
 			}))
 			return
 		}
+		s.recorder.RecordUnregisterMessageReceived()
 		s.logger.Debug("AddressMessageHandler unregister msg received", lager.Data(map[string]interface{}{
 			"msgJson": string(msg.Data),
 		}))
-		s.table.Remove(registryMessage.InfraNames, registryMessage.IP)
+		infraNames := s.allowedInfraNames(registryMessage)
+		if len(infraNames) == 0 {
+			return
+		}
+		s.table.RemoveEndpoint(infraNames, registryMessage.endpoint())
 	}))
 
 	if err != nil {
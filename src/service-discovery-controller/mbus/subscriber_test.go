@@ -7,6 +7,7 @@ import (
 
 	"time"
 
+	"service-discovery-controller/addresstable"
 	"service-discovery-controller/mbus/fakes"
 
 	"code.cloudfoundry.org/cf-networking-helpers/testsupport/ports"
@@ -22,22 +23,23 @@ import (
 
 var _ = Describe("Subscriber", func() {
 	var (
-		gnatsServer      *server.Server
-		fakeRouteEmitter *nats.Conn
-		subscriber       *Subscriber
-		subOpts          SubscriberOpts
-		natsUrl          string
-		addressTable     *fakes.AddressTable
-		subcriberLogger  lager.Logger
-		localIP          string
-		startMsgChan     chan *nats.Msg
-		greetMsgChan     chan *nats.Msg
-		metricsSender    *fakes.MetricsSender
-		messageRecorder  *fakes.RouteMessageRecorder
-		provider         NatsConnProvider
-		port             int
-		fakeClock        *fakeclock.FakeClock
-		warmingDuration  time.Duration
+		gnatsServer        *server.Server
+		fakeRouteEmitter   *nats.Conn
+		subscriber         *Subscriber
+		subOpts            SubscriberOpts
+		natsUrl            string
+		addressTable       *fakes.AddressTable
+		subcriberLogger    lager.Logger
+		localIP            string
+		startMsgChan       chan *nats.Msg
+		greetMsgChan       chan *nats.Msg
+		metricsSender      *fakes.MetricsSender
+		messageRecorder    *fakes.RouteMessageRecorder
+		registrationPolicy *fakes.RegistrationPolicy
+		provider           NatsConnProvider
+		port               int
+		fakeClock          *fakeclock.FakeClock
+		warmingDuration    time.Duration
 	)
 
 	BeforeEach(func() {
@@ -67,6 +69,8 @@ var _ = Describe("Subscriber", func() {
 			PruneThresholdInSeconds:          120,
 		}
 		messageRecorder = &fakes.RouteMessageRecorder{}
+		registrationPolicy = &fakes.RegistrationPolicy{}
+		registrationPolicy.AllowsRegistrationReturns(true)
 
 		addressTable = &fakes.AddressTable{}
 		subcriberLogger = NewLogger("test")
@@ -78,7 +82,7 @@ var _ = Describe("Subscriber", func() {
 		metricsSender = &fakes.MetricsSender{}
 		warmingDuration = time.Duration(60) * time.Second
 
-		subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, subcriberLogger, fakeClock)
+		subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, registrationPolicy, subcriberLogger, fakeClock)
 		Expect(subscriber.RunOnce()).ToNot(HaveOccurred())
 	})
 
@@ -168,6 +172,13 @@ var _ = Describe("Subscriber", func() {
 		})
 	})
 
+	It("reports the server it is connected to without credentials", func() {
+		Expect(subscriber.NatsStatus()).To(Equal(NatsStatus{
+			Connected: true,
+			Server:    "nats://" + gnatsServer.Addr().String(),
+		}))
+	})
+
 	Context("when nats client connection is closed", func() {
 		BeforeEach(func() {
 			subscriber.Close()
@@ -196,6 +207,9 @@ var _ = Describe("Subscriber", func() {
 		It("tells the address table stop pruning", func() {
 			Eventually(addressTable.PausePruningCallCount).Should(Equal(1))
 		})
+		It("reports that it is not connected", func() {
+			Eventually(subscriber.NatsStatus, 5*time.Second).Should(Equal(NatsStatus{}))
+		})
 	})
 
 	Context("when subscriber loses nats server connectivity and then regains connectivity", func() {
@@ -249,6 +263,76 @@ var _ = Describe("Subscriber", func() {
 		})
 	})
 
+	Context("when reconnected to other nats servers", func() {
+		var (
+			otherGnatsServer  *server.Server
+			otherRouteEmitter *nats.Conn
+			otherStartMsgChan chan *nats.Msg
+			otherProvider     NatsConnProvider
+		)
+
+		BeforeEach(func() {
+			otherGnatsServer = RunServerOnPort(ports.PickAPort())
+			otherGnatsServer.Start()
+
+			otherNatsUrl := "nats://username:password@" + otherGnatsServer.Addr().String()
+			otherRouteEmitter = newFakeRouteEmitter(otherNatsUrl)
+
+			otherStartMsgChan = make(chan *nats.Msg, 1)
+			_, err := otherRouteEmitter.ChanSubscribe("service-discovery.start", otherStartMsgChan)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(otherRouteEmitter.Flush()).To(Succeed())
+
+			otherProvider = &NatsConnWithUrlProvider{Url: otherNatsUrl}
+		})
+
+		AfterEach(func() {
+			otherRouteEmitter.Close()
+			otherGnatsServer.Shutdown()
+		})
+
+		It("sends a start message to the new servers and subscribes there", func() {
+			Expect(subscriber.Reconnect(otherProvider)).To(Succeed())
+			Eventually(otherStartMsgChan, 4).Should(Receive())
+
+			Expect(otherRouteEmitter.Publish("service-discovery.register", []byte(`{"host":"192.168.0.2","uris":["foo.com"]}`))).To(Succeed())
+			Expect(otherRouteEmitter.Flush()).To(Succeed())
+			Eventually(addressTable.AddEndpointCallCount).Should(Equal(1))
+
+			Expect(subscriber.NatsStatus()).To(Equal(NatsStatus{
+				Connected: true,
+				Server:    "nats://" + otherGnatsServer.Addr().String(),
+			}))
+		})
+
+		It("closes the previous connection without pausing pruning", func() {
+			Expect(subscriber.Reconnect(otherProvider)).To(Succeed())
+
+			Eventually(subcriberLogger).Should(HaveLogged(
+				Info(
+					Message("test.ClosedHandler closed previous nats connection"),
+				)))
+			Consistently(addressTable.PausePruningCallCount).Should(Equal(0))
+
+			Expect(fakeRouteEmitter.Publish("service-discovery.register", []byte(`{"host":"192.168.0.2","uris":["foo.com"]}`))).To(Succeed())
+			Expect(fakeRouteEmitter.Flush()).To(Succeed())
+			Consistently(addressTable.AddEndpointCallCount).Should(Equal(0))
+		})
+
+		Context("when connecting to the new servers fails", func() {
+			It("returns an error and keeps the current connection", func() {
+				badProvider := &fakes.NatsConnProvider{}
+				badProvider.ConnectionReturns(nil, errors.New("CANT"))
+
+				Expect(subscriber.Reconnect(badProvider)).To(MatchError("unable to create nats connection: CANT"))
+
+				Expect(fakeRouteEmitter.Publish("service-discovery.register", []byte(`{"host":"192.168.0.2","uris":["foo.com"]}`))).To(Succeed())
+				Expect(fakeRouteEmitter.Flush()).To(Succeed())
+				Eventually(addressTable.AddEndpointCallCount).Should(Equal(1))
+			})
+		})
+	})
+
 	Context("when a registration message is received", func() {
 		It("should write it to the address table", func() {
 			natsRegistryMsg := nats.Msg{
@@ -261,13 +345,72 @@ var _ = Describe("Subscriber", func() {
 
 			Eventually(func() int {
 				fakeRouteEmitter.PublishMsg(&natsRegistryMsg)
-				return addressTable.AddCallCount()
+				return addressTable.AddEndpointCallCount()
 			}).Should(Equal(1))
 
-			hostnames, ip := addressTable.AddArgsForCall(0)
+			hostnames, endpoint := addressTable.AddEndpointArgsForCall(0)
 
 			Expect(hostnames).To(Equal([]string{"foo.com", "0.foo.com"}))
-			Expect(ip).To(Equal("192.168.0.1"))
+			Expect(endpoint).To(Equal(addresstable.Endpoint{IP: "192.168.0.1"}))
+		})
+
+		It("should write the port to the address table", func() {
+			natsRegistryMsg := nats.Msg{
+				Subject: "service-discovery.register",
+				Data: []byte(`{
+					"host": "192.168.0.1",
+					"port": 8080,
+					"uris": ["foo.com", "0.foo.com"]
+				}`),
+			}
+
+			Eventually(func() int {
+				fakeRouteEmitter.PublishMsg(&natsRegistryMsg)
+				return addressTable.AddEndpointCallCount()
+			}).Should(Equal(1))
+
+			_, endpoint := addressTable.AddEndpointArgsForCall(0)
+			Expect(endpoint).To(Equal(addresstable.Endpoint{IP: "192.168.0.1", Port: 8080}))
+		})
+
+		It("should write the cell, availability zone and app to the address table", func() {
+			natsRegistryMsg := nats.Msg{
+				Subject: "service-discovery.register",
+				Data: []byte(`{
+					"host": "192.168.0.1",
+					"uris": ["foo.com"],
+					"cell_id": "cell-1",
+					"availability_zone": "z1",
+					"app": "app-1"
+				}`),
+			}
+
+			Eventually(func() int {
+				fakeRouteEmitter.PublishMsg(&natsRegistryMsg)
+				return addressTable.AddEndpointCallCount()
+			}).Should(Equal(1))
+
+			_, endpoint := addressTable.AddEndpointArgsForCall(0)
+			Expect(endpoint).To(Equal(addresstable.Endpoint{IP: "192.168.0.1", CellID: "cell-1", AZ: "z1", AppID: "app-1"}))
+		})
+
+		It("should write whether the instance is unhealthy to the address table", func() {
+			natsRegistryMsg := nats.Msg{
+				Subject: "service-discovery.register",
+				Data: []byte(`{
+					"host": "192.168.0.1",
+					"uris": ["foo.com"],
+					"healthy": false
+				}`),
+			}
+
+			Eventually(func() int {
+				fakeRouteEmitter.PublishMsg(&natsRegistryMsg)
+				return addressTable.AddEndpointCallCount()
+			}).Should(Equal(1))
+
+			_, endpoint := addressTable.AddEndpointArgsForCall(0)
+			Expect(endpoint).To(Equal(addresstable.Endpoint{IP: "192.168.0.1", Unhealthy: true}))
 		})
 
 		It("should record the time it took to get from BBS to the SDC", func() {
@@ -342,7 +485,7 @@ var _ = Describe("Subscriber", func() {
 						Data("msgJson", json),
 					)))
 
-				Expect(addressTable.AddCallCount()).To(Equal(0))
+				Expect(addressTable.AddEndpointCallCount()).To(Equal(0))
 			})
 		})
 
@@ -365,7 +508,7 @@ var _ = Describe("Subscriber", func() {
 						Data("msgJson", json),
 					)))
 
-				Expect(addressTable.AddCallCount()).To(Equal(0))
+				Expect(addressTable.AddEndpointCallCount()).To(Equal(0))
 			})
 		})
 
@@ -388,7 +531,82 @@ var _ = Describe("Subscriber", func() {
 						Data("msgJson", json),
 					)))
 
-				Expect(addressTable.AddCallCount()).To(Equal(0))
+				Expect(addressTable.AddEndpointCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the app may not register some of the hostnames", func() {
+			BeforeEach(func() {
+				registrationPolicy.AllowsRegistrationStub = func(hostname, appID string) bool {
+					return hostname == "foo.com"
+				}
+			})
+
+			It("only writes the allowed hostnames and logs the rest", func() {
+				natsRegistryMsg := nats.Msg{
+					Subject: "service-discovery.register",
+					Data: []byte(`{
+						"host": "192.168.0.1",
+						"uris": ["foo.com", "0.foo.com"],
+						"app": "app-1"
+					}`),
+				}
+
+				Eventually(func() int {
+					fakeRouteEmitter.PublishMsg(&natsRegistryMsg)
+					return addressTable.AddEndpointCallCount()
+				}).Should(BeNumerically(">", 0))
+
+				hostnames, _ := addressTable.AddEndpointArgsForCall(0)
+				Expect(hostnames).To(Equal([]string{"foo.com"}))
+
+				hostname, appID := registrationPolicy.AllowsRegistrationArgsForCall(1)
+				Expect(hostname).To(Equal("0.foo.com"))
+				Expect(appID).To(Equal("app-1"))
+
+				Expect(subcriberLogger).To(HaveLogged(
+					Info(
+						Message("test.AddressMessageHandler rejected hostnames the app may not register"),
+						Data("app", "app-1"),
+					)))
+			})
+		})
+
+		Context("when the app may not register any of the hostnames", func() {
+			It("should not add", func() {
+				registrationPolicy.AllowsRegistrationReturns(false)
+				natsRegistryMsg := nats.Msg{
+					Subject: "service-discovery.register",
+					Data:    []byte(`{"host": "192.168.0.1", "uris": ["foo.com"]}`),
+				}
+
+				Eventually(func() int {
+					fakeRouteEmitter.PublishMsg(&natsRegistryMsg)
+					return registrationPolicy.AllowsRegistrationCallCount()
+				}).Should(BeNumerically(">", 0))
+
+				Consistently(addressTable.AddEndpointCallCount).Should(Equal(0))
+			})
+		})
+
+		Context("when the registration policy is replaced", func() {
+			It("checks the hostnames of later messages with the new policy", func() {
+				newPolicy := &fakes.RegistrationPolicy{}
+				newPolicy.AllowsRegistrationReturns(false)
+				subscriber.SetRegistrationPolicy(newPolicy)
+
+				natsRegistryMsg := nats.Msg{
+					Subject: "service-discovery.register",
+					Data:    []byte(`{"host": "192.168.0.1", "uris": ["foo.com"]}`),
+				}
+
+				Eventually(func() int {
+					fakeRouteEmitter.PublishMsg(&natsRegistryMsg)
+					return newPolicy.AllowsRegistrationCallCount()
+				}).Should(BeNumerically(">", 0))
+
+				Expect(registrationPolicy.AllowsRegistrationCallCount()).To(Equal(0))
+				Consistently(addressTable.AddEndpointCallCount).Should(Equal(0))
 			})
 		})
 	})
@@ -405,12 +623,27 @@ var _ = Describe("Subscriber", func() {
 
 			Eventually(func() int {
 				fakeRouteEmitter.PublishMsg(&natsUnRegisterMsg)
-				return addressTable.RemoveCallCount()
+				return addressTable.RemoveEndpointCallCount()
 			}).Should(Equal(1))
 
-			uris, host := addressTable.RemoveArgsForCall(0)
+			uris, endpoint := addressTable.RemoveEndpointArgsForCall(0)
 			Expect(uris).To(Equal([]string{"foo.com", "0.foo.com"}))
-			Expect(host).To(Equal("192.168.0.1"))
+			Expect(endpoint).To(Equal(addresstable.Endpoint{IP: "192.168.0.1"}))
+		})
+
+		It("should record the unregister message", func() {
+			natsUnRegisterMsg := nats.Msg{
+				Subject: "service-discovery.unregister",
+				Data: []byte(`{
+					"host": "192.168.0.1",
+					"uris": ["foo.com", "0.foo.com"]
+				}`),
+			}
+
+			Eventually(func() int {
+				fakeRouteEmitter.PublishMsg(&natsUnRegisterMsg)
+				return messageRecorder.RecordUnregisterMessageReceivedCallCount()
+			}).Should(Equal(1))
 		})
 
 		It("should log the message", func() {
@@ -451,7 +684,7 @@ var _ = Describe("Subscriber", func() {
 						Data("msgJson", json),
 					)))
 
-				Expect(addressTable.RemoveCallCount()).To(Equal(0))
+				Expect(addressTable.RemoveEndpointCallCount()).To(Equal(0))
 			})
 		})
 
@@ -467,10 +700,10 @@ var _ = Describe("Subscriber", func() {
 
 				Eventually(func() int {
 					fakeRouteEmitter.PublishMsg(&natsUnRegisterMsg)
-					return addressTable.RemoveCallCount()
+					return addressTable.RemoveEndpointCallCount()
 				}).Should(BeNumerically(">", 0))
 
-				Expect(addressTable.RemoveArgsForCall(0)).To(Equal([]string{"foo.com", "0.foo.com"}))
+				Expect(addressTable.RemoveEndpointArgsForCall(0)).To(Equal([]string{"foo.com", "0.foo.com"}))
 			})
 		})
 
@@ -491,7 +724,27 @@ var _ = Describe("Subscriber", func() {
 						Data("msgJson", json),
 					)))
 
-				Expect(addressTable.RemoveCallCount()).To(Equal(0))
+				Expect(addressTable.RemoveEndpointCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the app may not register some of the hostnames", func() {
+			It("only removes the allowed hostnames", func() {
+				registrationPolicy.AllowsRegistrationStub = func(hostname, appID string) bool {
+					return hostname == "0.foo.com"
+				}
+				natsUnRegisterMsg := nats.Msg{
+					Subject: "service-discovery.unregister",
+					Data:    []byte(`{"host": "192.168.0.1", "uris": ["foo.com", "0.foo.com"]}`),
+				}
+
+				Eventually(func() int {
+					fakeRouteEmitter.PublishMsg(&natsUnRegisterMsg)
+					return addressTable.RemoveEndpointCallCount()
+				}).Should(BeNumerically(">", 0))
+
+				uris, _ := addressTable.RemoveEndpointArgsForCall(0)
+				Expect(uris).To(Equal([]string{"0.foo.com"}))
 			})
 		})
 	})
@@ -514,7 +767,7 @@ var _ = Describe("Subscriber", func() {
 				provider.ConnectionReturns(natsConn, errors.New("CANT"))
 
 				subscriber.Close()
-				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, subcriberLogger, fakeClock)
+				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, registrationPolicy, subcriberLogger, fakeClock)
 			})
 
 			It("run returns an error", func() {
@@ -527,7 +780,7 @@ var _ = Describe("Subscriber", func() {
 		Context("when the nats server goes down for an extended amount of time", func() {
 			BeforeEach(func() {
 				subscriber.Close()
-				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, subcriberLogger, fakeClock)
+				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, registrationPolicy, subcriberLogger, fakeClock)
 			})
 
 			It("should never stop retrying to reconnect", func() {
@@ -542,7 +795,7 @@ var _ = Describe("Subscriber", func() {
 		Context("when calling run and sending start message fails", func() {
 			BeforeEach(func() {
 				natsConn.PublishMsgReturns(errors.New("NO START"))
-				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, subcriberLogger, fakeClock)
+				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, registrationPolicy, subcriberLogger, fakeClock)
 			})
 
 			It("returns an error", func() {
@@ -562,7 +815,7 @@ var _ = Describe("Subscriber", func() {
 				natsConn.PublishMsgReturnsOnCall(0, nil)
 				natsConn.SubscribeReturns(nil, errors.New("NO GREET"))
 
-				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, subcriberLogger, fakeClock)
+				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, registrationPolicy, subcriberLogger, fakeClock)
 			})
 
 			It("self closes", func() {
@@ -593,7 +846,7 @@ var _ = Describe("Subscriber", func() {
 				natsConn.PublishMsgReturnsOnCall(0, nil)
 				natsConn.SubscribeReturnsOnCall(1, nil, errors.New("NO SUBSCRIBE"))
 
-				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, subcriberLogger, fakeClock)
+				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, registrationPolicy, subcriberLogger, fakeClock)
 			})
 
 			It("returns an error", func() {
@@ -624,7 +877,7 @@ var _ = Describe("Subscriber", func() {
 				natsConn.PublishMsgReturnsOnCall(0, nil)
 				natsConn.SubscribeReturnsOnCall(2, nil, errors.New("NO SUBSCRIBE when unregister"))
 
-				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, subcriberLogger, fakeClock)
+				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, registrationPolicy, subcriberLogger, fakeClock)
 			})
 
 			It("returns an error", func() {
@@ -655,7 +908,7 @@ var _ = Describe("Subscriber", func() {
 				provider.ConnectionReturns(natsConn, nil)
 
 				subscriber.Close()
-				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, subcriberLogger, fakeClock)
+				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, registrationPolicy, subcriberLogger, fakeClock)
 				natsConn.FlushReturns(errors.New("failed to flush"))
 			})
 
@@ -682,7 +935,7 @@ var _ = Describe("Subscriber", func() {
 				provider.ConnectionReturns(natsConn, nil)
 
 				subscriber.Close()
-				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, subcriberLogger, fakeClock)
+				subscriber = NewSubscriber(provider, subOpts, warmingDuration, addressTable, localIP, messageRecorder, registrationPolicy, subcriberLogger, fakeClock)
 			})
 
 			It("should not have any side effects", func() {
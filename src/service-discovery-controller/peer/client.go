@@ -0,0 +1,167 @@
+package peer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"service-discovery-controller/addresstable"
+	"strconv"
+	"time"
+
+	"github.com/pivotal-cf/paraphernalia/secure/tlsconfig"
+)
+
+// Cursor is the position in a peer's changes up to which its table has been
+// fetched. The zero Cursor fetches the whole table.
+type Cursor struct {
+	Epoch   string
+	Version uint64
+}
+
+// Table is the address table of a peer, or what changed in it since a
+// Cursor, the endpoints recently removed from it, and whether the peer
+// considers it warm. Full is set when it is the whole table. Cursor is the
+// position to fetch the next changes from.
+type Table struct {
+	Warm     bool
+	Full     bool
+	Cursor   Cursor
+	Entries  []addresstable.SnapshotEntry
+	Removals []addresstable.Removal
+}
+
+type tableResponse struct {
+	Warm     bool              `json:"warm"`
+	Epoch    string            `json:"epoch"`
+	Version  uint64            `json:"version"`
+	Full     bool              `json:"full"`
+	Entries  []entryResponse   `json:"entries"`
+	Removals []removalResponse `json:"removals"`
+}
+
+type entryResponse struct {
+	Hostname  string `json:"hostname"`
+	IP        string `json:"ip"`
+	Port      uint16 `json:"port"`
+	CellID    string `json:"cell_id"`
+	AZ        string `json:"availability_zone"`
+	AppID     string `json:"app_id"`
+	Unhealthy bool   `json:"unhealthy"`
+	AgeNS     int64  `json:"age_ns"`
+}
+
+type removalResponse struct {
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	Port     uint16 `json:"port"`
+	AgeNS    int64  `json:"age_ns"`
+}
+
+// Client fetches address tables from other service-discovery-controller
+// instances over mutual TLS.
+type Client struct {
+	httpClient *http.Client
+}
+
+func NewClient(caPath, clientCertPath, clientKeyPath, serverName string) (*Client, error) {
+	caPemBytes, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		return nil, fmt.Errorf("read CA file: %s", err)
+	}
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caPemBytes) {
+		return nil, fmt.Errorf("load CA file into cert pool")
+	}
+
+	cert, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("load client key pair: %s", err)
+	}
+
+	tlsConfig := tlsconfig.Build(
+		tlsconfig.WithIdentity(cert),
+		tlsconfig.WithInternalServiceDefaults(),
+	).Client(tlsconfig.WithAuthority(caCertPool))
+	tlsConfig.ServerName = serverName
+
+	return &Client{
+		httpClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+			Timeout:   10 * time.Second,
+		},
+	}, nil
+}
+
+// Table fetches what changed since the cursor in the address table of the
+// peer listening on address, given as host:port. The peer sends the age of
+// each entry and removal rather than its time, which is turned back into a
+// time on this instance's clock, so that the clocks of the instances do not
+// have to agree.
+func (c *Client) Table(address string, since Cursor) (Table, error) {
+	tableURL := fmt.Sprintf("https://%s/v1/peer/table", address)
+	if since.Epoch != "" {
+		tableURL += "?" + url.Values{
+			"epoch": {since.Epoch},
+			"since": {strconv.FormatUint(since.Version, 10)},
+		}.Encode()
+	}
+
+	resp, err := c.httpClient.Get(tableURL)
+	if err != nil {
+		return Table{}, fmt.Errorf("get peer table: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Table{}, fmt.Errorf("get peer table: unexpected status code %d", resp.StatusCode)
+	}
+
+	var response tableResponse
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return Table{}, fmt.Errorf("decode peer table: %s", err)
+	}
+
+	now := time.Now()
+	table := Table{
+		Warm:     response.Warm,
+		Full:     response.Full,
+		Cursor:   Cursor{Epoch: response.Epoch, Version: response.Version},
+		Entries:  []addresstable.SnapshotEntry{},
+		Removals: []addresstable.Removal{},
+	}
+	for _, entry := range response.Entries {
+		table.Entries = append(table.Entries, addresstable.SnapshotEntry{
+			Hostname:   entry.Hostname,
+			IP:         entry.IP,
+			Port:       entry.Port,
+			CellID:     entry.CellID,
+			AZ:         entry.AZ,
+			AppID:      entry.AppID,
+			Unhealthy:  entry.Unhealthy,
+			UpdateTime: timeFromAge(now, entry.AgeNS),
+		})
+	}
+	for _, removal := range response.Removals {
+		table.Removals = append(table.Removals, addresstable.Removal{
+			Hostname:   removal.Hostname,
+			IP:         removal.IP,
+			Port:       removal.Port,
+			RemoveTime: timeFromAge(now, removal.AgeNS),
+		})
+	}
+	return table, nil
+}
+
+// timeFromAge returns the time ageNS nanoseconds before now. Negative ages
+// are treated as 0, so that a peer can never date an entry in the future.
+func timeFromAge(now time.Time, ageNS int64) time.Time {
+	if ageNS < 0 {
+		ageNS = 0
+	}
+	return now.Add(-time.Duration(ageNS))
+}
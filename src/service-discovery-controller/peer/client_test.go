@@ -0,0 +1,157 @@
+package peer_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"service-discovery-controller/addresstable"
+	"service-discovery-controller/peer"
+	"strings"
+	"test-helpers"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		client     *peer.Client
+		fakeServer *ghttp.Server
+
+		caFileName         string
+		clientCertFileName string
+		clientKeyFileName  string
+		serverCert         tls.Certificate
+	)
+
+	BeforeEach(func() {
+		caFileName, clientCertFileName, clientKeyFileName, serverCert = testhelpers.GenerateCaAndMutualTlsCerts()
+	})
+
+	AfterEach(func() {
+		os.Remove(caFileName)
+		os.Remove(clientCertFileName)
+		os.Remove(clientKeyFileName)
+	})
+
+	Describe("NewClient", func() {
+		Context("when the CA file does not exist", func() {
+			It("returns an error", func() {
+				_, err := peer.NewClient("non-existent", clientCertFileName, clientKeyFileName, "")
+				Expect(err).To(MatchError("read CA file: open non-existent: no such file or directory"))
+			})
+		})
+
+		Context("when the client key pair does not exist", func() {
+			It("returns an error", func() {
+				_, err := peer.NewClient(caFileName, "non-existent", clientKeyFileName, "")
+				Expect(err).To(MatchError("load client key pair: open non-existent: no such file or directory"))
+			})
+		})
+	})
+
+	Describe("Table", func() {
+		var address string
+
+		BeforeEach(func() {
+			fakeServer = ghttp.NewUnstartedServer()
+			fakeServer.HTTPTestServer.TLS = &tls.Config{}
+			fakeServer.HTTPTestServer.TLS.ClientCAs = testhelpers.CertPool(caFileName)
+			fakeServer.HTTPTestServer.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+			fakeServer.HTTPTestServer.TLS.Certificates = []tls.Certificate{serverCert}
+			fakeServer.HTTPTestServer.StartTLS()
+			address = strings.TrimPrefix(fakeServer.URL(), "https://")
+
+			var err error
+			client, err = peer.NewClient(caFileName, clientCertFileName, clientKeyFileName, "")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			fakeServer.Close()
+		})
+
+		It("returns the peer's table, dating entries and removals by their age", func() {
+			fakeServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v1/peer/table", ""),
+				ghttp.RespondWith(http.StatusOK, `{
+					"warm": true,
+					"epoch": "some-epoch",
+					"version": 42,
+					"full": true,
+					"entries": [
+						{"hostname": "app-id.internal.local.", "ip": "192.168.0.2", "port": 8080, "cell_id": "cell-1", "availability_zone": "z1", "app_id": "app-1", "unhealthy": true, "age_ns": 60000000000}
+					],
+					"removals": [
+						{"hostname": "app-id.internal.local.", "ip": "192.168.0.3", "port": 8080, "age_ns": 30000000000}
+					]
+				}`),
+			))
+
+			table, err := client.Table(address, peer.Cursor{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(table.Warm).To(BeTrue())
+			Expect(table.Full).To(BeTrue())
+			Expect(table.Cursor).To(Equal(peer.Cursor{Epoch: "some-epoch", Version: 42}))
+
+			Expect(table.Entries).To(HaveLen(1))
+			entry := table.Entries[0]
+			Expect(entry.UpdateTime).To(BeTemporally("~", time.Now().Add(-time.Minute), 5*time.Second))
+			entry.UpdateTime = time.Time{}
+			Expect(entry).To(Equal(addresstable.SnapshotEntry{
+				Hostname: "app-id.internal.local.", IP: "192.168.0.2", Port: 8080, CellID: "cell-1", AZ: "z1", AppID: "app-1", Unhealthy: true,
+			}))
+
+			Expect(table.Removals).To(HaveLen(1))
+			removal := table.Removals[0]
+			Expect(removal.RemoveTime).To(BeTemporally("~", time.Now().Add(-30*time.Second), 5*time.Second))
+			removal.RemoveTime = time.Time{}
+			Expect(removal).To(Equal(addresstable.Removal{Hostname: "app-id.internal.local.", IP: "192.168.0.3", Port: 8080}))
+		})
+
+		It("asks for the changes since the cursor", func() {
+			fakeServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/v1/peer/table", "epoch=some-epoch&since=42"),
+				ghttp.RespondWith(http.StatusOK, `{"warm": true, "epoch": "some-epoch", "version": 43, "full": false, "entries": [], "removals": []}`),
+			))
+
+			table, err := client.Table(address, peer.Cursor{Epoch: "some-epoch", Version: 42})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(table.Full).To(BeFalse())
+			Expect(table.Cursor).To(Equal(peer.Cursor{Epoch: "some-epoch", Version: 43}))
+		})
+
+		Context("when the peer sends a negative age", func() {
+			It("dates the entry now rather than in the future", func() {
+				fakeServer.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{
+					"warm": true,
+					"entries": [{"hostname": "app-id.internal.local.", "ip": "192.168.0.2", "age_ns": -60000000000}]
+				}`))
+
+				table, err := client.Table(address, peer.Cursor{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(table.Entries[0].UpdateTime).To(BeTemporally("~", time.Now(), 5*time.Second))
+			})
+		})
+
+		Context("when the peer responds with an error", func() {
+			It("returns an error", func() {
+				fakeServer.AppendHandlers(ghttp.RespondWith(http.StatusInternalServerError, ""))
+
+				_, err := client.Table(address, peer.Cursor{})
+				Expect(err).To(MatchError("get peer table: unexpected status code 500"))
+			})
+		})
+
+		Context("when the peer responds with invalid json", func() {
+			It("returns an error", func() {
+				fakeServer.AppendHandlers(ghttp.RespondWith(http.StatusOK, "{"))
+
+				_, err := client.Table(address, peer.Cursor{})
+				Expect(err).To(MatchError(ContainSubstring("decode peer table")))
+			})
+		})
+	})
+})
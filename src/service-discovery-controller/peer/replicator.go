@@ -0,0 +1,198 @@
+package peer
+
+import (
+	"os"
+	"service-discovery-controller/addresstable"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o fakes/address_table.go --fake-name AddressTable . AddressTable
+type AddressTable interface {
+	Snapshot() []addresstable.SnapshotEntry
+	Restore(entries []addresstable.SnapshotEntry)
+	Merge(entries []addresstable.SnapshotEntry) int
+	MergeRemovals(removals []addresstable.Removal) int
+	IsWarm() bool
+}
+
+//go:generate counterfeiter -o fakes/peer_client.go --fake-name PeerClient . PeerClient
+type PeerClient interface {
+	Table(address string, since Cursor) (Table, error)
+}
+
+// fullSyncEvery is how many syncs with a peer fetch its full table once in.
+// The others only fetch what changed since the previous one.
+const fullSyncEvery = 10
+
+// Replicator keeps the address table in line with the tables of the other
+// service-discovery-controller instances. On start it restores the table of
+// the first warm peer, so that lookups are answered before NATS has been
+// heard from. After that it merges what changed in every warm peer's table
+// on each tick, so that instances which missed NATS messages, for example
+// during a partition, converge again. The removals of each peer are merged
+// before its entries, so that an endpoint unregistered on any instance is
+// not added back by an instance that has not seen the unregister. Once
+// every fullSyncEvery ticks the full table of a peer is fetched instead,
+// to measure how far the tables have diverged.
+type Replicator struct {
+	Table    AddressTable
+	Client   PeerClient
+	Peers    []string
+	Interval time.Duration
+	Clock    clock.Clock
+	Logger   lager.Logger
+
+	cursors map[string]Cursor
+	deltas  map[string]int
+
+	mutex       sync.Mutex
+	divergences map[string]int
+}
+
+func (r *Replicator) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	r.cursors = map[string]Cursor{}
+	r.deltas = map[string]int{}
+	r.mutex.Lock()
+	r.divergences = map[string]int{}
+	r.mutex.Unlock()
+
+	r.bootstrap()
+
+	ticker := r.Clock.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	close(ready)
+
+	for {
+		select {
+		case <-ticker.C():
+			r.sync()
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// GetDivergence returns the largest number of entries that this instance
+// and a single warm peer disagreed on when their full tables were last
+// compared.
+func (r *Replicator) GetDivergence() (float64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	divergence := 0
+	for _, d := range r.divergences {
+		if d > divergence {
+			divergence = d
+		}
+	}
+	return float64(divergence), nil
+}
+
+func (r *Replicator) bootstrap() {
+	for _, address := range r.Peers {
+		table, err := r.Client.Table(address, Cursor{})
+		if err != nil {
+			r.Logger.Error("fetch-peer-table", err, lager.Data{"peer": address})
+			continue
+		}
+		if !table.Warm {
+			continue
+		}
+
+		r.Table.MergeRemovals(table.Removals)
+		r.Table.Restore(table.Entries)
+		r.cursors[address] = table.Cursor
+		r.Logger.Info("bootstrapped-from-peer", lager.Data{"peer": address, "entries": len(table.Entries)})
+		return
+	}
+}
+
+func (r *Replicator) sync() {
+	// The local table is only read once a full table is to be compared
+	// with it.
+	var local []addresstable.SnapshotEntry
+	localWarm := r.Table.IsWarm()
+
+	for _, address := range r.Peers {
+		since := r.cursors[address]
+		if r.deltas[address] >= fullSyncEvery-1 {
+			since = Cursor{}
+		}
+
+		table, err := r.Client.Table(address, since)
+		if err != nil {
+			r.Logger.Error("fetch-peer-table", err, lager.Data{"peer": address})
+			r.forget(address)
+			continue
+		}
+		if !table.Warm {
+			r.forget(address)
+			continue
+		}
+
+		if table.Full {
+			r.deltas[address] = 0
+			if localWarm {
+				if local == nil {
+					local = r.Table.Snapshot()
+				}
+				r.setDivergence(address, divergence(local, table.Entries))
+			}
+		} else {
+			r.deltas[address]++
+		}
+
+		removed := r.Table.MergeRemovals(table.Removals)
+		merged := r.Table.Merge(table.Entries)
+		r.cursors[address] = table.Cursor
+		if merged > 0 || removed > 0 {
+			r.Logger.Info("merged-peer-table", lager.Data{"peer": address, "full": table.Full, "merged": merged, "removed": removed})
+		}
+	}
+}
+
+// forget drops what is known of a peer that cannot be synced with, so that
+// the next sync fetches its full table and it does not count towards the
+// divergence until then.
+func (r *Replicator) forget(address string) {
+	delete(r.cursors, address)
+	delete(r.deltas, address)
+	r.mutex.Lock()
+	delete(r.divergences, address)
+	r.mutex.Unlock()
+}
+
+func (r *Replicator) setDivergence(address string, divergence int) {
+	r.mutex.Lock()
+	r.divergences[address] = divergence
+	r.mutex.Unlock()
+}
+
+type entryKey struct {
+	hostname string
+	ip       string
+	port     uint16
+}
+
+// divergence counts the entries that are in only one of the tables.
+func divergence(local, remote []addresstable.SnapshotEntry) int {
+	keys := map[entryKey]int{}
+	for _, entry := range local {
+		keys[entryKey{entry.Hostname, entry.IP, entry.Port}] |= 1
+	}
+	for _, entry := range remote {
+		keys[entryKey{entry.Hostname, entry.IP, entry.Port}] |= 2
+	}
+
+	count := 0
+	for _, sides := range keys {
+		if sides != 3 {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,206 @@
+package peer_test
+
+import (
+	"errors"
+	"os"
+	"service-discovery-controller/addresstable"
+	"service-discovery-controller/peer"
+	"service-discovery-controller/peer/fakes"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Replicator", func() {
+	var (
+		table      *fakes.AddressTable
+		peerClient *fakes.PeerClient
+		fakeClock  *fakeclock.FakeClock
+		logger     *lagertest.TestLogger
+		replicator *peer.Replicator
+		process    ifrit.Process
+		peerTables map[string]peer.Table
+		localEntry addresstable.SnapshotEntry
+		peerEntry  addresstable.SnapshotEntry
+		removal    addresstable.Removal
+	)
+
+	BeforeEach(func() {
+		table = &fakes.AddressTable{}
+		peerClient = &fakes.PeerClient{}
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		logger = lagertest.NewTestLogger("test")
+
+		localEntry = addresstable.SnapshotEntry{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: fakeClock.Now()}
+		peerEntry = addresstable.SnapshotEntry{Hostname: "bar.com.", IP: "192.0.0.2", UpdateTime: fakeClock.Now()}
+		removal = addresstable.Removal{Hostname: "baz.com.", IP: "192.0.0.3", RemoveTime: fakeClock.Now()}
+
+		peerTables = map[string]peer.Table{
+			"peer-a:8054": {Warm: false, Entries: []addresstable.SnapshotEntry{}},
+			"peer-b:8054": {
+				Warm:     true,
+				Full:     true,
+				Cursor:   peer.Cursor{Epoch: "epoch-b", Version: 7},
+				Entries:  []addresstable.SnapshotEntry{localEntry, peerEntry},
+				Removals: []addresstable.Removal{removal},
+			},
+		}
+		peerClient.TableStub = func(address string, since peer.Cursor) (peer.Table, error) {
+			table, ok := peerTables[address]
+			if !ok {
+				return peer.Table{}, errors.New("banana")
+			}
+			if since != (peer.Cursor{}) {
+				table.Full = false
+				table.Entries = []addresstable.SnapshotEntry{peerEntry}
+			}
+			return table, nil
+		}
+
+		replicator = &peer.Replicator{
+			Table:    table,
+			Client:   peerClient,
+			Peers:    []string{"peer-a:8054", "peer-b:8054"},
+			Interval: time.Second,
+			Clock:    fakeClock,
+			Logger:   logger,
+		}
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("bootstraps from the first warm peer", func() {
+		process = ifrit.Invoke(replicator)
+
+		Expect(table.RestoreCallCount()).To(Equal(1))
+		Expect(table.RestoreArgsForCall(0)).To(Equal([]addresstable.SnapshotEntry{localEntry, peerEntry}))
+		_, since := peerClient.TableArgsForCall(1)
+		Expect(since).To(Equal(peer.Cursor{}))
+		Expect(table.MergeRemovalsCallCount()).To(Equal(1))
+		Expect(table.MergeRemovalsArgsForCall(0)).To(Equal([]addresstable.Removal{removal}))
+		Expect(logger).To(gbytes.Say("bootstrapped-from-peer"))
+	})
+
+	Context("when no peer is warm", func() {
+		BeforeEach(func() {
+			delete(peerTables, "peer-b:8054")
+		})
+
+		It("does not restore anything", func() {
+			process = ifrit.Invoke(replicator)
+
+			Expect(table.RestoreCallCount()).To(Equal(0))
+			Expect(logger.Logs()).To(ContainElement(SatisfyAll(
+				LogsWith(lager.ERROR, "test.fetch-peer-table"),
+				HaveLogData(HaveKeyWithValue("peer", "peer-b:8054")),
+			)))
+		})
+	})
+
+	Context("on each interval", func() {
+		BeforeEach(func() {
+			table.IsWarmReturns(true)
+			table.SnapshotReturns([]addresstable.SnapshotEntry{localEntry})
+			table.MergeReturns(1)
+		})
+
+		JustBeforeEach(func() {
+			process = ifrit.Invoke(replicator)
+		})
+
+		It("merges what changed in the tables of warm peers since the last sync", func() {
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+			Eventually(table.MergeCallCount).Should(Equal(1))
+			Expect(table.MergeArgsForCall(0)).To(Equal([]addresstable.SnapshotEntry{peerEntry}))
+			address, since := peerClient.TableArgsForCall(3)
+			Expect(address).To(Equal("peer-b:8054"))
+			Expect(since).To(Equal(peer.Cursor{Epoch: "epoch-b", Version: 7}))
+			Eventually(logger).Should(gbytes.Say("merged-peer-table"))
+		})
+
+		It("fetches the full table of a peer once every ten syncs", func() {
+			for i := 1; i <= 10; i++ {
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(table.MergeCallCount).Should(Equal(i))
+			}
+
+			_, since := peerClient.TableArgsForCall(peerClient.TableCallCount() - 1)
+			Expect(since).To(Equal(peer.Cursor{}))
+			Expect(table.MergeArgsForCall(9)).To(Equal([]addresstable.SnapshotEntry{localEntry, peerEntry}))
+		})
+
+		Context("when a peer cannot be fetched", func() {
+			It("fetches its full table next time", func() {
+				peerClient.TableStub = func(address string, since peer.Cursor) (peer.Table, error) {
+					if address == "peer-b:8054" && peerClient.TableCallCount() == 4 {
+						return peer.Table{}, errors.New("banana")
+					}
+					return peerTables["peer-b:8054"], nil
+				}
+
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(peerClient.TableCallCount).Should(Equal(4))
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(peerClient.TableCallCount).Should(Equal(6))
+
+				_, since := peerClient.TableArgsForCall(5)
+				Expect(since).To(Equal(peer.Cursor{}))
+			})
+		})
+
+		It("merges the removals of warm peers before their entries", func() {
+			calls := make(chan string, 10)
+			table.MergeRemovalsStub = func([]addresstable.Removal) int {
+				calls <- "removals"
+				return 0
+			}
+			table.MergeStub = func([]addresstable.SnapshotEntry) int {
+				calls <- "entries"
+				return 0
+			}
+
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+			Eventually(calls).Should(Receive(Equal("removals")))
+			Eventually(calls).Should(Receive(Equal("entries")))
+			Expect(table.MergeRemovalsArgsForCall(1)).To(Equal([]addresstable.Removal{removal}))
+		})
+
+		It("reports how far the table has diverged from its peers when it fetches their full tables", func() {
+			peerClient.TableStub = func(address string, since peer.Cursor) (peer.Table, error) {
+				return peerTables["peer-b:8054"], nil
+			}
+
+			divergence, err := replicator.GetDivergence()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(divergence).To(Equal(0.0))
+
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+			Eventually(replicator.GetDivergence).Should(Equal(1.0))
+		})
+
+		Context("when the local table is not warm", func() {
+			BeforeEach(func() {
+				table.IsWarmReturns(false)
+			})
+
+			It("does not report divergence", func() {
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+				Eventually(table.MergeCallCount).Should(Equal(1))
+				Expect(replicator.GetDivergence()).To(Equal(0.0))
+			})
+		})
+	})
+})
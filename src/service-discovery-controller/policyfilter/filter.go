@@ -0,0 +1,106 @@
+package policyfilter
+
+import (
+	"os"
+	"policy-server/api"
+	"service-discovery-controller/addresstable"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o fakes/policy_client.go --fake-name PolicyClient . PolicyClient
+type PolicyClient interface {
+	GetPolicies() ([]api.Policy, error)
+}
+
+//go:generate counterfeiter -o fakes/address_table.go --fake-name AddressTable . AddressTable
+type AddressTable interface {
+	AppIDForIP(ip string) string
+}
+
+// Filter hides endpoints of apps that the querying app may not reach, so
+// that service discovery does not reveal apps across tenants. It polls the
+// internal API of the policy server, and allows an app to reach another
+// when a container-to-container policy has it as the source and the other
+// as the destination. The querying app is found by the IP of the container
+// that sent the query.
+//
+// Endpoints registered without an app are not filtered. Until policies
+// have been fetched, and for queries from containers of unknown apps, every
+// endpoint with an app is hidden.
+type Filter struct {
+	Client   PolicyClient
+	Table    AddressTable
+	Interval time.Duration
+	Clock    clock.Clock
+	Logger   lager.Logger
+
+	mutex   sync.RWMutex
+	allowed map[string]map[string]bool
+}
+
+func (f *Filter) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	f.sync()
+
+	ticker := f.Clock.NewTicker(f.Interval)
+	defer ticker.Stop()
+
+	close(ready)
+
+	for {
+		select {
+		case <-ticker.C():
+			f.sync()
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// Reachable returns the endpoints that the app of the container with
+// sourceIP may reach.
+func (f *Filter) Reachable(sourceIP string, endpoints []addresstable.Endpoint) []addresstable.Endpoint {
+	sourceAppID := ""
+	if sourceIP != "" {
+		sourceAppID = f.Table.AppIDForIP(sourceIP)
+	}
+
+	f.mutex.RLock()
+	allowed := f.allowed[sourceAppID]
+	f.mutex.RUnlock()
+
+	reachable := []addresstable.Endpoint{}
+	for _, endpoint := range endpoints {
+		if endpoint.AppID == "" || (sourceAppID != "" && allowed[endpoint.AppID]) {
+			reachable = append(reachable, endpoint)
+		}
+	}
+	return reachable
+}
+
+// sync replaces the allowed apps with the current policies. When they
+// cannot be fetched, the last ones are kept.
+func (f *Filter) sync() {
+	policies, err := f.Client.GetPolicies()
+	if err != nil {
+		f.Logger.Error("fetch-policies", err)
+		return
+	}
+
+	allowed := map[string]map[string]bool{}
+	for _, policy := range policies {
+		if allowed[policy.Source.ID] == nil {
+			allowed[policy.Source.ID] = map[string]bool{}
+		}
+		allowed[policy.Source.ID][policy.Destination.ID] = true
+	}
+
+	f.mutex.Lock()
+	f.allowed = allowed
+	f.mutex.Unlock()
+
+	f.Logger.Debug("fetched-policies", lager.Data{"policies": len(policies)})
+}
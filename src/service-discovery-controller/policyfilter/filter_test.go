@@ -0,0 +1,134 @@
+package policyfilter_test
+
+import (
+	"errors"
+	"os"
+	"policy-server/api"
+	"service-discovery-controller/addresstable"
+	"service-discovery-controller/policyfilter"
+	"service-discovery-controller/policyfilter/fakes"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Filter", func() {
+	var (
+		filter       *policyfilter.Filter
+		policyClient *fakes.PolicyClient
+		addressTable *fakes.AddressTable
+		fakeClock    *fakeclock.FakeClock
+		logger       *lagertest.TestLogger
+		process      ifrit.Process
+		endpoints    []addresstable.Endpoint
+	)
+
+	policy := func(source, destination string) api.Policy {
+		return api.Policy{
+			Source:      api.Source{ID: source},
+			Destination: api.Destination{ID: destination, Protocol: "tcp", Ports: api.Ports{Start: 8080, End: 8080}},
+		}
+	}
+
+	BeforeEach(func() {
+		policyClient = &fakes.PolicyClient{}
+		policyClient.GetPoliciesReturns([]api.Policy{policy("app-a", "app-b")}, nil)
+
+		addressTable = &fakes.AddressTable{}
+		addressTable.AppIDForIPStub = func(ip string) string {
+			return map[string]string{"10.0.0.1": "app-a", "10.0.0.3": "app-c"}[ip]
+		}
+
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		logger = lagertest.NewTestLogger("test")
+
+		filter = &policyfilter.Filter{
+			Client:   policyClient,
+			Table:    addressTable,
+			Interval: time.Second,
+			Clock:    fakeClock,
+			Logger:   logger,
+		}
+
+		endpoints = []addresstable.Endpoint{
+			{IP: "10.0.0.2", AppID: "app-b"},
+			{IP: "10.0.0.4", AppID: "app-d"},
+			{IP: "10.0.0.5"},
+		}
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+			process = nil
+		}
+	})
+
+	Context("before policies have been fetched", func() {
+		It("hides every endpoint with an app", func() {
+			Expect(filter.Reachable("10.0.0.1", endpoints)).To(Equal([]addresstable.Endpoint{
+				{IP: "10.0.0.5"},
+			}))
+		})
+	})
+
+	Context("once started", func() {
+		BeforeEach(func() {
+			process = ifrit.Invoke(filter)
+		})
+
+		It("returns the endpoints of apps that the source app may reach", func() {
+			Expect(filter.Reachable("10.0.0.1", endpoints)).To(Equal([]addresstable.Endpoint{
+				{IP: "10.0.0.2", AppID: "app-b"},
+				{IP: "10.0.0.5"},
+			}))
+		})
+
+		It("hides apps from sources without a policy to them", func() {
+			Expect(filter.Reachable("10.0.0.3", endpoints)).To(Equal([]addresstable.Endpoint{
+				{IP: "10.0.0.5"},
+			}))
+		})
+
+		It("hides apps from sources that are not registered", func() {
+			Expect(filter.Reachable("10.0.0.9", endpoints)).To(Equal([]addresstable.Endpoint{
+				{IP: "10.0.0.5"},
+			}))
+			Expect(filter.Reachable("", endpoints)).To(Equal([]addresstable.Endpoint{
+				{IP: "10.0.0.5"},
+			}))
+		})
+
+		It("picks up policy changes on every tick", func() {
+			policyClient.GetPoliciesReturns([]api.Policy{policy("app-c", "app-d")}, nil)
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+			Eventually(func() []addresstable.Endpoint {
+				return filter.Reachable("10.0.0.3", endpoints)
+			}).Should(Equal([]addresstable.Endpoint{
+				{IP: "10.0.0.4", AppID: "app-d"},
+				{IP: "10.0.0.5"},
+			}))
+			Expect(filter.Reachable("10.0.0.1", endpoints)).To(Equal([]addresstable.Endpoint{
+				{IP: "10.0.0.5"},
+			}))
+		})
+
+		Context("when policies cannot be fetched", func() {
+			It("keeps the last policies", func() {
+				policyClient.GetPoliciesReturns(nil, errors.New("potato"))
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+				Eventually(policyClient.GetPoliciesCallCount).Should(Equal(2))
+				Eventually(logger).Should(gbytes.Say("fetch-policies"))
+				Expect(filter.Reachable("10.0.0.1", endpoints)).To(ContainElement(addresstable.Endpoint{IP: "10.0.0.2", AppID: "app-b"}))
+			})
+		})
+	})
+})
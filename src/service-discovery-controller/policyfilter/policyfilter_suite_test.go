@@ -0,0 +1,13 @@
+package policyfilter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPolicyfilter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Policyfilter Suite")
+}
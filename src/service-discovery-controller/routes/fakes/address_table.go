@@ -2,145 +2,298 @@
 package fakes
 
 import (
+	"service-discovery-controller/addresstable"
 	"service-discovery-controller/routes"
 	"sync"
 )
 
 type AddressTable struct {
-	LookupStub        func(hostname string) []string
-	lookupMutex       sync.RWMutex
-	lookupArgsForCall []struct {
-		hostname string
+	AllEndpointsStub        func() map[string][]addresstable.Endpoint
+	allEndpointsMutex       sync.RWMutex
+	allEndpointsArgsForCall []struct {
 	}
-	lookupReturns struct {
-		result1 []string
+	allEndpointsReturns struct {
+		result1 map[string][]addresstable.Endpoint
 	}
-	lookupReturnsOnCall map[int]struct {
-		result1 []string
+	allEndpointsReturnsOnCall map[int]struct {
+		result1 map[string][]addresstable.Endpoint
+	}
+	AppIDForIPStub        func(string) string
+	appIDForIPMutex       sync.RWMutex
+	appIDForIPArgsForCall []struct {
+		arg1 string
+	}
+	appIDForIPReturns struct {
+		result1 string
+	}
+	appIDForIPReturnsOnCall map[int]struct {
+		result1 string
 	}
-	GetAllAddressesStub        func() map[string][]string
-	getAllAddressesMutex       sync.RWMutex
-	getAllAddressesArgsForCall []struct{}
-	getAllAddressesReturns     struct {
-		result1 map[string][]string
+	ChangesSinceStub        func(string, uint64) addresstable.Changes
+	changesSinceMutex       sync.RWMutex
+	changesSinceArgsForCall []struct {
+		arg1 string
+		arg2 uint64
 	}
-	getAllAddressesReturnsOnCall map[int]struct {
-		result1 map[string][]string
+	changesSinceReturns struct {
+		result1 addresstable.Changes
+	}
+	changesSinceReturnsOnCall map[int]struct {
+		result1 addresstable.Changes
 	}
 	IsWarmStub        func() bool
 	isWarmMutex       sync.RWMutex
-	isWarmArgsForCall []struct{}
-	isWarmReturns     struct {
+	isWarmArgsForCall []struct {
+	}
+	isWarmReturns struct {
 		result1 bool
 	}
 	isWarmReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	LookupEndpointsStub        func(string) []addresstable.Endpoint
+	lookupEndpointsMutex       sync.RWMutex
+	lookupEndpointsArgsForCall []struct {
+		arg1 string
+	}
+	lookupEndpointsReturns struct {
+		result1 []addresstable.Endpoint
+	}
+	lookupEndpointsReturnsOnCall map[int]struct {
+		result1 []addresstable.Endpoint
+	}
+	LookupFamilyStub        func(string, addresstable.Family) []addresstable.Endpoint
+	lookupFamilyMutex       sync.RWMutex
+	lookupFamilyArgsForCall []struct {
+		arg1 string
+		arg2 addresstable.Family
+	}
+	lookupFamilyReturns struct {
+		result1 []addresstable.Endpoint
+	}
+	lookupFamilyReturnsOnCall map[int]struct {
+		result1 []addresstable.Endpoint
+	}
+	ReverseLookupStub        func(string) []string
+	reverseLookupMutex       sync.RWMutex
+	reverseLookupArgsForCall []struct {
+		arg1 string
+	}
+	reverseLookupReturns struct {
+		result1 []string
+	}
+	reverseLookupReturnsOnCall map[int]struct {
+		result1 []string
+	}
+	WatchStub        func([]string) (<-chan struct{}, func())
+	watchMutex       sync.RWMutex
+	watchArgsForCall []struct {
+		arg1 []string
+	}
+	watchReturns struct {
+		result1 <-chan struct{}
+		result2 func()
+	}
+	watchReturnsOnCall map[int]struct {
+		result1 <-chan struct{}
+		result2 func()
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *AddressTable) Lookup(hostname string) []string {
-	fake.lookupMutex.Lock()
-	ret, specificReturn := fake.lookupReturnsOnCall[len(fake.lookupArgsForCall)]
-	fake.lookupArgsForCall = append(fake.lookupArgsForCall, struct {
-		hostname string
-	}{hostname})
-	fake.recordInvocation("Lookup", []interface{}{hostname})
-	fake.lookupMutex.Unlock()
-	if fake.LookupStub != nil {
-		return fake.LookupStub(hostname)
+func (fake *AddressTable) AllEndpoints() map[string][]addresstable.Endpoint {
+	fake.allEndpointsMutex.Lock()
+	ret, specificReturn := fake.allEndpointsReturnsOnCall[len(fake.allEndpointsArgsForCall)]
+	fake.allEndpointsArgsForCall = append(fake.allEndpointsArgsForCall, struct {
+	}{})
+	stub := fake.AllEndpointsStub
+	fakeReturns := fake.allEndpointsReturns
+	fake.recordInvocation("AllEndpoints", []interface{}{})
+	fake.allEndpointsMutex.Unlock()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.lookupReturns.result1
+	return fakeReturns.result1
 }
 
-func (fake *AddressTable) LookupCallCount() int {
-	fake.lookupMutex.RLock()
-	defer fake.lookupMutex.RUnlock()
-	return len(fake.lookupArgsForCall)
+func (fake *AddressTable) AllEndpointsCallCount() int {
+	fake.allEndpointsMutex.RLock()
+	defer fake.allEndpointsMutex.RUnlock()
+	return len(fake.allEndpointsArgsForCall)
 }
 
-func (fake *AddressTable) LookupArgsForCall(i int) string {
-	fake.lookupMutex.RLock()
-	defer fake.lookupMutex.RUnlock()
-	return fake.lookupArgsForCall[i].hostname
+func (fake *AddressTable) AllEndpointsCalls(stub func() map[string][]addresstable.Endpoint) {
+	fake.allEndpointsMutex.Lock()
+	defer fake.allEndpointsMutex.Unlock()
+	fake.AllEndpointsStub = stub
 }
 
-func (fake *AddressTable) LookupReturns(result1 []string) {
-	fake.LookupStub = nil
-	fake.lookupReturns = struct {
-		result1 []string
+func (fake *AddressTable) AllEndpointsReturns(result1 map[string][]addresstable.Endpoint) {
+	fake.allEndpointsMutex.Lock()
+	defer fake.allEndpointsMutex.Unlock()
+	fake.AllEndpointsStub = nil
+	fake.allEndpointsReturns = struct {
+		result1 map[string][]addresstable.Endpoint
 	}{result1}
 }
 
-func (fake *AddressTable) LookupReturnsOnCall(i int, result1 []string) {
-	fake.LookupStub = nil
-	if fake.lookupReturnsOnCall == nil {
-		fake.lookupReturnsOnCall = make(map[int]struct {
-			result1 []string
+func (fake *AddressTable) AllEndpointsReturnsOnCall(i int, result1 map[string][]addresstable.Endpoint) {
+	fake.allEndpointsMutex.Lock()
+	defer fake.allEndpointsMutex.Unlock()
+	fake.AllEndpointsStub = nil
+	if fake.allEndpointsReturnsOnCall == nil {
+		fake.allEndpointsReturnsOnCall = make(map[int]struct {
+			result1 map[string][]addresstable.Endpoint
 		})
 	}
-	fake.lookupReturnsOnCall[i] = struct {
-		result1 []string
+	fake.allEndpointsReturnsOnCall[i] = struct {
+		result1 map[string][]addresstable.Endpoint
 	}{result1}
 }
 
-func (fake *AddressTable) GetAllAddresses() map[string][]string {
-	fake.getAllAddressesMutex.Lock()
-	ret, specificReturn := fake.getAllAddressesReturnsOnCall[len(fake.getAllAddressesArgsForCall)]
-	fake.getAllAddressesArgsForCall = append(fake.getAllAddressesArgsForCall, struct{}{})
-	fake.recordInvocation("GetAllAddresses", []interface{}{})
-	fake.getAllAddressesMutex.Unlock()
-	if fake.GetAllAddressesStub != nil {
-		return fake.GetAllAddressesStub()
+func (fake *AddressTable) AppIDForIP(arg1 string) string {
+	fake.appIDForIPMutex.Lock()
+	ret, specificReturn := fake.appIDForIPReturnsOnCall[len(fake.appIDForIPArgsForCall)]
+	fake.appIDForIPArgsForCall = append(fake.appIDForIPArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.AppIDForIPStub
+	fakeReturns := fake.appIDForIPReturns
+	fake.recordInvocation("AppIDForIP", []interface{}{arg1})
+	fake.appIDForIPMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.getAllAddressesReturns.result1
+	return fakeReturns.result1
+}
+
+func (fake *AddressTable) AppIDForIPCallCount() int {
+	fake.appIDForIPMutex.RLock()
+	defer fake.appIDForIPMutex.RUnlock()
+	return len(fake.appIDForIPArgsForCall)
 }
 
-func (fake *AddressTable) GetAllAddressesCallCount() int {
-	fake.getAllAddressesMutex.RLock()
-	defer fake.getAllAddressesMutex.RUnlock()
-	return len(fake.getAllAddressesArgsForCall)
+func (fake *AddressTable) AppIDForIPCalls(stub func(string) string) {
+	fake.appIDForIPMutex.Lock()
+	defer fake.appIDForIPMutex.Unlock()
+	fake.AppIDForIPStub = stub
 }
 
-func (fake *AddressTable) GetAllAddressesReturns(result1 map[string][]string) {
-	fake.GetAllAddressesStub = nil
-	fake.getAllAddressesReturns = struct {
-		result1 map[string][]string
+func (fake *AddressTable) AppIDForIPArgsForCall(i int) string {
+	fake.appIDForIPMutex.RLock()
+	defer fake.appIDForIPMutex.RUnlock()
+	argsForCall := fake.appIDForIPArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *AddressTable) AppIDForIPReturns(result1 string) {
+	fake.appIDForIPMutex.Lock()
+	defer fake.appIDForIPMutex.Unlock()
+	fake.AppIDForIPStub = nil
+	fake.appIDForIPReturns = struct {
+		result1 string
 	}{result1}
 }
 
-func (fake *AddressTable) GetAllAddressesReturnsOnCall(i int, result1 map[string][]string) {
-	fake.GetAllAddressesStub = nil
-	if fake.getAllAddressesReturnsOnCall == nil {
-		fake.getAllAddressesReturnsOnCall = make(map[int]struct {
-			result1 map[string][]string
+func (fake *AddressTable) AppIDForIPReturnsOnCall(i int, result1 string) {
+	fake.appIDForIPMutex.Lock()
+	defer fake.appIDForIPMutex.Unlock()
+	fake.AppIDForIPStub = nil
+	if fake.appIDForIPReturnsOnCall == nil {
+		fake.appIDForIPReturnsOnCall = make(map[int]struct {
+			result1 string
 		})
 	}
-	fake.getAllAddressesReturnsOnCall[i] = struct {
-		result1 map[string][]string
+	fake.appIDForIPReturnsOnCall[i] = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *AddressTable) ChangesSince(arg1 string, arg2 uint64) addresstable.Changes {
+	fake.changesSinceMutex.Lock()
+	ret, specificReturn := fake.changesSinceReturnsOnCall[len(fake.changesSinceArgsForCall)]
+	fake.changesSinceArgsForCall = append(fake.changesSinceArgsForCall, struct {
+		arg1 string
+		arg2 uint64
+	}{arg1, arg2})
+	stub := fake.ChangesSinceStub
+	fakeReturns := fake.changesSinceReturns
+	fake.recordInvocation("ChangesSince", []interface{}{arg1, arg2})
+	fake.changesSinceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *AddressTable) ChangesSinceCallCount() int {
+	fake.changesSinceMutex.RLock()
+	defer fake.changesSinceMutex.RUnlock()
+	return len(fake.changesSinceArgsForCall)
+}
+
+func (fake *AddressTable) ChangesSinceCalls(stub func(string, uint64) addresstable.Changes) {
+	fake.changesSinceMutex.Lock()
+	defer fake.changesSinceMutex.Unlock()
+	fake.ChangesSinceStub = stub
+}
+
+func (fake *AddressTable) ChangesSinceArgsForCall(i int) (string, uint64) {
+	fake.changesSinceMutex.RLock()
+	defer fake.changesSinceMutex.RUnlock()
+	argsForCall := fake.changesSinceArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *AddressTable) ChangesSinceReturns(result1 addresstable.Changes) {
+	fake.changesSinceMutex.Lock()
+	defer fake.changesSinceMutex.Unlock()
+	fake.ChangesSinceStub = nil
+	fake.changesSinceReturns = struct {
+		result1 addresstable.Changes
+	}{result1}
+}
+
+func (fake *AddressTable) ChangesSinceReturnsOnCall(i int, result1 addresstable.Changes) {
+	fake.changesSinceMutex.Lock()
+	defer fake.changesSinceMutex.Unlock()
+	fake.ChangesSinceStub = nil
+	if fake.changesSinceReturnsOnCall == nil {
+		fake.changesSinceReturnsOnCall = make(map[int]struct {
+			result1 addresstable.Changes
+		})
+	}
+	fake.changesSinceReturnsOnCall[i] = struct {
+		result1 addresstable.Changes
 	}{result1}
 }
 
 func (fake *AddressTable) IsWarm() bool {
 	fake.isWarmMutex.Lock()
 	ret, specificReturn := fake.isWarmReturnsOnCall[len(fake.isWarmArgsForCall)]
-	fake.isWarmArgsForCall = append(fake.isWarmArgsForCall, struct{}{})
+	fake.isWarmArgsForCall = append(fake.isWarmArgsForCall, struct {
+	}{})
+	stub := fake.IsWarmStub
+	fakeReturns := fake.isWarmReturns
 	fake.recordInvocation("IsWarm", []interface{}{})
 	fake.isWarmMutex.Unlock()
-	if fake.IsWarmStub != nil {
-		return fake.IsWarmStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.isWarmReturns.result1
+	return fakeReturns.result1
 }
 
 func (fake *AddressTable) IsWarmCallCount() int {
@@ -149,7 +302,15 @@ func (fake *AddressTable) IsWarmCallCount() int {
 	return len(fake.isWarmArgsForCall)
 }
 
+func (fake *AddressTable) IsWarmCalls(stub func() bool) {
+	fake.isWarmMutex.Lock()
+	defer fake.isWarmMutex.Unlock()
+	fake.IsWarmStub = stub
+}
+
 func (fake *AddressTable) IsWarmReturns(result1 bool) {
+	fake.isWarmMutex.Lock()
+	defer fake.isWarmMutex.Unlock()
 	fake.IsWarmStub = nil
 	fake.isWarmReturns = struct {
 		result1 bool
@@ -157,6 +318,8 @@ func (fake *AddressTable) IsWarmReturns(result1 bool) {
 }
 
 func (fake *AddressTable) IsWarmReturnsOnCall(i int, result1 bool) {
+	fake.isWarmMutex.Lock()
+	defer fake.isWarmMutex.Unlock()
 	fake.IsWarmStub = nil
 	if fake.isWarmReturnsOnCall == nil {
 		fake.isWarmReturnsOnCall = make(map[int]struct {
@@ -168,15 +331,278 @@ func (fake *AddressTable) IsWarmReturnsOnCall(i int, result1 bool) {
 	}{result1}
 }
 
+func (fake *AddressTable) LookupEndpoints(arg1 string) []addresstable.Endpoint {
+	fake.lookupEndpointsMutex.Lock()
+	ret, specificReturn := fake.lookupEndpointsReturnsOnCall[len(fake.lookupEndpointsArgsForCall)]
+	fake.lookupEndpointsArgsForCall = append(fake.lookupEndpointsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.LookupEndpointsStub
+	fakeReturns := fake.lookupEndpointsReturns
+	fake.recordInvocation("LookupEndpoints", []interface{}{arg1})
+	fake.lookupEndpointsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *AddressTable) LookupEndpointsCallCount() int {
+	fake.lookupEndpointsMutex.RLock()
+	defer fake.lookupEndpointsMutex.RUnlock()
+	return len(fake.lookupEndpointsArgsForCall)
+}
+
+func (fake *AddressTable) LookupEndpointsCalls(stub func(string) []addresstable.Endpoint) {
+	fake.lookupEndpointsMutex.Lock()
+	defer fake.lookupEndpointsMutex.Unlock()
+	fake.LookupEndpointsStub = stub
+}
+
+func (fake *AddressTable) LookupEndpointsArgsForCall(i int) string {
+	fake.lookupEndpointsMutex.RLock()
+	defer fake.lookupEndpointsMutex.RUnlock()
+	argsForCall := fake.lookupEndpointsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *AddressTable) LookupEndpointsReturns(result1 []addresstable.Endpoint) {
+	fake.lookupEndpointsMutex.Lock()
+	defer fake.lookupEndpointsMutex.Unlock()
+	fake.LookupEndpointsStub = nil
+	fake.lookupEndpointsReturns = struct {
+		result1 []addresstable.Endpoint
+	}{result1}
+}
+
+func (fake *AddressTable) LookupEndpointsReturnsOnCall(i int, result1 []addresstable.Endpoint) {
+	fake.lookupEndpointsMutex.Lock()
+	defer fake.lookupEndpointsMutex.Unlock()
+	fake.LookupEndpointsStub = nil
+	if fake.lookupEndpointsReturnsOnCall == nil {
+		fake.lookupEndpointsReturnsOnCall = make(map[int]struct {
+			result1 []addresstable.Endpoint
+		})
+	}
+	fake.lookupEndpointsReturnsOnCall[i] = struct {
+		result1 []addresstable.Endpoint
+	}{result1}
+}
+
+func (fake *AddressTable) LookupFamily(arg1 string, arg2 addresstable.Family) []addresstable.Endpoint {
+	fake.lookupFamilyMutex.Lock()
+	ret, specificReturn := fake.lookupFamilyReturnsOnCall[len(fake.lookupFamilyArgsForCall)]
+	fake.lookupFamilyArgsForCall = append(fake.lookupFamilyArgsForCall, struct {
+		arg1 string
+		arg2 addresstable.Family
+	}{arg1, arg2})
+	stub := fake.LookupFamilyStub
+	fakeReturns := fake.lookupFamilyReturns
+	fake.recordInvocation("LookupFamily", []interface{}{arg1, arg2})
+	fake.lookupFamilyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *AddressTable) LookupFamilyCallCount() int {
+	fake.lookupFamilyMutex.RLock()
+	defer fake.lookupFamilyMutex.RUnlock()
+	return len(fake.lookupFamilyArgsForCall)
+}
+
+func (fake *AddressTable) LookupFamilyCalls(stub func(string, addresstable.Family) []addresstable.Endpoint) {
+	fake.lookupFamilyMutex.Lock()
+	defer fake.lookupFamilyMutex.Unlock()
+	fake.LookupFamilyStub = stub
+}
+
+func (fake *AddressTable) LookupFamilyArgsForCall(i int) (string, addresstable.Family) {
+	fake.lookupFamilyMutex.RLock()
+	defer fake.lookupFamilyMutex.RUnlock()
+	argsForCall := fake.lookupFamilyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *AddressTable) LookupFamilyReturns(result1 []addresstable.Endpoint) {
+	fake.lookupFamilyMutex.Lock()
+	defer fake.lookupFamilyMutex.Unlock()
+	fake.LookupFamilyStub = nil
+	fake.lookupFamilyReturns = struct {
+		result1 []addresstable.Endpoint
+	}{result1}
+}
+
+func (fake *AddressTable) LookupFamilyReturnsOnCall(i int, result1 []addresstable.Endpoint) {
+	fake.lookupFamilyMutex.Lock()
+	defer fake.lookupFamilyMutex.Unlock()
+	fake.LookupFamilyStub = nil
+	if fake.lookupFamilyReturnsOnCall == nil {
+		fake.lookupFamilyReturnsOnCall = make(map[int]struct {
+			result1 []addresstable.Endpoint
+		})
+	}
+	fake.lookupFamilyReturnsOnCall[i] = struct {
+		result1 []addresstable.Endpoint
+	}{result1}
+}
+
+func (fake *AddressTable) ReverseLookup(arg1 string) []string {
+	fake.reverseLookupMutex.Lock()
+	ret, specificReturn := fake.reverseLookupReturnsOnCall[len(fake.reverseLookupArgsForCall)]
+	fake.reverseLookupArgsForCall = append(fake.reverseLookupArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.ReverseLookupStub
+	fakeReturns := fake.reverseLookupReturns
+	fake.recordInvocation("ReverseLookup", []interface{}{arg1})
+	fake.reverseLookupMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *AddressTable) ReverseLookupCallCount() int {
+	fake.reverseLookupMutex.RLock()
+	defer fake.reverseLookupMutex.RUnlock()
+	return len(fake.reverseLookupArgsForCall)
+}
+
+func (fake *AddressTable) ReverseLookupCalls(stub func(string) []string) {
+	fake.reverseLookupMutex.Lock()
+	defer fake.reverseLookupMutex.Unlock()
+	fake.ReverseLookupStub = stub
+}
+
+func (fake *AddressTable) ReverseLookupArgsForCall(i int) string {
+	fake.reverseLookupMutex.RLock()
+	defer fake.reverseLookupMutex.RUnlock()
+	argsForCall := fake.reverseLookupArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *AddressTable) ReverseLookupReturns(result1 []string) {
+	fake.reverseLookupMutex.Lock()
+	defer fake.reverseLookupMutex.Unlock()
+	fake.ReverseLookupStub = nil
+	fake.reverseLookupReturns = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *AddressTable) ReverseLookupReturnsOnCall(i int, result1 []string) {
+	fake.reverseLookupMutex.Lock()
+	defer fake.reverseLookupMutex.Unlock()
+	fake.ReverseLookupStub = nil
+	if fake.reverseLookupReturnsOnCall == nil {
+		fake.reverseLookupReturnsOnCall = make(map[int]struct {
+			result1 []string
+		})
+	}
+	fake.reverseLookupReturnsOnCall[i] = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *AddressTable) Watch(arg1 []string) (<-chan struct{}, func()) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.watchMutex.Lock()
+	ret, specificReturn := fake.watchReturnsOnCall[len(fake.watchArgsForCall)]
+	fake.watchArgsForCall = append(fake.watchArgsForCall, struct {
+		arg1 []string
+	}{arg1Copy})
+	stub := fake.WatchStub
+	fakeReturns := fake.watchReturns
+	fake.recordInvocation("Watch", []interface{}{arg1Copy})
+	fake.watchMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *AddressTable) WatchCallCount() int {
+	fake.watchMutex.RLock()
+	defer fake.watchMutex.RUnlock()
+	return len(fake.watchArgsForCall)
+}
+
+func (fake *AddressTable) WatchCalls(stub func([]string) (<-chan struct{}, func())) {
+	fake.watchMutex.Lock()
+	defer fake.watchMutex.Unlock()
+	fake.WatchStub = stub
+}
+
+func (fake *AddressTable) WatchArgsForCall(i int) []string {
+	fake.watchMutex.RLock()
+	defer fake.watchMutex.RUnlock()
+	argsForCall := fake.watchArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *AddressTable) WatchReturns(result1 <-chan struct{}, result2 func()) {
+	fake.watchMutex.Lock()
+	defer fake.watchMutex.Unlock()
+	fake.WatchStub = nil
+	fake.watchReturns = struct {
+		result1 <-chan struct{}
+		result2 func()
+	}{result1, result2}
+}
+
+func (fake *AddressTable) WatchReturnsOnCall(i int, result1 <-chan struct{}, result2 func()) {
+	fake.watchMutex.Lock()
+	defer fake.watchMutex.Unlock()
+	fake.WatchStub = nil
+	if fake.watchReturnsOnCall == nil {
+		fake.watchReturnsOnCall = make(map[int]struct {
+			result1 <-chan struct{}
+			result2 func()
+		})
+	}
+	fake.watchReturnsOnCall[i] = struct {
+		result1 <-chan struct{}
+		result2 func()
+	}{result1, result2}
+}
+
 func (fake *AddressTable) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.lookupMutex.RLock()
-	defer fake.lookupMutex.RUnlock()
-	fake.getAllAddressesMutex.RLock()
-	defer fake.getAllAddressesMutex.RUnlock()
+	fake.allEndpointsMutex.RLock()
+	defer fake.allEndpointsMutex.RUnlock()
+	fake.appIDForIPMutex.RLock()
+	defer fake.appIDForIPMutex.RUnlock()
+	fake.changesSinceMutex.RLock()
+	defer fake.changesSinceMutex.RUnlock()
 	fake.isWarmMutex.RLock()
 	defer fake.isWarmMutex.RUnlock()
+	fake.lookupEndpointsMutex.RLock()
+	defer fake.lookupEndpointsMutex.RUnlock()
+	fake.lookupFamilyMutex.RLock()
+	defer fake.lookupFamilyMutex.RUnlock()
+	fake.reverseLookupMutex.RLock()
+	defer fake.reverseLookupMutex.RUnlock()
+	fake.watchMutex.RLock()
+	defer fake.watchMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
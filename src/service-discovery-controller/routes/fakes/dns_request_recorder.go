@@ -7,19 +7,63 @@ import (
 )
 
 type DNSRequestRecorder struct {
+	RecordLookupStub        func(string, string, int)
+	recordLookupMutex       sync.RWMutex
+	recordLookupArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 int
+	}
 	RecordRequestStub        func()
 	recordRequestMutex       sync.RWMutex
-	recordRequestArgsForCall []struct{}
-	invocations              map[string][][]interface{}
-	invocationsMutex         sync.RWMutex
+	recordRequestArgsForCall []struct {
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *DNSRequestRecorder) RecordLookup(arg1 string, arg2 string, arg3 int) {
+	fake.recordLookupMutex.Lock()
+	fake.recordLookupArgsForCall = append(fake.recordLookupArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 int
+	}{arg1, arg2, arg3})
+	stub := fake.RecordLookupStub
+	fake.recordInvocation("RecordLookup", []interface{}{arg1, arg2, arg3})
+	fake.recordLookupMutex.Unlock()
+	if stub != nil {
+		fake.RecordLookupStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *DNSRequestRecorder) RecordLookupCallCount() int {
+	fake.recordLookupMutex.RLock()
+	defer fake.recordLookupMutex.RUnlock()
+	return len(fake.recordLookupArgsForCall)
+}
+
+func (fake *DNSRequestRecorder) RecordLookupCalls(stub func(string, string, int)) {
+	fake.recordLookupMutex.Lock()
+	defer fake.recordLookupMutex.Unlock()
+	fake.RecordLookupStub = stub
+}
+
+func (fake *DNSRequestRecorder) RecordLookupArgsForCall(i int) (string, string, int) {
+	fake.recordLookupMutex.RLock()
+	defer fake.recordLookupMutex.RUnlock()
+	argsForCall := fake.recordLookupArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *DNSRequestRecorder) RecordRequest() {
 	fake.recordRequestMutex.Lock()
-	fake.recordRequestArgsForCall = append(fake.recordRequestArgsForCall, struct{}{})
+	fake.recordRequestArgsForCall = append(fake.recordRequestArgsForCall, struct {
+	}{})
+	stub := fake.RecordRequestStub
 	fake.recordInvocation("RecordRequest", []interface{}{})
 	fake.recordRequestMutex.Unlock()
-	if fake.RecordRequestStub != nil {
+	if stub != nil {
 		fake.RecordRequestStub()
 	}
 }
@@ -30,9 +74,17 @@ func (fake *DNSRequestRecorder) RecordRequestCallCount() int {
 	return len(fake.recordRequestArgsForCall)
 }
 
+func (fake *DNSRequestRecorder) RecordRequestCalls(stub func()) {
+	fake.recordRequestMutex.Lock()
+	defer fake.recordRequestMutex.Unlock()
+	fake.RecordRequestStub = stub
+}
+
 func (fake *DNSRequestRecorder) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.recordLookupMutex.RLock()
+	defer fake.recordLookupMutex.RUnlock()
 	fake.recordRequestMutex.RLock()
 	defer fake.recordRequestMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
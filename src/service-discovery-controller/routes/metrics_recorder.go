@@ -1,10 +1,40 @@
 package routes
 
-import "sync"
+import (
+	"service-discovery-controller/domains"
+	"service-discovery-controller/topmetrics"
+	"strings"
+	"sync"
+)
+
+// maxTrackedKeys bounds the hostnames and source IPs that are counted
+// separately between reads.
+const maxTrackedKeys = 1000
 
 type MetricsRecorder struct {
 	requestCount int
 	mutex        sync.RWMutex
+
+	lookups         *topmetrics.Counter
+	emptyAnswers    *topmetrics.Counter
+	lookupsBySource *topmetrics.Counter
+	lookupsByDomain *topmetrics.Counter
+	rejectedLookups *topmetrics.Counter
+	domains         *domains.Set
+}
+
+// NewMetricsRecorder returns a recorder that also counts lookups per
+// hostname, per source IP and per domain, and lookups of hostnames outside
+// the domains.
+func NewMetricsRecorder(domainSet *domains.Set) *MetricsRecorder {
+	return &MetricsRecorder{
+		lookups:         topmetrics.NewCounter(maxTrackedKeys),
+		emptyAnswers:    topmetrics.NewCounter(maxTrackedKeys),
+		lookupsBySource: topmetrics.NewCounter(maxTrackedKeys),
+		lookupsByDomain: topmetrics.NewCounter(maxTrackedKeys),
+		rejectedLookups: topmetrics.NewCounter(maxTrackedKeys),
+		domains:         domainSet,
+	}
 }
 
 func (m *MetricsRecorder) RecordRequest() {
@@ -13,6 +43,36 @@ func (m *MetricsRecorder) RecordRequest() {
 	m.mutex.Unlock()
 }
 
+// SetDomains sets the internal domains that lookups are counted by.
+func (m *MetricsRecorder) SetDomains(domainSet *domains.Set) {
+	m.mutex.Lock()
+	m.domains = domainSet
+	m.mutex.Unlock()
+}
+
+// RecordLookup counts a lookup of hostname that was answered with the given
+// number of hosts. Lookups without hosts are the equivalent of NXDOMAIN.
+func (m *MetricsRecorder) RecordLookup(hostname, sourceIP string, answers int) {
+	m.mutex.RLock()
+	domainSet := m.domains
+	m.mutex.RUnlock()
+
+	if domainSet.Serves(hostname) {
+		m.lookupsByDomain.Add(domainSet.NameOf(hostname), 1)
+	} else {
+		m.rejectedLookups.Add(strings.TrimSuffix(hostname, "."), 1)
+	}
+
+	hostname = strings.TrimSuffix(hostname, ".")
+	m.lookups.Add(hostname, 1)
+	if answers == 0 {
+		m.emptyAnswers.Add(hostname, 1)
+	}
+	if sourceIP != "" {
+		m.lookupsBySource.Add(sourceIP, 1)
+	}
+}
+
 func (m *MetricsRecorder) Getter() (float64, error) {
 	m.mutex.Lock()
 	count := m.requestCount
@@ -21,3 +81,32 @@ func (m *MetricsRecorder) Getter() (float64, error) {
 
 	return float64(count), nil
 }
+
+// TakeLookups returns the lookups of each hostname since the last call.
+func (m *MetricsRecorder) TakeLookups() map[string]int {
+	return m.lookups.Take()
+}
+
+// TakeEmptyAnswers returns the lookups of each hostname that were answered
+// without hosts since the last call.
+func (m *MetricsRecorder) TakeEmptyAnswers() map[string]int {
+	return m.emptyAnswers.Take()
+}
+
+// TakeLookupsBySource returns the lookups sent by each source IP since the
+// last call.
+func (m *MetricsRecorder) TakeLookupsBySource() map[string]int {
+	return m.lookupsBySource.Take()
+}
+
+// TakeLookupsByDomain returns the lookups of each domain since the last
+// call.
+func (m *MetricsRecorder) TakeLookupsByDomain() map[string]int {
+	return m.lookupsByDomain.Take()
+}
+
+// TakeRejectedLookups returns the lookups of each hostname outside the
+// domains since the last call.
+func (m *MetricsRecorder) TakeRejectedLookups() map[string]int {
+	return m.rejectedLookups.Take()
+}
@@ -1,6 +1,7 @@
 package routes_test
 
 import (
+	"service-discovery-controller/domains"
 	"service-discovery-controller/routes"
 
 	. "github.com/onsi/ginkgo"
@@ -46,6 +47,80 @@ var _ = Describe("MetricsRecorder", func() {
 		})
 	})
 
+	Context("when lookups are recorded", func() {
+		BeforeEach(func() {
+			metricsRecorder = routes.NewMetricsRecorder(domains.NewSet(nil))
+			metricsRecorder.RecordLookup("app-id.apps.internal.", "10.255.0.1", 2)
+			metricsRecorder.RecordLookup("app-id.apps.internal", "10.255.0.1", 2)
+			metricsRecorder.RecordLookup("missing.apps.internal.", "10.255.0.2", 0)
+			metricsRecorder.RecordLookup("missing.apps.internal.", "", 0)
+		})
+
+		It("counts the lookups of each hostname since the last call", func() {
+			Expect(metricsRecorder.TakeLookups()).To(Equal(map[string]int{
+				"app-id.apps.internal":  2,
+				"missing.apps.internal": 2,
+			}))
+			Expect(metricsRecorder.TakeLookups()).To(BeEmpty())
+		})
+
+		It("counts the lookups answered without hosts", func() {
+			Expect(metricsRecorder.TakeEmptyAnswers()).To(Equal(map[string]int{
+				"missing.apps.internal": 2,
+			}))
+		})
+
+		It("counts the lookups of each source IP", func() {
+			Expect(metricsRecorder.TakeLookupsBySource()).To(Equal(map[string]int{
+				"10.255.0.1": 2,
+				"10.255.0.2": 1,
+			}))
+		})
+	})
+
+	Context("when domains are configured", func() {
+		BeforeEach(func() {
+			metricsRecorder = routes.NewMetricsRecorder(domains.NewSet([]domains.Domain{
+				{Name: "apps.internal"},
+				{Name: "tenant-a.internal"},
+			}))
+			metricsRecorder.RecordLookup("app-id.apps.internal.", "10.255.0.1", 2)
+			metricsRecorder.RecordLookup("app-id.tenant-a.internal.", "10.255.0.1", 1)
+			metricsRecorder.RecordLookup("other.tenant-a.internal.", "10.255.0.1", 0)
+			metricsRecorder.RecordLookup("app-id.unknown.internal.", "10.255.0.1", 0)
+		})
+
+		It("counts the lookups of each domain", func() {
+			Expect(metricsRecorder.TakeLookupsByDomain()).To(Equal(map[string]int{
+				"apps.internal":     1,
+				"tenant-a.internal": 2,
+			}))
+			Expect(metricsRecorder.TakeLookupsByDomain()).To(BeEmpty())
+		})
+
+		It("counts the lookups of hostnames outside the domains", func() {
+			Expect(metricsRecorder.TakeRejectedLookups()).To(Equal(map[string]int{
+				"app-id.unknown.internal": 1,
+			}))
+		})
+
+		It("counts later lookups by the domains that were set last", func() {
+			metricsRecorder.TakeLookupsByDomain()
+			metricsRecorder.TakeRejectedLookups()
+
+			metricsRecorder.SetDomains(domains.NewSet([]domains.Domain{{Name: "unknown.internal"}}))
+			metricsRecorder.RecordLookup("app-id.unknown.internal.", "10.255.0.1", 1)
+			metricsRecorder.RecordLookup("app-id.apps.internal.", "10.255.0.1", 1)
+
+			Expect(metricsRecorder.TakeLookupsByDomain()).To(Equal(map[string]int{
+				"unknown.internal": 1,
+			}))
+			Expect(metricsRecorder.TakeRejectedLookups()).To(Equal(map[string]int{
+				"app-id.apps.internal": 1,
+			}))
+		})
+	})
+
 	Context("concurrency", func() {
 		BeforeEach(func() {
 			go func() {
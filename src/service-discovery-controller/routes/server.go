@@ -1,21 +1,23 @@
 package routes
 
 import (
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"path"
+	"service-discovery-controller/addresstable"
+	"service-discovery-controller/certstore"
 	"service-discovery-controller/config"
+	"service-discovery-controller/domains"
+	"strconv"
+	"sync"
 
 	"code.cloudfoundry.org/cf-networking-helpers/metrics"
 	"code.cloudfoundry.org/cf-networking-helpers/middleware"
 	"code.cloudfoundry.org/lager"
-	"github.com/pivotal-cf/paraphernalia/secure/tlsconfig"
 
 	"time"
 )
@@ -26,6 +28,13 @@ type Server struct {
 	addressTable       AddressTable
 	dnsRequestRecorder DNSRequestRecorder
 	metricsSender      MetricsSender
+	policyFilter       PolicyFilter
+	domains            *domains.Set
+	domainsMutex       sync.RWMutex
+	certs              *certstore.Store
+	peerName           string
+	sourceIPClients    map[string]bool
+	identitiesMutex    sync.RWMutex
 }
 
 type host struct {
@@ -39,9 +48,24 @@ type host struct {
 }
 
 type registration struct {
-	Hosts   []host `json:"hosts"`
-	Env     string `json:"env"`
-	Service string `json:"service"`
+	Hosts      []host `json:"hosts"`
+	Env        string `json:"env"`
+	Service    string `json:"service"`
+	TTLSeconds int    `json:"ttl_seconds,omitempty"`
+}
+
+type batchRequest struct {
+	Hostnames []string `json:"hostnames"`
+	Family    string   `json:"family"`
+}
+
+type batchResponse struct {
+	Registrations map[string]registration `json:"registrations"`
+}
+
+type watchEvent struct {
+	Hostname string `json:"hostname"`
+	Hosts    []host `json:"hosts"`
 }
 
 type routes struct {
@@ -49,17 +73,64 @@ type routes struct {
 }
 
 type address struct {
-	Hostname string   `json:"hostname"`
-	Ips      []string `json:"ips"`
+	Hostname     string   `json:"hostname"`
+	Ips          []string `json:"ips"`
+	UnhealthyIps []string `json:"unhealthy_ips,omitempty"`
+}
+
+type reverse struct {
+	IP        string   `json:"ip"`
+	Hostnames []string `json:"hostnames"`
+}
+
+type peerTable struct {
+	Warm     bool          `json:"warm"`
+	Epoch    string        `json:"epoch"`
+	Version  uint64        `json:"version"`
+	Full     bool          `json:"full"`
+	Entries  []peerEntry   `json:"entries"`
+	Removals []peerRemoval `json:"removals"`
+}
+
+// Times are sent as ages, so that the clocks of the instances do not have
+// to agree.
+type peerEntry struct {
+	Hostname  string `json:"hostname"`
+	IP        string `json:"ip"`
+	Port      uint16 `json:"port,omitempty"`
+	CellID    string `json:"cell_id,omitempty"`
+	AZ        string `json:"availability_zone,omitempty"`
+	AppID     string `json:"app_id,omitempty"`
+	Unhealthy bool   `json:"unhealthy,omitempty"`
+	AgeNS     int64  `json:"age_ns"`
+}
+
+type peerRemoval struct {
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	Port     uint16 `json:"port,omitempty"`
+	AgeNS    int64  `json:"age_ns"`
 }
 
 //go:generate counterfeiter -o fakes/address_table.go --fake-name AddressTable . AddressTable
 type AddressTable interface {
-	Lookup(hostname string) []string
-	GetAllAddresses() map[string][]string
+	LookupEndpoints(hostname string) []addresstable.Endpoint
+	LookupFamily(hostname string, family addresstable.Family) []addresstable.Endpoint
+	AllEndpoints() map[string][]addresstable.Endpoint
+	ChangesSince(epoch string, since uint64) addresstable.Changes
+	Watch(hostnames []string) (<-chan struct{}, func())
+	ReverseLookup(ip string) []string
+	AppIDForIP(ip string) string
 	IsWarm() bool
 }
 
+// maxHostnames limits the hostnames of a batch lookup or watch.
+const maxHostnames = 1000
+
+// watchKeepaliveInterval is how often a comment is written to idle watches,
+// so that proxies between the client and the server keep them open.
+const watchKeepaliveInterval = 30 * time.Second
+
 //go:generate counterfeiter -o fakes/metrics_sender.go --fake-name MetricsSender . MetricsSender
 type MetricsSender interface {
 	SendDuration(string, time.Duration)
@@ -68,14 +139,34 @@ type MetricsSender interface {
 //go:generate counterfeiter -o fakes/dns_request_recorder.go --fake-name DNSRequestRecorder . DNSRequestRecorder
 type DNSRequestRecorder interface {
 	RecordRequest()
+	RecordLookup(hostname, sourceIP string, answers int)
 }
 
-func NewServer(addressTable AddressTable, config *config.Config, dnsRequestRecorder DNSRequestRecorder, metricsSender MetricsSender, logger lager.Logger) *Server {
+//go:generate counterfeiter -o fakes/policy_filter.go --fake-name PolicyFilter . PolicyFilter
+type PolicyFilter interface {
+	Reachable(sourceIP string, endpoints []addresstable.Endpoint) []addresstable.Endpoint
+}
+
+// SourceIPHeader carries the IP of the container that sent a DNS query, for
+// clients such as the bosh-dns-adapter that query on its behalf. It is only
+// honored from clients whose certificate has one of the configured source
+// IP client names.
+const SourceIPHeader = "X-Source-IP"
+
+// NewServer returns a server answering lookups from addressTable. When
+// policyFilter is not nil, lookups and the list of routes only return the
+// endpoints that the querying container may reach. Lookups of hostnames outside the configured
+// domains are answered with a 404, so that they can be told apart from
+// hostnames without registrations.
+func NewServer(addressTable AddressTable, config *config.Config, dnsRequestRecorder DNSRequestRecorder, metricsSender MetricsSender, policyFilter PolicyFilter, logger lager.Logger) *Server {
 	return &Server{
 		addressTable:       addressTable,
 		config:             config,
 		dnsRequestRecorder: dnsRequestRecorder,
 		metricsSender:      metricsSender,
+		policyFilter:       policyFilter,
+		domains:            config.DomainSet(),
+		certs:              &certstore.Store{},
 		logger:             logger,
 	}
 }
@@ -96,9 +187,13 @@ func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	}
 
 	mux.HandleFunc("/v1/registration/", metricsWrap("Registration", http.HandlerFunc(s.handleRegistrationRequest)).ServeHTTP)
+	mux.HandleFunc("/v1/registrations", metricsWrap("BatchRegistration", http.HandlerFunc(s.handleBatchRegistrationRequest)).ServeHTTP)
+	mux.HandleFunc("/v1/reverse/", metricsWrap("ReverseLookup", http.HandlerFunc(s.handleReverseRequest)).ServeHTTP)
+	mux.HandleFunc("/v1/watch", s.handleWatchRequest)
 	mux.HandleFunc("/routes", s.handleRoutesRequest)
+	mux.HandleFunc("/v1/peer/table", s.handlePeerTableRequest)
 
-	tlsConfig, err := s.buildTLSServerConfig()
+	err := s.ReloadCertificates(s.config)
 	if err != nil {
 		return err
 	}
@@ -107,7 +202,7 @@ func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	httpServer := &http.Server{
 		Addr:      serverAddress,
 		Handler:   mux,
-		TLSConfig: tlsConfig,
+		TLSConfig: s.certs.ServerConfig(),
 	}
 
 	exited := make(chan error)
@@ -135,29 +230,89 @@ func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	}
 }
 
-func (s *Server) buildTLSServerConfig() (*tls.Config, error) {
-	caCert, err := ioutil.ReadFile(s.config.CACert)
+// ReloadCertificates loads the server certificate, key and CA of conf, and
+// answers new connections with them. Established connections are kept. The
+// common name of the peer client certificate of conf is the only client
+// that the address table is served to, and the source IP client names of
+// conf are the only clients whose source IP header is honored.
+func (s *Server) ReloadCertificates(conf *config.Config) error {
+	peerName := ""
+	if conf.PeerClientCert != "" {
+		var err error
+		peerName, err = certstore.CommonName(conf.PeerClientCert)
+		if err != nil {
+			return fmt.Errorf("peer client certificate: %s", err)
+		}
+	}
+
+	err := s.certs.Load(conf.ServerCert, conf.ServerKey, conf.CACert)
 	if err != nil {
-		fmt.Errorf("unable to read ca file: %s", err)
-		return nil, err
+		return err
+	}
+
+	sourceIPClients := map[string]bool{}
+	for _, name := range conf.SourceIPClientNames {
+		sourceIPClients[name] = true
 	}
-	caCertPool := x509.NewCertPool()
-	caCertPool.AppendCertsFromPEM(caCert)
 
-	cert, err := tls.LoadX509KeyPair(s.config.ServerCert, s.config.ServerKey)
+	s.identitiesMutex.Lock()
+	s.peerName = peerName
+	s.sourceIPClients = sourceIPClients
+	s.identitiesMutex.Unlock()
+	return nil
+}
+
+// isPeer returns whether the client presented a certificate with the
+// common name of this instance's peer client certificate, which every
+// service-discovery-controller instance shares.
+func (s *Server) isPeer(req *http.Request) bool {
+	s.identitiesMutex.RLock()
+	peerName := s.peerName
+	s.identitiesMutex.RUnlock()
+	return peerName != "" && clientName(req) == peerName
+}
+
+// sourceIP returns the IP of the container that sent the query, from the
+// source IP header when the client is trusted with it, or else the IP of
+// the client.
+func (s *Server) sourceIP(req *http.Request) string {
+	if ip := req.Header.Get(SourceIPHeader); ip != "" {
+		s.identitiesMutex.RLock()
+		trusted := s.sourceIPClients[clientName(req)]
+		s.identitiesMutex.RUnlock()
+		if trusted {
+			return ip
+		}
+		s.logger.Debug("ignored-source-ip-header", lager.Data{"client": clientName(req)})
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
 	if err != nil {
-		fmt.Errorf("unable to load x509 key pair: %s", err)
-		return nil, err
+		return req.RemoteAddr
 	}
+	return host
+}
 
-	tlsConfig := tlsconfig.Build(
-		tlsconfig.WithIdentity(cert),
-		tlsconfig.WithInternalServiceDefaults(),
-	)
+// clientName returns the common name of the client's certificate.
+func clientName(req *http.Request) string {
+	if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	return req.TLS.PeerCertificates[0].Subject.CommonName
+}
 
-	serverConfig := tlsConfig.Server(tlsconfig.WithClientAuthentication(caCertPool))
-	serverConfig.BuildNameToCertificate()
-	return serverConfig, err
+// SetDomains sets the internal domains that lookups are answered for, and
+// the TTLs of their hostnames. Lookups in progress finish with the previous
+// domains.
+func (s *Server) SetDomains(domainSet *domains.Set) {
+	s.domainsMutex.Lock()
+	s.domains = domainSet
+	s.domainsMutex.Unlock()
+}
+
+func (s *Server) domainSet() *domains.Set {
+	s.domainsMutex.RLock()
+	defer s.domainsMutex.RUnlock()
+	return s.domains
 }
 
 func (s *Server) handleRegistrationRequest(resp http.ResponseWriter, req *http.Request) {
@@ -173,20 +328,31 @@ func (s *Server) handleRegistrationRequest(resp http.ResponseWriter, req *http.R
 		return
 	}
 
-	lookupStartTime := time.Now()
-	ips := s.addressTable.Lookup(serviceKey)
-	lookupDuration := time.Now().Sub(lookupStartTime)
-	s.metricsSender.SendDuration("addressTableLookupTime", lookupDuration)
-	hosts := make([]host, cap(ips))
-	for index, ip := range ips {
-		hosts[index] = host{
-			IPAddress: ip,
-			Tags:      make(map[string]interface{}),
-		}
+	family, err := parseFamily(req.URL.Query().Get("family"))
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		s.logger.Debug("failed-request", lager.Data{
+			"serviceKey": serviceKey,
+			"reason":     "invalid-family",
+		})
+		return
 	}
 
-	var err error
-	json, err := json.Marshal(registration{Hosts: hosts})
+	source := s.sourceIP(req)
+	if !s.domainSet().Serves(serviceKey) {
+		s.dnsRequestRecorder.RecordRequest()
+		s.dnsRequestRecorder.RecordLookup(serviceKey, source, 0)
+		http.Error(resp, "hostname is outside the configured domains", http.StatusNotFound)
+		s.logger.Debug("rejected-lookup", lager.Data{
+			"serviceKey": serviceKey,
+			"reason":     "outside-domains",
+		})
+		return
+	}
+
+	hosts := s.lookupHosts(serviceKey, family, source)
+
+	json, err := json.Marshal(s.registration(serviceKey, hosts))
 	if err != nil {
 		http.Error(resp, err.Error(), http.StatusInternalServerError)
 		return
@@ -198,6 +364,7 @@ func (s *Server) handleRegistrationRequest(resp http.ResponseWriter, req *http.R
 	}
 
 	s.dnsRequestRecorder.RecordRequest()
+	s.dnsRequestRecorder.RecordLookup(serviceKey, source, len(hosts))
 
 	s.logger.Debug("HTTPServer access", lager.Data(map[string]interface{}{
 		"serviceKey":   serviceKey,
@@ -205,13 +372,300 @@ func (s *Server) handleRegistrationRequest(resp http.ResponseWriter, req *http.R
 	}))
 }
 
+// handleReverseRequest returns the hostnames registered for an IP, such as
+// the overlay IP of a container, for PTR queries. Hostnames outside the
+// configured domains are left out, and when policies are enforced, the
+// hostnames are only returned to containers that may reach the IP.
+func (s *Server) handleReverseRequest(resp http.ResponseWriter, req *http.Request) {
+	ip := path.Base(req.URL.Path)
+
+	if !s.addressTable.IsWarm() {
+		http.Error(resp, "address table is not warm", http.StatusInternalServerError)
+		s.logger.Debug("failed-request", lager.Data{
+			"ip":     ip,
+			"reason": "address-table-not-warm",
+		})
+		return
+	}
+
+	if net.ParseIP(ip) == nil {
+		http.Error(resp, fmt.Sprintf("invalid IP %q", ip), http.StatusBadRequest)
+		return
+	}
+
+	hostnames := []string{}
+	for _, hostname := range s.addressTable.ReverseLookup(ip) {
+		if s.domainSet().Serves(hostname) {
+			hostnames = append(hostnames, hostname)
+		}
+	}
+
+	if s.policyFilter != nil && len(hostnames) > 0 {
+		endpoint := addresstable.Endpoint{IP: ip, AppID: s.addressTable.AppIDForIP(ip)}
+		if len(s.policyFilter.Reachable(s.sourceIP(req), []addresstable.Endpoint{endpoint})) == 0 {
+			hostnames = []string{}
+		}
+	}
+
+	json, err := json.Marshal(reverse{IP: ip, Hostnames: hostnames})
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = resp.Write(json)
+	if err != nil {
+		s.logger.Debug("Error writing to http response body")
+	}
+
+	s.dnsRequestRecorder.RecordRequest()
+	s.logger.Debug("reverse-lookup-served", lager.Data{"ip": ip, "hostnames": len(hostnames)})
+}
+
+// handleBatchRegistrationRequest looks up many hostnames in one request.
+// The registrations are keyed by hostname as it was requested.
+func (s *Server) handleBatchRegistrationRequest(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.addressTable.IsWarm() {
+		http.Error(resp, "address table is not warm", http.StatusInternalServerError)
+		s.logger.Debug("failed-request", lager.Data{
+			"reason": "address-table-not-warm",
+		})
+		return
+	}
+
+	var batch batchRequest
+	err := json.NewDecoder(req.Body).Decode(&batch)
+	if err != nil {
+		http.Error(resp, fmt.Sprintf("invalid request body: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateHostnames(batch.Hostnames); err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	family, err := parseFamily(batch.Family)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source := s.sourceIP(req)
+	response := batchResponse{Registrations: map[string]registration{}}
+	for _, hostname := range batch.Hostnames {
+		hosts := s.lookupHosts(hostname, family, source)
+		if s.domainSet().Serves(hostname) {
+			response.Registrations[hostname] = s.registration(hostname, hosts)
+		}
+		s.dnsRequestRecorder.RecordRequest()
+		s.dnsRequestRecorder.RecordLookup(hostname, source, len(hosts))
+	}
+
+	json, err := json.Marshal(response)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = resp.Write(json)
+	if err != nil {
+		s.logger.Debug("Error writing to http response body")
+	}
+
+	s.logger.Debug("batch-registration-served", lager.Data{"hostnames": len(batch.Hostnames)})
+}
+
+// handleWatchRequest streams the registrations of the hostnames in the name
+// parameters as server-sent events. The registration of every hostname is
+// sent first, then the registration of a hostname is sent again whenever it
+// changes, until the client disconnects.
+func (s *Server) handleWatchRequest(resp http.ResponseWriter, req *http.Request) {
+	hostnames := req.URL.Query()["name"]
+	if err := validateHostnames(hostnames); err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	family, err := parseFamily(req.URL.Query().Get("family"))
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !s.addressTable.IsWarm() {
+		http.Error(resp, "address table is not warm", http.StatusInternalServerError)
+		s.logger.Debug("failed-request", lager.Data{
+			"reason": "address-table-not-warm",
+		})
+		return
+	}
+
+	flusher, ok := resp.(http.Flusher)
+	if !ok {
+		http.Error(resp, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Watch before the first lookup, so that no change is missed.
+	changes, stop := s.addressTable.Watch(hostnames)
+	defer stop()
+
+	resp.Header().Set("Content-Type", "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.WriteHeader(http.StatusOK)
+
+	logger := s.logger.Session("watch", lager.Data{"hostnames": hostnames})
+	logger.Debug("started")
+	defer logger.Debug("stopped")
+
+	source := s.sourceIP(req)
+	sent := map[string]string{}
+	sendChanges := func() error {
+		for _, hostname := range hostnames {
+			event, err := json.Marshal(watchEvent{
+				Hostname: hostname,
+				Hosts:    s.lookupHosts(hostname, family, source),
+			})
+			if err != nil {
+				return err // not tested
+			}
+			if sent[hostname] == string(event) {
+				continue
+			}
+			_, err = fmt.Fprintf(resp, "event: registration\ndata: %s\n\n", event)
+			if err != nil {
+				return err
+			}
+			sent[hostname] = string(event)
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	if err := sendChanges(); err != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(watchKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-changes:
+			if err := sendChanges(); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(resp, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// lookupHosts returns the hosts registered for hostname, only those in the
+// family unless it is unknown, and only those that the container with
+// source IP may reach when policies are enforced. Hostnames outside the
+// configured domains have no hosts.
+func (s *Server) lookupHosts(hostname string, family addresstable.Family, source string) []host {
+	if !s.domainSet().Serves(hostname) {
+		s.logger.Debug("rejected-lookup", lager.Data{
+			"serviceKey": hostname,
+			"reason":     "outside-domains",
+		})
+		return []host{}
+	}
+
+	lookupStartTime := time.Now()
+	var endpoints []addresstable.Endpoint
+	if family == addresstable.UnknownFamily {
+		endpoints = s.addressTable.LookupEndpoints(hostname)
+	} else {
+		endpoints = s.addressTable.LookupFamily(hostname, family)
+	}
+	if s.policyFilter != nil {
+		endpoints = s.policyFilter.Reachable(source, endpoints)
+	}
+	lookupDuration := time.Now().Sub(lookupStartTime)
+	s.metricsSender.SendDuration("addressTableLookupTime", lookupDuration)
+
+	hosts := make([]host, len(endpoints))
+	for index, endpoint := range endpoints {
+		hosts[index] = host{
+			IPAddress: endpoint.IP,
+			Port:      int32(endpoint.Port),
+			Tags:      localityTags(endpoint),
+		}
+	}
+	return hosts
+}
+
+// registration returns the registration of hostname with the TTL of its
+// domain, when it has one.
+func (s *Server) registration(hostname string, hosts []host) registration {
+	return registration{
+		Hosts:      hosts,
+		TTLSeconds: int(s.domainSet().TTL(hostname) / time.Second),
+	}
+}
+
+// parseFamily returns UnknownFamily, meaning any, for an empty parameter.
+func parseFamily(param string) (addresstable.Family, error) {
+	if param == "" {
+		return addresstable.UnknownFamily, nil
+	}
+	return addresstable.ParseFamily(param)
+}
+
+func validateHostnames(hostnames []string) error {
+	if len(hostnames) == 0 {
+		return fmt.Errorf("no hostnames")
+	}
+	if len(hostnames) > maxHostnames {
+		return fmt.Errorf("more than %d hostnames", maxHostnames)
+	}
+	return nil
+}
+
+// localityTags lets clients prefer instances near them.
+func localityTags(endpoint addresstable.Endpoint) map[string]interface{} {
+	tags := make(map[string]interface{})
+	if endpoint.CellID != "" {
+		tags["cell_id"] = endpoint.CellID
+	}
+	if endpoint.AZ != "" {
+		tags["availability_zone"] = endpoint.AZ
+	}
+	return tags
+}
+
+// handleRoutesRequest lists the IPs of every hostname. When policies are
+// enforced, only the endpoints that the querying container may reach are
+// listed, and hostnames without any are left out.
 func (s *Server) handleRoutesRequest(resp http.ResponseWriter, req *http.Request) {
-	availableAddresses := s.addressTable.GetAllAddresses()
+	source := s.sourceIP(req)
 	addresses := []address{}
-	for i, availableAddress := range availableAddresses {
+	for hostname, endpoints := range s.addressTable.AllEndpoints() {
+		if s.policyFilter != nil {
+			endpoints = s.policyFilter.Reachable(source, endpoints)
+			if len(endpoints) == 0 {
+				continue
+			}
+		}
+		ips, unhealthyIps := ipsByHealth(endpoints)
 		addresses = append(addresses, address{
-			Hostname: i,
-			Ips:      availableAddress,
+			Hostname:     hostname,
+			Ips:          ips,
+			UnhealthyIps: unhealthyIps,
 		})
 	}
 
@@ -231,3 +685,100 @@ func (s *Server) handleRoutesRequest(resp http.ResponseWriter, req *http.Request
 		"responseJson": string(json),
 	}))
 }
+
+// ipsByHealth returns each IP of endpoints once, and separately the IPs
+// that are only registered as unhealthy.
+func ipsByHealth(endpoints []addresstable.Endpoint) ([]string, []string) {
+	ips := []string{}
+	healthy := map[string]bool{}
+	for _, endpoint := range endpoints {
+		if _, seen := healthy[endpoint.IP]; !seen {
+			ips = append(ips, endpoint.IP)
+		}
+		healthy[endpoint.IP] = healthy[endpoint.IP] || !endpoint.Unhealthy
+	}
+
+	var unhealthyIps []string
+	for _, ip := range ips {
+		if !healthy[ip] {
+			unhealthyIps = append(unhealthyIps, ip)
+		}
+	}
+	return ips, unhealthyIps
+}
+
+// handlePeerTableRequest serves the address table, and the endpoints
+// recently removed from it, to other service-discovery-controller
+// instances, which merge them into their own. A peer that passes the epoch
+// and version of its last response is only sent what changed since. Other
+// clients are refused.
+func (s *Server) handlePeerTableRequest(resp http.ResponseWriter, req *http.Request) {
+	if !s.isPeer(req) {
+		s.logger.Info("peer-table-refused", lager.Data{"client": clientName(req)})
+		http.Error(resp, "only service-discovery-controller peers may fetch the table", http.StatusForbidden)
+		return
+	}
+
+	var since uint64
+	if param := req.URL.Query().Get("since"); param != "" {
+		var err error
+		since, err = strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			http.Error(resp, fmt.Sprintf("invalid since: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	changes := s.addressTable.ChangesSince(req.URL.Query().Get("epoch"), since)
+	table := peerTable{
+		Warm:     s.addressTable.IsWarm(),
+		Epoch:    changes.Epoch,
+		Version:  changes.Version,
+		Full:     changes.Full,
+		Entries:  []peerEntry{},
+		Removals: []peerRemoval{},
+	}
+	now := time.Now()
+	for _, entry := range changes.Entries {
+		table.Entries = append(table.Entries, peerEntry{
+			Hostname:  entry.Hostname,
+			IP:        entry.IP,
+			Port:      entry.Port,
+			CellID:    entry.CellID,
+			AZ:        entry.AZ,
+			AppID:     entry.AppID,
+			Unhealthy: entry.Unhealthy,
+			AgeNS:     age(now, entry.UpdateTime),
+		})
+	}
+	for _, removal := range changes.Removals {
+		table.Removals = append(table.Removals, peerRemoval{
+			Hostname: removal.Hostname,
+			IP:       removal.IP,
+			Port:     removal.Port,
+			AgeNS:    age(now, removal.RemoveTime),
+		})
+	}
+
+	json, err := json.Marshal(table)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = resp.Write(json)
+	if err != nil {
+		s.logger.Debug("Error writing to http response body")
+	}
+
+	s.logger.Debug("peer-table-served", lager.Data{"full": table.Full, "entries": len(table.Entries), "removals": len(table.Removals)})
+}
+
+// age returns how long before now t was, in nanoseconds, and never less
+// than 0.
+func age(now, t time.Time) int64 {
+	if t.After(now) {
+		return 0
+	}
+	return int64(now.Sub(t))
+}
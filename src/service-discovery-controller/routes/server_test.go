@@ -4,14 +4,20 @@ import (
 	"test-helpers"
 
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"service-discovery-controller/addresstable"
 	"service-discovery-controller/config"
+	"service-discovery-controller/domains"
 	. "service-discovery-controller/routes"
 	"service-discovery-controller/routes/fakes"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -20,6 +26,7 @@ import (
 	"code.cloudfoundry.org/cf-networking-helpers/testsupport/ports"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/tedsuo/ifrit"
 )
 
@@ -36,6 +43,7 @@ var _ = Describe("Server", func() {
 		testLogger         *lagertest.TestLogger
 		client             *http.Client
 		server             *Server
+		serverConfig       *config.Config
 		port               int
 	)
 
@@ -45,7 +53,7 @@ var _ = Describe("Server", func() {
 		port = ports.PickAPort()
 
 		testLogger = lagertest.NewTestLogger("test")
-		config := &config.Config{
+		serverConfig = &config.Config{
 			Port:       strconv.Itoa(port),
 			Address:    "127.0.0.1",
 			CACert:     caFile,
@@ -55,7 +63,7 @@ var _ = Describe("Server", func() {
 		addressTable = &fakes.AddressTable{}
 		dnsRequestRecorder = &fakes.DNSRequestRecorder{}
 		metricsSender = &fakes.MetricsSender{}
-		server = NewServer(addressTable, config, dnsRequestRecorder, metricsSender, testLogger)
+		server = NewServer(addressTable, serverConfig, dnsRequestRecorder, metricsSender, nil, testLogger)
 		client = testhelpers.NewClient(testhelpers.CertPool(caFile), clientCert)
 	})
 
@@ -64,11 +72,14 @@ var _ = Describe("Server", func() {
 
 		BeforeEach(func() {
 			serverProc = ifrit.Invoke(server)
-			addressTable.LookupStub = func(hostname string) []string {
+			addressTable.LookupEndpointsStub = func(hostname string) []addresstable.Endpoint {
 				if hostname == "app-id.internal.local." {
-					return []string{"192.168.0.2"}
+					return []addresstable.Endpoint{
+						{IP: "192.168.0.2"},
+						{IP: "192.168.0.3", Port: 8080, CellID: "cell-1", AZ: "z1"},
+					}
 				}
-				return []string{}
+				return []addresstable.Endpoint{}
 			}
 			addressTable.IsWarmReturns(true)
 
@@ -101,6 +112,15 @@ var _ = Describe("Server", func() {
 					"service": "",
 					"service_repo_name": "",
 					"tags": {}
+				},
+				{
+					"ip_address": "192.168.0.3",
+					"last_check_in": "",
+					"port": 8080,
+					"revision": "",
+					"service": "",
+					"service_repo_name": "",
+					"tags": {"cell_id": "cell-1", "availability_zone": "z1"}
 				}],
 				"service": ""
 			}`))
@@ -110,6 +130,14 @@ var _ = Describe("Server", func() {
 			Expect(dnsRequestRecorder.RecordRequestCallCount()).To(BeNumerically(">=", 1))
 		})
 
+		It("records the lookup with its hostname, source and number of hosts", func() {
+			Expect(dnsRequestRecorder.RecordLookupCallCount()).To(Equal(1))
+			hostname, source, answers := dnsRequestRecorder.RecordLookupArgsForCall(0)
+			Expect(hostname).To(Equal("app-id.internal.local."))
+			Expect(source).To(Equal("127.0.0.1"))
+			Expect(answers).To(Equal(2))
+		})
+
 		It("invokes our metrics sender", func() {
 			Expect(metricsSender.SendDurationCallCount()).To(BeNumerically(">=", 1))
 			name, time := metricsSender.SendDurationArgsForCall(0)
@@ -118,6 +146,717 @@ var _ = Describe("Server", func() {
 		})
 	})
 
+	Context("when policies are enforced", func() {
+		var (
+			policyFilter *fakes.PolicyFilter
+			named        map[string]testhelpers.NamedCert
+			namedCA      string
+			namedCert    string
+			namedKey     string
+		)
+
+		BeforeEach(func() {
+			namedCA, namedCert, namedKey, named = testhelpers.GenerateCaAndNamedMutualTlsCerts("bosh-dns-adapter", "some-other-client")
+			serverConfig.CACert = namedCA
+			serverConfig.ServerCert = namedCert
+			serverConfig.ServerKey = namedKey
+			serverConfig.SourceIPClientNames = []string{"bosh-dns-adapter"}
+
+			policyFilter = &fakes.PolicyFilter{}
+			policyFilter.ReachableReturns([]addresstable.Endpoint{{IP: "192.168.0.2"}})
+			server = NewServer(addressTable, serverConfig, dnsRequestRecorder, metricsSender, policyFilter, testLogger)
+			serverProc = ifrit.Invoke(server)
+
+			addressTable.LookupEndpointsReturns([]addresstable.Endpoint{
+				{IP: "192.168.0.2"},
+				{IP: "192.168.0.3", AppID: "app-b"},
+			})
+			addressTable.IsWarmReturns(true)
+		})
+
+		AfterEach(func() {
+			serverProc.Signal(os.Interrupt)
+			Eventually(serverProc.Wait()).Should(Receive())
+			os.Remove(namedCA)
+			os.Remove(namedCert)
+			os.Remove(namedKey)
+			for _, cert := range named {
+				os.Remove(cert.CertFileName)
+				os.Remove(cert.PrivateKeyFileName)
+			}
+		})
+
+		get := func(clientName, path, sourceIP string) *http.Response {
+			req, err := http.NewRequest("GET", fmt.Sprintf("https://127.0.0.1:%d%s", port, path), nil)
+			Expect(err).NotTo(HaveOccurred())
+			if sourceIP != "" {
+				req.Header.Set("X-Source-IP", sourceIP)
+			}
+
+			client := testhelpers.NewClient(testhelpers.CertPool(namedCA), named[clientName].Cert)
+			var resp *http.Response
+			Eventually(func() error {
+				resp, err = client.Do(req)
+				return err
+			}).Should(Succeed())
+			return resp
+		}
+
+		getHosts := func(clientName, sourceIP string) []string {
+			resp := get(clientName, "/v1/registration/app-id.internal.local.", sourceIP)
+
+			var body struct {
+				Hosts []struct {
+					IPAddress string `json:"ip_address"`
+				} `json:"hosts"`
+			}
+			Expect(json.NewDecoder(resp.Body).Decode(&body)).To(Succeed())
+			ips := []string{}
+			for _, host := range body.Hosts {
+				ips = append(ips, host.IPAddress)
+			}
+			return ips
+		}
+
+		It("returns the endpoints reachable from the source IP header", func() {
+			Expect(getHosts("bosh-dns-adapter", "10.255.0.1")).To(Equal([]string{"192.168.0.2"}))
+
+			Expect(policyFilter.ReachableCallCount()).To(Equal(1))
+			sourceIP, endpoints := policyFilter.ReachableArgsForCall(0)
+			Expect(sourceIP).To(Equal("10.255.0.1"))
+			Expect(endpoints).To(Equal([]addresstable.Endpoint{
+				{IP: "192.168.0.2"},
+				{IP: "192.168.0.3", AppID: "app-b"},
+			}))
+		})
+
+		It("uses the client IP without a source IP header", func() {
+			getHosts("bosh-dns-adapter", "")
+
+			Expect(policyFilter.ReachableCallCount()).To(Equal(1))
+			sourceIP, _ := policyFilter.ReachableArgsForCall(0)
+			Expect(sourceIP).To(Equal("127.0.0.1"))
+		})
+
+		Context("when the client is not trusted with the source IP header", func() {
+			It("ignores the header and uses the client IP", func() {
+				getHosts("some-other-client", "10.255.0.1")
+
+				Expect(policyFilter.ReachableCallCount()).To(Equal(1))
+				sourceIP, _ := policyFilter.ReachableArgsForCall(0)
+				Expect(sourceIP).To(Equal("127.0.0.1"))
+				Expect(testLogger).To(gbytes.Say("ignored-source-ip-header.*some-other-client"))
+			})
+		})
+
+		Context("when all routes are requested", func() {
+			BeforeEach(func() {
+				addressTable.AllEndpointsReturns(map[string][]addresstable.Endpoint{
+					"app-id.internal.local.": {{IP: "192.168.0.2"}, {IP: "192.168.0.3", AppID: "app-b"}},
+					"other.internal.local.":  {{IP: "192.168.0.4", AppID: "app-c"}},
+				})
+				policyFilter.ReachableStub = func(sourceIP string, endpoints []addresstable.Endpoint) []addresstable.Endpoint {
+					reachable := []addresstable.Endpoint{}
+					for _, endpoint := range endpoints {
+						if endpoint.AppID == "" {
+							reachable = append(reachable, endpoint)
+						}
+					}
+					return reachable
+				}
+			})
+
+			It("only lists the endpoints reachable from the source IP", func() {
+				resp := get("bosh-dns-adapter", "/routes", "10.255.0.1")
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+				respBodyBytes, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(respBodyBytes)).To(MatchJSON(`{
+					"addresses": [
+						{"hostname": "app-id.internal.local.", "ips": ["192.168.0.2"]}
+					]
+				}`))
+
+				Expect(policyFilter.ReachableCallCount()).To(Equal(2))
+				sourceIP, _ := policyFilter.ReachableArgsForCall(0)
+				Expect(sourceIP).To(Equal("10.255.0.1"))
+			})
+		})
+	})
+
+	Context("when domains are configured", func() {
+		BeforeEach(func() {
+			serverConfig.Domains = []config.DomainConfig{
+				{Name: "internal.local", TTLSeconds: 5},
+				{Name: "other.local"},
+			}
+			server = NewServer(addressTable, serverConfig, dnsRequestRecorder, metricsSender, nil, testLogger)
+			serverProc = ifrit.Invoke(server)
+			addressTable.IsWarmReturns(true)
+			addressTable.LookupEndpointsReturns([]addresstable.Endpoint{{IP: "192.168.0.2"}})
+		})
+
+		AfterEach(func() {
+			serverProc.Signal(os.Interrupt)
+			Eventually(serverProc.Wait()).Should(Receive())
+		})
+
+		getRegistration := func(hostname string) string {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/registration/%s", port, hostname))
+				return err
+			}).Should(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			respBodyBytes, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return string(respBodyBytes)
+		}
+
+		It("returns the TTL of the domain of the hostname", func() {
+			respBody := getRegistration("app-id.internal.local.")
+			Expect(respBody).To(ContainSubstring(`"ip_address":"192.168.0.2"`))
+			Expect(respBody).To(ContainSubstring(`"ttl_seconds":5`))
+		})
+
+		It("leaves out the TTL when the domain has none", func() {
+			respBody := getRegistration("app-id.other.local.")
+			Expect(respBody).To(ContainSubstring(`"ip_address":"192.168.0.2"`))
+			Expect(respBody).NotTo(ContainSubstring("ttl_seconds"))
+		})
+
+		It("answers hostnames outside the domains with a 404", func() {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/registration/%s", port, "app-id.unknown.local."))
+				return err
+			}).Should(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+			Expect(addressTable.LookupEndpointsCallCount()).To(Equal(0))
+
+			hostname, _, answers := dnsRequestRecorder.RecordLookupArgsForCall(0)
+			Expect(hostname).To(Equal("app-id.unknown.local."))
+			Expect(answers).To(Equal(0))
+		})
+
+		It("answers with the domains that were set last", func() {
+			server.SetDomains(domains.NewSet([]domains.Domain{{Name: "unknown.local", TTL: 7 * time.Second}}))
+
+			respBody := getRegistration("app-id.unknown.local.")
+			Expect(respBody).To(ContainSubstring(`"ttl_seconds":7`))
+
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/registration/%s", port, "app-id.internal.local."))
+				return err
+			}).Should(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("leaves hostnames outside the domains out of a batch", func() {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Post(fmt.Sprintf("https://127.0.0.1:%d/v1/registrations", port), "application/json",
+					strings.NewReader(`{"hostnames": ["app-id.internal.local.", "app-id.unknown.local."]}`))
+				return err
+			}).Should(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			var response struct {
+				Registrations map[string]interface{} `json:"registrations"`
+			}
+			Expect(json.NewDecoder(resp.Body).Decode(&response)).To(Succeed())
+			Expect(response.Registrations).To(HaveKey("app-id.internal.local."))
+			Expect(response.Registrations).NotTo(HaveKey("app-id.unknown.local."))
+			Expect(dnsRequestRecorder.RecordLookupCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("when an IP is looked up in reverse", func() {
+		BeforeEach(func() {
+			serverConfig.Domains = []config.DomainConfig{{Name: "internal.local"}}
+			addressTable.IsWarmReturns(true)
+			addressTable.ReverseLookupReturns([]string{"app-id.internal.local.", "app-id.other.local."})
+			addressTable.AppIDForIPReturns("app-a")
+		})
+
+		JustBeforeEach(func() {
+			serverProc = ifrit.Invoke(server)
+		})
+
+		AfterEach(func() {
+			serverProc.Signal(os.Interrupt)
+			Eventually(serverProc.Wait()).Should(Receive())
+		})
+
+		getReverse := func(ip string) (int, string) {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/reverse/%s", port, ip))
+				return err
+			}).Should(BeNil())
+
+			respBodyBytes, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return resp.StatusCode, string(respBodyBytes)
+		}
+
+		Context("without policies", func() {
+			BeforeEach(func() {
+				server = NewServer(addressTable, serverConfig, dnsRequestRecorder, metricsSender, nil, testLogger)
+			})
+
+			It("returns the hostnames in the configured domains", func() {
+				status, body := getReverse("10.255.3.4")
+				Expect(status).To(Equal(http.StatusOK))
+				Expect(body).To(MatchJSON(`{"ip": "10.255.3.4", "hostnames": ["app-id.internal.local."]}`))
+				Expect(addressTable.ReverseLookupArgsForCall(0)).To(Equal("10.255.3.4"))
+				Expect(dnsRequestRecorder.RecordRequestCallCount()).To(Equal(1))
+			})
+
+			It("rejects an invalid IP", func() {
+				status, _ := getReverse("not-an-ip")
+				Expect(status).To(Equal(http.StatusBadRequest))
+			})
+
+			It("returns an internal server error when the address table is not warm", func() {
+				addressTable.IsWarmReturns(false)
+				status, body := getReverse("10.255.3.4")
+				Expect(status).To(Equal(http.StatusInternalServerError))
+				Expect(body).To(ContainSubstring("address table is not warm"))
+			})
+		})
+
+		Context("when policies are enforced", func() {
+			var policyFilter *fakes.PolicyFilter
+
+			BeforeEach(func() {
+				policyFilter = &fakes.PolicyFilter{}
+				server = NewServer(addressTable, serverConfig, dnsRequestRecorder, metricsSender, policyFilter, testLogger)
+			})
+
+			It("returns the hostnames to containers that may reach the IP", func() {
+				policyFilter.ReachableStub = func(sourceIP string, endpoints []addresstable.Endpoint) []addresstable.Endpoint {
+					return endpoints
+				}
+
+				_, body := getReverse("10.255.3.4")
+				Expect(body).To(MatchJSON(`{"ip": "10.255.3.4", "hostnames": ["app-id.internal.local."]}`))
+
+				sourceIP, endpoints := policyFilter.ReachableArgsForCall(0)
+				Expect(sourceIP).To(Equal("127.0.0.1"))
+				Expect(endpoints).To(Equal([]addresstable.Endpoint{{IP: "10.255.3.4", AppID: "app-a"}}))
+			})
+
+			It("returns no hostnames to other containers", func() {
+				policyFilter.ReachableReturns([]addresstable.Endpoint{})
+
+				_, body := getReverse("10.255.3.4")
+				Expect(body).To(MatchJSON(`{"ip": "10.255.3.4", "hostnames": []}`))
+			})
+		})
+	})
+
+	Context("when a family is requested", func() {
+		BeforeEach(func() {
+			serverProc = ifrit.Invoke(server)
+			addressTable.IsWarmReturns(true)
+			addressTable.LookupFamilyReturns([]addresstable.Endpoint{{IP: "fd00::1"}})
+		})
+
+		AfterEach(func() {
+			serverProc.Signal(os.Interrupt)
+			Eventually(serverProc.Wait()).Should(Receive())
+		})
+
+		It("returns only addresses of that family", func() {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/registration/app-id.internal.local.?family=ipv6", port))
+				return err
+			}).Should(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			respBodyBytes, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(respBodyBytes)).To(ContainSubstring(`"ip_address":"fd00::1"`))
+
+			Expect(addressTable.LookupFamilyCallCount()).To(Equal(1))
+			hostname, family := addressTable.LookupFamilyArgsForCall(0)
+			Expect(hostname).To(Equal("app-id.internal.local."))
+			Expect(family).To(Equal(addresstable.IPv6))
+			Expect(addressTable.LookupEndpointsCallCount()).To(Equal(0))
+		})
+
+		It("rejects an unknown family", func() {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/registration/app-id.internal.local.?family=ipx", port))
+				return err
+			}).Should(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+			respBodyBytes, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(respBodyBytes)).To(ContainSubstring(`invalid address family "ipx"`))
+		})
+	})
+
+	Context("when a batch of hostnames is requested", func() {
+		BeforeEach(func() {
+			serverProc = ifrit.Invoke(server)
+			addressTable.IsWarmReturns(true)
+			addressTable.LookupEndpointsStub = func(hostname string) []addresstable.Endpoint {
+				if hostname == "app-id.internal.local." {
+					return []addresstable.Endpoint{{IP: "192.168.0.2", Port: 8080}}
+				}
+				return []addresstable.Endpoint{}
+			}
+		})
+
+		AfterEach(func() {
+			serverProc.Signal(os.Interrupt)
+			Eventually(serverProc.Wait()).Should(Receive())
+		})
+
+		postBatch := func(body string) *http.Response {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Post(fmt.Sprintf("https://127.0.0.1:%d/v1/registrations", port), "application/json", strings.NewReader(body))
+				return err
+			}).Should(BeNil())
+			return resp
+		}
+
+		It("returns the registrations of every hostname", func() {
+			resp := postBatch(`{"hostnames": ["app-id.internal.local.", "missing.internal.local."]}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			respBodyBytes, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(respBodyBytes)).To(MatchJSON(`{
+				"registrations": {
+					"app-id.internal.local.": {
+						"env": "",
+						"hosts": [{
+							"ip_address": "192.168.0.2",
+							"last_check_in": "",
+							"port": 8080,
+							"revision": "",
+							"service": "",
+							"service_repo_name": "",
+							"tags": {}
+						}],
+						"service": ""
+					},
+					"missing.internal.local.": {"env": "", "hosts": [], "service": ""}
+				}
+			}`))
+			Expect(dnsRequestRecorder.RecordRequestCallCount()).To(Equal(2))
+			Expect(dnsRequestRecorder.RecordLookupCallCount()).To(Equal(2))
+		})
+
+		It("looks up only the requested family", func() {
+			resp := postBatch(`{"hostnames": ["app-id.internal.local."], "family": "ipv6"}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(addressTable.LookupFamilyCallCount()).To(Equal(1))
+			_, family := addressTable.LookupFamilyArgsForCall(0)
+			Expect(family).To(Equal(addresstable.IPv6))
+		})
+
+		It("rejects a request without hostnames", func() {
+			resp := postBatch(`{"hostnames": []}`)
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects an invalid body", func() {
+			resp := postBatch(`{`)
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects other methods", func() {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/registrations", port))
+				return err
+			}).Should(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+
+	Context("when hostnames are watched", func() {
+		var (
+			changes   chan struct{}
+			stopCount int32
+			endpoints []addresstable.Endpoint
+			lock      sync.Mutex
+		)
+
+		BeforeEach(func() {
+			serverProc = ifrit.Invoke(server)
+			addressTable.IsWarmReturns(true)
+
+			changes = make(chan struct{}, 1)
+			atomic.StoreInt32(&stopCount, 0)
+			addressTable.WatchReturns(changes, func() { atomic.AddInt32(&stopCount, 1) })
+
+			endpoints = []addresstable.Endpoint{{IP: "192.168.0.2", Port: 8080}}
+			addressTable.LookupEndpointsStub = func(hostname string) []addresstable.Endpoint {
+				lock.Lock()
+				defer lock.Unlock()
+				if hostname == "app-id.internal.local." {
+					return endpoints
+				}
+				return []addresstable.Endpoint{}
+			}
+		})
+
+		AfterEach(func() {
+			serverProc.Signal(os.Interrupt)
+			Eventually(serverProc.Wait()).Should(Receive())
+		})
+
+		watch := func(query string) *http.Response {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/watch?%s", port, query))
+				return err
+			}).Should(BeNil())
+			return resp
+		}
+
+		It("streams the registrations and their changes", func() {
+			resp := watch("name=app-id.internal.local.&name=other.internal.local.")
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+			Expect(addressTable.WatchCallCount()).To(Equal(1))
+			Expect(addressTable.WatchArgsForCall(0)).To(Equal([]string{"app-id.internal.local.", "other.internal.local."}))
+
+			body := gbytes.BufferReader(resp.Body)
+			Eventually(body).Should(gbytes.Say(`event: registration\ndata: {"hostname":"app-id.internal.local.","hosts":\[{"ip_address":"192.168.0.2"`))
+			Eventually(body).Should(gbytes.Say(`event: registration\ndata: {"hostname":"other.internal.local.","hosts":\[\]}`))
+
+			lock.Lock()
+			endpoints = []addresstable.Endpoint{{IP: "192.168.0.3", Port: 8080}}
+			lock.Unlock()
+			changes <- struct{}{}
+
+			Eventually(body).Should(gbytes.Say(`event: registration\ndata: {"hostname":"app-id.internal.local.","hosts":\[{"ip_address":"192.168.0.3"`))
+			Consistently(body).ShouldNot(gbytes.Say("other.internal.local."))
+		})
+
+		It("stops watching when the client disconnects", func() {
+			resp := watch("name=app-id.internal.local.")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			resp.Body.Close()
+
+			Eventually(func() int32 { return atomic.LoadInt32(&stopCount) }).Should(Equal(int32(1)))
+		})
+
+		It("rejects a watch without names", func() {
+			resp := watch("")
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(addressTable.WatchCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when a peer requests the table", func() {
+		var (
+			named     map[string]testhelpers.NamedCert
+			peerCA    string
+			peerCert  string
+			peerKey   string
+			updatedAt time.Time
+		)
+
+		BeforeEach(func() {
+			peerCA, peerCert, peerKey, named = testhelpers.GenerateCaAndNamedMutualTlsCerts("service-discovery-controller", "bosh-dns-adapter")
+			serverConfig.CACert = peerCA
+			serverConfig.ServerCert = peerCert
+			serverConfig.ServerKey = peerKey
+			serverConfig.PeerClientCert = named["service-discovery-controller"].CertFileName
+			serverProc = ifrit.Invoke(server)
+
+			updatedAt = time.Now().Add(-time.Minute)
+			addressTable.IsWarmReturns(true)
+			addressTable.ChangesSinceReturns(addresstable.Changes{
+				Epoch:   "some-epoch",
+				Version: 42,
+				Full:    true,
+				Entries: []addresstable.SnapshotEntry{
+					{Hostname: "app-id.internal.local.", IP: "192.168.0.2", Port: 8080, CellID: "cell-1", AZ: "z1", AppID: "app-1", UpdateTime: updatedAt},
+					{Hostname: "other.internal.local.", IP: "192.168.0.3", Unhealthy: true, UpdateTime: time.Now().Add(time.Hour)},
+				},
+				Removals: []addresstable.Removal{
+					{Hostname: "app-id.internal.local.", IP: "192.168.0.4", RemoveTime: updatedAt},
+				},
+			})
+		})
+
+		AfterEach(func() {
+			serverProc.Signal(os.Interrupt)
+			Eventually(serverProc.Wait()).Should(Receive())
+			os.Remove(peerCA)
+			os.Remove(peerCert)
+			os.Remove(peerKey)
+			for _, cert := range named {
+				os.Remove(cert.CertFileName)
+				os.Remove(cert.PrivateKeyFileName)
+			}
+		})
+
+		get := func(cert tls.Certificate, query string) *http.Response {
+			client := testhelpers.NewClient(testhelpers.CertPool(peerCA), cert)
+			var resp *http.Response
+			Eventually(func() error {
+				var err error
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/peer/table%s", port, query))
+				return err
+			}).Should(Succeed())
+			return resp
+		}
+
+		It("returns the changes since the version, with the age of each entry and removal", func() {
+			resp := get(named["service-discovery-controller"].Cert, "?epoch=some-epoch&since=41")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(addressTable.ChangesSinceCallCount()).To(Equal(1))
+			epoch, since := addressTable.ChangesSinceArgsForCall(0)
+			Expect(epoch).To(Equal("some-epoch"))
+			Expect(since).To(Equal(uint64(41)))
+
+			var table struct {
+				Warm     bool   `json:"warm"`
+				Epoch    string `json:"epoch"`
+				Version  uint64 `json:"version"`
+				Full     bool   `json:"full"`
+				Entries  []map[string]interface{}
+				Removals []map[string]interface{}
+			}
+			Expect(json.NewDecoder(resp.Body).Decode(&table)).To(Succeed())
+			Expect(table.Warm).To(BeTrue())
+			Expect(table.Epoch).To(Equal("some-epoch"))
+			Expect(table.Version).To(Equal(uint64(42)))
+			Expect(table.Full).To(BeTrue())
+
+			Expect(table.Entries).To(HaveLen(2))
+			Expect(table.Entries[0]).To(HaveKeyWithValue("hostname", "app-id.internal.local."))
+			Expect(table.Entries[0]).To(HaveKeyWithValue("ip", "192.168.0.2"))
+			Expect(table.Entries[0]).To(HaveKeyWithValue("port", BeNumerically("==", 8080)))
+			Expect(table.Entries[0]).To(HaveKeyWithValue("cell_id", "cell-1"))
+			Expect(table.Entries[0]).To(HaveKeyWithValue("availability_zone", "z1"))
+			Expect(table.Entries[0]).To(HaveKeyWithValue("app_id", "app-1"))
+			Expect(table.Entries[0]).To(HaveKeyWithValue("age_ns", BeNumerically("~", float64(time.Minute), float64(10*time.Second))))
+			Expect(table.Entries[1]).To(HaveKeyWithValue("unhealthy", true))
+			Expect(table.Entries[1]).To(HaveKeyWithValue("age_ns", BeNumerically("==", 0)))
+
+			Expect(table.Removals).To(HaveLen(1))
+			Expect(table.Removals[0]).To(HaveKeyWithValue("ip", "192.168.0.4"))
+			Expect(table.Removals[0]).To(HaveKeyWithValue("age_ns", BeNumerically("~", float64(time.Minute), float64(10*time.Second))))
+		})
+
+		It("asks for every change without a version", func() {
+			resp := get(named["service-discovery-controller"].Cert, "")
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			epoch, since := addressTable.ChangesSinceArgsForCall(0)
+			Expect(epoch).To(BeEmpty())
+			Expect(since).To(BeZero())
+		})
+
+		It("rejects an invalid version", func() {
+			resp := get(named["service-discovery-controller"].Cert, "?since=banana")
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(addressTable.ChangesSinceCallCount()).To(Equal(0))
+		})
+
+		Context("when the client is not a peer", func() {
+			It("refuses the request", func() {
+				resp := get(named["bosh-dns-adapter"].Cert, "")
+				Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+				Expect(addressTable.ChangesSinceCallCount()).To(Equal(0))
+				Expect(testLogger).To(gbytes.Say("peer-table-refused.*bosh-dns-adapter"))
+			})
+		})
+	})
+
+	Context("when peer replication is not configured and the table is requested", func() {
+		BeforeEach(func() {
+			serverProc = ifrit.Invoke(server)
+		})
+
+		AfterEach(func() {
+			serverProc.Signal(os.Interrupt)
+			Eventually(serverProc.Wait()).Should(Receive())
+		})
+
+		It("refuses the request", func() {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/v1/peer/table", port))
+				return err
+			}).Should(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("when all routes are requested", func() {
+		BeforeEach(func() {
+			serverProc = ifrit.Invoke(server)
+			addressTable.AllEndpointsReturns(map[string][]addresstable.Endpoint{
+				"app-id.internal.local.": {
+					{IP: "192.168.0.1"},
+					{IP: "192.168.0.2", Unhealthy: true},
+					{IP: "192.168.0.3", Port: 8080},
+					{IP: "192.168.0.3", Port: 9090, Unhealthy: true},
+				},
+			})
+		})
+
+		AfterEach(func() {
+			serverProc.Signal(os.Interrupt)
+			Eventually(serverProc.Wait()).Should(Receive())
+		})
+
+		It("lists the IPs of each hostname and the unhealthy ones", func() {
+			var resp *http.Response
+			var err error
+			Eventually(func() error {
+				resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/routes", port))
+				return err
+			}).Should(BeNil())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			respBodyBytes, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(respBodyBytes)).To(MatchJSON(`{
+				"addresses": [
+					{
+						"hostname": "app-id.internal.local.",
+						"ips": ["192.168.0.1", "192.168.0.2", "192.168.0.3"],
+						"unhealthy_ips": ["192.168.0.2"]
+					}
+				]
+			}`))
+		})
+	})
+
 	Context("when the address table is not warm", func() {
 		var (
 			resp *http.Response
@@ -155,6 +894,50 @@ var _ = Describe("Server", func() {
 		})
 	})
 
+	Context("when the certificates are reloaded", func() {
+		var (
+			newCAFile     string
+			newServerCert string
+			newServerKey  string
+			newClientCert tls.Certificate
+		)
+
+		BeforeEach(func() {
+			newCAFile, newServerCert, newServerKey, newClientCert = testhelpers.GenerateCaAndMutualTlsCerts()
+			serverProc = ifrit.Invoke(server)
+			addressTable.IsWarmReturns(true)
+		})
+
+		AfterEach(func() {
+			serverProc.Signal(os.Interrupt)
+			Eventually(serverProc.Wait()).Should(Receive())
+			os.Remove(newCAFile)
+			os.Remove(newServerCert)
+			os.Remove(newServerKey)
+		})
+
+		It("serves new connections with the new certificates without restarting", func() {
+			url := fmt.Sprintf("https://127.0.0.1:%d/v1/registration/app-id.internal.local.", port)
+			Eventually(func() error {
+				_, err := client.Get(url)
+				return err
+			}).Should(Succeed())
+
+			Expect(server.ReloadCertificates(&config.Config{
+				CACert:     newCAFile,
+				ServerCert: newServerCert,
+				ServerKey:  newServerKey,
+			})).To(Succeed())
+
+			_, err := testhelpers.NewClient(testhelpers.CertPool(caFile), clientCert).Get(url)
+			Expect(err).To(HaveOccurred())
+
+			resp, err := testhelpers.NewClient(testhelpers.CertPool(newCAFile), newClientCert).Get(url)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
 	Context("when signaled an interrupt", func() {
 		It("shuts down", func() {
 			serverProc = ifrit.Invoke(server)
@@ -0,0 +1,175 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"service-discovery-controller/addresstable"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+)
+
+const version = 1
+
+type file struct {
+	Version int     `json:"version"`
+	Entries []entry `json:"entries"`
+}
+
+type entry struct {
+	Hostname     string `json:"hostname"`
+	IP           string `json:"ip"`
+	Port         uint16 `json:"port,omitempty"`
+	CellID       string `json:"cell_id,omitempty"`
+	AZ           string `json:"availability_zone,omitempty"`
+	AppID        string `json:"app_id,omitempty"`
+	Unhealthy    bool   `json:"unhealthy,omitempty"`
+	UpdateTimeNS int64  `json:"update_time_ns"`
+}
+
+//go:generate counterfeiter -o fakes/address_table.go --fake-name AddressTable . AddressTable
+type AddressTable interface {
+	Snapshot() []addresstable.SnapshotEntry
+}
+
+// Snapshotter periodically writes the address table to Path, and once more
+// when it is signalled to stop.
+type Snapshotter struct {
+	Table    AddressTable
+	Path     string
+	Interval time.Duration
+	Clock    clock.Clock
+	Logger   lager.Logger
+}
+
+func (s *Snapshotter) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ticker := s.Clock.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	close(ready)
+
+	for {
+		select {
+		case <-ticker.C():
+			s.save()
+		case <-signals:
+			s.save()
+			return nil
+		}
+	}
+}
+
+func (s *Snapshotter) save() {
+	entries := s.Table.Snapshot()
+	err := Save(s.Path, entries)
+	if err != nil {
+		s.Logger.Error("save-snapshot", err)
+		return
+	}
+	s.Logger.Debug("saved-snapshot", lager.Data{"entries": len(entries)})
+}
+
+// Save replaces the snapshot at path. The new snapshot is written and synced
+// to a temporary file first, which is then renamed over path, and the rename
+// is synced to the directory, so that a crash leaves either the previous
+// snapshot or the new one, never a partial one.
+func Save(path string, entries []addresstable.SnapshotEntry) error {
+	snapshot := file{Version: version, Entries: []entry{}}
+	for _, e := range entries {
+		snapshot.Entries = append(snapshot.Entries, entry{
+			Hostname:     e.Hostname,
+			IP:           e.IP,
+			Port:         e.Port,
+			CellID:       e.CellID,
+			AZ:           e.AZ,
+			AppID:        e.AppID,
+			Unhealthy:    e.Unhealthy,
+			UpdateTimeNS: e.UpdateTime.UnixNano(),
+		})
+	}
+
+	bytes, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %s", err) // not tested
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("create temp file: %s", err)
+	}
+
+	_, err = tempFile.Write(bytes)
+	if err == nil {
+		err = tempFile.Sync()
+	}
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("write temp file: %s", err)
+	}
+
+	err = os.Rename(tempFile.Name(), path)
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("rename temp file: %s", err)
+	}
+
+	err = syncDir(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("sync snapshot directory: %s", err)
+	}
+	return nil
+}
+
+// syncDir flushes the entries of the directory at path, such as a file
+// renamed into it, to disk.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// Load reads the snapshot at path. A missing snapshot is not an error.
+func Load(path string) ([]addresstable.SnapshotEntry, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []addresstable.SnapshotEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %s", err)
+	}
+
+	var snapshot file
+	err = json.Unmarshal(bytes, &snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal snapshot: %s", err)
+	}
+	if snapshot.Version != version {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+
+	entries := []addresstable.SnapshotEntry{}
+	for _, e := range snapshot.Entries {
+		entries = append(entries, addresstable.SnapshotEntry{
+			Hostname:   e.Hostname,
+			IP:         e.IP,
+			Port:       e.Port,
+			CellID:     e.CellID,
+			AZ:         e.AZ,
+			AppID:      e.AppID,
+			Unhealthy:  e.Unhealthy,
+			UpdateTime: time.Unix(0, e.UpdateTimeNS),
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,158 @@
+package snapshot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"service-discovery-controller/addresstable"
+	"service-discovery-controller/snapshot"
+	"service-discovery-controller/snapshot/fakes"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Snapshot", func() {
+	var (
+		dir     string
+		path    string
+		entries []addresstable.SnapshotEntry
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "snapshot")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "address-table.json")
+
+		entries = []addresstable.SnapshotEntry{
+			{Hostname: "foo.com.", IP: "192.0.0.1", UpdateTime: time.Unix(0, 1000)},
+			{Hostname: "foo.com.", IP: "fd00::1", Port: 8080, CellID: "cell-1", AZ: "z1", AppID: "app-1", Unhealthy: true, UpdateTime: time.Unix(0, 2000)},
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("Save and Load", func() {
+		It("round trips the entries", func() {
+			Expect(snapshot.Save(path, entries)).To(Succeed())
+
+			loaded, err := snapshot.Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(Equal(entries))
+		})
+
+		It("replaces an existing snapshot without leaving temp files behind", func() {
+			Expect(snapshot.Save(path, entries)).To(Succeed())
+			Expect(snapshot.Save(path, entries[:1])).To(Succeed())
+
+			loaded, err := snapshot.Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(Equal(entries[:1]))
+
+			files, err := ioutil.ReadDir(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+		})
+
+		Context("when the directory does not exist", func() {
+			It("returns an error", func() {
+				err := snapshot.Save(filepath.Join(dir, "missing", "address-table.json"), entries)
+				Expect(err).To(MatchError(ContainSubstring("create temp file")))
+			})
+		})
+	})
+
+	Describe("Load", func() {
+		Context("when there is no snapshot", func() {
+			It("returns no entries", func() {
+				loaded, err := snapshot.Load(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(loaded).To(BeEmpty())
+			})
+		})
+
+		Context("when the snapshot is garbage", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(path, []byte("garbage"), 0600)).To(Succeed())
+				_, err := snapshot.Load(path)
+				Expect(err).To(MatchError(ContainSubstring("unmarshal snapshot")))
+			})
+		})
+
+		Context("when the snapshot has an unknown version", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(path, []byte(`{"version": 99, "entries": []}`), 0600)).To(Succeed())
+				_, err := snapshot.Load(path)
+				Expect(err).To(MatchError("unsupported snapshot version 99"))
+			})
+		})
+	})
+
+	Describe("Snapshotter", func() {
+		var (
+			fakeTable   *fakes.AddressTable
+			fakeClock   *fakeclock.FakeClock
+			logger      *lagertest.TestLogger
+			snapshotter *snapshot.Snapshotter
+			process     ifrit.Process
+		)
+
+		BeforeEach(func() {
+			fakeTable = &fakes.AddressTable{}
+			fakeTable.SnapshotReturns(entries)
+			fakeClock = fakeclock.NewFakeClock(time.Now())
+			logger = lagertest.NewTestLogger("test")
+			snapshotter = &snapshot.Snapshotter{
+				Table:    fakeTable,
+				Path:     path,
+				Interval: time.Minute,
+				Clock:    fakeClock,
+				Logger:   logger,
+			}
+			process = ifrit.Invoke(snapshotter)
+		})
+
+		AfterEach(func() {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+		})
+
+		It("saves a snapshot on each interval", func() {
+			Consistently(fakeTable.SnapshotCallCount).Should(Equal(0))
+
+			fakeClock.WaitForWatcherAndIncrement(time.Minute)
+			Eventually(fakeTable.SnapshotCallCount).Should(Equal(1))
+
+			loaded, err := snapshot.Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(Equal(entries))
+		})
+
+		It("saves a snapshot when signalled", func() {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+
+			Expect(fakeTable.SnapshotCallCount()).To(Equal(1))
+			Expect(path).To(BeAnExistingFile())
+		})
+
+		Context("when saving fails", func() {
+			BeforeEach(func() {
+				os.RemoveAll(dir)
+			})
+
+			It("logs the error and keeps running", func() {
+				fakeClock.WaitForWatcherAndIncrement(time.Minute)
+				Eventually(logger.LogMessages).Should(ContainElement("test.save-snapshot"))
+				Consistently(process.Wait()).ShouldNot(Receive())
+			})
+		})
+	})
+})
@@ -0,0 +1,130 @@
+package topmetrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/cf-networking-helpers/metrics"
+)
+
+//go:generate counterfeiter -o fakes/sender.go --fake-name Sender . Sender
+type Sender interface {
+	SendValue(name string, value float64, unit string)
+}
+
+// Counter counts occurrences of keys, such as hostnames or IPs, between
+// reads. To bound its memory, at most maxKeys keys are counted separately.
+// Occurrences of other keys are counted under the empty key, so that they
+// are still part of the total.
+type Counter struct {
+	maxKeys int
+	counts  map[string]int
+	mutex   sync.Mutex
+}
+
+func NewCounter(maxKeys int) *Counter {
+	return &Counter{
+		maxKeys: maxKeys,
+		counts:  map[string]int{},
+	}
+}
+
+func (c *Counter) Add(key string, n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.counts[key]; !ok && len(c.counts) >= c.maxKeys {
+		key = ""
+	}
+	c.counts[key] += n
+}
+
+// Take returns the counts since the last call, and resets them.
+func (c *Counter) Take() map[string]int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	counts := c.counts
+	c.counts = map[string]int{}
+	return counts
+}
+
+// Source returns a metric source for the total of the counts returned by
+// take. Each time it is read, it also sends the counts of the top n keys as
+// name.<key>, so that the busiest keys can be told apart without emitting a
+// metric for every key.
+func Source(name, unit string, take func() map[string]int, n int, sender Sender) metrics.MetricSource {
+	return metrics.MetricSource{
+		Name: name,
+		Unit: unit,
+		Getter: func() (float64, error) {
+			counts := take()
+
+			total := 0
+			for _, count := range counts {
+				total += count
+			}
+
+			for _, key := range Top(counts, n) {
+				sender.SendValue(name+"."+strings.TrimSuffix(key, "."), float64(counts[key]), unit)
+			}
+			return float64(total), nil
+		},
+	}
+}
+
+// DistributionSource returns a metric source for the number of values
+// returned by values. Each time it is read, it also sends their median, 95th
+// percentile and maximum as name.p50, name.p95 and name.max.
+func DistributionSource(name, unit string, values func() []int, sender Sender) metrics.MetricSource {
+	return metrics.MetricSource{
+		Name: name,
+		Unit: unit,
+		Getter: func() (float64, error) {
+			sorted := values()
+			if len(sorted) == 0 {
+				return 0, nil
+			}
+			sort.Ints(sorted)
+
+			sender.SendValue(name+".p50", float64(percentile(sorted, 50)), unit)
+			sender.SendValue(name+".p95", float64(percentile(sorted, 95)), unit)
+			sender.SendValue(name+".max", float64(sorted[len(sorted)-1]), unit)
+			return float64(len(sorted)), nil
+		},
+	}
+}
+
+// Top returns the n keys with the highest counts, highest first. Ties are
+// ordered by key. The empty key is never returned.
+func Top(counts map[string]int, n int) []string {
+	keys := []string{}
+	for key := range counts {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// percentile returns the value at or below which p percent of the sorted
+// values fall.
+func percentile(sorted []int, p int) int {
+	index := (len(sorted)*p+99)/100 - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index]
+}
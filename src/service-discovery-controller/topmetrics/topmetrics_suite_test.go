@@ -0,0 +1,13 @@
+package topmetrics_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestTopmetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Topmetrics Suite")
+}
@@ -0,0 +1,108 @@
+package topmetrics_test
+
+import (
+	"service-discovery-controller/topmetrics"
+	"service-discovery-controller/topmetrics/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Counter", func() {
+	var counter *topmetrics.Counter
+
+	BeforeEach(func() {
+		counter = topmetrics.NewCounter(2)
+	})
+
+	It("returns the counts since the last call", func() {
+		counter.Add("a.apps.internal.", 1)
+		counter.Add("a.apps.internal.", 2)
+		counter.Add("b.apps.internal.", 1)
+
+		Expect(counter.Take()).To(Equal(map[string]int{"a.apps.internal.": 3, "b.apps.internal.": 1}))
+		Expect(counter.Take()).To(BeEmpty())
+	})
+
+	It("counts keys beyond the limit under the empty key", func() {
+		counter.Add("a.apps.internal.", 1)
+		counter.Add("b.apps.internal.", 1)
+		counter.Add("c.apps.internal.", 1)
+		counter.Add("d.apps.internal.", 1)
+		counter.Add("a.apps.internal.", 1)
+
+		Expect(counter.Take()).To(Equal(map[string]int{"a.apps.internal.": 2, "b.apps.internal.": 1, "": 2}))
+	})
+})
+
+var _ = Describe("Top", func() {
+	It("returns the keys with the highest counts, ordering ties by key", func() {
+		counts := map[string]int{"a": 1, "b": 5, "c": 3, "d": 3, "": 10}
+		Expect(topmetrics.Top(counts, 3)).To(Equal([]string{"b", "c", "d"}))
+		Expect(topmetrics.Top(counts, 10)).To(Equal([]string{"b", "c", "d", "a"}))
+		Expect(topmetrics.Top(counts, 0)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Source", func() {
+	var sender *fakes.Sender
+
+	BeforeEach(func() {
+		sender = &fakes.Sender{}
+	})
+
+	It("returns the total and sends the top counts", func() {
+		source := topmetrics.Source("lookups", "request", func() map[string]int {
+			return map[string]int{"a.apps.internal.": 3, "b.apps.internal.": 1, "": 4}
+		}, 1, sender)
+
+		Expect(source.Name).To(Equal("lookups"))
+		Expect(source.Getter()).To(Equal(float64(8)))
+
+		Expect(sender.SendValueCallCount()).To(Equal(1))
+		name, value, unit := sender.SendValueArgsForCall(0)
+		Expect(name).To(Equal("lookups.a.apps.internal"))
+		Expect(value).To(Equal(float64(3)))
+		Expect(unit).To(Equal("request"))
+	})
+})
+
+var _ = Describe("DistributionSource", func() {
+	var sender *fakes.Sender
+
+	BeforeEach(func() {
+		sender = &fakes.Sender{}
+	})
+
+	It("returns the number of values and sends their distribution", func() {
+		values := []int{}
+		for i := 20; i > 0; i-- {
+			values = append(values, i)
+		}
+		source := topmetrics.DistributionSource("entriesPerHostname", "entry", func() []int {
+			return values
+		}, sender)
+
+		Expect(source.Getter()).To(Equal(float64(20)))
+
+		sent := map[string]float64{}
+		for i := 0; i < sender.SendValueCallCount(); i++ {
+			name, value, _ := sender.SendValueArgsForCall(i)
+			sent[name] = value
+		}
+		Expect(sent).To(Equal(map[string]float64{
+			"entriesPerHostname.p50": 10,
+			"entriesPerHostname.p95": 19,
+			"entriesPerHostname.max": 20,
+		}))
+	})
+
+	It("sends nothing when there are no values", func() {
+		source := topmetrics.DistributionSource("entriesPerHostname", "entry", func() []int {
+			return nil
+		}, sender)
+
+		Expect(source.Getter()).To(Equal(float64(0)))
+		Expect(sender.SendValueCallCount()).To(Equal(0))
+	})
+})
@@ -37,6 +37,39 @@ func GenerateCaAndMutualTlsCerts() (caFileName string, certFileName string, priv
 	return
 }
 
+// NamedCert is a client certificate, and the files it was written to.
+type NamedCert struct {
+	CertFileName       string
+	PrivateKeyFileName string
+	Cert               tls.Certificate
+}
+
+// GenerateCaAndNamedMutualTlsCerts is GenerateCaAndMutualTlsCerts, with a
+// certificate signed by the same CA for each of commonNames.
+func GenerateCaAndNamedMutualTlsCerts(commonNames ...string) (caFileName string, certFileName string, privateKeyFileName string, named map[string]NamedCert) {
+	caFileName, privKey, err := buildCaFile()
+	Expect(err).NotTo(HaveOccurred())
+
+	certPem, keyPem, err := buildCertPem(privKey, caFileName)
+	Expect(err).NotTo(HaveOccurred())
+	certFileName = writeClientCredFile(certPem)
+	privateKeyFileName = writeClientCredFile(keyPem)
+
+	named = map[string]NamedCert{}
+	for _, commonName := range commonNames {
+		certPem, keyPem, err := buildCertPemWithCommonName(privKey, caFileName, commonName)
+		Expect(err).NotTo(HaveOccurred())
+		cert, err := tls.X509KeyPair(certPem, keyPem)
+		Expect(err).NotTo(HaveOccurred())
+		named[commonName] = NamedCert{
+			CertFileName:       writeClientCredFile(certPem),
+			PrivateKeyFileName: writeClientCredFile(keyPem),
+			Cert:               cert,
+		}
+	}
+	return
+}
+
 func CertPool(certName string) *x509.CertPool {
 	certPool := x509.NewCertPool()
 	caCertificate := mapToX509Cert(certName)
@@ -111,6 +144,10 @@ func buildCaFile() (string, *rsa.PrivateKey, error) {
 }
 
 func buildCertPem(privKey *rsa.PrivateKey, caFilePath string) (cert []byte, key []byte, err error) {
+	return buildCertPemWithCommonName(privKey, caFilePath, "")
+}
+
+func buildCertPemWithCommonName(privKey *rsa.PrivateKey, caFilePath, commonName string) (cert []byte, key []byte, err error) {
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
 	if err != nil {
@@ -125,6 +162,7 @@ func buildCertPem(privKey *rsa.PrivateKey, caFilePath string) (cert []byte, key
 		Subject: pkix.Name{
 			Country:      []string{"USA"},
 			Organization: []string{"Cloud Foundry"},
+			CommonName:   commonName,
 		},
 		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
 		NotBefore:             now,
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"test/performance-sd/replay"
+	"time"
+
+	"code.cloudfoundry.org/cf-networking-helpers/mutualtls"
+	"code.cloudfoundry.org/clock"
+	"github.com/nats-io/gnatsd/server"
+	"github.com/nats-io/go-nats"
+)
+
+const usage = `usage: sd-replay <command> [flags]
+
+commands:
+  record      record register and unregister messages from NATS to a file
+  synthesize  write synthesized traffic with N hostnames and M IPs to a file
+  replay      replay traffic from a file to a service discovery controller
+              through NATS, and report lookup latency and staleness accuracy
+
+run sd-replay <command> -h for the flags of a command
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "record":
+		err = record(os.Args[2:])
+	case "synthesize":
+		err = synthesize(os.Args[2:])
+	case "replay":
+		err = replayTraffic(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func record(args []string) error {
+	flags := flag.NewFlagSet("record", flag.ExitOnError)
+	natsURL := flags.String("nats-url", "nats://127.0.0.1:4222", "URL of the NATS server, with credentials")
+	duration := flags.Duration("duration", time.Minute, "how long to record for")
+	out := flags.String("out", "", "file to write the traffic to")
+	flags.Parse(args)
+
+	if *out == "" {
+		return fmt.Errorf("must include a file with -out")
+	}
+
+	conn, err := nats.Connect(*natsURL)
+	if err != nil {
+		return fmt.Errorf("connect to nats: %s", err)
+	}
+	defer conn.Close()
+
+	recorder := &replay.Recorder{
+		Subscriber: conn,
+		Clock:      clock.NewClock(),
+	}
+	if err := recorder.Start(); err != nil {
+		return fmt.Errorf("subscribe: %s", err)
+	}
+
+	log.Printf("recording for %s", *duration)
+	select {
+	case <-time.After(*duration):
+	case <-interrupted():
+	}
+
+	messages := recorder.Messages()
+	if err := writeTraffic(*out, messages); err != nil {
+		return err
+	}
+	log.Printf("recorded %d messages to %s", len(messages), *out)
+	return nil
+}
+
+func synthesize(args []string) error {
+	flags := flag.NewFlagSet("synthesize", flag.ExitOnError)
+	config := replay.SynthesizeConfig{}
+	flags.IntVar(&config.Hostnames, "hostnames", 100, "number of hostnames")
+	flags.IntVar(&config.IPs, "ips", 1000, "number of IPs, spread over the hostnames")
+	flags.StringVar(&config.Domain, "domain", "apps.internal", "domain of the hostnames")
+	flags.DurationVar(&config.Duration, "duration", time.Minute, "duration of the traffic")
+	flags.Float64Var(&config.RegisterRate, "register-rate", 100, "register messages per second")
+	flags.Float64Var(&config.UnregisterRate, "unregister-rate", 0, "unregister messages per second")
+	out := flags.String("out", "", "file to write the traffic to")
+	flags.Parse(args)
+
+	if *out == "" {
+		return fmt.Errorf("must include a file with -out")
+	}
+
+	messages, err := replay.Synthesize(config)
+	if err != nil {
+		return err
+	}
+
+	if err := writeTraffic(*out, messages); err != nil {
+		return err
+	}
+	log.Printf("synthesized %d messages to %s", len(messages), *out)
+	return nil
+}
+
+type report struct {
+	Published int                     `json:"published"`
+	Duration  time.Duration           `json:"duration_ns"`
+	Latency   replay.LatencyReport    `json:"latency"`
+	Accuracy  []replay.AccuracyReport `json:"accuracy"`
+}
+
+func replayTraffic(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	in := flags.String("in", "", "file to read the traffic from")
+	natsURL := flags.String("nats-url", "nats://127.0.0.1:4222", "URL of the NATS server that the controller subscribes to, with credentials")
+	embeddedNatsPort := flags.Int("embedded-nats-port", 0, "start a NATS server on this port for the controller to subscribe to, instead of using -nats-url")
+	speed := flags.Float64("speed", 1, "replay speed relative to the recording, or 0 to publish as fast as possible")
+	sdcURL := flags.String("sdc-url", "https://127.0.0.1:8054", "URL of the service discovery controller")
+	sdcCACert := flags.String("sdc-ca-cert", "", "CA of the server certificate of the controller")
+	sdcClientCert := flags.String("sdc-client-cert", "", "client certificate for the controller")
+	sdcClientKey := flags.String("sdc-client-key", "", "client key for the controller")
+	sdcTimeout := flags.Duration("sdc-timeout", time.Minute, "how long to wait for the controller to answer lookups before replaying")
+	stalenessThreshold := flags.Duration("staleness-threshold", 180*time.Second, "staleness threshold of the controller")
+	pruningInterval := flags.Duration("pruning-interval", 60*time.Second, "pruning interval of the controller")
+	concurrency := flags.Int("lookup-concurrency", 4, "number of concurrent lookups while replaying")
+	accuracyChecks := flags.Int("accuracy-checks", 1, "number of times to check every hostname after replaying")
+	accuracyInterval := flags.Duration("accuracy-interval", 5*time.Second, "interval between the checks, the first of which is after one interval")
+	flags.Parse(args)
+
+	if *in == "" {
+		return fmt.Errorf("must include a file with -in")
+	}
+
+	messages, err := readTraffic(*in)
+	if err != nil {
+		return err
+	}
+
+	if *embeddedNatsPort != 0 {
+		natsServer, err := runNatsServer(*embeddedNatsPort)
+		if err != nil {
+			return err
+		}
+		defer natsServer.Shutdown()
+
+		*natsURL = fmt.Sprintf("nats://127.0.0.1:%d", *embeddedNatsPort)
+		log.Printf("started nats on %s", *natsURL)
+	}
+
+	conn, err := nats.Connect(*natsURL)
+	if err != nil {
+		return fmt.Errorf("connect to nats: %s", err)
+	}
+	defer conn.Close()
+
+	tlsConfig, err := mutualtls.NewClientTLSConfig(*sdcClientCert, *sdcClientKey, *sdcCACert)
+	if err != nil {
+		return fmt.Errorf("create tls config: %s", err)
+	}
+	sdcClient := &replay.SDCClient{
+		URL: *sdcURL,
+		Client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+			Timeout:   5 * time.Second,
+		},
+	}
+
+	realClock := clock.NewClock()
+	if err := waitForSDC(sdcClient, realClock, *sdcTimeout); err != nil {
+		return err
+	}
+
+	model := replay.NewModel(*stalenessThreshold, *pruningInterval)
+	replayer := &replay.Replayer{
+		Publisher: conn,
+		Clock:     realClock,
+		Speed:     *speed,
+		Model:     model,
+	}
+	prober := &replay.Prober{
+		Lookuper:    sdcClient,
+		Model:       model,
+		Clock:       realClock,
+		Concurrency: *concurrency,
+	}
+
+	stop := interrupted()
+	stopProbing := make(chan struct{})
+	latency := make(chan replay.LatencyReport)
+	go func() {
+		latency <- prober.Probe(stopProbing)
+	}()
+
+	log.Printf("replaying %d messages", len(messages))
+	start := realClock.Now()
+	published, err := replayer.Replay(messages, stop)
+	if err == nil {
+		err = conn.Flush()
+	}
+	duration := realClock.Since(start)
+	close(stopProbing)
+
+	result := report{
+		Published: published,
+		Duration:  duration,
+		Latency:   <-latency,
+		Accuracy:  []replay.AccuracyReport{},
+	}
+	if err != nil {
+		return fmt.Errorf("replay: %s", err)
+	}
+
+	for i := 0; i < *accuracyChecks; i++ {
+		select {
+		case <-realClock.After(*accuracyInterval):
+		case <-stop:
+			return printReport(result)
+		}
+		result.Accuracy = append(result.Accuracy, replay.CheckAccuracy(sdcClient, model, realClock.Now()))
+	}
+
+	return printReport(result)
+}
+
+func runNatsServer(port int) (*server.Server, error) {
+	natsServer := server.New(&server.Options{
+		Host:   "127.0.0.1",
+		Port:   port,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	go natsServer.Start()
+
+	if !natsServer.ReadyForConnections(10 * time.Second) {
+		return nil, fmt.Errorf("nats server did not start on port %d", port)
+	}
+	return natsServer, nil
+}
+
+// waitForSDC waits until the controller answers lookups, which it does not
+// until its address table is warm.
+func waitForSDC(sdcClient *replay.SDCClient, realClock clock.Clock, timeout time.Duration) error {
+	deadline := realClock.Now().Add(timeout)
+	for {
+		_, err := sdcClient.Lookup("sd-replay.apps.internal.")
+		if err == nil {
+			return nil
+		}
+		if realClock.Now().After(deadline) {
+			return fmt.Errorf("controller did not answer lookups: %s", err)
+		}
+		realClock.Sleep(time.Second)
+	}
+}
+
+func interrupted() <-chan struct{} {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	stop := make(chan struct{})
+	go func() {
+		<-signals
+		close(stop)
+	}()
+	return stop
+}
+
+func readTraffic(path string) ([]replay.Message, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open traffic: %s", err)
+	}
+	defer file.Close()
+
+	return replay.ReadMessages(file)
+}
+
+func writeTraffic(path string, messages []replay.Message) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create traffic: %s", err)
+	}
+	defer file.Close()
+
+	return replay.WriteMessages(file, messages)
+}
+
+func printReport(result report) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
@@ -0,0 +1,208 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+//go:generate counterfeiter -o fakes/lookuper.go --fake-name Lookuper . Lookuper
+type Lookuper interface {
+	Lookup(hostname string) ([]string, error)
+}
+
+// SDCClient looks up hostnames on the registration endpoint of a service
+// discovery controller.
+type SDCClient struct {
+	URL    string
+	Client *http.Client
+}
+
+type registration struct {
+	Hosts []struct {
+		IPAddress string `json:"ip_address"`
+	} `json:"hosts"`
+}
+
+// Lookup returns the IPs that the controller answers for hostname.
+func (c *SDCClient) Lookup(hostname string) ([]string, error) {
+	resp, err := c.Client.Get(fmt.Sprintf("%s/v1/registration/%s", c.URL, url.PathEscape(hostname)))
+	if err != nil {
+		return nil, fmt.Errorf("lookup %s: %s", hostname, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %s", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lookup %s: status %d: %s", hostname, resp.StatusCode, body)
+	}
+
+	var reg registration
+	if err := json.Unmarshal(body, &reg); err != nil {
+		return nil, fmt.Errorf("decode response: %s", err)
+	}
+
+	ips := []string{}
+	for _, host := range reg.Hosts {
+		ips = append(ips, host.IPAddress)
+	}
+	return ips, nil
+}
+
+// LatencyReport summarizes the durations of lookups.
+type LatencyReport struct {
+	Lookups int           `json:"lookups"`
+	Errors  int           `json:"errors"`
+	P50     time.Duration `json:"p50_ns"`
+	P95     time.Duration `json:"p95_ns"`
+	P99     time.Duration `json:"p99_ns"`
+	Max     time.Duration `json:"max_ns"`
+}
+
+// Prober looks up the hostnames of Model in turn with Concurrency
+// goroutines, to measure lookup latency while traffic is replayed.
+type Prober struct {
+	Lookuper    Lookuper
+	Model       *Model
+	Clock       clock.Clock
+	Concurrency int
+
+	mutex     sync.Mutex
+	durations []time.Duration
+	errors    int
+}
+
+// Probe looks up hostnames until stop is closed, and then returns the
+// latency of the lookups.
+func (p *Prober) Probe(stop <-chan struct{}) LatencyReport {
+	wg := sync.WaitGroup{}
+	for i := 0; i < p.Concurrency; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			p.probe(worker, stop)
+		}(i)
+	}
+	wg.Wait()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return latencyReport(p.durations, p.errors)
+}
+
+func (p *Prober) probe(worker int, stop <-chan struct{}) {
+	for n := worker; ; n += p.Concurrency {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		hostnames := p.Model.Hostnames()
+		if len(hostnames) == 0 {
+			select {
+			case <-stop:
+				return
+			case <-p.Clock.After(10 * time.Millisecond):
+			}
+			continue
+		}
+
+		start := p.Clock.Now()
+		_, err := p.Lookuper.Lookup(hostnames[n%len(hostnames)])
+		duration := p.Clock.Since(start)
+
+		p.mutex.Lock()
+		if err != nil {
+			p.errors++
+		} else {
+			p.durations = append(p.durations, duration)
+		}
+		p.mutex.Unlock()
+	}
+}
+
+func latencyReport(durations []time.Duration, errors int) LatencyReport {
+	report := LatencyReport{
+		Lookups: len(durations) + errors,
+		Errors:  errors,
+	}
+	if len(durations) == 0 {
+		return report
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	report.P50 = percentile(sorted, 50)
+	report.P95 = percentile(sorted, 95)
+	report.P99 = percentile(sorted, 99)
+	report.Max = sorted[len(sorted)-1]
+	return report
+}
+
+// percentile returns the duration at or below which p percent of the
+// sorted durations fall.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	index := (len(sorted)*p+99)/100 - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index]
+}
+
+// AccuracyReport compares the answers of the controller with the model.
+// Missing IPs were registered within the staleness threshold but were not
+// answered. Stale IPs were answered although they were unregistered or
+// should have been pruned.
+type AccuracyReport struct {
+	Hostnames int `json:"hostnames"`
+	Errors    int `json:"errors"`
+	Expected  int `json:"expected"`
+	Missing   int `json:"missing"`
+	Stale     int `json:"stale"`
+}
+
+// CheckAccuracy looks up every hostname of model once, and compares the
+// answers with what the model expects at now.
+func CheckAccuracy(lookuper Lookuper, model *Model, now time.Time) AccuracyReport {
+	report := AccuracyReport{}
+	for _, hostname := range model.Hostnames() {
+		report.Hostnames++
+
+		ips, err := lookuper.Lookup(hostname)
+		if err != nil {
+			report.Errors++
+			continue
+		}
+
+		expected, allowed := model.Expected(hostname, now)
+		report.Expected += len(expected)
+
+		answered := map[string]bool{}
+		for _, ip := range ips {
+			answered[ip] = true
+			if !allowed[ip] {
+				report.Stale++
+			}
+		}
+		for ip := range expected {
+			if !answered[ip] {
+				report.Missing++
+			}
+		}
+	}
+	return report
+}
@@ -0,0 +1,126 @@
+package replay_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"test/performance-sd/replay"
+	"test/performance-sd/replay/fakes"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SDCClient", func() {
+	var (
+		server    *httptest.Server
+		sdcClient *replay.SDCClient
+		status    int
+		path      string
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.WriteHeader(status)
+			w.Write([]byte(`{"hosts":[{"ip_address":"10.0.0.1"},{"ip_address":"10.0.0.2"}]}`))
+		}))
+		sdcClient = &replay.SDCClient{URL: server.URL, Client: http.DefaultClient}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("returns the IPs of the hostname", func() {
+		Expect(sdcClient.Lookup("app.apps.internal.")).To(Equal([]string{"10.0.0.1", "10.0.0.2"}))
+		Expect(path).To(Equal("/v1/registration/app.apps.internal."))
+	})
+
+	Context("when the controller does not answer with 200", func() {
+		It("returns an error", func() {
+			status = http.StatusInternalServerError
+			_, err := sdcClient.Lookup("app.apps.internal.")
+			Expect(err).To(MatchError(ContainSubstring("status 500")))
+		})
+	})
+})
+
+var _ = Describe("CheckAccuracy", func() {
+	It("counts missing and stale IPs", func() {
+		now := time.Now()
+		model := replay.NewModel(10*time.Second, 5*time.Second)
+		register := func(ip, hostname string, at time.Time) {
+			data, _ := json.Marshal(map[string]interface{}{"host": ip, "uris": []string{hostname}})
+			Expect(model.Apply(replay.Message{Subject: replay.RegisterSubject, Data: data}, at)).To(Succeed())
+		}
+		register("10.0.0.1", "a.apps.internal", now)
+		register("10.0.0.2", "a.apps.internal", now)
+		register("10.0.0.3", "a.apps.internal", now.Add(-20*time.Second))
+		register("10.0.0.4", "b.apps.internal", now)
+		register("10.0.0.5", "c.apps.internal", now)
+
+		lookuper := &fakes.Lookuper{}
+		lookuper.LookupStub = func(hostname string) ([]string, error) {
+			switch hostname {
+			case "a.apps.internal.":
+				return []string{"10.0.0.1", "10.0.0.3"}, nil
+			case "b.apps.internal.":
+				return []string{"10.0.0.4"}, nil
+			}
+			return nil, errors.New("potato")
+		}
+
+		Expect(replay.CheckAccuracy(lookuper, model, now)).To(Equal(replay.AccuracyReport{
+			Hostnames: 3,
+			Errors:    1,
+			Expected:  3,
+			Missing:   1,
+			Stale:     1,
+		}))
+	})
+})
+
+var _ = Describe("Prober", func() {
+	It("looks up the hostnames of the model until stopped", func() {
+		model := replay.NewModel(time.Minute, time.Minute)
+		data := json.RawMessage(`{"host":"10.0.0.1","uris":["a.apps.internal","b.apps.internal"]}`)
+		Expect(model.Apply(replay.Message{Subject: replay.RegisterSubject, Data: data}, time.Now())).To(Succeed())
+
+		lookuper := &fakes.Lookuper{}
+		lookuper.LookupStub = func(hostname string) ([]string, error) {
+			if hostname == "b.apps.internal." {
+				return nil, errors.New("potato")
+			}
+			return []string{"10.0.0.1"}, nil
+		}
+
+		prober := &replay.Prober{
+			Lookuper:    lookuper,
+			Model:       model,
+			Clock:       clock.NewClock(),
+			Concurrency: 2,
+		}
+
+		stop := make(chan struct{})
+		reports := make(chan replay.LatencyReport)
+		go func() {
+			reports <- prober.Probe(stop)
+		}()
+
+		Eventually(lookuper.LookupCallCount).Should(BeNumerically(">", 10))
+		close(stop)
+
+		var report replay.LatencyReport
+		Eventually(reports).Should(Receive(&report))
+		Expect(report.Lookups).To(BeNumerically(">", 10))
+		Expect(report.Errors).To(BeNumerically(">", 0))
+		Expect(report.Errors).To(BeNumerically("<", report.Lookups))
+		Expect(report.P50).To(BeNumerically("<=", report.P99))
+		Expect(report.P99).To(BeNumerically("<=", report.Max))
+	})
+})
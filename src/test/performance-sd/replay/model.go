@@ -0,0 +1,110 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Model is what the service discovery controller should answer after the
+// messages applied so far. An IP is expected while it was registered less
+// than the staleness threshold ago, and must be gone once it was
+// unregistered, or once it is older than the staleness threshold plus the
+// pruning interval. In between, the controller may still answer with it.
+type Model struct {
+	StalenessThreshold time.Duration
+	PruningInterval    time.Duration
+
+	mutex   sync.RWMutex
+	entries map[string]map[string]modelEntry
+}
+
+type modelEntry struct {
+	registered time.Time
+	unhealthy  bool
+}
+
+func NewModel(stalenessThreshold, pruningInterval time.Duration) *Model {
+	return &Model{
+		StalenessThreshold: stalenessThreshold,
+		PruningInterval:    pruningInterval,
+		entries:            map[string]map[string]modelEntry{},
+	}
+}
+
+// Apply updates the model with a message published at publishedAt.
+// Messages on other subjects are ignored.
+func (m *Model) Apply(message Message, publishedAt time.Time) error {
+	if message.Subject != RegisterSubject && message.Subject != UnregisterSubject {
+		return nil
+	}
+
+	var registry registryMessage
+	if err := json.Unmarshal(message.Data, &registry); err != nil {
+		return fmt.Errorf("decode message: %s", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, uri := range registry.URIs {
+		hostname := fqdn(uri)
+		if message.Subject == UnregisterSubject {
+			delete(m.entries[hostname], registry.IP)
+			continue
+		}
+
+		if m.entries[hostname] == nil {
+			m.entries[hostname] = map[string]modelEntry{}
+		}
+		m.entries[hostname][registry.IP] = modelEntry{
+			registered: publishedAt,
+			unhealthy:  registry.Healthy != nil && !*registry.Healthy,
+		}
+	}
+	return nil
+}
+
+// Hostnames returns every hostname that has been registered, sorted.
+func (m *Model) Hostnames() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	hostnames := []string{}
+	for hostname := range m.entries {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// Expected returns the IPs that the controller must answer for hostname at
+// now, and the IPs that it may answer, which include the expected ones.
+// Unhealthy IPs are never expected, as the controller omits them.
+func (m *Model) Expected(hostname string, now time.Time) (expected, allowed map[string]bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	expected = map[string]bool{}
+	allowed = map[string]bool{}
+	for ip, entry := range m.entries[fqdn(hostname)] {
+		age := now.Sub(entry.registered)
+		if age < m.StalenessThreshold+m.PruningInterval {
+			allowed[ip] = true
+		}
+		if age < m.StalenessThreshold && !entry.unhealthy {
+			expected[ip] = true
+		}
+	}
+	return expected, allowed
+}
+
+func fqdn(hostname string) string {
+	if strings.HasSuffix(hostname, ".") {
+		return hostname
+	}
+	return hostname + "."
+}
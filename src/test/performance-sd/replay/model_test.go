@@ -0,0 +1,73 @@
+package replay_test
+
+import (
+	"encoding/json"
+	"test/performance-sd/replay"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Model", func() {
+	var (
+		model *replay.Model
+		start time.Time
+	)
+
+	message := func(subject, data string) replay.Message {
+		return replay.Message{Subject: subject, Data: json.RawMessage(data)}
+	}
+
+	BeforeEach(func() {
+		model = replay.NewModel(10*time.Second, 5*time.Second)
+		start = time.Now()
+
+		Expect(model.Apply(message(replay.RegisterSubject, `{"host":"10.0.0.1","uris":["a.apps.internal","b.apps.internal."]}`), start)).To(Succeed())
+		Expect(model.Apply(message(replay.RegisterSubject, `{"host":"10.0.0.2","uris":["a.apps.internal"]}`), start.Add(5*time.Second))).To(Succeed())
+	})
+
+	It("returns the registered hostnames", func() {
+		Expect(model.Hostnames()).To(Equal([]string{"a.apps.internal.", "b.apps.internal."}))
+	})
+
+	It("expects IPs registered within the staleness threshold", func() {
+		expected, allowed := model.Expected("a.apps.internal", start.Add(time.Second))
+		Expect(expected).To(Equal(map[string]bool{"10.0.0.1": true, "10.0.0.2": true}))
+		Expect(allowed).To(Equal(expected))
+	})
+
+	It("allows stale IPs until they should have been pruned", func() {
+		expected, allowed := model.Expected("a.apps.internal.", start.Add(12*time.Second))
+		Expect(expected).To(Equal(map[string]bool{"10.0.0.2": true}))
+		Expect(allowed).To(Equal(map[string]bool{"10.0.0.1": true, "10.0.0.2": true}))
+
+		expected, allowed = model.Expected("a.apps.internal.", start.Add(16*time.Second))
+		Expect(expected).To(BeEmpty())
+		Expect(allowed).To(Equal(map[string]bool{"10.0.0.2": true}))
+	})
+
+	It("neither expects nor allows unregistered IPs", func() {
+		Expect(model.Apply(message(replay.UnregisterSubject, `{"host":"10.0.0.1","uris":["a.apps.internal"]}`), start)).To(Succeed())
+
+		expected, allowed := model.Expected("a.apps.internal.", start.Add(6*time.Second))
+		Expect(expected).To(Equal(map[string]bool{"10.0.0.2": true}))
+		Expect(allowed).To(Equal(expected))
+	})
+
+	It("allows but does not expect unhealthy IPs", func() {
+		Expect(model.Apply(message(replay.RegisterSubject, `{"host":"10.0.0.3","uris":["b.apps.internal"],"healthy":false}`), start)).To(Succeed())
+
+		expected, allowed := model.Expected("b.apps.internal.", start)
+		Expect(expected).To(Equal(map[string]bool{"10.0.0.1": true}))
+		Expect(allowed).To(Equal(map[string]bool{"10.0.0.1": true, "10.0.0.3": true}))
+	})
+
+	It("ignores other subjects", func() {
+		Expect(model.Apply(message("service-discovery.greet", `garbage`), start)).To(Succeed())
+	})
+
+	It("returns an error for invalid messages", func() {
+		Expect(model.Apply(message(replay.RegisterSubject, `garbage`), start)).To(MatchError(ContainSubstring("decode message")))
+	})
+})
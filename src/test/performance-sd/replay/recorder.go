@@ -0,0 +1,65 @@
+package replay
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/nats-io/go-nats"
+)
+
+//go:generate counterfeiter -o fakes/subscriber.go --fake-name Subscriber . Subscriber
+type Subscriber interface {
+	Subscribe(subject string, handler nats.MsgHandler) (*nats.Subscription, error)
+}
+
+// Recorder records the register and unregister messages published by the
+// route emitters, so that they can be replayed later.
+type Recorder struct {
+	Subscriber Subscriber
+	Clock      clock.Clock
+
+	mutex    sync.Mutex
+	start    time.Time
+	messages []Message
+}
+
+// Start subscribes to the register and unregister subjects. Offsets are
+// relative to when Start is called.
+func (r *Recorder) Start() error {
+	r.mutex.Lock()
+	r.start = r.Clock.Now()
+	r.messages = []Message{}
+	r.mutex.Unlock()
+
+	for _, subject := range []string{RegisterSubject, UnregisterSubject} {
+		if _, err := r.Subscriber.Subscribe(subject, r.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Messages returns the messages recorded so far.
+func (r *Recorder) Messages() []Message {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	messages := make([]Message, len(r.messages))
+	copy(messages, r.messages)
+	return messages
+}
+
+func (r *Recorder) record(msg *nats.Msg) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	data := make([]byte, len(msg.Data))
+	copy(data, msg.Data)
+
+	r.messages = append(r.messages, Message{
+		Offset:  r.Clock.Since(r.start),
+		Subject: msg.Subject,
+		Data:    data,
+	})
+}
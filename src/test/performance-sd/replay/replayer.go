@@ -0,0 +1,86 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+//go:generate counterfeiter -o fakes/publisher.go --fake-name Publisher . Publisher
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// Replayer publishes traffic with the timing it was recorded with, divided
+// by Speed. A Speed of 0 publishes the messages as fast as possible.
+// Each message is applied to Model once it has been published.
+type Replayer struct {
+	Publisher Publisher
+	Clock     clock.Clock
+	Speed     float64
+	Model     *Model
+}
+
+// Replay publishes messages until they have all been published, or stop
+// is closed. It returns how many messages were published. The
+// endpoint_updated_at_ns of register messages is set to when they are
+// published, so that the transit time metrics of the controller stay
+// meaningful.
+func (r *Replayer) Replay(messages []Message, stop <-chan struct{}) (int, error) {
+	start := r.Clock.Now()
+
+	for i, message := range messages {
+		if r.Speed > 0 {
+			publishAt := start.Add(time.Duration(float64(message.Offset) / r.Speed))
+			if wait := publishAt.Sub(r.Clock.Now()); wait > 0 {
+				timer := r.Clock.NewTimer(wait)
+				select {
+				case <-timer.C():
+				case <-stop:
+					timer.Stop()
+					return i, nil
+				}
+			}
+		}
+
+		select {
+		case <-stop:
+			return i, nil
+		default:
+		}
+
+		publishedAt := r.Clock.Now()
+		data := message.Data
+		if message.Subject == RegisterSubject {
+			var err error
+			data, err = stamp(data, publishedAt)
+			if err != nil {
+				return i, err
+			}
+		}
+
+		if err := r.Publisher.Publish(message.Subject, data); err != nil {
+			return i, fmt.Errorf("publish message: %s", err)
+		}
+
+		if err := r.Model.Apply(message, publishedAt); err != nil {
+			return i + 1, err
+		}
+	}
+
+	return len(messages), nil
+}
+
+// stamp sets the endpoint_updated_at_ns of a message, and leaves its other
+// fields as they are.
+func stamp(data []byte, at time.Time) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("decode message: %s", err)
+	}
+
+	fields["endpoint_updated_at_ns"] = json.RawMessage(fmt.Sprintf("%d", at.UnixNano()))
+	return json.Marshal(fields)
+}
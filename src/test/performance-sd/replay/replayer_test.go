@@ -0,0 +1,132 @@
+package replay_test
+
+import (
+	"encoding/json"
+	"errors"
+	"test/performance-sd/replay"
+	"test/performance-sd/replay/fakes"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/nats-io/go-nats"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Replayer", func() {
+	var (
+		replayer  *replay.Replayer
+		publisher *fakes.Publisher
+		fakeClock *fakeclock.FakeClock
+		model     *replay.Model
+		messages  []replay.Message
+	)
+
+	BeforeEach(func() {
+		publisher = &fakes.Publisher{}
+		fakeClock = fakeclock.NewFakeClock(time.Unix(100, 0))
+		model = replay.NewModel(time.Minute, time.Minute)
+
+		replayer = &replay.Replayer{
+			Publisher: publisher,
+			Clock:     fakeClock,
+			Speed:     2,
+			Model:     model,
+		}
+
+		messages = []replay.Message{
+			{Offset: 0, Subject: replay.RegisterSubject, Data: json.RawMessage(`{"host":"10.0.0.1","uris":["a.apps.internal"],"app":"some-app"}`)},
+			{Offset: 4 * time.Second, Subject: replay.UnregisterSubject, Data: json.RawMessage(`{"host":"10.0.0.1","uris":["a.apps.internal"]}`)},
+		}
+	})
+
+	It("publishes the messages with their timing divided by the speed", func() {
+		done := make(chan int)
+		go func() {
+			defer GinkgoRecover()
+			published, err := replayer.Replay(messages, make(chan struct{}))
+			Expect(err).NotTo(HaveOccurred())
+			done <- published
+		}()
+
+		Eventually(publisher.PublishCallCount).Should(Equal(1))
+		subject, data := publisher.PublishArgsForCall(0)
+		Expect(subject).To(Equal(replay.RegisterSubject))
+		Expect(data).To(MatchJSON(`{"host":"10.0.0.1","uris":["a.apps.internal"],"app":"some-app","endpoint_updated_at_ns":100000000000}`))
+
+		expected, _ := model.Expected("a.apps.internal.", fakeClock.Now())
+		Expect(expected).To(HaveKey("10.0.0.1"))
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Consistently(publisher.PublishCallCount).Should(Equal(1))
+
+		fakeClock.Increment(time.Second)
+		Eventually(done).Should(Receive(Equal(2)))
+
+		subject, data = publisher.PublishArgsForCall(1)
+		Expect(subject).To(Equal(replay.UnregisterSubject))
+		Expect(data).To(MatchJSON(`{"host":"10.0.0.1","uris":["a.apps.internal"]}`))
+
+		expected, _ = model.Expected("a.apps.internal.", fakeClock.Now())
+		Expect(expected).To(BeEmpty())
+	})
+
+	It("stops when told to", func() {
+		stop := make(chan struct{})
+		done := make(chan int)
+		go func() {
+			defer GinkgoRecover()
+			published, err := replayer.Replay(messages, stop)
+			Expect(err).NotTo(HaveOccurred())
+			done <- published
+		}()
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		close(stop)
+		Eventually(done).Should(Receive(Equal(1)))
+	})
+
+	Context("when the speed is 0", func() {
+		It("publishes the messages without waiting", func() {
+			replayer.Speed = 0
+			Expect(replayer.Replay(messages, make(chan struct{}))).To(Equal(2))
+		})
+	})
+
+	Context("when publishing fails", func() {
+		It("returns an error", func() {
+			publisher.PublishReturns(errors.New("potato"))
+			published, err := replayer.Replay(messages, make(chan struct{}))
+			Expect(err).To(MatchError("publish message: potato"))
+			Expect(published).To(Equal(0))
+		})
+	})
+})
+
+var _ = Describe("Recorder", func() {
+	It("records register and unregister messages with their offsets", func() {
+		subscriber := &fakes.Subscriber{}
+		fakeClock := fakeclock.NewFakeClock(time.Now())
+		recorder := &replay.Recorder{
+			Subscriber: subscriber,
+			Clock:      fakeClock,
+		}
+
+		Expect(recorder.Start()).To(Succeed())
+		Expect(subscriber.SubscribeCallCount()).To(Equal(2))
+		registerSubject, registerHandler := subscriber.SubscribeArgsForCall(0)
+		unregisterSubject, unregisterHandler := subscriber.SubscribeArgsForCall(1)
+		Expect(registerSubject).To(Equal(replay.RegisterSubject))
+		Expect(unregisterSubject).To(Equal(replay.UnregisterSubject))
+
+		fakeClock.Increment(time.Second)
+		registerHandler(&nats.Msg{Subject: replay.RegisterSubject, Data: []byte(`{"host":"10.0.0.1"}`)})
+		fakeClock.Increment(time.Second)
+		unregisterHandler(&nats.Msg{Subject: replay.UnregisterSubject, Data: []byte(`{"host":"10.0.0.1"}`)})
+
+		Expect(recorder.Messages()).To(Equal([]replay.Message{
+			{Offset: time.Second, Subject: replay.RegisterSubject, Data: json.RawMessage(`{"host":"10.0.0.1"}`)},
+			{Offset: 2 * time.Second, Subject: replay.UnregisterSubject, Data: json.RawMessage(`{"host":"10.0.0.1"}`)},
+		}))
+	})
+})
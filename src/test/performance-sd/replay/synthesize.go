@@ -0,0 +1,94 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SynthesizeConfig is the traffic to synthesize. IP i is registered under
+// hostname i modulo Hostnames, so each hostname gets about IPs/Hostnames
+// entries.
+type SynthesizeConfig struct {
+	Hostnames      int
+	IPs            int
+	Domain         string
+	Duration       time.Duration
+	RegisterRate   float64
+	UnregisterRate float64
+}
+
+// Synthesize returns route emitter traffic for config. Register messages
+// are published at RegisterRate per second, cycling through the IPs like
+// the periodic emits of the route emitter, and unregister messages at
+// UnregisterRate per second, cycling through the IPs from the other end.
+func Synthesize(config SynthesizeConfig) ([]Message, error) {
+	if config.Hostnames < 1 || config.IPs < 1 {
+		return nil, fmt.Errorf("hostnames and ips must be at least 1")
+	}
+	if config.IPs > 1<<24 {
+		return nil, fmt.Errorf("ips must be at most %d", 1<<24)
+	}
+
+	messages := []Message{}
+
+	registers, err := synthesizeSubject(config, RegisterSubject, config.RegisterRate, func(n int) int {
+		return n % config.IPs
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages = append(messages, registers...)
+
+	unregisters, err := synthesizeSubject(config, UnregisterSubject, config.UnregisterRate, func(n int) int {
+		return config.IPs - 1 - n%config.IPs
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages = append(messages, unregisters...)
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Offset < messages[j].Offset
+	})
+	return messages, nil
+}
+
+func synthesizeSubject(config SynthesizeConfig, subject string, rate float64, ipIndex func(n int) int) ([]Message, error) {
+	messages := []Message{}
+	if rate <= 0 {
+		return messages, nil
+	}
+
+	interval := time.Duration(float64(time.Second) / rate)
+	for n := 0; time.Duration(n)*interval < config.Duration; n++ {
+		index := ipIndex(n)
+		data, err := json.Marshal(registryMessage{
+			IP:   SynthesizedIP(index),
+			Port: 8080,
+			URIs: []string{SynthesizedHostname(index%config.Hostnames, config.Domain)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("encode message: %s", err)
+		}
+
+		messages = append(messages, Message{
+			Offset:  time.Duration(n) * interval,
+			Subject: subject,
+			Data:    data,
+		})
+	}
+	return messages, nil
+}
+
+// SynthesizedIP returns the IP of the index-th synthesized instance, in
+// 10.0.0.0/8.
+func SynthesizedIP(index int) string {
+	return fmt.Sprintf("10.%d.%d.%d", index>>16&0xff, index>>8&0xff, index&0xff)
+}
+
+// SynthesizedHostname returns the index-th synthesized hostname in domain.
+func SynthesizedHostname(index int, domain string) string {
+	return fmt.Sprintf("app-%d.%s", index, domain)
+}
@@ -0,0 +1,66 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	RegisterSubject   = "service-discovery.register"
+	UnregisterSubject = "service-discovery.unregister"
+)
+
+// Message is a message published by the route emitter, with when it was
+// published relative to the first message of the traffic.
+type Message struct {
+	Offset  time.Duration   `json:"offset_ns"`
+	Subject string          `json:"subject"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// registryMessage is the part of a route emitter message that decides what
+// the service discovery controller should answer.
+type registryMessage struct {
+	IP      string   `json:"host"`
+	Port    uint16   `json:"port,omitempty"`
+	URIs    []string `json:"uris"`
+	Healthy *bool    `json:"healthy,omitempty"`
+}
+
+// WriteMessages writes messages as one JSON object per line.
+func WriteMessages(w io.Writer, messages []Message) error {
+	encoder := json.NewEncoder(w)
+	for _, message := range messages {
+		if err := encoder.Encode(message); err != nil {
+			return fmt.Errorf("encode message: %s", err)
+		}
+	}
+	return nil
+}
+
+// ReadMessages reads messages written by WriteMessages.
+func ReadMessages(r io.Reader) ([]Message, error) {
+	messages := []Message{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var message Message
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return nil, fmt.Errorf("decode message on line %d: %s", line, err)
+		}
+		messages = append(messages, message)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read messages: %s", err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,80 @@
+package replay_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"test/performance-sd/replay"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Traffic", func() {
+	It("reads the messages that were written", func() {
+		messages := []replay.Message{
+			{Offset: 0, Subject: replay.RegisterSubject, Data: json.RawMessage(`{"host":"10.0.0.1","uris":["app.apps.internal"]}`)},
+			{Offset: 2 * time.Second, Subject: replay.UnregisterSubject, Data: json.RawMessage(`{"host":"10.0.0.1","uris":["app.apps.internal"]}`)},
+		}
+
+		buffer := &bytes.Buffer{}
+		Expect(replay.WriteMessages(buffer, messages)).To(Succeed())
+		Expect(strings.Count(buffer.String(), "\n")).To(Equal(2))
+
+		Expect(replay.ReadMessages(buffer)).To(Equal(messages))
+	})
+
+	It("returns an error with the line of an invalid message", func() {
+		_, err := replay.ReadMessages(strings.NewReader("{\"subject\":\"a\",\"data\":{}}\n\ngarbage\n"))
+		Expect(err).To(MatchError(ContainSubstring("decode message on line 3")))
+	})
+})
+
+var _ = Describe("Synthesize", func() {
+	It("registers each IP under a hostname at the register rate", func() {
+		messages, err := replay.Synthesize(replay.SynthesizeConfig{
+			Hostnames:    2,
+			IPs:          3,
+			Domain:       "apps.internal",
+			Duration:     time.Second,
+			RegisterRate: 4,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages).To(HaveLen(4))
+
+		Expect(messages[1].Offset).To(Equal(250 * time.Millisecond))
+		Expect(messages[1].Subject).To(Equal(replay.RegisterSubject))
+		Expect(messages[1].Data).To(MatchJSON(`{"host":"10.0.0.1","port":8080,"uris":["app-1.apps.internal"]}`))
+		Expect(messages[2].Data).To(MatchJSON(`{"host":"10.0.0.2","port":8080,"uris":["app-0.apps.internal"]}`))
+		Expect(messages[3].Data).To(MatchJSON(`{"host":"10.0.0.0","port":8080,"uris":["app-0.apps.internal"]}`))
+	})
+
+	It("interleaves unregister messages by offset", func() {
+		messages, err := replay.Synthesize(replay.SynthesizeConfig{
+			Hostnames:      1,
+			IPs:            3,
+			Domain:         "apps.internal",
+			Duration:       time.Second,
+			RegisterRate:   2,
+			UnregisterRate: 1,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		subjects := []string{}
+		for _, message := range messages {
+			subjects = append(subjects, message.Subject)
+		}
+		Expect(subjects).To(Equal([]string{replay.RegisterSubject, replay.UnregisterSubject, replay.RegisterSubject}))
+		Expect(messages[1].Data).To(MatchJSON(`{"host":"10.0.0.2","port":8080,"uris":["app-0.apps.internal"]}`))
+	})
+
+	It("returns an error without hostnames or IPs", func() {
+		_, err := replay.Synthesize(replay.SynthesizeConfig{Hostnames: 0, IPs: 1})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("spreads IPs over 10.0.0.0/8", func() {
+		Expect(replay.SynthesizedIP(65536 + 256 + 7)).To(Equal("10.1.1.7"))
+	})
+})